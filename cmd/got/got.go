@@ -10,7 +10,7 @@ import (
 //var sum string
 
 func main() {
-	if err := cmd.GotCmd.Execute(); err != nil {
+	if err := cmd.Execute(); err != nil {
 		os.Exit(1)
 	}
 	/*//fmt.Println(g.HashObject([]byte("test content"), true, objects.TypeBlob))
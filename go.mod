@@ -8,4 +8,5 @@ require (
 	github.com/magiconair/properties v1.8.1
 	github.com/pkg/errors v0.9.1
 	github.com/spf13/cobra v1.1.1
+	github.com/stretchr/testify v1.3.0
 )
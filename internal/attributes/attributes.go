@@ -0,0 +1,257 @@
+// Package attributes parses .gotattributes files, which assign attributes
+// such as 'text', 'eol=lf' or 'diff=golang' to the paths matching their
+// patterns, like git's gitattributes.
+package attributes
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"got/internal/pathspec"
+)
+
+// The name of the attributes file of a directory of the working tree
+const FileName = ".gotattributes"
+
+// The state of an attribute for a path. Besides being set, unset or
+// unspecified an attribute can have a value, e.g. 'lf' for 'eol=lf'. As in
+// git a value of 'set' or 'unset' can't be told apart from the states.
+type Value string
+
+const (
+	Unspecified Value = ""
+	Set         Value = "set"
+	Unset       Value = "unset"
+)
+
+func (v Value) String() string {
+	if v == Unspecified {
+		return "unspecified"
+	}
+	return string(v)
+}
+
+// Reports whether the attribute has a value other than set, unset or
+// unspecified
+func (v Value) IsValue() bool {
+	return v != Unspecified && v != Set && v != Unset
+}
+
+// An attribute as written in an attributes file, 'text', '-text', '!text' or
+// 'eol=lf'
+type assignment struct {
+	name  string
+	value Value
+}
+
+// A line of an attributes file
+type rule struct {
+	// The directory of the file, relative to the root of the repository
+	// with a trailing separator, or empty for the root
+	dir     string
+	pattern string
+	// Set if the pattern contains a separator, in which case it's matched
+	// against the path relative to dir instead of against the basename
+	anchored bool
+	attrs    []assignment
+}
+
+// Reads the attributes file of a directory, given relative to the root of
+// the repository and empty for the root. Returns nil if there's no file.
+type Reader func(dir string) ([]byte, error)
+
+// The attributes of the files of a repository. The attributes files of the
+// directories a path is in are read when the path is first looked up, with
+// the files of deeper directories taking precedence and the lines further
+// down a file taking precedence over the ones above.
+type Attributes struct {
+	read Reader
+	// The parsed files by directory
+	dirs map[string][]rule
+	// The rules that take precedence over all files, from
+	// .got/info/attributes
+	info   []rule
+	macros map[string][]assignment
+}
+
+// Returns the attributes read by read, which may be nil if there are no
+// files, overridden by the rules in info. Macros, e.g.
+// '[attr]binary -diff -merge -text', can be defined in info and the file at
+// the root.
+func New(read Reader, info []byte) *Attributes {
+	a := &Attributes{
+		read: read,
+		dirs: make(map[string][]rule),
+		macros: map[string][]assignment{
+			"binary": {{"diff", Unset}, {"merge", Unset}, {"text", Unset}},
+		},
+	}
+	a.info = a.parse("", info, true)
+	return a
+}
+
+// Returns the attributes that are set, unset or have a value for the path,
+// which is relative to the root of the repository
+func (a *Attributes) Lookup(path string) (map[string]Value, error) {
+	rules, err := a.rulesFor(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]Value)
+	for _, r := range rules {
+		if !r.matches(path) {
+			continue
+		}
+		for _, as := range r.attrs {
+			a.assign(values, as, 0)
+		}
+	}
+	return values, nil
+}
+
+// Returns the state of a single attribute of the path
+func (a *Attributes) Get(path, name string) (Value, error) {
+	values, err := a.Lookup(path)
+	if err != nil {
+		return Unspecified, err
+	}
+	return values[name], nil
+}
+
+// Returns the names of the attributes in values, sorted
+func Names(values map[string]Value) []string {
+	var names []string
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// The depth up to which macros may use other macros, which stops cycles
+const maxMacroDepth = 8
+
+func (a *Attributes) assign(values map[string]Value, as assignment, depth int) {
+	if as.value == Unspecified {
+		delete(values, as.name)
+	} else {
+		values[as.name] = as.value
+	}
+	if macro, ok := a.macros[as.name]; ok && as.value == Set && depth < maxMacroDepth {
+		for _, m := range macro {
+			a.assign(values, m, depth+1)
+		}
+	}
+}
+
+// Returns the rules that may apply to the path in increasing precedence
+func (a *Attributes) rulesFor(path string) ([]rule, error) {
+	dirs := []string{""}
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' {
+			dirs = append(dirs, path[:i])
+		}
+	}
+	var rules []rule
+	for _, dir := range dirs {
+		rs, ok := a.dirs[dir]
+		if !ok && a.read != nil {
+			bs, err := a.read(dir)
+			if err != nil {
+				return nil, err
+			}
+			rs = a.parse(dir, bs, dir == "")
+			a.dirs[dir] = rs
+		}
+		rules = append(rules, rs...)
+	}
+	return append(rules, a.info...), nil
+}
+
+// Parses an attributes file of the directory. Lines with a pattern that
+// can't match files, such as negated patterns or ones ending in a separator,
+// are ignored like git does.
+func (a *Attributes) parse(dir string, bs []byte, allowMacros bool) []rule {
+	if dir != "" {
+		dir += "/"
+	}
+	var rules []rule
+	for _, line := range strings.Split(string(bs), "\n") {
+		line = strings.TrimSpace(strings.TrimSuffix(line, "\r"))
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		pattern, rest := splitPattern(line)
+		fields := strings.Fields(rest)
+		var attrs []assignment
+		for _, f := range fields {
+			attrs = append(attrs, parseAssignment(f))
+		}
+		if strings.HasPrefix(pattern, "[attr]") {
+			if allowMacros {
+				a.macros[strings.TrimPrefix(pattern, "[attr]")] = attrs
+			}
+			continue
+		}
+		if pattern == "" || pattern[0] == '!' || strings.HasSuffix(pattern, "/") {
+			continue
+		}
+		r := rule{dir: dir, pattern: pattern, attrs: attrs}
+		if strings.Contains(pattern, "/") {
+			r.anchored = true
+			r.pattern = strings.TrimPrefix(pattern, "/")
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// Splits a line into the pattern, which may be quoted, and the attributes
+func splitPattern(line string) (string, string) {
+	if line[0] == '"' {
+		for i := 1; i < len(line); i++ {
+			if line[i] == '\\' {
+				i++
+				continue
+			}
+			if line[i] == '"' {
+				pattern, err := strconv.Unquote(line[:i+1])
+				if err != nil {
+					return "", ""
+				}
+				return pattern, line[i+1:]
+			}
+		}
+		return "", ""
+	}
+	i := strings.IndexAny(line, " \t")
+	if i < 0 {
+		return line, ""
+	}
+	return line[:i], line[i:]
+}
+
+func parseAssignment(s string) assignment {
+	switch {
+	case s[0] == '-':
+		return assignment{s[1:], Unset}
+	case s[0] == '!':
+		return assignment{s[1:], Unspecified}
+	}
+	if i := strings.IndexByte(s, '='); i >= 0 {
+		return assignment{s[:i], Value(s[i+1:])}
+	}
+	return assignment{s, Set}
+}
+
+func (r rule) matches(path string) bool {
+	if !strings.HasPrefix(path, r.dir) {
+		return false
+	}
+	rel := path[len(r.dir):]
+	if r.anchored {
+		return pathspec.Wildmatch(r.pattern, rel, true)
+	}
+	return pathspec.Wildmatch(r.pattern, rel[strings.LastIndexByte(rel, '/')+1:], false)
+}
@@ -0,0 +1,75 @@
+package attributes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testAttributes(files map[string]string, info string) *Attributes {
+	return New(func(dir string) ([]byte, error) {
+		if contents, ok := files[dir]; ok {
+			return []byte(contents), nil
+		}
+		return nil, nil
+	}, []byte(info))
+}
+
+func TestLookup(t *testing.T) {
+	a := testAttributes(map[string]string{
+		"": `# comment
+*.png binary
+*.sh text eol=lf
+*.go diff=golang
+*.lock merge=ours
+/docs/*.txt -text
+"with space.txt" text
+`,
+		"sub": `*.sh !eol
+*.png text`,
+	}, "")
+
+	values, err := a.Lookup("a/b/image.png")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Value{"binary": Set, "diff": Unset, "merge": Unset, "text": Unset}, values)
+
+	values, err = a.Lookup("run.sh")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Value{"text": Set, "eol": "lf"}, values)
+
+	values, err = a.Lookup("sub/run.sh")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Value{"text": Set}, values)
+
+	v, err := a.Get("sub/deeper/image.png", "text")
+	assert.NoError(t, err)
+	assert.Equal(t, Set, v)
+
+	v, _ = a.Get("docs/a.txt", "text")
+	assert.Equal(t, Unset, v)
+	v, _ = a.Get("docs/more/a.txt", "text")
+	assert.Equal(t, Unspecified, v)
+	v, _ = a.Get("with space.txt", "text")
+	assert.Equal(t, Set, v)
+	v, _ = a.Get("main.go", "diff")
+	assert.Equal(t, Value("golang"), v)
+	assert.True(t, v.IsValue())
+	assert.Equal(t, "unspecified", Unspecified.String())
+}
+
+func TestInfoAndMacros(t *testing.T) {
+	a := testAttributes(map[string]string{
+		"":    "[attr]generated -diff linguist\n*.pb.go generated\n*.go text",
+		"sub": "[attr]ignored text\n*.c ignored",
+	}, "*.go -text\n")
+
+	values, err := a.Lookup("api.pb.go")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"diff", "generated", "linguist", "text"}, Names(values))
+	assert.Equal(t, Unset, values["text"])
+	assert.Equal(t, Unset, values["diff"])
+
+	// Macros are only read from the root and info
+	values, _ = a.Lookup("sub/x.c")
+	assert.Equal(t, map[string]Value{"ignored": Set}, values)
+}
@@ -6,22 +6,44 @@ import (
 	"github.com/spf13/cobra"
 
 	"got/internal/got/filesystem"
+	"got/internal/interactive"
+	"got/internal/pkg/terminal"
 )
 
 var Cmd = &cobra.Command{
-	Use:   "add <file>...",
+	Use:   "add [-p] <file>...",
 	Short: "Add file(s) into the index",
-	Args:  cobra.MinimumNArgs(1),
-	Run:   runAdd,
+	Long: `Adds the contents of files to the index. With -p the hunks of the changes
+to tracked files are shown one at a time and only the ones chosen are
+staged.`,
 }
 
-func runAdd(cmd *cobra.Command, args []string) {
+func init() {
+	patch := Cmd.Flags().BoolP("patch", "p", false, "interactively choose hunks to stage")
+	Cmd.Run = func(cmd *cobra.Command, args []string) {
+		runAdd(cmd, args, *patch)
+	}
+}
+
+func runAdd(cmd *cobra.Command, args []string, patch bool) {
+	if !patch && len(args) == 0 {
+		fmt.Println("Nothing specified, nothing added.")
+		return
+	}
 	g, err := filesystem.NewGot()
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
-	err = g.AddPath(args...)
+	if patch {
+		s := interactive.NewSession(cmd.InOrStdin(), cmd.OutOrStdout(), "Stage this hunk")
+		s.Edit = func(text string) (string, error) {
+			return terminal.Edit(g.Editor(), text)
+		}
+		err = g.AddPatch(args, s.Select)
+	} else {
+		err = g.AddPath(args...)
+	}
 	if err != nil {
 		fmt.Println(err)
 	}
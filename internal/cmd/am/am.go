@@ -0,0 +1,74 @@
+package am
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"got/internal/got/filesystem"
+)
+
+var Cmd = &cobra.Command{
+	Use: `am [<mbox>...]
+   am (--continue | --skip | --abort)`,
+	Short: "Apply the patches of a mailbox as commits",
+	Long: `Applies the mails written by 'got format-patch' as commits on top of HEAD,
+keeping the author and date of each. Mails are read from the given mailboxes,
+or standard input if none are given. When a patch doesn't apply, am stops so
+that it can be resolved and added to the index before running
+'got am --continue'. The patch can instead be dropped with 'got am --skip',
+or the whole session undone with 'got am --abort'.`,
+	DisableFlagsInUseLine: true,
+}
+
+func init() {
+	cont := Cmd.Flags().Bool("continue", false, "commit the resolved patch and go on with the rest")
+	skip := Cmd.Flags().Bool("skip", false, "drop the patch that didn't apply and go on with the rest")
+	abort := Cmd.Flags().Bool("abort", false, "undo the whole session")
+	Cmd.Run = func(cmd *cobra.Command, args []string) {
+		runAm(cmd, args, *cont, *skip, *abort)
+	}
+}
+
+func runAm(cmd *cobra.Command, args []string, cont, skip, abort bool) {
+	g, err := filesystem.NewGot()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(128)
+	}
+	switch {
+	case cont:
+		err = g.AmContinue()
+	case skip:
+		err = g.AmSkip()
+	case abort:
+		err = g.AmAbort()
+	default:
+		var readers []io.Reader
+		for _, a := range args {
+			f, err := os.Open(a)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(128)
+			}
+			defer f.Close()
+			readers = append(readers, f)
+		}
+		if len(readers) == 0 {
+			readers = append(readers, os.Stdin)
+		}
+		err = g.Am(readers...)
+	}
+	if err == nil {
+		return
+	}
+	fmt.Println(err)
+	if !abort && g.AmInProgress() {
+		fmt.Println(`When you have resolved this problem, add the changes with 'got add' and
+run 'got am --continue'. To drop this patch run 'got am --skip' instead, and
+to undo the whole session run 'got am --abort'.`)
+	}
+	os.Exit(1)
+}
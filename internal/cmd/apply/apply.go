@@ -0,0 +1,109 @@
+package apply
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"got/internal/got/filesystem"
+)
+
+var Cmd = &cobra.Command{
+	Use:   "apply [--cached | --index] [--check] [-R] [-3] [--whitespace=<action>] [<patch>...]",
+	Short: "Apply a patch to files in the working tree or the index",
+	Long: `Reads unified diffs from the given files, or standard input if none
+are given, and applies them to the working tree, or to the index with
+--cached. Hunks that moved since the patch was made are searched for. With -3
+hunks that don't apply are merged with the blobs the patch was made against
+instead, leaving conflict markers in the working tree where needed. Binary
+files are replaced as a whole by git binary patches, which have to be made
+against the current contents of the files.
+
+--whitespace decides what happens to added lines with trailing whitespace or
+spaces before tabs in their indentation: 'nowarn' lets them be, 'warn', the
+default unless apply.whitespace says otherwise, reports them, 'fix' removes
+the whitespace and 'error' refuses to apply the patch.`,
+}
+
+func init() {
+	var opts filesystem.ApplyOptions
+	Cmd.Flags().BoolVar(&opts.Cached, "cached", false, "apply the patch to the index without touching the working tree")
+	Cmd.Flags().BoolVar(&opts.Index, "index", false, "apply the patch to both the index and the working tree")
+	Cmd.Flags().BoolVar(&opts.Check, "check", false, "only check whether the patch applies")
+	Cmd.Flags().BoolVarP(&opts.Reverse, "reverse", "R", false, "apply the patch in reverse")
+	Cmd.Flags().BoolVarP(&opts.ThreeWay, "3way", "3", false, "fall back to a three-way merge when hunks don't apply")
+	whitespace := Cmd.Flags().String("whitespace", "", "what to do about whitespace errors: nowarn, warn, fix or error")
+	Cmd.Run = func(cmd *cobra.Command, args []string) {
+		runApply(cmd, args, opts, *whitespace)
+	}
+}
+
+func runApply(cmd *cobra.Command, args []string, opts filesystem.ApplyOptions, whitespace string) {
+	g, err := filesystem.NewGot()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(128)
+	}
+	if whitespace == "" {
+		whitespace = g.Config.GetString("apply.whitespace", string(filesystem.WhitespaceWarn))
+	}
+	opts.Whitespace, err = filesystem.ParseWhitespaceAction(whitespace)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(128)
+	}
+	var readers []io.Reader
+	for _, a := range args {
+		if a == "-" {
+			readers = append(readers, os.Stdin)
+			continue
+		}
+		f, err := os.Open(a)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(128)
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+	if len(readers) == 0 {
+		readers = append(readers, os.Stdin)
+	}
+	result, err := g.Apply(io.MultiReader(readers...), opts)
+	if result != nil {
+		for _, e := range result.WhitespaceErrors {
+			fmt.Println(e)
+		}
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if n := len(result.WhitespaceErrors); n > 0 {
+		switch opts.Whitespace {
+		case filesystem.WhitespaceFix:
+			fmt.Printf("warning: %s applied after fixing whitespace errors.\n", lines(n))
+		case filesystem.WhitespaceWarn:
+			verb := "adds"
+			if n > 1 {
+				verb = "add"
+			}
+			fmt.Printf("warning: %s %s whitespace errors.\n", lines(n), verb)
+		}
+	}
+	for _, c := range result.Conflicts {
+		fmt.Printf("Applied patch to '%s' with conflicts.\n", c)
+	}
+	if len(result.Conflicts) > 0 {
+		os.Exit(1)
+	}
+}
+
+func lines(n int) string {
+	if n == 1 {
+		return "1 line"
+	}
+	return fmt.Sprintf("%d lines", n)
+}
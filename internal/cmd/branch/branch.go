@@ -53,7 +53,7 @@ func runBranch(cmd *cobra.Command, args []string, list bool, delete bool) {
 		fmt.Println(err)
 		return
 	}
-	g, err := filesystem.NewGot()
+	g, err := filesystem.NewGotAllowBare()
 	if err != nil {
 		fmt.Println(err)
 		return
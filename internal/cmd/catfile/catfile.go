@@ -3,6 +3,8 @@ package catfile
 import (
 	"errors"
 	"fmt"
+	"io"
+	"os"
 
 	"got/internal/got/filesystem"
 
@@ -39,7 +41,7 @@ func run(cmd *cobra.Command, args []string, showType bool, prettyPrint bool) {
 		fmt.Println(err.Error())
 		return
 	}
-	g, err := filesystem.NewGot()
+	g, err := filesystem.NewGotAllowBare()
 	if err != nil {
 		fmt.Println(err)
 		return
@@ -54,12 +56,21 @@ func run(cmd *cobra.Command, args []string, showType bool, prettyPrint bool) {
 		fmt.Println(err)
 		return
 	}
+	if flagUsed == flagPrettyPrint && t == objects.TypeBlob {
+		err = printBlob(g, id)
+		if err != nil {
+			fmt.Println(err)
+		}
+		return
+	}
 	var o objects.Object
 	switch t {
 	case objects.TypeBlob:
 		o, _ = g.Objects.GetBlob(id)
 	case objects.TypeTree:
 		o, _ = g.Objects.GetTree(id)
+	case objects.TypeCommit:
+		o, _ = g.Objects.GetCommit(id)
 	default:
 		fmt.Println("no object found")
 		return
@@ -72,6 +83,17 @@ func run(cmd *cobra.Command, args []string, showType bool, prettyPrint bool) {
 	}
 }
 
+// Streams the contents of a blob to stdout without reading it into memory
+func printBlob(g *filesystem.Got, id objects.ID) error {
+	r, _, err := g.Objects.OpenBlob(id)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	_, err = io.CopyBuffer(os.Stdout, r, make([]byte, objects.BufferSize))
+	return err
+}
+
 func flagsAreCompatible(showType, prettyPrint bool) (flag, error) {
 	if !showType && !prettyPrint {
 		return "", errors.New("one of -t or -p flag must be used")
@@ -0,0 +1,77 @@
+package checkattr
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"got/internal/attributes"
+	"got/internal/got/filesystem"
+)
+
+var Cmd = &cobra.Command{
+	Use: `check-attr [-a | --all] <attr>... [--] <path>...
+   check-attr -a [--] <path>...`,
+	Short: "Display gotattributes information",
+	Long: `Shows the attributes the .gotattributes files and .got/info/attributes
+give each path, one 'path: attribute: value' line per attribute. The value is
+'set', 'unset', 'unspecified' or the value given, e.g. 'lf' for 'eol=lf'.
+Without -- the first argument is the attribute and the others are paths.
+--all shows all the attributes that are set, unset or have a value.`,
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.MinimumNArgs(1),
+}
+
+func init() {
+	all := Cmd.Flags().BoolP("all", "a", false, "show all attributes of the paths")
+	Cmd.Run = func(cmd *cobra.Command, args []string) {
+		runCheckAttr(cmd, args, *all)
+	}
+}
+
+func runCheckAttr(cmd *cobra.Command, args []string, all bool) {
+	var names, paths []string
+	switch dash := cmd.ArgsLenAtDash(); {
+	case dash >= 0:
+		names, paths = args[:dash], args[dash:]
+	case all:
+		paths = args
+	default:
+		names, paths = args[:1], args[1:]
+	}
+	if all && len(names) > 0 {
+		fmt.Println("attributes can't be named with --all")
+		os.Exit(129)
+	}
+	if !all && len(names) == 0 {
+		fmt.Println("no attribute specified")
+		os.Exit(129)
+	}
+	if len(paths) == 0 {
+		fmt.Println("no path specified")
+		os.Exit(129)
+	}
+
+	g, err := filesystem.NewGotAllowBare()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(128)
+	}
+	for _, path := range paths {
+		values, err := g.CheckAttr(path, names)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(128)
+		}
+		if all {
+			names = attributes.Names(values)
+		}
+		for _, name := range names {
+			fmt.Printf("%s: %s: %s\n", path, name, values[name])
+		}
+		if all {
+			names = nil
+		}
+	}
+}
@@ -2,6 +2,7 @@ package checkout
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/spf13/cobra"
 
@@ -31,6 +32,6 @@ func runCheckout(cmd *cobra.Command, args []string, create bool) {
 	err = g.Checkout(branchName, create)
 	if err != nil {
 		fmt.Println(err)
-		return
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,104 @@
+package clean
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"got/internal/got/filesystem"
+)
+
+var Cmd = &cobra.Command{
+	Use:   "clean [-n] [-f] [-d] [-x | -X] [-i] [--] [<path>...]",
+	Short: "Remove untracked files from the working tree",
+	Long: `Removes the files that aren't tracked, limited to the given paths. Ignored
+files are only removed with -x, or only they are with -X. Untracked
+directories are removed with -d. Unless clean.requireForce is set to false
+nothing is removed without -f, -n or -i.`,
+}
+
+type options struct {
+	dryRun      bool
+	force       bool
+	interactive bool
+}
+
+func init() {
+	var opts options
+	var cleanOpts filesystem.CleanOptions
+	Cmd.Flags().BoolVarP(&opts.dryRun, "dry-run", "n", false, "only show what would be removed")
+	Cmd.Flags().BoolVarP(&opts.force, "force", "f", false, "remove the files")
+	Cmd.Flags().BoolVarP(&opts.interactive, "interactive", "i", false, "ask before removing each file")
+	Cmd.Flags().BoolVarP(&cleanOpts.Directories, "directories", "d", false, "remove untracked directories too")
+	Cmd.Flags().BoolVarP(&cleanOpts.Ignored, "ignored", "x", false, "remove ignored files too")
+	Cmd.Flags().BoolVarP(&cleanOpts.OnlyIgnored, "only-ignored", "X", false, "only remove ignored files")
+	Cmd.Run = func(cmd *cobra.Command, args []string) {
+		cleanOpts.Paths = args
+		runClean(cmd.InOrStdin(), opts, cleanOpts)
+	}
+}
+
+func runClean(in io.Reader, opts options, cleanOpts filesystem.CleanOptions) {
+	g, err := filesystem.NewGot()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if cleanOpts.Ignored && cleanOpts.OnlyIgnored {
+		fmt.Println("-x and -X cannot be used together")
+		os.Exit(1)
+	}
+	if !opts.force && !opts.dryRun && !opts.interactive && g.Config.GetBool("clean.requireForce", true) {
+		fmt.Println("clean.requireForce defaults to true and neither -i, -n, nor -f given; refusing to clean")
+		os.Exit(128)
+	}
+	candidates, err := g.CleanCandidates(cleanOpts)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if opts.dryRun {
+		for _, c := range candidates {
+			fmt.Printf("Would remove %s\n", c)
+		}
+		return
+	}
+	if opts.interactive {
+		candidates = selectCandidates(in, candidates)
+	}
+	for _, c := range candidates {
+		fmt.Printf("Removing %s\n", c)
+	}
+	err = g.Clean(candidates)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// Asks whether to remove each of the candidates and returns the ones
+// confirmed. 'a' removes the rest without asking and 'q' keeps them.
+func selectCandidates(in io.Reader, candidates []string) []string {
+	r := bufio.NewReader(in)
+	var selected []string
+	for i, c := range candidates {
+		fmt.Printf("Remove %s [y,n,a,q]? ", c)
+		answer, err := r.ReadString('\n')
+		switch strings.TrimSpace(strings.ToLower(answer)) {
+		case "y", "yes":
+			selected = append(selected, c)
+		case "a", "all":
+			return append(selected, candidates[i:]...)
+		case "q", "quit":
+			return selected
+		}
+		if err != nil {
+			return selected
+		}
+	}
+	return selected
+}
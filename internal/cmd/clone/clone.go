@@ -0,0 +1,51 @@
+package clone
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"got/internal/got/filesystem"
+)
+
+var Cmd = &cobra.Command{
+	Use:   "clone <repository> [<directory>]",
+	Short: "Clone a repository into a new directory",
+	Long: `Creates a repository in the directory, by default named after the cloned
+repository, adds the cloned repository as its remote origin, fetches it and
+checks out the branch its HEAD points at.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		runClone(args)
+	},
+}
+
+func runClone(args []string) {
+	url := args[0]
+	var dir string
+	if len(args) > 1 {
+		dir = args[1]
+	} else {
+		dir = defaultDir(url)
+	}
+	fmt.Printf("Cloning into '%s'...\n", dir)
+	g, err := filesystem.Clone(url, dir)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(128)
+	}
+	if branches, err := g.Refs.Branches(); err == nil && len(branches) == 0 {
+		fmt.Println("warning: You appear to have cloned an empty repository.")
+	}
+}
+
+// Returns the directory a repository is cloned into if none is given, the
+// last part of its path without a '.got' suffix
+func defaultDir(url string) string {
+	url = strings.TrimRight(url, "/")
+	url = strings.TrimSuffix(url, "/"+".got")
+	return strings.TrimSuffix(filepath.Base(url), ".got")
+}
@@ -2,6 +2,7 @@ package commit
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/spf13/cobra"
 
@@ -9,27 +10,29 @@ import (
 )
 
 var Cmd = &cobra.Command{
-	Use:   "commit -m message",
+	Use:   "commit [-n] -m message",
 	Short: "Commit changes in the index",
 	Args:  cobra.NoArgs,
 }
 
 func init() {
 	message := Cmd.Flags().StringP("message", "m", "", "The message to describe the commit")
+	var opts filesystem.CommitOptions
+	Cmd.Flags().BoolVarP(&opts.NoVerify, "no-verify", "n", false, "skip the pre-commit and commit-msg hooks")
 	Cmd.Run = func(cmd *cobra.Command, args []string) {
-		run(cmd, args, *message)
+		run(cmd, args, *message, opts)
 	}
 }
 
-func run(cmd *cobra.Command, args []string, message string) {
+func run(cmd *cobra.Command, args []string, message string, opts filesystem.CommitOptions) {
 	g, err := filesystem.NewGot()
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
-	err = g.Commit(message)
+	err = g.Commit(message, opts)
 	if err != nil {
 		fmt.Println(err)
-		return
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,57 @@
+package commitgraph
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"got/internal/got/filesystem"
+)
+
+var Cmd = &cobra.Command{
+	Use:   "commit-graph {write | verify}",
+	Short: "Write and verify the commit-graph file used to speed up history traversal",
+}
+
+var writeCmd = &cobra.Command{
+	Use:   "write",
+	Short: "Write a commit-graph of every commit reachable from a branch",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		g, err := filesystem.NewGotAllowBare()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		n, err := g.WriteCommitGraph()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("Wrote commit-graph with %d commits\n", n)
+	},
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify the commit-graph against the commit objects",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		g, err := filesystem.NewGotAllowBare()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		err = g.VerifyCommitGraph()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	Cmd.AddCommand(writeCmd)
+	Cmd.AddCommand(verifyCmd)
+}
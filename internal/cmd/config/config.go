@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"got/internal/config"
+	"got/internal/got/filesystem"
+)
+
+var Cmd = &cobra.Command{
+	Use: `config [--global] <key> [<value>]
+   config [--global] --unset <key>
+   config [--global] --list`,
+	Short:                 "Get and set repository or global options",
+	DisableFlagsInUseLine: true,
+}
+
+func init() {
+	global := Cmd.Flags().Bool("global", false, "use the global config file instead of the repository one")
+	unset := Cmd.Flags().Bool("unset", false, "remove the key from the config")
+	list := Cmd.Flags().BoolP("list", "l", false, "list all keys and their values")
+	Cmd.Args = func(cmd *cobra.Command, args []string) error {
+		switch {
+		case *list && len(args) != 0:
+			return errors.New("wrong number of arguments")
+		case *unset && len(args) != 1:
+			return errors.New("wrong number of arguments")
+		case !*list && (len(args) < 1 || len(args) > 2):
+			return errors.New("wrong number of arguments")
+		}
+		return nil
+	}
+	Cmd.Run = func(cmd *cobra.Command, args []string) {
+		runConfig(cmd, args, *global, *unset, *list)
+	}
+}
+
+func runConfig(cmd *cobra.Command, args []string, global, unset, list bool) {
+	conf, err := readConfig(global)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	switch {
+	case list:
+		for _, k := range conf.Keys() {
+			v, _ := conf.Get(k)
+			fmt.Printf("%s=%s\n", k, v)
+		}
+	case unset:
+		err = conf.Unset(args[0])
+	case len(args) == 2:
+		err = conf.Set(args[0], args[1])
+	default:
+		v, ok := conf.Get(args[0])
+		if ok {
+			fmt.Println(v)
+		}
+	}
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+func readConfig(global bool) (*config.Config, error) {
+	if global {
+		return config.ReadGlobal()
+	}
+	g, err := filesystem.NewGotAllowBare()
+	if err != nil {
+		return nil, err
+	}
+	return g.Config, nil
+}
@@ -2,48 +2,243 @@ package diff
 
 import (
 	"fmt"
+	"os"
+	"regexp"
+	"strings"
 
+	"github.com/gookit/color"
 	"github.com/spf13/cobra"
 
+	"got/internal/diff"
+	"got/internal/diff/algorithm"
 	"got/internal/got/filesystem"
 )
 
 var Cmd = &cobra.Command{
-	Use:   "diff [--cached] <path>",
-	Short: "Show the diff between your working directory and the the index",
-	Args:  cobra.ExactArgs(1),
+	Use:   "diff [--cached] [<options>] [<commit> [<commit>]] [--] [<path>...]",
+	Short: "Show changes between the working tree, the index and commits",
+	Long: `Shows the changes between
+  - the working tree and the index, without commits
+  - the index and HEAD, with --cached
+  - the working tree and a commit, or the index and a commit with --cached
+  - two commits, given as two arguments or as 'a..b'
+  - the merge base of two commits and the second, given as 'a...b'
+The paths after the commits, or after '--', limit the diff to them.`,
 }
 
 func init() {
-	cached := Cmd.Flags().Bool("cached", false, "Show diff between your index and HEAD")
+	cached := Cmd.Flags().Bool("cached", false, "Show diff between your index and HEAD or the given commit")
+	diffAlgorithm := Cmd.Flags().String("diff-algorithm", "", "Choose a diff algorithm: "+strings.Join(algorithm.Names(), ", "))
+	unified := Cmd.Flags().IntP("unified", "U", diff.DefaultContext, "Generate diffs with n lines of context")
+	format := FormatFlags(Cmd, filesystem.DiffFormatPatch)
+	renames := RenameFlags(Cmd)
+	binary := Cmd.Flags().Bool("binary", false, "Output binary files as patches that can be applied")
+	wordDiff := WordDiffFlags(Cmd)
+	whitespace := WhitespaceFlags(Cmd)
 	Cmd.Run = func(cmd *cobra.Command, args []string) {
-		runDiff(cmd, args, *cached)
+		runDiff(cmd, args, *cached, *diffAlgorithm, *unified, *binary, format(), renames, wordDiff, whitespace)
 	}
 }
 
-func runDiff(cmd *cobra.Command, args []string, cached bool) {
+func runDiff(cmd *cobra.Command, args []string, cached bool, diffAlgorithm string, unified int, binary bool, format filesystem.DiffFormat, renames, wordDiff func(*filesystem.Got) error, whitespace func(*filesystem.Got)) {
 	g, err := filesystem.NewGot()
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
-
-	var diffs string
-	if cached {
-		diffs, err = g.DiffIndexPath(args[0])
+	err = renames(g)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	err = wordDiff(g)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if diffAlgorithm != "" {
+		g.Differ, err = algorithm.ByName(diffAlgorithm)
 		if err != nil {
 			fmt.Println(err)
 			return
 		}
-	} else {
-		diffs, err = g.DiffPath(args[0])
-		if err != nil {
-			fmt.Println(err)
-			return
+	}
+	whitespace(g)
+	if cmd.Flags().Changed("unified") {
+		g.DiffContext = unified
+	}
+	g.BinaryPatches = binary
+
+	revs, paths := SplitRevisionsAndPaths(g, args, cmd.ArgsLenAtDash())
+	diffs, err := g.Diff(filesystem.DiffOptions{
+		Revisions: revs,
+		Cached:    cached,
+		Paths:     paths,
+		Format:    format,
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Print(diffs)
+}
+
+// Adds the flags choosing the output format of a diff to the command. The
+// returned function returns the chosen format once the flags are parsed.
+func FormatFlags(cmd *cobra.Command, def filesystem.DiffFormat) func() filesystem.DiffFormat {
+	formats := []struct {
+		format filesystem.DiffFormat
+		usage  string
+		short  string
+	}{
+		{filesystem.DiffFormatPatch, "Show the changes as a patch", "p"},
+		{filesystem.DiffFormatNameOnly, "Show only the names of the changed files", ""},
+		{filesystem.DiffFormatNameStatus, "Show the names and the kind of change of the changed files", ""},
+		{filesystem.DiffFormatStat, "Show a diffstat", ""},
+		{filesystem.DiffFormatNumstat, "Show the number of added and deleted lines of each file", ""},
+		{filesystem.DiffFormatRaw, "Show the modes, IDs and kind of change of the changed files", ""},
+	}
+	set := make(map[filesystem.DiffFormat]*bool)
+	for _, f := range formats {
+		set[f.format] = cmd.Flags().BoolP(string(f.format), f.short, false, f.usage)
+	}
+	return func() filesystem.DiffFormat {
+		for _, f := range formats {
+			if *set[f.format] {
+				return f.format
+			}
+		}
+		return def
+	}
+}
+
+// Adds the flags controlling the detection of renames and copies to the
+// command. The returned function applies them to g once the flags are
+// parsed.
+func RenameFlags(cmd *cobra.Command) func(g *filesystem.Got) error {
+	def := fmt.Sprintf("%d%%", diff.DefaultRenameThreshold)
+	renames := cmd.Flags().StringP("find-renames", "M", "", "Detect renames of files that are at least n% similar")
+	cmd.Flags().Lookup("find-renames").NoOptDefVal = def
+	copies := cmd.Flags().StringP("find-copies", "C", "", "Detect copies as well as renames of files that are at least n% similar")
+	cmd.Flags().Lookup("find-copies").NoOptDefVal = def
+	noRenames := cmd.Flags().Bool("no-renames", false, "Don't detect renames")
+	return func(g *filesystem.Got) error {
+		for _, f := range []struct {
+			name, value string
+		}{{"find-renames", *renames}, {"find-copies", *copies}} {
+			if !cmd.Flags().Changed(f.name) {
+				continue
+			}
+			threshold, err := diff.ParseSimilarity(f.value)
+			if err != nil {
+				return err
+			}
+			g.Renames.Detect = true
+			g.Renames.Threshold = threshold
+			g.Renames.Copies = g.Renames.Copies || f.name == "find-copies"
 		}
+		if *noRenames {
+			g.Renames.Detect = false
+		}
+		return nil
 	}
+}
+
+// Adds the flags showing the hunks of patches as word diffs to the command.
+// The returned function applies them to g once the flags are parsed.
+func WordDiffFlags(cmd *cobra.Command) func(g *filesystem.Got) error {
+	mode := cmd.Flags().String("word-diff", "", "Show a word diff, in color, plain (the default), porcelain or none mode")
+	cmd.Flags().Lookup("word-diff").NoOptDefVal = string(diff.WordDiffPlain)
+	regex := cmd.Flags().String("word-diff-regex", "", "Use the regular expression to match words, implies --word-diff")
+	return func(g *filesystem.Got) error {
+		if cmd.Flags().Changed("word-diff-regex") {
+			re, err := regexp.Compile(*regex)
+			if err != nil {
+				return err
+			}
+			g.WordRegex = re
+			g.WordDiff = diff.WordDiffPlain
+		}
+		if cmd.Flags().Changed("word-diff") {
+			m, err := diff.ParseWordDiffMode(*mode)
+			if err != nil {
+				return err
+			}
+			g.WordDiff = m
+		}
+		if g.WordDiff == diff.WordDiffColor {
+			// The changes would be lost without colors, so they're shown
+			// even if the output isn't a terminal
+			color.Enable = true
+			color.ForceOpenColor()
+		}
+		return nil
+	}
+}
+
+// Adds the flags choosing the differences in whitespace that diffs ignore to
+// the command. The returned function makes the Differ of g ignore them once
+// the flags are parsed.
+func WhitespaceFlags(cmd *cobra.Command) func(g *filesystem.Got) {
+	var opts diff.WhitespaceOptions
+	cmd.Flags().BoolVarP(&opts.IgnoreAllSpace, "ignore-all-space", "w", false, "Ignore whitespace when comparing lines")
+	cmd.Flags().BoolVarP(&opts.IgnoreSpaceChange, "ignore-space-change", "b", false, "Ignore changes in the amount of whitespace")
+	cmd.Flags().BoolVar(&opts.IgnoreSpaceAtEOL, "ignore-space-at-eol", false, "Ignore changes in whitespace at the end of lines")
+	cmd.Flags().BoolVar(&opts.IgnoreCRAtEOL, "ignore-cr-at-eol", false, "Ignore carriage returns at the end of lines")
+	cmd.Flags().BoolVar(&opts.IgnoreBlankLines, "ignore-blank-lines", false, "Ignore changes whose lines are all blank")
+	return func(g *filesystem.Got) {
+		g.Differ = opts.Differ(g.Differ)
+	}
+}
 
-	if diffs != "" {
-		fmt.Print(diffs)
+var similarityArgRegex = regexp.MustCompile(`^-([MC])(\d+%?)$`)
+
+// Rewrites the -M<n> and -C<n> options in the command line into their long
+// forms, as flags with optional values can't take an attached value in their
+// short form. Arguments after '--' are left alone.
+func ExpandSimilarityArgs(args []string) []string {
+	expanded := make([]string, len(args))
+	copy(expanded, args)
+	for i, arg := range expanded {
+		if arg == "--" {
+			break
+		}
+		if m := similarityArgRegex.FindStringSubmatch(arg); m != nil {
+			name := "find-renames"
+			if m[1] == "C" {
+				name = "find-copies"
+			}
+			expanded[i] = fmt.Sprintf("--%s=%s", name, m[2])
+		}
+	}
+	return expanded
+}
+
+// Splits arguments into the revisions at the front and the paths after
+// them. Arguments after dash, as returned by ArgsLenAtDash, are always paths.
+// Before it the revisions end at the first argument that is an existing file
+// or doesn't name a commit.
+func SplitRevisionsAndPaths(g *filesystem.Got, args []string, dash int) ([]string, []string) {
+	end := len(args)
+	if dash >= 0 {
+		end = dash
+	}
+	i := 0
+	for ; i < end; i++ {
+		if !isRevision(g, args[i]) {
+			break
+		}
+	}
+	return args[:i], args[i:]
+}
+
+func isRevision(g *filesystem.Got, arg string) bool {
+	if _, err := os.Stat(arg); err == nil {
+		return false
+	}
+	if strings.Contains(arg, "..") {
+		return true
 	}
+	_, err := g.ResolveRevision(arg)
+	return err == nil
 }
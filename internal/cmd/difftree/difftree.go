@@ -0,0 +1,51 @@
+package difftree
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"got/internal/cmd/diff"
+	"got/internal/got/filesystem"
+)
+
+var Cmd = &cobra.Command{
+	Use:   "diff-tree [-r] [<options>] <commit> [<commit>] [--] [<path>...]",
+	Short: "Compare the trees of two commits, or of a commit and its first parent",
+	Long: `Compares the trees of two commits, or of a single commit and its first
+parent, in which case the ID of the commit is printed first. The changes are
+shown in raw format unless another format is chosen. Trees are always
+compared recursively, -r is accepted for compatibility with git.`,
+	Args: cobra.MinimumNArgs(1),
+}
+
+func init() {
+	Cmd.Flags().BoolP("recursive", "r", true, "Compare subtrees too")
+	format := diff.FormatFlags(Cmd, filesystem.DiffFormatRaw)
+	renames := diff.RenameFlags(Cmd)
+	Cmd.Run = func(cmd *cobra.Command, args []string) {
+		runDiffTree(cmd, args, format(), renames)
+	}
+}
+
+func runDiffTree(cmd *cobra.Command, args []string, format filesystem.DiffFormat, renames func(*filesystem.Got) error) {
+	g, err := filesystem.NewGotAllowBare()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	// Like git's plumbing, diff-tree only detects renames when asked to
+	g.Renames.Detect = false
+	err = renames(g)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	revs, paths := diff.SplitRevisionsAndPaths(g, args, cmd.ArgsLenAtDash())
+	out, err := g.DiffTree(revs, paths, format)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Print(out)
+}
@@ -0,0 +1,67 @@
+package fetch
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"got/internal/got/filesystem"
+	"got/internal/pretty"
+)
+
+var Cmd = &cobra.Command{
+	Use:   "fetch [-p] [<remote>]",
+	Short: "Download objects and branches from another repository",
+	Long: `Copies the commits of the branches of the remote that are missing, with
+everything they need, and updates the remote-tracking branches
+refs/remotes/<remote>/*. The remote defaults to the upstream remote of the
+checked out branch or origin.`,
+	Args: cobra.MaximumNArgs(1),
+}
+
+func init() {
+	prune := Cmd.Flags().BoolP("prune", "p", false, "remove remote-tracking branches whose branch no longer exists")
+	Cmd.Run = func(cmd *cobra.Command, args []string) {
+		runFetch(args, *prune)
+	}
+}
+
+func runFetch(args []string, prune bool) {
+	g, err := filesystem.NewGotAllowBare()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	remote := g.DefaultRemote()
+	if len(args) > 0 {
+		remote = args[0]
+	}
+	fetched, err := g.Fetch(remote, prune)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	PrintFetched(g.RemoteURL(remote), fetched)
+}
+
+// Prints the remote-tracking branches changed by a fetch from url, one line
+// per branch
+func PrintFetched(url string, fetched []filesystem.FetchedRef) {
+	if len(fetched) == 0 {
+		return
+	}
+	fmt.Printf("From %s\n", url)
+	for _, f := range fetched {
+		switch {
+		case f.New == "":
+			fmt.Printf(" - %-17s %-10s -> %s\n", "[deleted]", "(none)", f.Tracking)
+		case f.Old == "":
+			fmt.Printf(" * %-17s %-10s -> %s\n", "[new branch]", f.Branch, f.Tracking)
+		case f.Forced:
+			fmt.Printf(" + %-17s %-10s -> %s  (forced update)\n", pretty.Abbrev(f.Old)+"..."+pretty.Abbrev(f.New), f.Branch, f.Tracking)
+		default:
+			fmt.Printf("   %-17s %-10s -> %s\n", pretty.Abbrev(f.Old)+".."+pretty.Abbrev(f.New), f.Branch, f.Tracking)
+		}
+	}
+}
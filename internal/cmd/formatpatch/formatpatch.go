@@ -0,0 +1,80 @@
+package formatpatch
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gookit/color"
+	"github.com/spf13/cobra"
+
+	"got/internal/got/filesystem"
+	"got/internal/mbox"
+)
+
+var Cmd = &cobra.Command{
+	Use:   "format-patch [-o <dir> | --stdout] [--no-binary] <revision range>",
+	Short: "Write each commit of a range as a mail with its patch",
+	Long: `Writes every commit of the revision range as a mail in mbox format to a
+file of its own, e.g. 0001-Fix-the-parser.patch, and prints the names of the
+files. A single revision selects the commits since it, i.e. '<rev>..HEAD'.
+The mails can be applied in another repository with 'got am'.`,
+	Args: cobra.ExactArgs(1),
+}
+
+func init() {
+	outputDir := Cmd.Flags().StringP("output-directory", "o", ".", "write the files to the given directory")
+	stdout := Cmd.Flags().Bool("stdout", false, "write all mails to standard output as a single mailbox")
+	noBinary := Cmd.Flags().Bool("no-binary", false, "only say that binary files differ instead of writing patches of them")
+	Cmd.Run = func(cmd *cobra.Command, args []string) {
+		runFormatPatch(cmd, args, *outputDir, *stdout, *noBinary)
+	}
+}
+
+func runFormatPatch(cmd *cobra.Command, args []string, outputDir string, stdout, noBinary bool) {
+	// Colors would end up in the patches
+	color.Disable()
+	g, err := filesystem.NewGotAllowBare()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	g.BinaryPatches = !noBinary
+	mails, err := g.FormatPatch(args[0])
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if stdout {
+		for i, m := range mails {
+			err = m.Write(os.Stdout, i+1, len(mails))
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+		}
+		return
+	}
+	err = os.MkdirAll(outputDir, os.ModePerm)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for i, m := range mails {
+		name := filepath.Join(outputDir, mbox.FileName(i+1, m.Subject))
+		f, err := os.Create(name)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		err = m.Write(f, i+1, len(mails))
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Println(name)
+	}
+}
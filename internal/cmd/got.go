@@ -2,6 +2,13 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/gookit/color"
 
 	"got/internal/cmd/checkout"
 
@@ -12,23 +19,62 @@ import (
 	"github.com/spf13/cobra"
 
 	"got/internal/cmd/add"
+	"got/internal/cmd/am"
+	"got/internal/cmd/apply"
 	"got/internal/cmd/catfile"
+	"got/internal/cmd/checkattr"
+	"got/internal/cmd/clean"
+	"got/internal/cmd/clone"
 	"got/internal/cmd/commit"
+	"got/internal/cmd/commitgraph"
+	"got/internal/cmd/config"
 	"got/internal/cmd/diff"
+	"got/internal/cmd/difftree"
+	"got/internal/cmd/fetch"
+	"got/internal/cmd/formatpatch"
 	"got/internal/cmd/hashobject"
 	gotInit "got/internal/cmd/init"
+	"got/internal/cmd/mergebase"
+	"got/internal/cmd/mv"
+	"got/internal/cmd/pull"
+	"got/internal/cmd/push"
 	"got/internal/cmd/readtree"
+	"got/internal/cmd/remote"
 	"got/internal/cmd/restore"
+	"got/internal/cmd/revlist"
+	"got/internal/cmd/rm"
+	"got/internal/cmd/serve"
+	"got/internal/cmd/show"
 	"got/internal/cmd/status"
 	"got/internal/cmd/updateindex"
 	"got/internal/cmd/writetree"
+	"got/internal/got/filesystem"
+	"got/internal/pkg/terminal"
 )
 
 var GotCmd = &cobra.Command{
+	Use:   "got [-C <path>] [--got-dir=<path>] [--work-tree=<path>] <command>",
 	Short: "The basics of git implemented in go",
+	Long: `The basics of git implemented in go.
+
+Options in front of the command choose the repository it runs in:
+  -C <path>             run as if got was started in the path
+  --got-dir=<path>      use the repository in the path, sets GOT_DIR
+  --work-tree=<path>    use the path as the working tree, sets GOT_WORK_TREE
+
+GOT_CEILING_DIRECTORIES lists the directories, separated by colons, that
+the search for a repository doesn't go up into, and GOT_INDEX_FILE names an
+index file to use instead of the repository's.`,
 }
 
 func init() {
+	// Colors would end up in patches and other output that is piped or
+	// redirected into files
+	GotCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		if !terminal.IsTerminal(os.Stdout) {
+			color.Disable()
+		}
+	}
 	GotCmd.Run = func(cmd *cobra.Command, args []string) {
 		fmt.Println("Nothing")
 	}
@@ -42,8 +88,103 @@ func init() {
 	GotCmd.AddCommand(status.Cmd)
 	GotCmd.AddCommand(commit.Cmd)
 	GotCmd.AddCommand(restore.Cmd)
+	GotCmd.AddCommand(rm.Cmd)
+	GotCmd.AddCommand(mv.Cmd)
+	GotCmd.AddCommand(clean.Cmd)
 	GotCmd.AddCommand(diff.Cmd)
 	GotCmd.AddCommand(log.Cmd)
 	GotCmd.AddCommand(branch.Cmd)
 	GotCmd.AddCommand(checkout.Cmd)
+	GotCmd.AddCommand(mergebase.Cmd)
+	GotCmd.AddCommand(revlist.Cmd)
+	GotCmd.AddCommand(commitgraph.Cmd)
+	GotCmd.AddCommand(config.Cmd)
+	GotCmd.AddCommand(apply.Cmd)
+	GotCmd.AddCommand(formatpatch.Cmd)
+	GotCmd.AddCommand(am.Cmd)
+	GotCmd.AddCommand(difftree.Cmd)
+	GotCmd.AddCommand(show.Cmd)
+	GotCmd.AddCommand(clone.Cmd)
+	GotCmd.AddCommand(remote.Cmd)
+	GotCmd.AddCommand(fetch.Cmd)
+	GotCmd.AddCommand(push.Cmd)
+	GotCmd.AddCommand(pull.Cmd)
+	GotCmd.AddCommand(serve.Cmd)
+	GotCmd.AddCommand(checkattr.Cmd)
+}
+
+// Runs the command of the command line after applying the global options
+// in front of it
+func Execute() error {
+	args, err := applyGlobalOptions(os.Args[1:])
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(129)
+	}
+	GotCmd.SetArgs(expandSimilarityArgs(args))
+	return GotCmd.Execute()
+}
+
+// Rewrites -M<n> and -C<n> for the commands with the rename flags of diff,
+// leaving them to mean something else to other commands, like the message
+// of commit -m
+func expandSimilarityArgs(args []string) []string {
+	cmd, _, err := GotCmd.Find(args)
+	if err != nil || cmd.Flags().Lookup("find-renames") == nil {
+		return args
+	}
+	return diff.ExpandSimilarityArgs(args)
+}
+
+// Applies the global options in front of the command and returns the
+// arguments from the command on. They're handled before cobra parses the
+// command line as -C means something else to diff and show.
+func applyGlobalOptions(args []string) ([]string, error) {
+	for len(args) > 0 {
+		var name, value string
+		switch arg := args[0]; {
+		case arg == "-C" || arg == "--got-dir" || arg == "--work-tree":
+			if len(args) < 2 {
+				return nil, errors.Errorf("option '%s' requires a value", arg)
+			}
+			name, value, args = arg, args[1], args[2:]
+		case strings.HasPrefix(arg, "--got-dir=") || strings.HasPrefix(arg, "--work-tree="):
+			parts := strings.SplitN(arg, "=", 2)
+			name, value, args = parts[0], parts[1], args[1:]
+		default:
+			return args, nil
+		}
+
+		switch name {
+		case "-C":
+			// Like Git an empty path leaves the directory as it is
+			if value != "" {
+				err := os.Chdir(value)
+				if err != nil {
+					return nil, errors.Errorf("cannot change to '%s': %v", value, err)
+				}
+			}
+		case "--got-dir":
+			err := setenvPath(filesystem.EnvGotDir, value)
+			if err != nil {
+				return nil, err
+			}
+		case "--work-tree":
+			err := setenvPath(filesystem.EnvWorkTree, value)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return args, nil
+}
+
+// Sets the environment variable to the path, made absolute so that it
+// stays the same after later -C options
+func setenvPath(env, path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	return os.Setenv(env, abs)
 }
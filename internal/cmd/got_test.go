@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"got/internal/got/filesystem"
+)
+
+// Runs applyGlobalOptions in a temporary directory with the directories a
+// and a/b and returns the working directory, GOT_DIR and GOT_WORK_TREE it
+// leaves relative to the temporary directory
+func globalOptions(t *testing.T, args ...string) ([]string, string, string, string, error) {
+	root, err := ioutil.TempDir("", "got-cmd")
+	assert.Nil(t, err)
+	defer os.RemoveAll(root)
+	root, err = filepath.EvalSymlinks(root)
+	assert.Nil(t, err)
+	assert.Nil(t, os.MkdirAll(filepath.Join(root, "a", "b"), os.ModePerm))
+	wd, err := os.Getwd()
+	assert.Nil(t, err)
+	defer os.Chdir(wd)
+	defer os.Unsetenv(filesystem.EnvGotDir)
+	defer os.Unsetenv(filesystem.EnvWorkTree)
+	assert.Nil(t, os.Chdir(root))
+
+	rest, err := applyGlobalOptions(args)
+	newWd, _ := os.Getwd()
+	rel := func(path string) string {
+		if path == "" {
+			return ""
+		}
+		r, _ := filepath.Rel(root, path)
+		return filepath.ToSlash(r)
+	}
+	return rest, rel(newWd), rel(os.Getenv(filesystem.EnvGotDir)), rel(os.Getenv(filesystem.EnvWorkTree)), err
+}
+
+func TestApplyGlobalOptions(t *testing.T) {
+	tests := []struct {
+		args                 []string
+		rest                 []string
+		wd, gotDir, workTree string
+	}{
+		{[]string{"status", "-C", "x"}, []string{"status", "-C", "x"}, ".", "", ""},
+		{[]string{"-C", "a", "-C", "b", "log"}, []string{"log"}, "a/b", "", ""},
+		{[]string{"-C", "", "log"}, []string{"log"}, ".", "", ""},
+		// Paths are relative to the directory of the -C options before them
+		{[]string{"-C", "a", "--got-dir", "repo", "--work-tree=b", "log"}, []string{"log"}, "a", "a/repo", "a/b"},
+		{[]string{"--got-dir=repo", "--work-tree", "tree", "-C", "a", "log"}, []string{"log"}, "a", "repo", "tree"},
+		{[]string{"--got-dir", "repo"}, []string{}, ".", "repo", ""},
+	}
+	for _, tt := range tests {
+		rest, wd, gotDir, workTree, err := globalOptions(t, tt.args...)
+		assert.Nil(t, err, tt.args)
+		assert.Equal(t, tt.rest, rest, tt.args)
+		assert.Equal(t, tt.wd, wd, tt.args)
+		assert.Equal(t, tt.gotDir, gotDir, tt.args)
+		assert.Equal(t, tt.workTree, workTree, tt.args)
+	}
+}
+
+func TestApplyGlobalOptionsErrors(t *testing.T) {
+	_, _, _, _, err := globalOptions(t, "--work-tree")
+	assert.EqualError(t, err, "option '--work-tree' requires a value")
+	_, _, _, _, err = globalOptions(t, "-C", "missing", "log")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot change to 'missing'")
+}
+
+func TestExpandSimilarityArgs(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"diff", "-M50"}, []string{"diff", "--find-renames=50"}},
+		{[]string{"log", "--stat", "-C", "-C30%"}, []string{"log", "--stat", "-C", "--find-copies=30%"}},
+		{[]string{"show", "-M90", "--", "-M10"}, []string{"show", "--find-renames=90", "--", "-M10"}},
+		{[]string{"commit", "-m", "-M50"}, []string{"commit", "-m", "-M50"}},
+		{[]string{"status", "-M50"}, []string{"status", "-M50"}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, expandSimilarityArgs(tt.args), tt.args)
+	}
+}
@@ -22,7 +22,7 @@ func init() {
 }
 
 func run(cmd *cobra.Command, args []string, write bool) {
-	g, err := filesystem.NewGot()
+	g, err := filesystem.NewGotAllowBare()
 	if err != nil {
 		fmt.Println(err)
 		return
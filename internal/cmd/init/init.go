@@ -3,6 +3,7 @@ package init
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
 
@@ -10,15 +11,37 @@ import (
 )
 
 var Cmd = &cobra.Command{
-	Use:   "init",
+	Use:   "init [--bare] [--initial-branch=<name>] [--template=<dir>] [<directory>]",
 	Short: "Initialized a got repository",
-	Run: func(cmd *cobra.Command, args []string) {
-		wd, _ := os.Getwd()
-		err := filesystem.Initialize(wd)
-		if err != nil {
-			fmt.Println(err)
-			return
-		}
-		fmt.Printf("Repository initialized in %s/.got", wd)
-	},
+	Long: `Creates a repository in the directory, by default the current one, which is
+created if it doesn't exist. A bare repository has no working tree and lives
+in the directory itself instead of its .got directory, which makes it a good
+target for pushes.`,
+	Args: cobra.MaximumNArgs(1),
+}
+
+func init() {
+	var opts filesystem.InitOptions
+	Cmd.Flags().BoolVar(&opts.Bare, "bare", false, "create a repository without a working tree")
+	Cmd.Flags().StringVarP(&opts.InitialBranch, "initial-branch", "b", "", "the branch the first commit creates")
+	Cmd.Flags().StringVar(&opts.Template, "template", "", "a directory whose files are copied into the repository")
+	Cmd.Run = func(cmd *cobra.Command, args []string) {
+		runInit(args, opts)
+	}
+}
+
+func runInit(args []string, opts filesystem.InitOptions) {
+	dir, _ := os.Getwd()
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	gotDir, err := filesystem.Initialize(dir, opts)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if abs, err := filepath.Abs(gotDir); err == nil {
+		gotDir = abs
+	}
+	fmt.Printf("Repository initialized in %s\n", gotDir)
 }
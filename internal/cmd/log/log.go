@@ -2,52 +2,224 @@ package log
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
-	"got/internal/pkg/terminal"
-
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
+	diffcmd "got/internal/cmd/diff"
+	"got/internal/diff"
 	"got/internal/got/filesystem"
+	"got/internal/pkg/terminal"
+	"got/internal/pretty"
+	"got/internal/revwalk"
 )
 
 var Cmd = &cobra.Command{
-	Use:   "log",
+	Use:   "log [<options>] [<revision range>] [[--] <path>...]",
 	Short: "List commits that are reachable by following the 'parent' links from HEAD",
-	Args:  cobra.NoArgs,
+	Long: `List commits that are reachable by following the 'parent' links from the
+given revisions, HEAD by default. Ranges such as 'a..b', 'a...b' and '^c' are
+supported and the paths after '--' limit the log to commits changing them.
+
+Placeholders for --format:
+  %H %h  commit ID        %an  author name     %s  subject
+  %T %t  tree ID          %ae  author email    %b  body
+  %P %p  parent IDs       %ad  author date     %B  raw message
+  %d %D  ref names        %ar  relative date   %n  newline
+  %Cred %Cgreen %Cblue %Cyellow %Creset        %%  a raw %`,
+}
+
+type options struct {
+	n        int
+	oneline  bool
+	format   string
+	pretty   string
+	author   string
+	grep     string
+	since    string
+	until    string
+	stat     bool
+	patch    bool
+	decorate bool
+	graph    bool
+	topo     bool
+	reverse  bool
+	all      bool
+	unified  int
+	follow   bool
 }
 
 func init() {
-	n := Cmd.Flags().IntP("number", "n", 0, "show a maximum of n entries")
+	var opts options
+	Cmd.Flags().IntVarP(&opts.n, "number", "n", 0, "show a maximum of n entries")
+	Cmd.Flags().BoolVar(&opts.oneline, "oneline", false, "show each commit on a single line")
+	Cmd.Flags().StringVar(&opts.format, "format", "", "pretty-print commits with the given placeholders")
+	Cmd.Flags().StringVar(&opts.pretty, "pretty", "", "pretty-print commits in a given format: oneline, short, medium, full or format:<string>")
+	Cmd.Flags().StringVar(&opts.author, "author", "", "only show commits whose author matches the pattern")
+	Cmd.Flags().StringVar(&opts.grep, "grep", "", "only show commits whose message matches the pattern")
+	Cmd.Flags().StringVar(&opts.since, "since", "", "only show commits more recent than the given date")
+	Cmd.Flags().StringVar(&opts.until, "until", "", "only show commits older than the given date")
+	Cmd.Flags().BoolVar(&opts.stat, "stat", false, "show a diffstat of the changes of each commit")
+	Cmd.Flags().BoolVarP(&opts.patch, "patch", "p", false, "show the changes of each commit as a patch")
+	Cmd.Flags().BoolVar(&opts.decorate, "decorate", false, "show the names of the refs pointing at commits")
+	Cmd.Flags().BoolVar(&opts.graph, "graph", false, "draw a graph of the history next to the log")
+	Cmd.Flags().BoolVar(&opts.topo, "topo-order", false, "show no parents before all of their children are shown")
+	Cmd.Flags().BoolVar(&opts.reverse, "reverse", false, "show the commits in reverse order")
+	Cmd.Flags().BoolVar(&opts.all, "all", false, "show the commits reachable from any branch")
+	Cmd.Flags().IntVarP(&opts.unified, "unified", "U", diff.DefaultContext, "show patches with n lines of context, implies --patch")
+	Cmd.Flags().BoolVar(&opts.follow, "follow", false, "follow the history of a single file through renames")
+	renames := diffcmd.RenameFlags(Cmd)
 	Cmd.Run = func(cmd *cobra.Command, args []string) {
-		runLog(cmd, args, *n)
+		runLog(cmd, args, opts, renames)
 	}
 }
 
-func runLog(cmd *cobra.Command, args []string, n int) {
-	g, err := filesystem.NewGot()
+func runLog(cmd *cobra.Command, args []string, opts options, renames func(*filesystem.Got) error) {
+	logOpts, err := logOptions(cmd, args, opts)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
-	log, err := g.Log(n)
+	g, err := filesystem.NewGotAllowBare()
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
-	height, err := terminal.Height()
+	err = renames(g)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
-	if len(strings.Split(log.String(), "\n")) >= height {
-		err := terminal.RunLess(log.String())
+	if opts.all {
+		branches, err := g.Refs.Branches()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		logOpts.Revisions = append(logOpts.Revisions, branches...)
+	}
+	if cmd.Flags().Changed("unified") {
+		g.DiffContext = opts.unified
+		logOpts.Patch = true
+	}
+	log, err := g.Log(logOpts)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	out := log.Format(prettyFormat(opts), opts.graph)
+	height, err := terminal.Height()
+	if err == nil && len(strings.Split(out, "\n")) >= height {
+		err := terminal.RunLess(out)
 		if err != nil {
 			fmt.Println(err)
 			return
 		}
 	} else {
-		fmt.Print(log)
+		fmt.Print(out)
+	}
+}
+
+func logOptions(cmd *cobra.Command, args []string, opts options) (filesystem.LogOptions, error) {
+	revisions, paths := args, []string(nil)
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		revisions, paths = args[:dash], args[dash:]
+	} else if opts.follow && len(args) > 0 {
+		// The file to follow doesn't need a '--' in front of it
+		revisions, paths = args[:len(args)-1], args[len(args)-1:]
 	}
+	logOpts := filesystem.LogOptions{
+		Revisions: revisions,
+		Paths:     paths,
+		MaxCount:  opts.n,
+		Sort:      revwalk.SortDate,
+		Author:    opts.author,
+		Grep:      opts.grep,
+		Decorate:  opts.decorate || opts.oneline || strings.Contains(prettyFormat(opts), "%d") || strings.Contains(prettyFormat(opts), "%D"),
+		Stat:      opts.stat,
+		Patch:     opts.patch,
+		Follow:    opts.follow,
+	}
+	if opts.topo || opts.graph {
+		logOpts.Sort = revwalk.SortTopological
+	}
+	if opts.reverse {
+		logOpts.Sort |= revwalk.SortReverse
+	}
+	var err error
+	if opts.since != "" {
+		logOpts.Since, err = ParseDate(opts.since, time.Now())
+		if err != nil {
+			return logOpts, err
+		}
+	}
+	if opts.until != "" {
+		logOpts.Until, err = ParseDate(opts.until, time.Now())
+		if err != nil {
+			return logOpts, err
+		}
+	}
+	return logOpts, nil
+}
 
+func prettyFormat(opts options) string {
+	switch {
+	case opts.format != "":
+		return "tformat:" + opts.format
+	case opts.pretty != "":
+		return opts.pretty
+	case opts.oneline:
+		return pretty.Oneline
+	}
+	return pretty.Medium
+}
+
+var relativeDateRegex = regexp.MustCompile(`^(\d+)[ .](second|minute|hour|day|week|month|year)s?[ .]ago$`)
+
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// Parses dates given to --since and --until. Both absolute dates such as
+// '2006-01-02' and relative ones such as '2 weeks ago' are understood.
+func ParseDate(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	switch s {
+	case "now":
+		return now, nil
+	case "yesterday":
+		return now.AddDate(0, 0, -1), nil
+	}
+	if m := relativeDateRegex.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		switch m[2] {
+		case "second":
+			return now.Add(-time.Duration(n) * time.Second), nil
+		case "minute":
+			return now.Add(-time.Duration(n) * time.Minute), nil
+		case "hour":
+			return now.Add(-time.Duration(n) * time.Hour), nil
+		case "day":
+			return now.AddDate(0, 0, -n), nil
+		case "week":
+			return now.AddDate(0, 0, -7*n), nil
+		case "month":
+			return now.AddDate(0, -n, 0), nil
+		case "year":
+			return now.AddDate(-n, 0, 0), nil
+		}
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Errorf("couldn't parse date %s", s)
 }
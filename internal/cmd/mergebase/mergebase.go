@@ -0,0 +1,59 @@
+package mergebase
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"got/internal/got/filesystem"
+)
+
+var Cmd = &cobra.Command{
+	Use: `merge-base [--all] <commit> <commit>
+   merge-base --is-ancestor <commit> <commit>`,
+	Short:                 "Find as good common ancestors as possible for a merge",
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.ExactArgs(2),
+}
+
+func init() {
+	all := Cmd.Flags().BoolP("all", "a", false, "output all merge bases instead of just one")
+	isAncestor := Cmd.Flags().Bool("is-ancestor", false, "exit with status 0 if the first commit is an ancestor of the second, 1 otherwise")
+	Cmd.Run = func(cmd *cobra.Command, args []string) {
+		runMergeBase(cmd, args, *all, *isAncestor)
+	}
+}
+
+func runMergeBase(cmd *cobra.Command, args []string, all bool, isAncestor bool) {
+	g, err := filesystem.NewGotAllowBare()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if isAncestor {
+		ok, err := g.IsAncestor(args[0], args[1])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(128)
+		}
+		if !ok {
+			os.Exit(1)
+		}
+		return
+	}
+	bases, err := g.MergeBases(args[0], args[1])
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if len(bases) == 0 {
+		os.Exit(1)
+	}
+	if !all {
+		bases = bases[:1]
+	}
+	for _, b := range bases {
+		fmt.Println(b)
+	}
+}
@@ -0,0 +1,38 @@
+package mv
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"got/internal/got/filesystem"
+)
+
+var Cmd = &cobra.Command{
+	Use:   "mv [-f] <source>... <destination>",
+	Short: "Move or rename a file or a directory",
+	Long: `Moves or renames tracked files and directories in the working tree and
+the index. With several sources the destination has to be a directory.`,
+	Args: cobra.MinimumNArgs(2),
+}
+
+func init() {
+	force := Cmd.Flags().BoolP("force", "f", false, "overwrite the destination if it exists")
+	Cmd.Run = func(cmd *cobra.Command, args []string) {
+		runMv(args, *force)
+	}
+}
+
+func runMv(args []string, force bool) {
+	g, err := filesystem.NewGot()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	err = g.Move(args[:len(args)-1], args[len(args)-1], force)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,65 @@
+package pull
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"got/internal/cmd/fetch"
+	"got/internal/got/filesystem"
+	"got/internal/pretty"
+)
+
+var Cmd = &cobra.Command{
+	Use:   "pull [--no-verify] [<remote> [<branch>]]",
+	Short: "Fetch from another repository and merge one of its branches",
+	Long: `Fetches the remote and merges one of its branches into HEAD, by default the
+upstream of the checked out branch. HEAD is fast-forwarded if possible and
+otherwise a merge commit is made. Conflicting files are left with conflict
+markers, and committing their resolution concludes the merge.`,
+	Args: cobra.MaximumNArgs(2),
+}
+
+func init() {
+	var opts filesystem.PullOptions
+	Cmd.Flags().BoolVar(&opts.NoVerify, "no-verify", false, "skip the pre-merge-commit hook")
+	Cmd.Run = func(cmd *cobra.Command, args []string) {
+		runPull(args, opts)
+	}
+}
+
+func runPull(args []string, opts filesystem.PullOptions) {
+	g, err := filesystem.NewGot()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	remote, branch := g.DefaultRemote(), ""
+	if len(args) > 0 {
+		remote = args[0]
+	}
+	if len(args) > 1 {
+		branch = args[1]
+	}
+	fetched, result, err := g.Pull(remote, branch, opts)
+	fetch.PrintFetched(g.RemoteURL(remote), fetched)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	switch {
+	case result.UpToDate:
+		fmt.Println("Already up to date.")
+	case result.FastForward:
+		fmt.Printf("Fast-forward to %s\n", pretty.Abbrev(result.ID))
+	case len(result.Conflicts) > 0:
+		for _, path := range result.Conflicts {
+			fmt.Printf("CONFLICT: Merge conflict in %s\n", path)
+		}
+		fmt.Println("Automatic merge failed; fix conflicts and then commit the result.")
+		os.Exit(1)
+	default:
+		fmt.Printf("Merge made as %s\n", pretty.Abbrev(result.ID))
+	}
+}
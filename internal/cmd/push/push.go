@@ -0,0 +1,84 @@
+package push
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"got/internal/got/filesystem"
+	"got/internal/pretty"
+	"got/internal/transport"
+)
+
+var Cmd = &cobra.Command{
+	Use:   "push [-f] [-u] [--no-verify] [<remote> [<refspec>...]]",
+	Short: "Update the branches of another repository",
+	Long: `Sends the commits of local revisions to a remote and updates its branches.
+A refspec is '[+]<src>[:<dst>]', pushing the revision src to the branch dst,
+which defaults to src. Without refspecs the checked out branch is pushed to
+its upstream. Branches of the remote are only updated if the pushed commit
+contains the commit they are at, unless forced with --force or '+'.`,
+}
+
+func init() {
+	var opts filesystem.PushOptions
+	Cmd.Flags().BoolVarP(&opts.Force, "force", "f", false, "update the branches even if that loses commits")
+	Cmd.Flags().BoolVarP(&opts.SetUpstream, "set-upstream", "u", false, "make the pushed branches the upstreams of the local ones")
+	Cmd.Flags().BoolVar(&opts.NoVerify, "no-verify", false, "skip the pre-push hook")
+	Cmd.Run = func(cmd *cobra.Command, args []string) {
+		runPush(args, opts)
+	}
+}
+
+func runPush(args []string, opts filesystem.PushOptions) {
+	g, err := filesystem.NewGotAllowBare()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	remote := g.DefaultRemote()
+	if len(args) > 0 {
+		remote = args[0]
+		args = args[1:]
+	}
+	pushed, err := g.Push(remote, args, opts)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	url := g.RemoteURL(remote)
+	rejected, upToDate := false, true
+	for _, p := range pushed {
+		upToDate = upToDate && p.UpToDate()
+	}
+	if upToDate {
+		fmt.Println("Everything up-to-date")
+		return
+	}
+	fmt.Printf("To %s\n", url)
+	for _, p := range pushed {
+		refs := p.Src + " -> " + p.Branch
+		switch {
+		case p.Rejected == transport.RejectedCheckedOut:
+			rejected = true
+			fmt.Printf(" ! %-17s %s (%s)\n", "[remote rejected]", refs, p.Rejected)
+		case p.Rejected != "":
+			rejected = true
+			fmt.Printf(" ! %-17s %s (%s)\n", "[rejected]", refs, p.Rejected)
+		case p.UpToDate():
+			fmt.Printf(" = %-17s %s\n", "[up to date]", refs)
+		case p.Old == "":
+			fmt.Printf(" * %-17s %s\n", "[new branch]", refs)
+		case p.Force:
+			fmt.Printf(" + %-17s %s (forced update)\n", pretty.Abbrev(p.Old)+"..."+pretty.Abbrev(p.New), refs)
+		default:
+			fmt.Printf("   %-17s %s\n", pretty.Abbrev(p.Old)+".."+pretty.Abbrev(p.New), refs)
+		}
+	}
+	if rejected {
+		fmt.Printf("error: failed to push some refs to '%s'\n", url)
+		os.Exit(1)
+	}
+}
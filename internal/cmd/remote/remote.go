@@ -0,0 +1,75 @@
+package remote
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"got/internal/got/filesystem"
+)
+
+var Cmd = &cobra.Command{
+	Use:   "remote [-v | --verbose] | remote {add <name> <url> | remove <name>}",
+	Short: "Manage the remote repositories whose branches are tracked",
+	Args:  cobra.NoArgs,
+}
+
+var addCmd = &cobra.Command{
+	Use:   "add <name> <url>",
+	Short: "Add a remote whose branches are fetched into refs/remotes/<name>",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		g := openGot()
+		err := g.RemoteAdd(args[0], args[1])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var removeCmd = &cobra.Command{
+	Use:     "remove <name>",
+	Aliases: []string{"rm"},
+	Short:   "Remove a remote and its remote-tracking branches",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		g := openGot()
+		err := g.RemoteRemove(args[0])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	verbose := Cmd.Flags().BoolP("verbose", "v", false, "show the URL of every remote")
+	Cmd.Run = func(cmd *cobra.Command, args []string) {
+		runList(*verbose)
+	}
+	Cmd.AddCommand(addCmd)
+	Cmd.AddCommand(removeCmd)
+}
+
+func runList(verbose bool) {
+	g := openGot()
+	for _, name := range g.Remotes() {
+		if !verbose {
+			fmt.Println(name)
+			continue
+		}
+		fmt.Printf("%s\t%s (fetch)\n", name, g.RemoteURL(name))
+		fmt.Printf("%s\t%s (push)\n", name, g.RemoteURL(name))
+	}
+}
+
+func openGot() *filesystem.Got {
+	g, err := filesystem.NewGotAllowBare()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return g
+}
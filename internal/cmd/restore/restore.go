@@ -6,28 +6,53 @@ import (
 	"github.com/spf13/cobra"
 
 	"got/internal/got/filesystem"
+	"got/internal/interactive"
+	"got/internal/pkg/terminal"
 )
 
 var Cmd = &cobra.Command{
-	Use:   "restore [--staged] <file>",
+	Use:   "restore [--staged] [-p] <file>",
 	Short: "Restore specified file in the working tree from HEAD or Index",
-	Args:  cobra.MinimumNArgs(1),
+	Long: `Restores files in the working tree from the index, or in the index from
+HEAD with --staged. With -p the hunks of the changes are shown one at a
+time and only the ones chosen are discarded or unstaged.`,
 }
 
 func init() {
 	staged := Cmd.Flags().Bool("staged", false, "unstage changes from index")
+	patch := Cmd.Flags().BoolP("patch", "p", false, "interactively choose hunks to restore")
 	Cmd.Run = func(cmd *cobra.Command, args []string) {
-		restoreRun(cmd, args, *staged)
+		restoreRun(cmd, args, *staged, *patch)
 	}
 }
 
-func restoreRun(cmd *cobra.Command, args []string, staged bool) {
+func restoreRun(cmd *cobra.Command, args []string, staged bool, patch bool) {
+	if !patch && len(args) == 0 {
+		fmt.Println("you must specify path(s) to restore")
+		return
+	}
 	g, err := filesystem.NewGot()
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
 
+	if patch {
+		question := "Discard this hunk from worktree"
+		if staged {
+			question = "Unstage this hunk"
+		}
+		s := interactive.NewSession(cmd.InOrStdin(), cmd.OutOrStdout(), question)
+		s.Edit = func(text string) (string, error) {
+			return terminal.Edit(g.Editor(), text)
+		}
+		err := g.RestorePatch(args, staged, s.Select)
+		if err != nil {
+			fmt.Println(err)
+		}
+		return
+	}
+
 	if staged {
 		err := g.UnstagePath(args...)
 		if err != nil {
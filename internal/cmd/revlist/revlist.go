@@ -0,0 +1,57 @@
+package revlist
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"got/internal/got/filesystem"
+	"got/internal/revwalk"
+)
+
+var Cmd = &cobra.Command{
+	Use:   "rev-list [--topo-order | --date-order] [--reverse] [-n <number>] <commit>...",
+	Short: "List commits in reverse chronological order",
+	Long: `List commits that are reachable by following the parent links from the given
+commits, excluding commits reachable from commits given with a leading '^'.
+'a..b' is short for '^a b' and 'a...b' lists commits reachable from either
+a or b but not from both.`,
+	Args: cobra.MinimumNArgs(1),
+}
+
+func init() {
+	topo := Cmd.Flags().Bool("topo-order", false, "show no parents before all of their children are shown")
+	date := Cmd.Flags().Bool("date-order", false, "show commits in commit timestamp order")
+	reverse := Cmd.Flags().Bool("reverse", false, "output the commits in reverse order")
+	n := Cmd.Flags().IntP("max-count", "n", 0, "limit the number of commits to output")
+	Cmd.Run = func(cmd *cobra.Command, args []string) {
+		runRevList(cmd, args, sortFromFlags(*topo, *date, *reverse), *n)
+	}
+}
+
+func runRevList(cmd *cobra.Command, args []string, sort revwalk.Sort, n int) {
+	g, err := filesystem.NewGotAllowBare()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	ids, err := g.RevList(args, sort, n)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, id := range ids {
+		fmt.Println(id)
+	}
+}
+
+func sortFromFlags(topo, date, reverse bool) revwalk.Sort {
+	sort := revwalk.SortDate
+	if topo && !date {
+		sort = revwalk.SortTopological
+	}
+	if reverse {
+		sort |= revwalk.SortReverse
+	}
+	return sort
+}
@@ -0,0 +1,49 @@
+package rm
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"got/internal/got/filesystem"
+)
+
+var Cmd = &cobra.Command{
+	Use:   "rm [--cached] [-r] [-f] <path>...",
+	Short: "Remove files from the working tree and the index",
+	Long: `Removes tracked files from the index and the working tree, or only from
+the index with --cached. Directories are only removed with -r. Files with
+changes that aren't committed are kept unless -f is given.`,
+	Args: cobra.MinimumNArgs(1),
+}
+
+func init() {
+	var opts filesystem.RemoveOptions
+	Cmd.Flags().BoolVar(&opts.Cached, "cached", false, "only remove the files from the index")
+	Cmd.Flags().BoolVarP(&opts.Recursive, "recursive", "r", false, "remove directories recursively")
+	Cmd.Flags().BoolVarP(&opts.Force, "force", "f", false, "remove files even if they have changes")
+	quiet := Cmd.Flags().BoolP("quiet", "q", false, "don't list the removed files")
+	Cmd.Run = func(cmd *cobra.Command, args []string) {
+		runRm(args, opts, *quiet)
+	}
+}
+
+func runRm(args []string, opts filesystem.RemoveOptions, quiet bool) {
+	g, err := filesystem.NewGot()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	removed, err := g.Remove(args, opts)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if quiet {
+		return
+	}
+	for _, path := range removed {
+		fmt.Printf("rm '%s'\n", path)
+	}
+}
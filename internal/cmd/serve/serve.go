@@ -0,0 +1,46 @@
+package serve
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	gothttp "got/internal/transport/http"
+)
+
+var Cmd = &cobra.Command{
+	Use:   "serve [--listen <address>] [<directory>]",
+	Short: "Serve repositories over HTTP for clone, fetch and push",
+	Long: `Serves the repositories in the directory, by default the current one, over
+HTTP. A repository is served at its path relative to the directory, e.g.
+http://host:8080/projects/app for the repository in projects/app, and at the
+root if the directory is a repository itself. Pushes are accepted from anyone
+who can reach the address.`,
+	Args: cobra.MaximumNArgs(1),
+}
+
+func init() {
+	listen := Cmd.Flags().StringP("listen", "l", ":8080", "the address to listen on")
+	Cmd.Run = func(cmd *cobra.Command, args []string) {
+		runServe(*listen, args)
+	}
+}
+
+func runServe(listen string, args []string) {
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	if _, err := os.Stat(dir); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Printf("Serving %s on %s\n", dir, listen)
+	err := http.ListenAndServe(listen, gothttp.NewHandler(dir))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
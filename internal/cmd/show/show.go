@@ -0,0 +1,63 @@
+package show
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"got/internal/cmd/diff"
+	"got/internal/got/filesystem"
+)
+
+var Cmd = &cobra.Command{
+	Use:   "show [<options>] [<commit>] [--] [<path>...]",
+	Short: "Show a commit and the changes it made",
+	Long: `Shows the log message of a commit, HEAD by default, and the changes it
+made compared to its first parent.`,
+}
+
+func init() {
+	format := diff.FormatFlags(Cmd, filesystem.DiffFormatPatch)
+	renames := diff.RenameFlags(Cmd)
+	binary := Cmd.Flags().Bool("binary", false, "Output binary files as patches that can be applied")
+	wordDiff := diff.WordDiffFlags(Cmd)
+	whitespace := diff.WhitespaceFlags(Cmd)
+	Cmd.Run = func(cmd *cobra.Command, args []string) {
+		runShow(cmd, args, *binary, format(), renames, wordDiff, whitespace)
+	}
+}
+
+func runShow(cmd *cobra.Command, args []string, binary bool, format filesystem.DiffFormat, renames, wordDiff func(*filesystem.Got) error, whitespace func(*filesystem.Got)) {
+	g, err := filesystem.NewGotAllowBare()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	g.BinaryPatches = binary
+	whitespace(g)
+	err = renames(g)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	err = wordDiff(g)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	revs, paths := diff.SplitRevisionsAndPaths(g, args, cmd.ArgsLenAtDash())
+	if len(revs) > 1 {
+		fmt.Println("show takes a single commit")
+		return
+	}
+	rev := "HEAD"
+	if len(revs) == 1 {
+		rev = revs[0]
+	}
+	out, err := g.Show(rev, paths, format)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Print(out)
+}
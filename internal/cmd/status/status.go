@@ -1,7 +1,9 @@
 package status
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 
 	"github.com/spf13/cobra"
 
@@ -9,39 +11,70 @@ import (
 )
 
 var Cmd = &cobra.Command{
-	Use:   "status",
+	Use:   "status [-s | --porcelain[=<version>] | --json] [-b] [-z]",
 	Short: "Show status of index and working tree",
-	Args:  cobra.NoArgs,
-	Run:   runStatus,
+	Long: `Shows the files that differ between HEAD and the index, the files that
+differ between the index and the working tree, and the untracked files.
+--short shows a line per file, --porcelain=v1 does the same without colors in
+a format that stays stable for scripts, and --porcelain=v2 adds the modes and
+IDs of the files. --json prints the status as a JSON object.`,
+	Args: cobra.NoArgs,
 }
 
-func runStatus(cmd *cobra.Command, args []string) {
+type options struct {
+	short     bool
+	branch    bool
+	porcelain string
+	z         bool
+	json      bool
+}
+
+func init() {
+	var opts options
+	Cmd.Flags().BoolVarP(&opts.short, "short", "s", false, "give the output in the short format")
+	Cmd.Flags().BoolVarP(&opts.branch, "branch", "b", false, "show the branch and its upstream in the short formats")
+	Cmd.Flags().StringVar(&opts.porcelain, "porcelain", "", "give the output in a stable format for scripts, v1 or v2")
+	Cmd.Flags().Lookup("porcelain").NoOptDefVal = "v1"
+	Cmd.Flags().BoolVarP(&opts.z, "null", "z", false, "terminate entries with NUL, implies --porcelain=v1 unless another format is given")
+	Cmd.Flags().BoolVar(&opts.json, "json", false, "give the output as JSON")
+	Cmd.Run = func(cmd *cobra.Command, args []string) {
+		runStatus(opts)
+	}
+}
+
+func runStatus(opts options) {
 	g, err := filesystem.NewGot()
 	if err != nil {
 		fmt.Println(err)
-		return
+		os.Exit(1)
 	}
-	headType, err := g.HeadType()
-	if err != nil {
-		fmt.Println(err)
-		return
+	if opts.z && opts.porcelain == "" && !opts.json {
+		opts.porcelain = "v1"
 	}
 	s, err := g.Status()
 	if err != nil {
 		fmt.Println(err)
-		return
+		os.Exit(1)
 	}
 
-	switch headType {
-	case filesystem.HeadTypeEmpty:
-		fmt.Println("No commits yet")
-	case filesystem.HeadTypeRef:
-		ref, err := g.HeadAsRef()
+	switch {
+	case opts.json:
+		bs, err := json.MarshalIndent(s, "", "  ")
 		if err != nil {
 			fmt.Println(err)
-			return
+			os.Exit(1)
 		}
-		fmt.Printf("On branch %s\n", ref.Name())
+		fmt.Println(string(bs))
+	case opts.porcelain == "v1":
+		fmt.Print(s.PorcelainV1(opts.branch, opts.z))
+	case opts.porcelain == "v2":
+		fmt.Print(s.PorcelainV2(opts.branch, opts.z))
+	case opts.porcelain != "":
+		fmt.Printf("unsupported porcelain version '%s'\n", opts.porcelain)
+		os.Exit(128)
+	case opts.short:
+		fmt.Print(s.Short(opts.branch))
+	default:
+		fmt.Print(s)
 	}
-	fmt.Println(s)
 }
@@ -0,0 +1,372 @@
+package commitgraph
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"got/internal/objects"
+	"got/internal/pkg/filesystem"
+	"got/internal/revwalk"
+)
+
+// The location of the commit-graph file relative to the objects directory
+var File = filepath.Join("info", "commit-graph")
+
+// The commit-graph file consists of
+//
+//	header     'CGPH', version, hash length, 2 reserved bytes, commit count
+//	fanout     256 cumulative counts of commits by the first byte of their ID
+//	ids        the sorted commit IDs, hashLength bytes each
+//	data       per commit: tree ID, first and second parent positions,
+//	           generation number and commit time
+//	extra      count followed by the parent positions of octopus merges
+//	trailer    SHA-1 of everything before it
+//
+// A second parent position with extraEdge set points into the extra parents,
+// which continue until a position with extraEdge set.
+const (
+	magic      = "CGPH"
+	version    = 1
+	hashLength = 20
+	headerSize = 12
+	fanoutSize = 256 * 4
+	dataSize   = hashLength + 4 + 4 + 4 + 8
+
+	noParent  = 0x70000000
+	extraEdge = 0x80000000
+)
+
+// A commit as stored in the commit-graph
+type Node struct {
+	Parents []objects.ID
+	TreeID  objects.ID
+	// One more than the largest generation of the parents, 1 for root
+	// commits. A commit can only be an ancestor of commits with a greater
+	// generation.
+	Generation uint32
+	// The commit time as a UNIX timestamp
+	Time int64
+}
+
+type Graph struct {
+	ids   []objects.ID
+	nodes []Node
+	index map[objects.ID]int
+}
+
+// Returns the commit-graph stored in the given objects directory
+func Read(objectsDir string) (*Graph, error) {
+	bs, err := ioutil.ReadFile(filepath.Join(objectsDir, File))
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't read commit-graph")
+	}
+	g, err := decode(bs)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't read commit-graph")
+	}
+	return g, nil
+}
+
+func (g *Graph) Len() int {
+	return len(g.ids)
+}
+
+// Returns the IDs of every commit in the graph, sorted
+func (g *Graph) IDs() []objects.ID {
+	return g.ids
+}
+
+func (g *Graph) Node(id objects.ID) (Node, bool) {
+	i, ok := g.index[id]
+	if !ok {
+		return Node{}, false
+	}
+	return g.nodes[i], true
+}
+
+// Implements revwalk.CommitGraph
+func (g *Graph) Lookup(id objects.ID) ([]objects.ID, int64, uint32, bool) {
+	n, ok := g.Node(id)
+	return n.Parents, n.Time, n.Generation, ok
+}
+
+// Writes a commit-graph of every commit reachable from tips to the given
+// objects directory and returns the number of commits in it.
+func Write(objectsDir string, commits revwalk.CommitGetter, tips []objects.ID) (int, error) {
+	w := revwalk.NewWalker(commits)
+	w.Sorting(revwalk.SortTopological | revwalk.SortReverse)
+	for _, tip := range tips {
+		w.Push(tip)
+	}
+	g := &Graph{index: make(map[objects.ID]int)}
+	generations := make(map[objects.ID]uint32)
+	var nodes = make(map[objects.ID]Node)
+	err := w.Walk(func(id objects.ID, c objects.Commit) error {
+		// Parents are always walked before their children
+		gen := uint32(1)
+		for _, p := range c.Parents() {
+			if generations[p]+1 > gen {
+				gen = generations[p] + 1
+			}
+		}
+		generations[id] = gen
+		nodes[id] = Node{Parents: c.Parents(), TreeID: c.TreeID, Generation: gen, Time: c.Time().Unix()}
+		g.ids = append(g.ids, id)
+		return nil
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "couldn't write commit-graph")
+	}
+	sort.Slice(g.ids, func(i, j int) bool { return g.ids[i] < g.ids[j] })
+	for i, id := range g.ids {
+		g.index[id] = i
+		g.nodes = append(g.nodes, nodes[id])
+	}
+
+	bs, err := g.encode()
+	if err != nil {
+		return 0, errors.Wrap(err, "couldn't write commit-graph")
+	}
+	err = filesystem.MkDirIfIsNotExist(filepath.Join(objectsDir, filepath.Dir(File)), os.ModePerm)
+	if err != nil {
+		return 0, errors.Wrap(err, "couldn't write commit-graph")
+	}
+	tmp := filepath.Join(objectsDir, File+".lock")
+	err = ioutil.WriteFile(tmp, bs, 0644)
+	if err != nil {
+		return 0, errors.Wrap(err, "couldn't write commit-graph")
+	}
+	err = os.Rename(tmp, filepath.Join(objectsDir, File))
+	if err != nil {
+		return 0, errors.Wrap(err, "couldn't write commit-graph")
+	}
+	return len(g.ids), nil
+}
+
+// Checks the checksum of the commit-graph in the given objects directory and
+// that every commit in it agrees with the commit objects.
+func Verify(objectsDir string, commits revwalk.CommitGetter) error {
+	g, err := Read(objectsDir)
+	if err != nil {
+		return err
+	}
+	for i, id := range g.ids {
+		if i > 0 && g.ids[i-1] >= id {
+			return errors.Errorf("commit-graph ids are not sorted at %s", id)
+		}
+		c, err := commits.GetCommit(id)
+		if err != nil {
+			return errors.Wrapf(err, "commit-graph contains unknown commit %s", id)
+		}
+		n := g.nodes[i]
+		if n.TreeID != c.TreeID {
+			return errors.Errorf("commit-graph has wrong tree for %s", id)
+		}
+		if n.Time != c.Time().Unix() {
+			return errors.Errorf("commit-graph has wrong time for %s", id)
+		}
+		parents := c.Parents()
+		if len(parents) != len(n.Parents) {
+			return errors.Errorf("commit-graph has wrong parents for %s", id)
+		}
+		gen := uint32(1)
+		for j, p := range parents {
+			if p != n.Parents[j] {
+				return errors.Errorf("commit-graph has wrong parents for %s", id)
+			}
+			pn, ok := g.Node(p)
+			if !ok {
+				return errors.Errorf("commit-graph is missing parent %s of %s", p, id)
+			}
+			if pn.Generation+1 > gen {
+				gen = pn.Generation + 1
+			}
+		}
+		if n.Generation != gen {
+			return errors.Errorf("commit-graph has wrong generation for %s", id)
+		}
+	}
+	return nil
+}
+
+func (g *Graph) encode() ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString(magic)
+	buf.Write([]byte{version, hashLength, 0, 0})
+	writeUint32(buf, uint32(len(g.ids)))
+
+	var fanout [256]uint32
+	for _, id := range g.ids {
+		raw, err := rawID(id)
+		if err != nil {
+			return nil, err
+		}
+		fanout[raw[0]]++
+	}
+	var total uint32
+	for _, count := range fanout {
+		total += count
+		writeUint32(buf, total)
+	}
+	for _, id := range g.ids {
+		raw, _ := rawID(id)
+		buf.Write(raw)
+	}
+
+	var extra []uint32
+	for _, n := range g.nodes {
+		tree, err := rawID(n.TreeID)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(tree)
+		var positions []uint32
+		for _, p := range n.Parents {
+			i, ok := g.index[p]
+			if !ok {
+				return nil, errors.Errorf("parent %s is missing from the commit-graph", p)
+			}
+			positions = append(positions, uint32(i))
+		}
+		switch len(positions) {
+		case 0:
+			writeUint32(buf, noParent)
+			writeUint32(buf, noParent)
+		case 1:
+			writeUint32(buf, positions[0])
+			writeUint32(buf, noParent)
+		case 2:
+			writeUint32(buf, positions[0])
+			writeUint32(buf, positions[1])
+		default:
+			writeUint32(buf, positions[0])
+			writeUint32(buf, extraEdge|uint32(len(extra)))
+			for j, p := range positions[1:] {
+				if j == len(positions)-2 {
+					p |= extraEdge
+				}
+				extra = append(extra, p)
+			}
+		}
+		writeUint32(buf, n.Generation)
+		_ = binary.Write(buf, binary.BigEndian, n.Time)
+	}
+	writeUint32(buf, uint32(len(extra)))
+	for _, e := range extra {
+		writeUint32(buf, e)
+	}
+
+	sum := sha1.Sum(buf.Bytes())
+	buf.Write(sum[:])
+	return buf.Bytes(), nil
+}
+
+func decode(bs []byte) (*Graph, error) {
+	if len(bs) < headerSize+fanoutSize+4+sha1.Size {
+		return nil, errors.New("file too short")
+	}
+	body, trailer := bs[:len(bs)-sha1.Size], bs[len(bs)-sha1.Size:]
+	if sum := sha1.Sum(body); !bytes.Equal(sum[:], trailer) {
+		return nil, errors.New("checksum mismatch")
+	}
+	if string(body[:4]) != magic || body[4] != version || body[5] != hashLength {
+		return nil, errors.New("unsupported format")
+	}
+	count := int(binary.BigEndian.Uint32(body[8:12]))
+	if int(binary.BigEndian.Uint32(body[headerSize+fanoutSize-4:])) != count {
+		return nil, errors.New("fanout doesn't match commit count")
+	}
+	idsStart := headerSize + fanoutSize
+	dataStart := idsStart + count*hashLength
+	extraStart := dataStart + count*dataSize
+	if len(body) < extraStart+4 {
+		return nil, errors.New("file too short")
+	}
+	extraCount := int(binary.BigEndian.Uint32(body[extraStart:]))
+	if len(body) != extraStart+4+extraCount*4 {
+		return nil, errors.New("unexpected file length")
+	}
+	extra := func(i int) uint32 {
+		return binary.BigEndian.Uint32(body[extraStart+4+i*4:])
+	}
+
+	g := &Graph{
+		ids:   make([]objects.ID, count),
+		nodes: make([]Node, count),
+		index: make(map[objects.ID]int, count),
+	}
+	for i := 0; i < count; i++ {
+		off := idsStart + i*hashLength
+		g.ids[i] = objects.ID(hex.EncodeToString(body[off : off+hashLength]))
+		g.index[g.ids[i]] = i
+	}
+	parent := func(pos uint32) (objects.ID, error) {
+		if int(pos) >= count {
+			return "", errors.Errorf("parent position %d out of range", pos)
+		}
+		return g.ids[pos], nil
+	}
+	for i := 0; i < count; i++ {
+		d := body[dataStart+i*dataSize:]
+		n := Node{
+			TreeID:     objects.ID(hex.EncodeToString(d[:hashLength])),
+			Generation: binary.BigEndian.Uint32(d[hashLength+8:]),
+			Time:       int64(binary.BigEndian.Uint64(d[hashLength+12:])),
+		}
+		p1 := binary.BigEndian.Uint32(d[hashLength:])
+		p2 := binary.BigEndian.Uint32(d[hashLength+4:])
+		if p1 != noParent {
+			id, err := parent(p1)
+			if err != nil {
+				return nil, err
+			}
+			n.Parents = append(n.Parents, id)
+		}
+		switch {
+		case p2 == noParent:
+		case p2&extraEdge != 0:
+			for j := int(p2 &^ extraEdge); ; j++ {
+				if j >= extraCount {
+					return nil, errors.New("extra parent out of range")
+				}
+				e := extra(j)
+				id, err := parent(e &^ extraEdge)
+				if err != nil {
+					return nil, err
+				}
+				n.Parents = append(n.Parents, id)
+				if e&extraEdge != 0 {
+					break
+				}
+			}
+		default:
+			id, err := parent(p2)
+			if err != nil {
+				return nil, err
+			}
+			n.Parents = append(n.Parents, id)
+		}
+		g.nodes[i] = n
+	}
+	return g, nil
+}
+
+func writeUint32(buf *bytes.Buffer, v uint32) {
+	_ = binary.Write(buf, binary.BigEndian, v)
+}
+
+func rawID(id objects.ID) ([]byte, error) {
+	raw, err := hex.DecodeString(string(id))
+	if err != nil || len(raw) != hashLength {
+		return nil, errors.Errorf("%s is not an id", id)
+	}
+	return raw, nil
+}
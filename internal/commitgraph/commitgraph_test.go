@@ -0,0 +1,120 @@
+package commitgraph
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"got/internal/objects"
+	"got/internal/revwalk"
+)
+
+type memCommits map[objects.ID]objects.Commit
+
+func (m memCommits) GetCommit(id objects.ID) (objects.Commit, error) {
+	c, ok := m[id]
+	if !ok {
+		return objects.Commit{}, errors.Errorf("no commit %s", id)
+	}
+	return c, nil
+}
+
+// Builds a linear history of n commits with an octopus merge on top and
+// returns the commits together with the ID of the merge.
+func history(n int) (memCommits, objects.ID) {
+	commits := make(memCommits)
+	tree := objects.Tree{}.ID()
+	var parents []objects.ID
+	var last objects.ID
+	for i := 0; i < n; i++ {
+		c := objects.NewMergeCommit(tree, parents, fmt.Sprintf("A <a@b.c> %d +0000", i), fmt.Sprint(i))
+		commits[c.ID()] = c
+		last = c.ID()
+		parents = []objects.ID{last}
+	}
+	var tips []objects.ID
+	for i := 0; i < 3; i++ {
+		c := objects.NewCommit(tree, &last, fmt.Sprintf("A <a@b.c> %d +0000", n), fmt.Sprint("side", i))
+		commits[c.ID()] = c
+		tips = append(tips, c.ID())
+	}
+	merge := objects.NewMergeCommit(tree, tips, fmt.Sprintf("A <a@b.c> %d +0000", n+1), "merge")
+	commits[merge.ID()] = merge
+	return commits, merge.ID()
+}
+
+func TestWriteRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "got-commit-graph")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	commits, tip := history(10)
+	n, err := Write(dir, commits, []objects.ID{tip})
+	assert.Nil(t, err)
+	assert.Equal(t, len(commits), n)
+
+	g, err := Read(dir)
+	assert.Nil(t, err)
+	for id, c := range commits {
+		node, ok := g.Node(id)
+		assert.True(t, ok)
+		assert.Equal(t, c.Parents(), node.Parents)
+		assert.Equal(t, c.TreeID, node.TreeID)
+		assert.Equal(t, c.Time().Unix(), node.Time)
+	}
+	node, _ := g.Node(tip)
+	assert.Equal(t, uint32(12), node.Generation)
+	assert.Nil(t, Verify(dir, commits))
+}
+
+func TestReadCorrupt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "got-commit-graph")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	commits, tip := history(3)
+	_, err = Write(dir, commits, []objects.ID{tip})
+	assert.Nil(t, err)
+	bs, err := ioutil.ReadFile(filepath.Join(dir, File))
+	assert.Nil(t, err)
+	bs[len(bs)/2] ^= 0xff
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, File), bs, 0644))
+	_, err = Read(dir)
+	assert.NotNil(t, err)
+}
+
+func TestWalkerUsesGraph(t *testing.T) {
+	dir, err := ioutil.TempDir("", "got-commit-graph")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	commits, tip := history(1000)
+	_, err = Write(dir, commits, []objects.ID{tip})
+	assert.Nil(t, err)
+	g, err := Read(dir)
+	assert.Nil(t, err)
+
+	// Traversal must not need the commit objects when they are in the graph
+	w := revwalk.NewWalker(memCommits{})
+	w.UseGraph(g)
+	root := g.IDs()[0]
+	for _, id := range g.IDs() {
+		if n, _ := g.Node(id); n.Generation == 1 {
+			root = id
+		}
+	}
+	ok, err := w.IsAncestor(root, tip)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, err = w.IsAncestor(tip, root)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	base, err := w.MergeBase(tip, root)
+	assert.Nil(t, err)
+	assert.Equal(t, root, base)
+}
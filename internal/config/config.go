@@ -0,0 +1,203 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/magiconair/properties"
+	"github.com/pkg/errors"
+
+	"got/internal/pkg/filesystem"
+)
+
+const (
+	// The config file of a repository, relative to the .got directory
+	File = "config"
+	// The config file shared by every repository, relative to the home
+	// directory of the user
+	GlobalFile = ".gotconfig"
+)
+
+// Configuration read from the repository and global config files. Keys are
+// dot separated, such as 'diff.algorithm' or 'remote.origin.url', and values
+// in the repository config take precedence over global ones.
+type Config struct {
+	file   string
+	local  *properties.Properties
+	global *properties.Properties
+}
+
+// Reads the config of the repository with the given .got directory together
+// with the global config. Missing files are treated as empty.
+func Read(gotDir string) (*Config, error) {
+	c := &Config{file: filepath.Join(gotDir, File)}
+	var err error
+	c.local, err = load(c.file)
+	if err != nil {
+		return nil, err
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		c.global, err = load(filepath.Join(home, GlobalFile))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		c.global = newProperties()
+	}
+	return c, nil
+}
+
+// Reads only the global config, for use outside of a repository
+func ReadGlobal() (*Config, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't read global config")
+	}
+	global, err := load(filepath.Join(home, GlobalFile))
+	if err != nil {
+		return nil, err
+	}
+	return &Config{file: filepath.Join(home, GlobalFile), local: global, global: newProperties()}, nil
+}
+
+func load(file string) (*properties.Properties, error) {
+	p := newProperties()
+	if !filesystem.FileExists(file) {
+		return p, nil
+	}
+	bs, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't read config %s", file)
+	}
+	err = p.Load(bs, properties.UTF8)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't read config %s", file)
+	}
+	return p, nil
+}
+
+func newProperties() *properties.Properties {
+	p := properties.NewProperties()
+	p.DisableExpansion = true
+	return p
+}
+
+func (c *Config) Get(key string) (string, bool) {
+	if v, ok := c.local.Get(key); ok {
+		return v, true
+	}
+	return c.global.Get(key)
+}
+
+func (c *Config) GetString(key, def string) string {
+	if v, ok := c.Get(key); ok {
+		return v
+	}
+	return def
+}
+
+// Returns the value of key as a boolean. 'true', 'yes', 'on' and '1' are
+// true and every other value false.
+func (c *Config) GetBool(key string, def bool) bool {
+	v, ok := c.Get(key)
+	if !ok {
+		return def
+	}
+	switch strings.ToLower(v) {
+	case "true", "yes", "on", "1":
+		return true
+	}
+	return false
+}
+
+func (c *Config) GetInt(key string, def int) int {
+	v, ok := c.Get(key)
+	if !ok {
+		return def
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return def
+	}
+	return i
+}
+
+// Returns every key, sorted
+func (c *Config) Keys() []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, k := range append(c.global.Keys(), c.local.Keys()...) {
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Returns the names of the subsections of a section, e.g. the names of all
+// remotes for the section 'remote' given the key 'remote.origin.url'.
+func (c *Config) Subsections(section string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, k := range c.Keys() {
+		if !strings.HasPrefix(k, section+".") {
+			continue
+		}
+		rest := strings.TrimPrefix(k, section+".")
+		i := strings.LastIndex(rest, ".")
+		if i < 0 {
+			continue
+		}
+		if name := rest[:i]; !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Sets key to value in the repository config and writes it to disk
+func (c *Config) Set(key, value string) error {
+	_, _, err := c.local.Set(key, value)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't set %s", key)
+	}
+	return c.write()
+}
+
+// Removes key from the repository config and writes it to disk
+func (c *Config) Unset(key string) error {
+	c.local.Delete(key)
+	return c.write()
+}
+
+// Removes every key of a subsection, such as 'remote.origin', from the
+// repository config and writes it to disk
+func (c *Config) RemoveSection(section string) error {
+	for _, k := range c.local.Keys() {
+		if strings.HasPrefix(k, section+".") {
+			c.local.Delete(k)
+		}
+	}
+	return c.write()
+}
+
+func (c *Config) write() error {
+	buf := bytes.NewBuffer(nil)
+	_, err := c.local.Write(buf, properties.UTF8)
+	if err != nil {
+		return errors.Wrap(err, "couldn't write config")
+	}
+	err = ioutil.WriteFile(c.file, buf.Bytes(), 0644)
+	if err != nil {
+		return errors.Wrap(err, "couldn't write config")
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package algorithm
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"got/internal/diff"
+	"got/internal/diff/simple"
+)
+
+// The names accepted by --diff-algorithm and the 'diff.algorithm' config
+const (
+	NameMyers     = "myers"
+	NameMinimal   = "minimal"
+	NamePatience  = "patience"
+	NameHistogram = "histogram"
+	NameSimple    = "simple"
+	Default       = NameMyers
+)
+
+// Returns the Differ with the given name
+func ByName(name string) (diff.Differ, error) {
+	switch strings.ToLower(name) {
+	case NameMyers, NameMinimal, "default", "":
+		return Myers{}, nil
+	case NamePatience:
+		return Patience{}, nil
+	case NameHistogram:
+		return Histogram{}, nil
+	case NameSimple:
+		return simple.Diff{}, nil
+	}
+	return nil, errors.Errorf("unknown diff algorithm %s", name)
+}
+
+// Returns the names of every algorithm
+func Names() []string {
+	names := []string{NameMyers, NameMinimal, NamePatience, NameHistogram, NameSimple}
+	sort.Strings(names)
+	return names
+}
+
+// The state of diffing two sequences of lines that the algorithms share. The
+// lines are interned into ints so that comparing them is cheap.
+type differ struct {
+	a, b []int
+	ops  []diff.EditType
+}
+
+func newDiffer(a, b []string) *differ {
+	ids := make(map[string]int)
+	intern := func(lines []string) []int {
+		is := make([]int, len(lines))
+		for i, l := range lines {
+			id, ok := ids[l]
+			if !ok {
+				id = len(ids)
+				ids[l] = id
+			}
+			is[i] = id
+		}
+		return is
+	}
+	return &differ{a: intern(a), b: intern(b)}
+}
+
+func (d *differ) emit(op diff.EditType, n int) {
+	for i := 0; i < n; i++ {
+		d.ops = append(d.ops, op)
+	}
+}
+
+// Emits the lines common to the start of both ranges and returns the new
+// start of the ranges.
+func (d *differ) commonPrefix(a0, a1, b0, b1 int) (int, int) {
+	n := 0
+	for a0+n < a1 && b0+n < b1 && d.a[a0+n] == d.b[b0+n] {
+		n++
+	}
+	d.emit(diff.EQL, n)
+	return a0 + n, b0 + n
+}
+
+// Returns the number of lines common to the end of both ranges
+func (d *differ) commonSuffix(a0, a1, b0, b1 int) int {
+	n := 0
+	for a1-n > a0 && b1-n > b0 && d.a[a1-n-1] == d.b[b1-n-1] {
+		n++
+	}
+	return n
+}
+
+// Diffs the lines of a and b with the given algorithm
+func diffBytes(a []byte, b []byte, algorithm func(d *differ, a0, a1, b0, b1 int)) diff.BytesDiff {
+	if a == nil && b == nil {
+		return nil
+	}
+	as, bs := diff.Lines(a), diff.Lines(b)
+	d := newDiffer(as, bs)
+	algorithm(d, 0, len(as), 0, len(bs))
+	return diff.NewBytesDiff(as, bs, d.ops)
+}
+
+// The parts of the diff.Differ interface that don't depend on the algorithm
+type fileDiffer struct{}
+
+func (fileDiffer) FilesDiff(a []byte, b []byte) bool {
+	return !bytes.Equal(a, b)
+}
+
+func (fileDiffer) DiffFiles(a []byte, b []byte) (diff.FileEditType, error) {
+	switch {
+	case a == nil && b == nil:
+		return "", nil
+	case a == nil:
+		return diff.FileEditTypeCreate, nil
+	case b == nil:
+		return diff.FileEditTypeDelete, nil
+	case bytes.Equal(a, b):
+		return diff.FileEditTypeUnmodified, nil
+	}
+	return diff.FileEditTypeInPlace, nil
+}
@@ -0,0 +1,128 @@
+package algorithm
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/assert"
+
+	"got/internal/diff"
+)
+
+var differs = map[string]diff.Differ{
+	NameMyers:     Myers{},
+	NamePatience:  Patience{},
+	NameHistogram: Histogram{},
+}
+
+// Generates the contents of a file from a small alphabet of lines so that
+// repeated and shared lines are common
+func randomContents(r *rand.Rand) []byte {
+	n := r.Intn(30)
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = string(rune('a' + r.Intn(6)))
+	}
+	if n == 0 {
+		return []byte{}
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+type contents []byte
+
+func (contents) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(contents(randomContents(r)))
+}
+
+func TestEditScriptReproducesTarget(t *testing.T) {
+	for name, d := range differs {
+		d := d
+		property := func(a, b contents) bool {
+			bd := d.DiffBytes(a, b)
+			return equalLines(diff.SplitLines(a), bd.Src()) && equalLines(diff.SplitLines(b), bd.Dst())
+		}
+		err := quick.Check(property, &quick.Config{MaxCount: 500})
+		assert.Nil(t, err, name)
+	}
+}
+
+func TestMyersIsMinimal(t *testing.T) {
+	property := func(a, b contents) bool {
+		bd := Myers{}.DiffBytes(a, b)
+		as, bs := diff.SplitLines(a), diff.SplitLines(b)
+		edits := 0
+		for _, le := range bd {
+			if le.EditType != diff.EQL {
+				edits++
+			}
+		}
+		return edits == len(as)+len(bs)-2*lcs(as, bs)
+	}
+	assert.Nil(t, quick.Check(property, &quick.Config{MaxCount: 500}))
+}
+
+func TestLineNumbers(t *testing.T) {
+	for name, d := range differs {
+		bd := d.DiffBytes([]byte("a\nb\nc\n"), []byte("a\nx\nc\nd\n"))
+		expected := diff.BytesDiff{
+			diff.NewLineEdit(diff.EQL, "a", 0, 0),
+			diff.NewLineEdit(diff.DEL, "b", 1, 1),
+			diff.NewLineEdit(diff.INS, "x", 2, 1),
+			diff.NewLineEdit(diff.EQL, "c", 2, 2),
+			diff.NewLineEdit(diff.INS, "d", 3, 3),
+		}
+		assert.Equal(t, expected, bd, name)
+	}
+}
+
+func TestLargeFiles(t *testing.T) {
+	var a, b strings.Builder
+	for i := 0; i < 50000; i++ {
+		fmt.Fprintf(&a, "line %d\n", i)
+		if i%1000 == 0 {
+			fmt.Fprintf(&b, "changed %d\n", i)
+		} else {
+			fmt.Fprintf(&b, "line %d\n", i)
+		}
+	}
+	for name, d := range differs {
+		bd := d.DiffBytes([]byte(a.String()), []byte(b.String()))
+		assert.Equal(t, 50000+50, len(bd), name)
+	}
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func lcs(a, b []string) int {
+	v := make([][]int, len(a)+1)
+	for i := range v {
+		v[i] = make([]int, len(b)+1)
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				v[i][j] = v[i-1][j-1] + 1
+			} else if v[i-1][j] > v[i][j-1] {
+				v[i][j] = v[i-1][j]
+			} else {
+				v[i][j] = v[i][j-1]
+			}
+		}
+	}
+	return v[len(a)][len(b)]
+}
@@ -0,0 +1,93 @@
+package algorithm
+
+import "got/internal/diff"
+
+// Lines occurring more often than this in a range are never used to split it
+const maxChainLength = 64
+
+// Histogram diff is an extension of patience diff that also uses lines that
+// occur more than once. It splits the ranges at the longest common region
+// containing the line that occurs the fewest times in a, and diffs the parts
+// before and after it recursively. Ranges without such a region are diffed
+// with Myers.
+type Histogram struct {
+	fileDiffer
+}
+
+func (Histogram) DiffBytes(a []byte, b []byte) diff.BytesDiff {
+	return diffBytes(a, b, (*differ).histogram)
+}
+
+func (d *differ) histogram(a0, a1, b0, b1 int) {
+	a0, b0 = d.commonPrefix(a0, a1, b0, b1)
+	suffix := d.commonSuffix(a0, a1, b0, b1)
+	a1 -= suffix
+	b1 -= suffix
+
+	switch {
+	case a0 == a1:
+		d.emit(diff.INS, b1-b0)
+	case b0 == b1:
+		d.emit(diff.DEL, a1-a0)
+	default:
+		as, ae, bs, be, ok := d.lowestOccurrenceRegion(a0, a1, b0, b1)
+		if !ok {
+			d.myers(a0, a1, b0, b1)
+		} else {
+			d.histogram(a0, as, b0, bs)
+			d.emit(diff.EQL, ae-as)
+			d.histogram(ae, a1, be, b1)
+		}
+	}
+	d.emit(diff.EQL, suffix)
+}
+
+// Returns the common region [as, ae) of a and [bs, be) of b whose rarest
+// line occurs the fewest times in a, preferring longer regions on ties.
+func (d *differ) lowestOccurrenceRegion(a0, a1, b0, b1 int) (int, int, int, int, bool) {
+	positions := make(map[int][]int)
+	for i := a0; i < a1; i++ {
+		positions[d.a[i]] = append(positions[d.a[i]], i)
+	}
+
+	found := false
+	bestCount := maxChainLength + 1
+	var as, ae, bs, be int
+	for bi := b0; bi < b1; {
+		next := bi + 1
+		occurrences := positions[d.b[bi]]
+		if len(occurrences) == 0 || len(occurrences) > bestCount {
+			bi = next
+			continue
+		}
+		for _, ai := range occurrences {
+			// Extend the match in both directions
+			s, t := ai, bi
+			for s > a0 && t > b0 && d.a[s-1] == d.b[t-1] {
+				s--
+				t--
+			}
+			e, f := ai+1, bi+1
+			for e < a1 && f < b1 && d.a[e] == d.b[f] {
+				e++
+				f++
+			}
+			count := len(occurrences)
+			for i := s; i < e; i++ {
+				if c := len(positions[d.a[i]]); c < count {
+					count = c
+				}
+			}
+			if count < bestCount || (count == bestCount && e-s > ae-as) {
+				found = true
+				bestCount = count
+				as, ae, bs, be = s, e, t, f
+			}
+			if f > next {
+				next = f
+			}
+		}
+		bi = next
+	}
+	return as, ae, bs, be, found
+}
@@ -0,0 +1,124 @@
+package algorithm
+
+import "got/internal/diff"
+
+// Finds a shortest edit script with Eugene Myers' O(ND) algorithm, using the
+// linear space refinement that bisects the edit graph at its middle snake.
+type Myers struct {
+	fileDiffer
+}
+
+func (Myers) DiffBytes(a []byte, b []byte) diff.BytesDiff {
+	return diffBytes(a, b, (*differ).myers)
+}
+
+func (d *differ) myers(a0, a1, b0, b1 int) {
+	a0, b0 = d.commonPrefix(a0, a1, b0, b1)
+	suffix := d.commonSuffix(a0, a1, b0, b1)
+	a1 -= suffix
+	b1 -= suffix
+
+	switch {
+	case a0 == a1:
+		d.emit(diff.INS, b1-b0)
+	case b0 == b1:
+		d.emit(diff.DEL, a1-a0)
+	default:
+		x, y, ok := d.middleSnake(a0, a1, b0, b1)
+		if !ok {
+			d.emit(diff.DEL, a1-a0)
+			d.emit(diff.INS, b1-b0)
+		} else {
+			d.myers(a0, x, b0, y)
+			d.myers(x, a1, y, b1)
+		}
+	}
+	d.emit(diff.EQL, suffix)
+}
+
+// Runs the search for the shortest edit script from both ends of the ranges
+// at once and returns where the two searches meet. The point splits the
+// problem into two smaller ones whose shortest edit scripts together form a
+// shortest edit script of the whole ranges.
+func (d *differ) middleSnake(a0, a1, b0, b1 int) (int, int, bool) {
+	n, m := a1-a0, b1-b0
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	size := 2 * maxD
+	forward := make([]int, size+2)
+	backward := make([]int, size+2)
+	for i := range forward {
+		forward[i] = -1
+		backward[i] = -1
+	}
+	forward[offset+1] = 0
+	backward[offset+1] = 0
+	delta := n - m
+	// The searches can only meet in the forward step if delta is odd
+	front := delta%2 != 0
+	// Diagonals that have run off the edges of the graph are skipped
+	k1start, k1end, k2start, k2end := 0, 0, 0, 0
+
+	for step := 0; step < maxD; step++ {
+		for k1 := -step + k1start; k1 <= step-k1end; k1 += 2 {
+			k1offset := offset + k1
+			var x1 int
+			if k1 == -step || (k1 != step && forward[k1offset-1] < forward[k1offset+1]) {
+				x1 = forward[k1offset+1]
+			} else {
+				x1 = forward[k1offset-1] + 1
+			}
+			y1 := x1 - k1
+			for x1 < n && y1 < m && d.a[a0+x1] == d.b[b0+y1] {
+				x1++
+				y1++
+			}
+			forward[k1offset] = x1
+			switch {
+			case x1 > n:
+				k1end += 2
+			case y1 > m:
+				k1start += 2
+			case front:
+				k2offset := offset + delta - k1
+				if k2offset >= 0 && k2offset < size && backward[k2offset] != -1 {
+					if x1 >= n-backward[k2offset] {
+						return a0 + x1, b0 + y1, true
+					}
+				}
+			}
+		}
+
+		for k2 := -step + k2start; k2 <= step-k2end; k2 += 2 {
+			k2offset := offset + k2
+			var x2 int
+			if k2 == -step || (k2 != step && backward[k2offset-1] < backward[k2offset+1]) {
+				x2 = backward[k2offset+1]
+			} else {
+				x2 = backward[k2offset-1] + 1
+			}
+			y2 := x2 - k2
+			for x2 < n && y2 < m && d.a[a1-x2-1] == d.b[b1-y2-1] {
+				x2++
+				y2++
+			}
+			backward[k2offset] = x2
+			switch {
+			case x2 > n:
+				k2end += 2
+			case y2 > m:
+				k2start += 2
+			case !front:
+				k1offset := offset + delta - k2
+				if k1offset >= 0 && k1offset < size && forward[k1offset] != -1 {
+					x1 := forward[k1offset]
+					y1 := offset + x1 - k1offset
+					if x1 >= n-x2 {
+						return a0 + x1, b0 + y1, true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,108 @@
+package algorithm
+
+import (
+	"sort"
+
+	"got/internal/diff"
+)
+
+// Patience diff matches up the lines that occur exactly once in both
+// sequences, keeps the longest run of them that appear in the same order and
+// diffs the gaps between them recursively. Gaps without unique lines are
+// diffed with Myers.
+type Patience struct {
+	fileDiffer
+}
+
+func (Patience) DiffBytes(a []byte, b []byte) diff.BytesDiff {
+	return diffBytes(a, b, (*differ).patience)
+}
+
+type match struct {
+	a, b int
+}
+
+func (d *differ) patience(a0, a1, b0, b1 int) {
+	a0, b0 = d.commonPrefix(a0, a1, b0, b1)
+	suffix := d.commonSuffix(a0, a1, b0, b1)
+	a1 -= suffix
+	b1 -= suffix
+
+	anchors := longestIncreasing(d.uniqueMatches(a0, a1, b0, b1))
+	if len(anchors) == 0 {
+		d.myers(a0, a1, b0, b1)
+	} else {
+		for _, m := range anchors {
+			d.patience(a0, m.a, b0, m.b)
+			d.emit(diff.EQL, 1)
+			a0, b0 = m.a+1, m.b+1
+		}
+		d.patience(a0, a1, b0, b1)
+	}
+	d.emit(diff.EQL, suffix)
+}
+
+// Returns the pairs of lines that occur exactly once in each range, sorted
+// by their position in a.
+func (d *differ) uniqueMatches(a0, a1, b0, b1 int) []match {
+	type occurrence struct {
+		countA, countB int
+		a, b           int
+	}
+	occurrences := make(map[int]*occurrence)
+	for i := a0; i < a1; i++ {
+		o, ok := occurrences[d.a[i]]
+		if !ok {
+			o = &occurrence{}
+			occurrences[d.a[i]] = o
+		}
+		o.countA++
+		o.a = i
+	}
+	for i := b0; i < b1; i++ {
+		if o, ok := occurrences[d.b[i]]; ok {
+			o.countB++
+			o.b = i
+		}
+	}
+	var matches []match
+	for _, o := range occurrences {
+		if o.countA == 1 && o.countB == 1 {
+			matches = append(matches, match{o.a, o.b})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].a < matches[j].a })
+	return matches
+}
+
+// Returns the longest subsequence of matches that is increasing in b, using
+// patience sorting.
+func longestIncreasing(matches []match) []match {
+	if len(matches) == 0 {
+		return nil
+	}
+	// The index of the match on top of each pile, and the match below each
+	// match when it was placed
+	var piles []int
+	prev := make([]int, len(matches))
+	for i, m := range matches {
+		pile := sort.Search(len(piles), func(p int) bool {
+			return matches[piles[p]].b > m.b
+		})
+		if pile > 0 {
+			prev[i] = piles[pile-1]
+		} else {
+			prev[i] = -1
+		}
+		if pile == len(piles) {
+			piles = append(piles, i)
+		} else {
+			piles[pile] = i
+		}
+	}
+	result := make([]match, len(piles))
+	for i, j := len(piles)-1, piles[len(piles)-1]; i >= 0; i, j = i-1, prev[j] {
+		result[i] = matches[j]
+	}
+	return result
+}
@@ -0,0 +1,160 @@
+package diff
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The number of bytes at the start of a file that are looked at to guess
+// whether it's binary
+const binaryCheckSize = 8000
+
+// Reports whether the contents look binary, i.e. have a NUL byte near the
+// start, like git guesses
+func IsBinary(bs []byte) bool {
+	if len(bs) > binaryCheckSize {
+		bs = bs[:binaryCheckSize]
+	}
+	return bytes.IndexByte(bs, 0) >= 0
+}
+
+// The line that starts the binary data of a git binary patch
+const BinaryPatchMarker = "GIT binary patch"
+
+// Writes the file as a git binary patch, which has the full IDs on its
+// 'index' line and the deflated contents of both sides as 'literal' hunks so
+// that it can be applied in either direction. Nothing is written for files
+// that are unchanged.
+func (fd *FileDiff) WriteBinaryData(w io.Writer, src, dst []byte) {
+	modeChanged := ModeString(fd.SrcPerm) != ModeString(fd.DstPerm) && fd.EditType == FileEditTypeInPlace
+	if fd.SrcID == fd.DstID && !modeChanged && (fd.EditType == FileEditTypeInPlace || fd.EditType == FileEditTypeUnmodified) {
+		return
+	}
+	fd.writeHeader(w, true)
+	if fd.SrcID == fd.DstID {
+		return
+	}
+	fmt.Fprintln(w, BinaryPatchMarker)
+	writeLiteral(w, dst)
+	writeLiteral(w, src)
+}
+
+func writeLiteral(w io.Writer, data []byte) {
+	buf := bytes.NewBuffer(nil)
+	zw := zlib.NewWriter(buf)
+	_, _ = zw.Write(data)
+	_ = zw.Close()
+	fmt.Fprintf(w, "literal %d\n", len(data))
+	fmt.Fprint(w, EncodeBase85Lines(buf.Bytes()))
+	fmt.Fprintln(w)
+}
+
+// Decodes the data of a 'literal' hunk of a git binary patch, given the
+// size on its 'literal' line and its lines of base85
+func DecodeLiteral(size int, lines []string) ([]byte, error) {
+	var deflated []byte
+	for _, line := range lines {
+		bs, err := DecodeBase85Line(line)
+		if err != nil {
+			return nil, err
+		}
+		deflated = append(deflated, bs...)
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(deflated))
+	if err != nil {
+		return nil, errors.Wrap(err, "corrupt binary patch")
+	}
+	data, err := ioutil.ReadAll(zr)
+	if err != nil {
+		return nil, errors.Wrap(err, "corrupt binary patch")
+	}
+	if len(data) != size {
+		return nil, errors.Errorf("corrupt binary patch: expected %d bytes, got %d", size, len(data))
+	}
+	return data, nil
+}
+
+// The alphabet of git's base85, which unlike Ascii85 needs no escaping in
+// patches
+const base85Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz!#$%&()*+-;<=>?@^_`{|}~"
+
+// The most bytes a line of base85 holds
+const base85LineBytes = 52
+
+// Encodes data as lines of git's base85. Each line starts with a letter
+// giving the number of bytes it holds, 'A' to 'Z' for 1 to 26 and 'a' to
+// 'z' for 27 to 52, followed by 5 characters for every 4 bytes.
+func EncodeBase85Lines(data []byte) string {
+	var sb strings.Builder
+	for len(data) > 0 {
+		n := len(data)
+		if n > base85LineBytes {
+			n = base85LineBytes
+		}
+		if n <= 26 {
+			sb.WriteByte(byte('A' + n - 1))
+		} else {
+			sb.WriteByte(byte('a' + n - 27))
+		}
+		for i := 0; i < n; i += 4 {
+			var acc uint32
+			for j := 0; j < 4; j++ {
+				acc <<= 8
+				if i+j < n {
+					acc |= uint32(data[i+j])
+				}
+			}
+			var group [5]byte
+			for k := 4; k >= 0; k-- {
+				group[k] = base85Alphabet[acc%85]
+				acc /= 85
+			}
+			sb.Write(group[:])
+		}
+		sb.WriteByte('\n')
+		data = data[n:]
+	}
+	return sb.String()
+}
+
+// Decodes a line written by EncodeBase85Lines
+func DecodeBase85Line(line string) ([]byte, error) {
+	if line == "" {
+		return nil, errors.New("corrupt binary patch: empty line")
+	}
+	var n int
+	switch c := line[0]; {
+	case 'A' <= c && c <= 'Z':
+		n = int(c-'A') + 1
+	case 'a' <= c && c <= 'z':
+		n = int(c-'a') + 27
+	default:
+		return nil, errors.Errorf("corrupt binary patch: bad length in %q", line)
+	}
+	encoded := line[1:]
+	if len(encoded) != (n+3)/4*5 {
+		return nil, errors.Errorf("corrupt binary patch: bad line length %q", line)
+	}
+	data := make([]byte, 0, (n+3)/4*4)
+	for i := 0; i < len(encoded); i += 5 {
+		var acc uint64
+		for _, c := range []byte(encoded[i : i+5]) {
+			v := strings.IndexByte(base85Alphabet, c)
+			if v < 0 {
+				return nil, errors.Errorf("corrupt binary patch: invalid character %q", c)
+			}
+			acc = acc*85 + uint64(v)
+		}
+		if acc > 0xffffffff {
+			return nil, errors.Errorf("corrupt binary patch: invalid group %q", encoded[i:i+5])
+		}
+		data = append(data, byte(acc>>24), byte(acc>>16), byte(acc>>8), byte(acc))
+	}
+	return data[:n], nil
+}
@@ -0,0 +1,58 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsBinary(t *testing.T) {
+	assert.False(t, IsBinary([]byte("text\n")))
+	assert.True(t, IsBinary([]byte("ab\x00cd")))
+	assert.False(t, IsBinary(append(bytes.Repeat([]byte("a"), binaryCheckSize), 0)))
+}
+
+func TestDecodeLiteral(t *testing.T) {
+	// As written by git diff --binary
+	data, err := DecodeLiteral(7, []string{"OcmYdHVn|L&O#=W3;sRCx"})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("ab\x00cdef"), data)
+
+	_, err = DecodeLiteral(8, []string{"OcmYdHVn|L&O#=W3;sRCx"})
+	assert.Error(t, err)
+	_, err = DecodeLiteral(7, []string{"OcmYdHVn|L&O#=W3;sRC"})
+	assert.Error(t, err)
+}
+
+func TestBase85RoundTrip(t *testing.T) {
+	for _, n := range []int{1, 3, 4, 26, 27, 52, 53, 200} {
+		data := make([]byte, n)
+		for i := range data {
+			data[i] = byte(i * 37)
+		}
+		var decoded []byte
+		for _, line := range strings.Split(strings.TrimSuffix(EncodeBase85Lines(data), "\n"), "\n") {
+			bs, err := DecodeBase85Line(line)
+			assert.NoError(t, err)
+			decoded = append(decoded, bs...)
+		}
+		assert.Equal(t, data, decoded, "%d bytes", n)
+	}
+}
+
+func TestWriteBinaryData(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	fd := NewInPlaceFileDiff(0644, 0644, "735a95829dd77133742c0416d45e0e0de7153ef6", "94061424c950fccc6cc6d024c2a2b040a75676f1", "x.bin")
+	fd.WriteBinaryData(buf, []byte("ab\x00cd"), []byte("ab\x00cdef"))
+	lines := strings.Split(buf.String(), "\n")
+	assert.Equal(t, "index 735a95829dd77133742c0416d45e0e0de7153ef6..94061424c950fccc6cc6d024c2a2b040a75676f1 100644", lines[1])
+	assert.Equal(t, BinaryPatchMarker, lines[2])
+	assert.Equal(t, "literal 7", lines[3])
+	data, err := DecodeLiteral(7, lines[4:5])
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("ab\x00cdef"), data)
+	assert.Equal(t, "", lines[5])
+	assert.Equal(t, "literal 5", lines[6])
+}
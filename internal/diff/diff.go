@@ -3,7 +3,10 @@ package diff
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os"
+	"regexp"
+	"strings"
 
 	"got/internal/objects"
 
@@ -24,6 +27,9 @@ type FileDiff struct {
 	DstID    objects.ID
 	SrcPath  string
 	DstPath  string
+	// How similar in percent the file is to the file it was renamed or copied
+	// from
+	Similarity int
 }
 
 func NewInPlaceFileDiff(oldPerm, newPerm os.FileMode, oldHash, newHash objects.ID, path string) *FileDiff {
@@ -74,6 +80,42 @@ func NewUnmodifiedFileDiff(perm os.FileMode, id objects.ID, path string) *FileDi
 	}
 }
 
+// Returns the path of the file after the edit, or before it if the file was
+// deleted.
+func (fd *FileDiff) Path() string {
+	if fd.DstPath != "" {
+		return fd.DstPath
+	}
+	return fd.SrcPath
+}
+
+// Returns the letter git uses for the kind of change in --name-status and
+// raw output, e.g. 'M' for a modified file
+func (fd *FileDiff) StatusLetter() string {
+	switch fd.EditType {
+	case FileEditTypeCreate:
+		return "A"
+	case FileEditTypeDelete:
+		return "D"
+	case FileEditTypeRename:
+		return fmt.Sprintf("R%03d", fd.Similarity)
+	case FileEditTypeCopy:
+		return fmt.Sprintf("C%03d", fd.Similarity)
+	case FileEditTypeUnmodified:
+		return " "
+	}
+	return "M"
+}
+
+// Returns the path of the file for --stat output, 'old => new' for a renamed
+// or copied file
+func (fd *FileDiff) DisplayPath() string {
+	if fd.EditType == FileEditTypeRename || fd.EditType == FileEditTypeCopy {
+		return fmt.Sprintf("%s => %s", fd.SrcPath, fd.DstPath)
+	}
+	return fd.Path()
+}
+
 type FileEditType string
 
 const (
@@ -81,44 +123,94 @@ const (
 	FileEditTypeCreate     FileEditType = "created"
 	FileEditTypeDelete     FileEditType = "deleted"
 	FileEditTypeUnmodified FileEditType = "unmodified"
+	FileEditTypeRename     FileEditType = "renamed"
+	FileEditTypeCopy       FileEditType = "copied"
 )
 
 type BytesDiff []LineEdit
 
+// The number of unchanged lines shown around changes by default
+const DefaultContext = 3
+
+// A group of edits that are close enough to each other to be shown together.
+// Starts are 1-based line numbers and Lines the number of lines the hunk
+// covers in each file. A hunk that covers no lines of a file starts at the
+// line before the hunk, or 0 at the start of the file.
 type Hunk struct {
 	SrcStart int
-	SrcEnd   int
+	SrcLines int
 	DstStart int
-	DstEnd   int
+	DstLines int
 	Edits    BytesDiff
+	// The line before the hunk that names the function it's in, shown after
+	// the header. Empty if there is none.
+	Function string
 }
 
 type Hunks []Hunk
 
+// Groups the edits into hunks with the default amount of context
 func (bd BytesDiff) Strip() Hunks {
-	var hunks []Hunk
-	var hunk *Hunk = nil
-	for i := range bd {
-		if bd.hasDiffWithInRangeAt(i, 3) {
-			if hunk == nil {
-				hunk = &Hunk{SrcStart: bd[i].ALine, DstStart: bd[i].BLine}
+	return bd.Hunks(DefaultContext)
+}
+
+// Groups the edits into hunks, each with up to context unchanged lines before
+// and after its changes. Changes separated by at most 2*context unchanged
+// lines end up in the same hunk.
+func (bd BytesDiff) Hunks(context int) Hunks {
+	context = max(0, context)
+	var hunks Hunks
+	for i := 0; i < len(bd); {
+		if !bd[i].isChange() {
+			i++
+			continue
+		}
+		start := max(0, i-context)
+		end := i
+		for end < len(bd) {
+			if bd[end].isChange() {
+				end++
+				continue
 			}
-			hunk.Edits = append(hunk.Edits, bd[i])
-			hunk.SrcEnd = bd[i].ALine
-			hunk.DstEnd = bd[i].BLine
-		} else {
-			if hunk != nil {
-				hunks = append(hunks, *hunk)
-				hunk = nil
+			next := end
+			for next < len(bd) && !bd[next].isChange() {
+				next++
 			}
+			if next == len(bd) || next-end > 2*context {
+				break
+			}
+			end = next
 		}
-	}
-	if hunk != nil {
-		hunks = append(hunks, *hunk)
+		end = min(len(bd), end+context)
+		hunks = append(hunks, newHunk(bd[start:end]))
+		i = end
 	}
 	return hunks
 }
 
+func newHunk(edits BytesDiff) Hunk {
+	h := Hunk{
+		SrcStart: edits[0].ALine + 1,
+		DstStart: edits[0].BLine + 1,
+		Edits:    edits,
+	}
+	for _, le := range edits {
+		if le.EditType != INS {
+			h.SrcLines++
+		}
+		if le.EditType != DEL {
+			h.DstLines++
+		}
+	}
+	if h.SrcLines == 0 {
+		h.SrcStart--
+	}
+	if h.DstLines == 0 {
+		h.DstStart--
+	}
+	return h
+}
+
 func (hs Hunks) String() string {
 	buf := bytes.NewBuffer(nil)
 	for _, h := range hs {
@@ -127,39 +219,131 @@ func (hs Hunks) String() string {
 	return buf.String()
 }
 
-func (bd BytesDiff) hasDiffWithInRangeAt(at int, within int) bool {
-	if bd[at].EditType != EQL {
-		return true
+// Returns the hunk header, e.g. '@@ -1,3 +1,4 @@ func main() {'. Counts of 1
+// are left out.
+func (h Hunk) Header() string {
+	header := fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.SrcStart, h.SrcLines), hunkRange(h.DstStart, h.DstLines))
+	if h.Function != "" {
+		header += " " + h.Function
 	}
-	for i := within; i > 0; i-- {
-		if (at-i >= 0 && bd[at-i].EditType != EQL) ||
-			(at+i < len(bd) && bd[at+i].EditType != EQL) {
-			return true
+	return header
+}
+
+// The longest function shown in hunk headers
+const maxFunctionLength = 80
+
+// Sets the function of each hunk to the closest line of the source before
+// the hunk that funcname matches. The function is the first group of the
+// expression that matched, or else the whole line.
+func (hs Hunks) SetFunctions(src []byte, funcname *regexp.Regexp) {
+	lines := strings.Split(string(src), "\n")
+	for i := range hs {
+		// The line before the hunk, as a 0-based index
+		last := hs[i].SrcStart - 2
+		if hs[i].SrcLines == 0 {
+			last++
+		}
+		for l := min(last, len(lines)-1); l >= 0; l-- {
+			if f, ok := matchFunction(lines[l], funcname); ok {
+				hs[i].Function = f
+				break
+			}
+		}
+	}
+}
+
+func matchFunction(line string, funcname *regexp.Regexp) (string, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	m := funcname.FindStringSubmatch(line)
+	if m == nil {
+		return "", false
+	}
+	f := m[0]
+	for _, group := range m[1:] {
+		if group != "" {
+			f = group
+			break
 		}
 	}
-	return false
+	f = strings.TrimRight(f, " \t")
+	if len(f) > maxFunctionLength {
+		f = f[:maxFunctionLength]
+	}
+	return f, f != ""
+}
+
+func hunkRange(start, lines int) string {
+	if lines == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
 }
 
 func (h Hunk) String() string {
 	buf := bytes.NewBuffer(nil)
-	fmt.Fprintf(buf, color.Cyan.Sprintf("@@ -%d,%d +%d,%d @@\n", h.SrcStart, h.SrcEnd, h.DstStart, h.DstEnd))
+	fmt.Fprintln(buf, color.Cyan.Sprint(h.Header()))
 	fmt.Fprint(buf, h.Edits)
 	return buf.String()
 }
 
+// Formats the edits as the lines of a patch. Where lines are removed and
+// the same number of lines added in their place, the parts of each pair of
+// lines that changed are highlighted.
 func (bd BytesDiff) String() string {
 	buf := bytes.NewBuffer(nil)
-	for _, le := range bd {
-		fmt.Fprintln(buf, le)
+	for i := 0; i < len(bd); {
+		dels := 0
+		for i+dels < len(bd) && bd[i+dels].EditType == DEL {
+			dels++
+		}
+		inss := 0
+		for i+dels+inss < len(bd) && bd[i+dels+inss].EditType == INS {
+			inss++
+		}
+		if dels == 0 || dels != inss {
+			writeLineEdit(buf, bd[i], bd[i].String())
+			i++
+			continue
+		}
+		added := make([]string, dels)
+		for j := i; j < i+dels; j++ {
+			aStart, aEnd, bStart, bEnd := changedParts(bd[j].Text, bd[j+dels].Text)
+			writeLineEdit(buf, bd[j], highlightLine(bd[j], aStart, aEnd))
+			added[j-i] = highlightLine(bd[j+dels], bStart, bEnd)
+		}
+		for j, line := range added {
+			writeLineEdit(buf, bd[i+dels+j], line)
+		}
+		i += 2 * dels
 	}
 	return buf.String()
 }
 
+func writeLineEdit(w io.Writer, le LineEdit, line string) {
+	fmt.Fprintln(w, line)
+	if le.NoNewline {
+		fmt.Fprintln(w, NoNewlineMarker)
+	}
+}
+
+// Follows a line in a patch that isn't terminated by a newline
+const NoNewlineMarker = "\\ No newline at end of file"
+
+// A line of a diff. ALine and BLine are the 0-based positions of the line in
+// the source and destination. An inserted line has the position in the
+// source that it is inserted before as its ALine, and a deleted line the
+// position in the destination that it was deleted before as its BLine.
 type LineEdit struct {
 	EditType EditType
 	Text     string
 	ALine    int
 	BLine    int
+	// Set if the line is the last one of its file and has no newline
+	NoNewline bool
+	// Set for changes that are shown in the hunks of other changes but
+	// don't make hunks of their own, such as changes of blank lines when
+	// those are ignored
+	Ignorable bool
 }
 
 func NewLineEdit(editType EditType, text string, ALine int, BLine int) LineEdit {
@@ -171,12 +355,20 @@ func (e LineEdit) String() string {
 	switch e.EditType {
 	case INS:
 		col = color.Green
+		if errs := WhitespaceErrors(e.Text); len(errs) > 0 {
+			return highlightWhitespaceErrors(col, e.Text, errs)
+		}
 	case DEL:
 		col = color.Red
 	}
 	return col.Sprintf("%s%s", e.EditType, e.Text)
 }
 
+// Reports whether the edit is a change that makes a hunk
+func (e LineEdit) isChange() bool {
+	return e.EditType != EQL && !e.Ignorable
+}
+
 type EditType string
 
 const (
@@ -0,0 +1,111 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Splits contents into lines, dropping the empty line after a trailing
+// newline.
+func SplitLines(bs []byte) []string {
+	if len(bs) == 0 {
+		return nil
+	}
+	lines := strings.Split(string(bs), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Splits contents into lines that keep their newline, so that a last line
+// without a newline differs from the same line with one.
+func Lines(bs []byte) []string {
+	var lines []string
+	for len(bs) > 0 {
+		i := bytes.IndexByte(bs, '\n')
+		if i < 0 {
+			lines = append(lines, string(bs))
+			break
+		}
+		lines = append(lines, string(bs[:i+1]))
+		bs = bs[i+1:]
+	}
+	return lines
+}
+
+// Builds a BytesDiff from lines split by Lines and a sequence of edit types
+// that turns a into b. Every
+// EQL and DEL consumes a line of a and every EQL and INS a line of b.
+// Deletions are moved in front of insertions that they are adjacent to so
+// that each changed block reads as the old lines followed by the new ones.
+func NewBytesDiff(a, b []string, ops []EditType) BytesDiff {
+	var bd BytesDiff
+	ai, bi := 0, 0
+	for start := 0; start < len(ops); {
+		if ops[start] == EQL {
+			bd = append(bd, newLineEdit(EQL, a[ai], ai, bi))
+			ai++
+			bi++
+			start++
+			continue
+		}
+		end := start
+		for end < len(ops) && ops[end] != EQL {
+			end++
+		}
+		for _, op := range ops[start:end] {
+			if op == DEL {
+				bd = append(bd, newLineEdit(DEL, a[ai], ai, bi))
+				ai++
+			}
+		}
+		for _, op := range ops[start:end] {
+			if op == INS {
+				bd = append(bd, newLineEdit(INS, b[bi], ai, bi))
+				bi++
+			}
+		}
+		start = end
+	}
+	return bd
+}
+
+// Creates a line edit from a line that may end with a newline
+func newLineEdit(editType EditType, line string, aLine int, bLine int) LineEdit {
+	le := NewLineEdit(editType, strings.TrimSuffix(line, "\n"), aLine, bLine)
+	le.NoNewline = !strings.HasSuffix(line, "\n")
+	return le
+}
+
+// Returns the lines the edits were made to, i.e. every EQL and DEL line
+func (bd BytesDiff) Src() []string {
+	var lines []string
+	for _, le := range bd {
+		if le.EditType != INS {
+			lines = append(lines, le.Text)
+		}
+	}
+	return lines
+}
+
+// Returns the lines that result from the edits, i.e. every EQL and INS line
+func (bd BytesDiff) Dst() []string {
+	var lines []string
+	for _, le := range bd {
+		if le.EditType != DEL {
+			lines = append(lines, le.Text)
+		}
+	}
+	return lines
+}
+
+// Returns true if any of the edits is an insertion or deletion
+func (bd BytesDiff) HasChanges() bool {
+	for _, le := range bd {
+		if le.EditType != EQL {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,224 @@
+package diff
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The similarity a file needs to the file it's renamed from by default
+const DefaultRenameThreshold = 50
+
+// Pairs of files are only compared by content if there are at most this
+// many, as every pair has to be scored
+const renameLimit = 1000 * 1000
+
+type RenameOptions struct {
+	// Whether renames are detected at all
+	Detect bool
+	// The minimum similarity in percent of a file to the file it's renamed or
+	// copied from
+	Threshold int
+	// Whether files copied from files that are kept are detected too
+	Copies bool
+}
+
+// Returns the contents of a file on the source or destination side of a diff
+type ContentsFunc func(path string, src bool) ([]byte, error)
+
+// Replaces the deletions and creations in diffs that are renames with a
+// single rename diff. A created file is a rename of a deleted file that has
+// the same ID, or failing that, of the deleted file it's most similar to if
+// the similarity is at least the threshold. With copies enabled created
+// files are paired with the files in sources, the files that are kept, in
+// the same way. The result is sorted by path.
+func DetectRenames(diffs []*FileDiff, sources []*FileDiff, contents ContentsFunc, opts RenameOptions) ([]*FileDiff, error) {
+	if !opts.Detect {
+		return diffs, nil
+	}
+	var created, deleted, rest []*FileDiff
+	for _, fd := range diffs {
+		switch fd.EditType {
+		case FileEditTypeCreate:
+			created = append(created, fd)
+		case FileEditTypeDelete:
+			deleted = append(deleted, fd)
+		default:
+			rest = append(rest, fd)
+		}
+	}
+	if len(created) == 0 || (len(deleted) == 0 && !opts.Copies) {
+		return diffs, nil
+	}
+	candidates := deleted
+	if opts.Copies {
+		candidates = append(append([]*FileDiff{}, deleted...), sources...)
+	}
+
+	d := &renameDetector{contents: contents, sizes: make(map[string]int)}
+	paired := make(map[*FileDiff]*FileDiff)
+	renamed := make(map[*FileDiff]bool)
+	pair := func(dst, src *FileDiff, similarity int) {
+		editType := FileEditTypeCopy
+		if src.EditType == FileEditTypeDelete && !renamed[src] {
+			editType = FileEditTypeRename
+			renamed[src] = true
+		} else if !opts.Copies {
+			return
+		}
+		paired[dst] = &FileDiff{
+			EditType:   editType,
+			SrcPerm:    src.SrcPerm,
+			DstPerm:    dst.DstPerm,
+			SrcID:      src.SrcID,
+			DstID:      dst.DstID,
+			SrcPath:    src.SrcPath,
+			DstPath:    dst.DstPath,
+			Similarity: similarity,
+		}
+	}
+
+	// Exact renames first as they're cheap to find
+	byID := make(map[string][]*FileDiff)
+	for _, c := range candidates {
+		byID[string(c.SrcID)] = append(byID[string(c.SrcID)], c)
+	}
+	for _, dst := range created {
+		for _, src := range byID[string(dst.DstID)] {
+			if src.EditType == FileEditTypeDelete && renamed[src] && !opts.Copies {
+				continue
+			}
+			pair(dst, src, 100)
+			break
+		}
+	}
+
+	var unpaired []*FileDiff
+	for _, dst := range created {
+		if paired[dst] == nil {
+			unpaired = append(unpaired, dst)
+		}
+	}
+	if len(unpaired)*len(candidates) <= renameLimit {
+		scores, err := d.score(unpaired, candidates, opts.Threshold)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range scores {
+			if paired[s.dst] != nil {
+				continue
+			}
+			if s.src.EditType == FileEditTypeDelete && renamed[s.src] && !opts.Copies {
+				continue
+			}
+			pair(s.dst, s.src, s.similarity)
+		}
+	}
+
+	var result []*FileDiff
+	result = append(result, rest...)
+	for _, fd := range created {
+		if paired[fd] != nil {
+			result = append(result, paired[fd])
+		} else {
+			result = append(result, fd)
+		}
+	}
+	for _, fd := range deleted {
+		if !renamed[fd] {
+			result = append(result, fd)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Path() < result[j].Path()
+	})
+	return result, nil
+}
+
+type renameScore struct {
+	dst, src   *FileDiff
+	similarity int
+}
+
+type renameDetector struct {
+	contents ContentsFunc
+	sizes    map[string]int
+}
+
+// Scores every pair of created file and candidate, returning the pairs that
+// are at least as similar as the threshold, most similar first
+func (d *renameDetector) score(created, candidates []*FileDiff, threshold int) ([]renameScore, error) {
+	var scores []renameScore
+	srcLines := make(map[*FileDiff]map[string]int)
+	for _, dst := range created {
+		b, err := d.contents(dst.DstPath, false)
+		if err != nil {
+			return nil, errors.Wrapf(err, "couldn't detect renames of %s", dst.DstPath)
+		}
+		dstLines := lineCounts(b)
+		for _, src := range candidates {
+			if srcLines[src] == nil {
+				a, err := d.contents(src.SrcPath, true)
+				if err != nil {
+					return nil, errors.Wrapf(err, "couldn't detect renames from %s", src.SrcPath)
+				}
+				srcLines[src] = lineCounts(a)
+				d.sizes[src.SrcPath] = len(a)
+			}
+			s := similarity(srcLines[src], d.sizes[src.SrcPath], dstLines, len(b))
+			if s >= threshold {
+				scores = append(scores, renameScore{dst: dst, src: src, similarity: s})
+			}
+		}
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].similarity > scores[j].similarity
+	})
+	return scores, nil
+}
+
+// Counts the occurrences of each line, including its newline
+func lineCounts(bs []byte) map[string]int {
+	counts := make(map[string]int)
+	for _, l := range Lines(bs) {
+		counts[l]++
+	}
+	return counts
+}
+
+// Returns the share in percent of the bigger of two files that the files
+// have in common, counted in bytes of shared lines
+func similarity(a map[string]int, aSize int, b map[string]int, bSize int) int {
+	size := max(aSize, bSize)
+	if size == 0 {
+		return 100
+	}
+	common := 0
+	for l, n := range a {
+		common += min(n, b[l]) * len(l)
+	}
+	return common * 100 / size
+}
+
+// Parses the similarity of a -M or -C option. A number followed by '%' is a
+// percentage, otherwise its digits are a fraction, e.g. '5' is 50% and
+// '05' is 5%, like in git.
+func ParseSimilarity(s string) (int, error) {
+	if s == "" {
+		return DefaultRenameThreshold, nil
+	}
+	if strings.HasSuffix(s, "%") {
+		n, err := strconv.Atoi(strings.TrimSuffix(s, "%"))
+		if err != nil || n < 0 || n > 100 {
+			return 0, errors.Errorf("invalid similarity %s", s)
+		}
+		return n, nil
+	}
+	f, err := strconv.ParseFloat("0."+s, 64)
+	if err != nil || strings.ContainsAny(s, ".+-eE") {
+		return 0, errors.Errorf("invalid similarity %s", s)
+	}
+	return int(f*100 + 0.5), nil
+}
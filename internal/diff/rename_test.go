@@ -0,0 +1,117 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"got/internal/objects"
+)
+
+func renameContents(src, dst map[string]string) ContentsFunc {
+	return func(path string, fromSrc bool) ([]byte, error) {
+		if fromSrc {
+			return []byte(src[path]), nil
+		}
+		return []byte(dst[path]), nil
+	}
+}
+
+func blobID(s string) objects.ID {
+	return objects.NewBlob([]byte(s)).ID()
+}
+
+func TestDetectExactRename(t *testing.T) {
+	diffs := []*FileDiff{
+		NewDeleteFileDiff(0644, blobID("a\n"), "old.txt"),
+		NewCreateFileDiff(0644, blobID("a\n"), "new.txt"),
+		NewCreateFileDiff(0644, blobID("b\n"), "other.txt"),
+	}
+	opts := RenameOptions{Detect: true, Threshold: DefaultRenameThreshold}
+	diffs, err := DetectRenames(diffs, nil, renameContents(map[string]string{"old.txt": "a\n"}, map[string]string{"new.txt": "a\n", "other.txt": "b\n"}), opts)
+	assert.NoError(t, err)
+	assert.Len(t, diffs, 2)
+	assert.Equal(t, FileEditTypeRename, diffs[0].EditType)
+	assert.Equal(t, "old.txt", diffs[0].SrcPath)
+	assert.Equal(t, "new.txt", diffs[0].DstPath)
+	assert.Equal(t, 100, diffs[0].Similarity)
+	assert.Equal(t, FileEditTypeCreate, diffs[1].EditType)
+}
+
+func TestDetectSimilarRename(t *testing.T) {
+	old := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	new := "1\n2\n3\n4\n5\n6\n7\n8\nnine\n10\n"
+	src := map[string]string{"old.txt": old}
+	dst := map[string]string{"new.txt": new}
+	diffs := []*FileDiff{
+		NewDeleteFileDiff(0644, blobID(old), "old.txt"),
+		NewCreateFileDiff(0644, blobID(new), "new.txt"),
+	}
+
+	renamed, err := DetectRenames(diffs, nil, renameContents(src, dst), RenameOptions{Detect: true, Threshold: 50})
+	assert.NoError(t, err)
+	assert.Len(t, renamed, 1)
+	assert.Equal(t, "R079", renamed[0].StatusLetter())
+	assert.Equal(t, "old.txt => new.txt", renamed[0].DisplayPath())
+
+	unchanged, err := DetectRenames(diffs, nil, renameContents(src, dst), RenameOptions{Detect: true, Threshold: 90})
+	assert.NoError(t, err)
+	assert.Len(t, unchanged, 2)
+
+	unchanged, err = DetectRenames(diffs, nil, renameContents(src, dst), RenameOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, diffs, unchanged)
+}
+
+func TestDetectCopy(t *testing.T) {
+	diffs := []*FileDiff{
+		NewCreateFileDiff(0644, blobID("a\n"), "copy.txt"),
+	}
+	sources := []*FileDiff{NewUnmodifiedFileDiff(0644, blobID("a\n"), "orig.txt")}
+	contents := renameContents(map[string]string{"orig.txt": "a\n"}, map[string]string{"copy.txt": "a\n"})
+
+	copies, err := DetectRenames(diffs, sources, contents, RenameOptions{Detect: true, Threshold: 50, Copies: true})
+	assert.NoError(t, err)
+	assert.Len(t, copies, 1)
+	assert.Equal(t, FileEditTypeCopy, copies[0].EditType)
+	assert.Equal(t, "C100", copies[0].StatusLetter())
+
+	copies, err = DetectRenames(diffs, sources, contents, RenameOptions{Detect: true, Threshold: 50})
+	assert.NoError(t, err)
+	assert.Equal(t, FileEditTypeCreate, copies[0].EditType)
+}
+
+func TestWriteRenamePatch(t *testing.T) {
+	fd := &FileDiff{
+		EditType:   FileEditTypeRename,
+		SrcPerm:    0644,
+		DstPerm:    0644,
+		SrcID:      blobID("a\n"),
+		DstID:      blobID("a\n"),
+		SrcPath:    "old.txt",
+		DstPath:    "new.txt",
+		Similarity: 100,
+	}
+	buf := bytes.NewBuffer(nil)
+	fd.WritePatch(buf, nil)
+	assert.Equal(t, `diff --got a/old.txt b/new.txt
+similarity index 100%
+rename from old.txt
+rename to new.txt
+`, buf.String())
+	assert.True(t, strings.HasSuffix(fd.Raw(), " R100\told.txt\tnew.txt"))
+}
+
+func TestParseSimilarity(t *testing.T) {
+	for s, expected := range map[string]int{"": 50, "90%": 90, "5": 50, "05": 5, "75": 75, "100%": 100} {
+		n, err := ParseSimilarity(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, expected, n, s)
+	}
+	for _, s := range []string{"x", "101%", "-5", "1.5"} {
+		_, err := ParseSimilarity(s)
+		assert.Error(t, err, s)
+	}
+}
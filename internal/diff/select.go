@@ -0,0 +1,164 @@
+package diff
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Splits a hunk into smaller hunks, one for every group of changes that is
+// separated from the next by unchanged lines. The unchanged lines between
+// two groups are context of both. A hunk with a single group of changes
+// can't be split and is returned as is.
+func (h Hunk) Split() Hunks {
+	var hunks Hunks
+	start := 0
+	for i := 0; i < len(h.Edits); {
+		if h.Edits[i].EditType == EQL {
+			i++
+			continue
+		}
+		end := i
+		for end < len(h.Edits) && h.Edits[end].EditType != EQL {
+			end++
+		}
+		next := end
+		for next < len(h.Edits) && h.Edits[next].EditType == EQL {
+			next++
+		}
+		hunks = append(hunks, newHunk(h.Edits[start:next]))
+		start = end
+		i = next
+	}
+	if len(hunks) <= 1 {
+		return Hunks{h}
+	}
+	return hunks
+}
+
+// Returns the hunk that undoes h, i.e. that turns the destination of h back
+// into its source
+func (h Hunk) Reverse() Hunk {
+	edits := make(BytesDiff, len(h.Edits))
+	for i, le := range h.Edits {
+		switch le.EditType {
+		case INS:
+			le.EditType = DEL
+		case DEL:
+			le.EditType = INS
+		}
+		le.ALine, le.BLine = le.BLine, le.ALine
+		edits[i] = le
+	}
+	return newHunk(edits)
+}
+
+// Applies hunks of a diff of src to src. The hunks may be any subset of the
+// hunks of the diff, in order, including hunks split from them with Split,
+// as only the lines between the first and last change of a hunk are
+// replaced, by the positions in src that they were computed for.
+func ApplyHunks(src []byte, hs Hunks) []byte {
+	lines := Lines(src)
+	var result []string
+	// The position in lines up to which the result has been built
+	done := 0
+	for _, h := range hs {
+		first, last := -1, -1
+		for i, le := range h.Edits {
+			if le.EditType != EQL {
+				if first < 0 {
+					first = i
+				}
+				last = i
+			}
+		}
+		if first < 0 {
+			continue
+		}
+		start := max(done, h.Edits[first].ALine)
+		result = append(result, lines[done:start]...)
+		done = start
+		for _, le := range h.Edits[first : last+1] {
+			switch le.EditType {
+			case EQL:
+				result = append(result, lines[le.ALine])
+			case INS:
+				result = append(result, le.line())
+			}
+			if le.EditType != INS {
+				done = le.ALine + 1
+			}
+		}
+	}
+	result = append(result, lines[min(done, len(lines)):]...)
+	return []byte(strings.Join(result, ""))
+}
+
+// Returns the line of the edit as it is in its file
+func (e LineEdit) line() string {
+	if e.NoNewline {
+		return e.Text
+	}
+	return e.Text + "\n"
+}
+
+// Reads a hunk back that was written with Hunk.String and edited by hand.
+// Lines starting with '#' and the hunk header are skipped. Lines to be added
+// and removed may be changed freely, but the unchanged and removed lines have
+// to be those of the original hunk, in the same order.
+func ParseEditedHunk(h Hunk, text string) (Hunk, error) {
+	if len(h.Edits) == 0 {
+		return h, errors.New("nothing to edit in an empty hunk")
+	}
+	a, b := h.Edits[0].ALine, h.Edits[0].BLine
+	var edits BytesDiff
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		var editType EditType
+		switch {
+		case strings.HasPrefix(line, "#"), strings.HasPrefix(line, "@@"):
+			continue
+		// Editors may strip the space of empty unchanged lines
+		case line == "":
+			editType, line = EQL, " "
+		case strings.HasPrefix(line, "\\"):
+			if len(edits) > 0 {
+				edits[len(edits)-1].NoNewline = true
+			}
+			continue
+		case line[0] == ' ', line[0] == '-', line[0] == '+':
+			editType = EditType(line[:1])
+		default:
+			return h, errors.Errorf("malformed line in edited hunk: %q", line)
+		}
+		edits = append(edits, NewLineEdit(editType, line[1:], a, b))
+		if editType != INS {
+			a++
+		}
+		if editType != DEL {
+			b++
+		}
+	}
+	var want, got []LineEdit
+	for _, le := range h.Edits {
+		if le.EditType != INS {
+			want = append(want, le)
+		}
+	}
+	for _, le := range edits {
+		if le.EditType != INS {
+			got = append(got, le)
+		}
+	}
+	if len(want) != len(got) {
+		return h, errors.New("edited hunk doesn't apply: the unchanged and removed lines have to stay")
+	}
+	for i := range want {
+		if want[i].line() != got[i].line() {
+			return h, errors.Errorf("edited hunk doesn't apply: expected %q, got %q", want[i].Text, got[i].Text)
+		}
+	}
+	if len(edits) == 0 {
+		return h, errors.New("edited hunk is empty")
+	}
+	return newHunk(edits), nil
+}
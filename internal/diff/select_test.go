@@ -0,0 +1,55 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// A diff of '1'..'8' changing '2' and '6', close enough to share a hunk
+func twoChanges() (string, string, BytesDiff) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n"
+	b := "1\ntwo\n3\n4\n5\nsix\n7\n8\n"
+	ops := []EditType{EQL, DEL, INS, EQL, EQL, EQL, DEL, INS, EQL, EQL}
+	return a, b, NewBytesDiff(Lines([]byte(a)), Lines([]byte(b)), ops)
+}
+
+func TestSplit(t *testing.T) {
+	_, _, bd := twoChanges()
+	hs := bd.Hunks(3)
+	assert.Len(t, hs, 1)
+	split := hs[0].Split()
+	assert.Len(t, split, 2)
+	assert.Equal(t, "@@ -1,5 +1,5 @@", split[0].Header())
+	assert.Equal(t, "@@ -3,6 +3,6 @@", split[1].Header())
+	assert.Len(t, split[0].Split(), 1)
+}
+
+func TestApplyHunks(t *testing.T) {
+	a, b, bd := twoChanges()
+	hs := bd.Hunks(3)
+	assert.Equal(t, b, string(ApplyHunks([]byte(a), hs)))
+	assert.Equal(t, a, string(ApplyHunks([]byte(a), nil)))
+
+	split := hs[0].Split()
+	assert.Equal(t, "1\ntwo\n3\n4\n5\n6\n7\n8\n", string(ApplyHunks([]byte(a), split[:1])))
+	assert.Equal(t, "1\n2\n3\n4\n5\nsix\n7\n8\n", string(ApplyHunks([]byte(a), split[1:])))
+	assert.Equal(t, b, string(ApplyHunks([]byte(a), split)))
+
+	assert.Equal(t, "1\n2\n3\n4\n5\nsix\n7\n8\n", string(ApplyHunks([]byte(b), Hunks{split[0].Reverse()})))
+	assert.Equal(t, a, string(ApplyHunks([]byte(b), Hunks{split[0].Reverse(), split[1].Reverse()})))
+}
+
+func TestParseEditedHunk(t *testing.T) {
+	a, _, bd := twoChanges()
+	h := bd.Hunks(3)[0]
+
+	edited, err := ParseEditedHunk(h, "# comment\n"+h.Header()+"\n 1\n-2\n+zwei\n 3\n 4\n 5\n 6\n 7\n 8\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "1\nzwei\n3\n4\n5\n6\n7\n8\n", string(ApplyHunks([]byte(a), Hunks{edited})))
+
+	_, err = ParseEditedHunk(h, " 1\n 3\n")
+	assert.Error(t, err)
+	_, err = ParseEditedHunk(h, "x\n")
+	assert.Error(t, err)
+}
@@ -3,7 +3,6 @@ package simple
 import (
 	"crypto/sha1"
 	"fmt"
-	"strings"
 
 	"github.com/pkg/errors"
 
@@ -59,19 +58,7 @@ type path struct {
 }
 
 func diffBytes(as, bs []byte) diff.BytesDiff {
-	var a, b []string
-	if len(as) > 0 {
-		a = strings.Split(string(as), "\n")
-		if a[len(a)-1] == "" {
-			a = a[:len(a)-1]
-		}
-	}
-	if len(bs) > 0 {
-		b = strings.Split(string(bs), "\n")
-		if b[len(b)-1] == "" {
-			b = b[:len(b)-1]
-		}
-	}
+	a, b := diff.Lines(as), diff.Lines(bs)
 	n := len(a)
 	m := len(b)
 	var v = make([][]int, m+1)
@@ -106,33 +93,25 @@ func diffBytes(as, bs []byte) diff.BytesDiff {
 			m--
 		} else if v[m][n-1] == d {
 			n--
-		} else if a[n-1] == b[m-1] && v[m-1][n-1] == d-1 {
-			d--
+		} else {
+			// Neither neighbour has the same length so the lines are equal
 			n--
 			m--
 		}
 	}
 
 	// Create the line diffs
-	var edits []diff.LineEdit
+	var ops []diff.EditType
 	for _, p := range reverse(toPath(points)) {
-		var aLine, bLine string
-		if p.From.A != len(a) {
-			aLine = a[p.From.A]
-		}
-		if p.From.B != len(b) {
-			bLine = b[p.From.B]
-		}
-
 		if p.From.A == p.To.A {
-			edits = append(edits, diff.NewLineEdit(diff.INS, bLine, 0, p.From.B))
+			ops = append(ops, diff.INS)
 		} else if p.From.B == p.To.B {
-			edits = append(edits, diff.NewLineEdit(diff.DEL, aLine, p.From.A, -1))
+			ops = append(ops, diff.DEL)
 		} else {
-			edits = append(edits, diff.NewLineEdit(diff.EQL, aLine, p.From.A, p.From.B))
+			ops = append(ops, diff.EQL)
 		}
 	}
-	return edits
+	return diff.NewBytesDiff(a, b, ops)
 }
 
 func max(x, y int) int {
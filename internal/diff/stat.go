@@ -0,0 +1,96 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/gookit/color"
+)
+
+// The widest a bar of '+' and '-' in a diffstat is allowed to be
+const statBarWidth = 50
+
+// The number of lines inserted and deleted in a file
+type FileStat struct {
+	Path       string
+	Insertions int
+	Deletions  int
+	Binary     bool
+}
+
+func NewFileStat(path string, bd BytesDiff) FileStat {
+	s := FileStat{Path: path}
+	for _, le := range bd {
+		switch le.EditType {
+		case INS:
+			s.Insertions++
+		case DEL:
+			s.Deletions++
+		}
+	}
+	return s
+}
+
+type Stats []FileStat
+
+// Formats the stats as a diffstat:
+//
+//	path/to/file | 3 ++-
+//	1 file changed, 2 insertions(+), 1 deletion(-)
+func (ss Stats) String() string {
+	buf := bytes.NewBuffer(nil)
+	nameWidth, countWidth, maxChanges := 0, 0, 0
+	for _, s := range ss {
+		nameWidth = max(nameWidth, len(s.Path))
+		countWidth = max(countWidth, len(fmt.Sprint(s.Insertions+s.Deletions)))
+		maxChanges = max(maxChanges, s.Insertions+s.Deletions)
+	}
+	for _, s := range ss {
+		if s.Binary {
+			fmt.Fprintf(buf, " %-*s | Bin\n", nameWidth, s.Path)
+			continue
+		}
+		ins, del := s.Insertions, s.Deletions
+		if maxChanges > statBarWidth {
+			ins = scale(ins, maxChanges)
+			del = scale(del, maxChanges)
+		}
+		fmt.Fprintf(buf, " %-*s | %*d %s%s\n", nameWidth, s.Path, countWidth, s.Insertions+s.Deletions,
+			color.Green.Sprint(strings.Repeat("+", ins)), color.Red.Sprint(strings.Repeat("-", del)))
+	}
+	fmt.Fprintln(buf, ss.Summary())
+	return buf.String()
+}
+
+// Returns the last line of the diffstat, e.g.
+// '1 file changed, 2 insertions(+), 1 deletion(-)'
+func (ss Stats) Summary() string {
+	insertions, deletions := 0, 0
+	for _, s := range ss {
+		insertions += s.Insertions
+		deletions += s.Deletions
+	}
+	summary := fmt.Sprintf(" %d %s changed", len(ss), plural(len(ss), "file", "files"))
+	if insertions > 0 || deletions == 0 {
+		summary += fmt.Sprintf(", %d %s(+)", insertions, plural(insertions, "insertion", "insertions"))
+	}
+	if deletions > 0 || insertions == 0 {
+		summary += fmt.Sprintf(", %d %s(-)", deletions, plural(deletions, "deletion", "deletions"))
+	}
+	return summary
+}
+
+func scale(n, total int) int {
+	if n == 0 {
+		return 0
+	}
+	return max(1, n*statBarWidth/total)
+}
+
+func plural(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
@@ -0,0 +1,181 @@
+package diff
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gookit/color"
+	"github.com/pkg/errors"
+
+	"got/internal/objects"
+)
+
+// The path used in patches for the missing side of a created or deleted file
+const DevNull = "/dev/null"
+
+// Returns the mode of a file as written in patches, '100755' for executable
+// files and '100644' for others.
+func ModeString(perm os.FileMode) string {
+	if perm&0111 != 0 {
+		return "100755"
+	}
+	return "100644"
+}
+
+// Formats the diff like git's raw output, e.g.
+// ':100644 100644 <src id> <dst id> M\tpath'. The missing side of a created or
+// deleted file has a zero mode and ID, and renamed or copied files have both
+// paths separated by a tab.
+func (fd *FileDiff) Raw() string {
+	srcMode, dstMode := ModeString(fd.SrcPerm), ModeString(fd.DstPerm)
+	srcID, dstID := string(fd.SrcID), string(fd.DstID)
+	nullID := strings.Repeat("0", 40)
+	switch fd.EditType {
+	case FileEditTypeCreate:
+		srcMode, srcID = "000000", nullID
+	case FileEditTypeDelete:
+		dstMode, dstID = "000000", nullID
+	}
+	path := fd.Path()
+	if fd.EditType == FileEditTypeRename || fd.EditType == FileEditTypeCopy {
+		path = fd.SrcPath + "\t" + fd.DstPath
+	}
+	return fmt.Sprintf(":%s %s %s %s %s\t%s", srcMode, dstMode, srcID, dstID, fd.StatusLetter(), path)
+}
+
+// Parses a mode written by ModeString
+func ParseMode(s string) (os.FileMode, error) {
+	switch s {
+	case "100755":
+		return 0755, nil
+	case "100644":
+		return 0644, nil
+	}
+	return 0, errors.Errorf("unsupported file mode %s", s)
+}
+
+// Writes the extended header lines of a patch for the file, i.e. the
+// 'diff --got' line, any lines describing a created, deleted, renamed or
+// copied file or a changed mode, and the 'index' line naming the blobs.
+func (fd *FileDiff) WriteHeader(w io.Writer) {
+	fd.writeHeader(w, false)
+}
+
+// Writes the extended header, with the full IDs on the 'index' line if
+// fullIndex is set
+func (fd *FileDiff) writeHeader(w io.Writer, fullIndex bool) {
+	srcPath, dstPath := fd.SrcPath, fd.DstPath
+	if srcPath == "" {
+		srcPath = dstPath
+	}
+	if dstPath == "" {
+		dstPath = srcPath
+	}
+	fmt.Fprintln(w, color.OpBold.Sprintf("diff --got a/%s b/%s", srcPath, dstPath))
+	index := fmt.Sprintf("index %s..%s", abbrev(fd.SrcID), abbrev(fd.DstID))
+	if fullIndex {
+		index = fmt.Sprintf("index %s..%s", fullID(fd.SrcID), fullID(fd.DstID))
+	}
+	switch fd.EditType {
+	case FileEditTypeCreate:
+		fmt.Fprintln(w, color.OpBold.Sprintf("new file mode %s", ModeString(fd.DstPerm)))
+	case FileEditTypeDelete:
+		fmt.Fprintln(w, color.OpBold.Sprintf("deleted file mode %s", ModeString(fd.SrcPerm)))
+	default:
+		if ModeString(fd.SrcPerm) != ModeString(fd.DstPerm) {
+			fmt.Fprintln(w, color.OpBold.Sprintf("old mode %s", ModeString(fd.SrcPerm)))
+			fmt.Fprintln(w, color.OpBold.Sprintf("new mode %s", ModeString(fd.DstPerm)))
+		} else {
+			index += " " + ModeString(fd.DstPerm)
+		}
+		if fd.EditType == FileEditTypeRename || fd.EditType == FileEditTypeCopy {
+			kind := "rename"
+			if fd.EditType == FileEditTypeCopy {
+				kind = "copy"
+			}
+			fmt.Fprintln(w, color.OpBold.Sprintf("similarity index %d%%", fd.Similarity))
+			fmt.Fprintln(w, color.OpBold.Sprintf("%s from %s", kind, fd.SrcPath))
+			fmt.Fprintln(w, color.OpBold.Sprintf("%s to %s", kind, fd.DstPath))
+		}
+	}
+	if fd.SrcID != fd.DstID {
+		fmt.Fprintln(w, color.OpBold.Sprint(index))
+	}
+}
+
+// Writes a unified diff of the file: the extended header, the '---' and
+// '+++' lines and the hunks. Nothing is written for files that are unchanged.
+func (fd *FileDiff) WritePatch(w io.Writer, hs Hunks) {
+	if fd.writePatchHeader(w, hs) {
+		fmt.Fprint(w, hs)
+	}
+}
+
+// Writes the extended header and the '---' and '+++' lines of a patch with
+// the hunks, and returns whether the hunks are to follow
+func (fd *FileDiff) writePatchHeader(w io.Writer, hs Hunks) bool {
+	modeChanged := ModeString(fd.SrcPerm) != ModeString(fd.DstPerm) && fd.EditType == FileEditTypeInPlace
+	if len(hs) == 0 && !modeChanged && (fd.EditType == FileEditTypeInPlace || fd.EditType == FileEditTypeUnmodified) {
+		return false
+	}
+	fd.WriteHeader(w)
+	if len(hs) == 0 {
+		return false
+	}
+	src, dst := "a/"+fd.SrcPath, "b/"+fd.Path()
+	switch fd.EditType {
+	case FileEditTypeCreate:
+		src = DevNull
+	case FileEditTypeDelete:
+		dst = DevNull
+	}
+	fmt.Fprintln(w, color.OpBold.Sprintf("--- %s", src))
+	fmt.Fprintln(w, color.OpBold.Sprintf("+++ %s", dst))
+	return true
+}
+
+// Writes the extended header of the file followed by a line saying that it
+// differs, for files whose contents aren't shown as text. Nothing is written
+// for files that are unchanged.
+func (fd *FileDiff) WriteBinaryPatch(w io.Writer) {
+	modeChanged := ModeString(fd.SrcPerm) != ModeString(fd.DstPerm) && fd.EditType == FileEditTypeInPlace
+	if fd.SrcID == fd.DstID && !modeChanged && (fd.EditType == FileEditTypeInPlace || fd.EditType == FileEditTypeUnmodified) {
+		return
+	}
+	fd.WriteHeader(w)
+	if fd.SrcID == fd.DstID {
+		return
+	}
+	src, dst := "a/"+fd.SrcPath, "b/"+fd.Path()
+	switch fd.EditType {
+	case FileEditTypeCreate:
+		src = DevNull
+	case FileEditTypeDelete:
+		dst = DevNull
+	}
+	fmt.Fprintf(w, "Binary files %s and %s differ\n", src, dst)
+}
+
+// Returns the ID for the 'index' line of a binary patch, zeroes for the
+// missing side of a created or deleted file
+func fullID(id objects.ID) string {
+	if id == "" {
+		return strings.Repeat("0", 40)
+	}
+	return string(id)
+}
+
+// Abbreviates an ID for the 'index' line. The missing side of a created or
+// deleted file is written as zeroes.
+func abbrev(id objects.ID) string {
+	const length = 7
+	if id == "" {
+		return strings.Repeat("0", length)
+	}
+	if len(id) > length {
+		return string(id)[:length]
+	}
+	return string(id)
+}
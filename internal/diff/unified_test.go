@@ -0,0 +1,115 @@
+package diff
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/gookit/color"
+	"github.com/stretchr/testify/assert"
+
+	"got/internal/objects"
+)
+
+func init() {
+	color.Disable()
+}
+
+func TestHunkHeaders(t *testing.T) {
+	a := Lines([]byte("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"))
+	b := Lines([]byte("1\ntwo\n3\n4\n5\n6\n7\n8\n9\n10\n11\n"))
+	ops := []EditType{EQL, DEL, INS, EQL, EQL, EQL, EQL, EQL, EQL, EQL, EQL, INS}
+	bd := NewBytesDiff(a, b, ops)
+
+	hs := bd.Hunks(3)
+	assert.Len(t, hs, 2)
+	assert.Equal(t, "@@ -1,5 +1,5 @@", hs[0].Header())
+	assert.Equal(t, "@@ -8,3 +8,4 @@", hs[1].Header())
+
+	hs = bd.Hunks(0)
+	assert.Len(t, hs, 2)
+	assert.Equal(t, "@@ -2 +2 @@", hs[0].Header())
+	assert.Equal(t, "@@ -10,0 +11 @@", hs[1].Header())
+
+	assert.Len(t, bd.Hunks(4), 1)
+	assert.Equal(t, "@@ -1,10 +1,11 @@", bd.Hunks(4)[0].Header())
+}
+
+func TestNoNewlineMarker(t *testing.T) {
+	a := Lines([]byte("a\nb"))
+	b := Lines([]byte("a\nb\n"))
+	bd := NewBytesDiff(a, b, []EditType{EQL, DEL, INS})
+	assert.Equal(t, "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n", bd.Strip().String())
+}
+
+func TestWritePatch(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	fd := NewCreateFileDiff(0755, "95cb0bfd2977c761298d9624e4b4d4c72a39974a", "bin/run")
+	fd.WritePatch(buf, NewBytesDiff(nil, Lines([]byte("y\n")), []EditType{INS}).Strip())
+	assert.Equal(t, `diff --got a/bin/run b/bin/run
+new file mode 100755
+index 0000000..95cb0bf
+--- /dev/null
++++ b/bin/run
+@@ -0,0 +1 @@
++y
+`, buf.String())
+
+	buf.Reset()
+	fd = NewDeleteFileDiff(0644, "95cb0bfd2977c761298d9624e4b4d4c72a39974a", "a.txt")
+	fd.WritePatch(buf, NewBytesDiff(Lines([]byte("y\n")), nil, []EditType{DEL}).Strip())
+	assert.Equal(t, `diff --got a/a.txt b/a.txt
+deleted file mode 100644
+index 95cb0bf..0000000
+--- a/a.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-y
+`, buf.String())
+
+	buf.Reset()
+	fd = NewUnmodifiedFileDiff(0644, "95cb0bfd2977c761298d9624e4b4d4c72a39974a", "a.txt")
+	fd.WritePatch(buf, nil)
+	assert.Empty(t, buf.String())
+}
+
+func TestRaw(t *testing.T) {
+	id := "95cb0bfd2977c761298d9624e4b4d4c72a39974a"
+	null := "0000000000000000000000000000000000000000"
+	assert.Equal(t, ":000000 100755 "+null+" "+id+" A\tbin/run", NewCreateFileDiff(0755, objects.ID(id), "bin/run").Raw())
+	assert.Equal(t, ":100644 000000 "+id+" "+null+" D\ta.txt", NewDeleteFileDiff(0644, objects.ID(id), "a.txt").Raw())
+	assert.Equal(t, ":100644 100755 "+id+" "+id+" M\ta.txt", NewInPlaceFileDiff(0644, 0755, objects.ID(id), objects.ID(id), "a.txt").Raw())
+}
+
+func TestSetFunctions(t *testing.T) {
+	src := []byte("package main\n\nfunc main() {\n\ta()\n\tb()\n\tc()\n\td()\n}\n")
+	dst := []byte("package main\n\nfunc main() {\n\ta()\n\tb()\n\tc()\n\tchanged()\n}\n")
+	bd := NewBytesDiff(Lines(src), Lines(dst), []EditType{EQL, EQL, EQL, EQL, EQL, EQL, DEL, INS, EQL})
+	hs := bd.Hunks(1)
+	hs.SetFunctions(src, regexp.MustCompile(`^(func .*)$`))
+	assert.Equal(t, "@@ -6,3 +6,3 @@ func main() {", hs[0].Header())
+
+	// Lines within the hunk don't count
+	hs = bd.Hunks(3)
+	hs.SetFunctions(src, regexp.MustCompile(`^(func .*)$`))
+	assert.Equal(t, "@@ -4,5 +4,5 @@ func main() {", hs[0].Header())
+	hs = bd.Hunks(5)
+	hs.SetFunctions(src, regexp.MustCompile(`^(func .*)$`))
+	assert.Equal(t, "@@ -2,7 +2,7 @@", hs[0].Header())
+}
+
+func TestWriteBinaryPatch(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	fd := NewCreateFileDiff(0644, "95cb0bfd2977c761298d9624e4b4d4c72a39974a", "a.png")
+	fd.WriteBinaryPatch(buf)
+	assert.Equal(t, `diff --got a/a.png b/a.png
+new file mode 100644
+index 0000000..95cb0bf
+Binary files /dev/null and b/a.png differ
+`, buf.String())
+
+	buf.Reset()
+	fd = NewUnmodifiedFileDiff(0644, "95cb0bfd2977c761298d9624e4b4d4c72a39974a", "a.png")
+	fd.WriteBinaryPatch(buf)
+	assert.Empty(t, buf.String())
+}
@@ -0,0 +1,192 @@
+package diff
+
+import (
+	"strings"
+
+	"github.com/gookit/color"
+)
+
+// Which differences in whitespace are ignored when lines are compared
+type WhitespaceOptions struct {
+	// Ignore all whitespace, even where one line has some and the other none
+	IgnoreAllSpace bool
+	// Ignore changes in the amount of whitespace and whitespace at the end
+	// of lines
+	IgnoreSpaceChange bool
+	// Ignore whitespace at the end of lines
+	IgnoreSpaceAtEOL bool
+	// Ignore a carriage return at the end of lines
+	IgnoreCRAtEOL bool
+	// Ignore changes whose lines are all blank, which are only shown in
+	// hunks of other changes
+	IgnoreBlankLines bool
+}
+
+// Returns a Differ that diffs lines with d but compares them as the options
+// say. Lines that are the same but for ignored whitespace are unchanged and
+// have the text of the source.
+func (o WhitespaceOptions) Differ(d Differ) Differ {
+	if o == (WhitespaceOptions{}) {
+		return d
+	}
+	return whitespaceDiffer{d, o}
+}
+
+type whitespaceDiffer struct {
+	Differ
+	opts WhitespaceOptions
+}
+
+func (w whitespaceDiffer) DiffBytes(a []byte, b []byte) BytesDiff {
+	if a == nil && b == nil {
+		return nil
+	}
+	al, bl := Lines(a), Lines(b)
+	edits := w.Differ.DiffBytes(w.normalize(al), w.normalize(bl))
+	bd := make(BytesDiff, len(edits))
+	for i, le := range edits {
+		if le.EditType == INS {
+			bd[i] = newLineEdit(INS, bl[le.BLine], le.ALine, le.BLine)
+		} else {
+			bd[i] = newLineEdit(le.EditType, al[le.ALine], le.ALine, le.BLine)
+		}
+	}
+	if w.opts.IgnoreBlankLines {
+		markBlankChanges(bd)
+	}
+	return bd
+}
+
+// Returns the lines, which keep their newlines, with the whitespace that is
+// ignored removed
+func (w whitespaceDiffer) normalize(lines []string) []byte {
+	var sb strings.Builder
+	for _, line := range lines {
+		text := strings.TrimSuffix(line, "\n")
+		switch {
+		case w.opts.IgnoreAllSpace:
+			text = strings.Join(strings.Fields(text), "")
+		case w.opts.IgnoreSpaceChange:
+			// Whitespace at the start of a line is still told from none
+			fields := strings.Fields(text)
+			if len(fields) > 0 && !strings.HasPrefix(text, fields[0]) {
+				fields = append([]string{""}, fields...)
+			}
+			text = strings.Join(fields, " ")
+		case w.opts.IgnoreSpaceAtEOL:
+			text = strings.TrimRight(text, " \t\r\v\f")
+		case w.opts.IgnoreCRAtEOL:
+			text = strings.TrimSuffix(text, "\r")
+		}
+		sb.WriteString(text)
+		if strings.HasSuffix(line, "\n") {
+			sb.WriteByte('\n')
+		}
+	}
+	return []byte(sb.String())
+}
+
+// Marks the runs of changes whose lines are all blank as ignorable
+func markBlankChanges(bd BytesDiff) {
+	for i := 0; i < len(bd); {
+		if bd[i].EditType == EQL {
+			i++
+			continue
+		}
+		end, blank := i, true
+		for ; end < len(bd) && bd[end].EditType != EQL; end++ {
+			blank = blank && strings.TrimSpace(bd[end].Text) == ""
+		}
+		for ; blank && i < end; i++ {
+			bd[i].Ignorable = true
+		}
+		i = end
+	}
+}
+
+// The kinds of whitespace errors in lines that are added
+const (
+	// Spaces or tabs at the end of a line. A carriage return is taken to be
+	// part of the line ending.
+	TrailingWhitespace = "trailing whitespace"
+	// A space before a tab in the indentation of a line
+	SpaceBeforeTab = "space before tab in indent"
+)
+
+type WhitespaceError struct {
+	Kind string
+	// The part of the line with the error
+	Start, End int
+}
+
+// Returns the whitespace errors of a line
+func WhitespaceErrors(line string) []WhitespaceError {
+	var errs []WhitespaceError
+	text := strings.TrimSuffix(line, "\r")
+	indent := len(text) - len(strings.TrimLeft(text, " \t"))
+	if tab := strings.LastIndexByte(text[:indent], '\t'); tab > 0 {
+		if space := strings.IndexByte(text[:tab], ' '); space >= 0 {
+			errs = append(errs, WhitespaceError{SpaceBeforeTab, space, tab + 1})
+		}
+	}
+	if trimmed := strings.TrimRight(text, " \t"); len(trimmed) < len(text) {
+		errs = append(errs, WhitespaceError{TrailingWhitespace, len(trimmed), len(text)})
+	}
+	return errs
+}
+
+// Returns the line with its whitespace errors fixed: whitespace at its end
+// is removed and in its indentation spaces before tabs are removed, unless
+// there are as many as a tab stands for, which are replaced by a tab
+func FixWhitespace(line string) string {
+	text := strings.TrimSuffix(line, "\r")
+	cr := line[len(text):]
+	text = strings.TrimRight(text, " \t")
+	indent := len(text) - len(strings.TrimLeft(text, " \t"))
+	tab := strings.LastIndexByte(text[:indent], '\t')
+	if tab < 0 || strings.IndexByte(text[:tab], ' ') < 0 {
+		return text + cr
+	}
+	var sb strings.Builder
+	spaces := 0
+	for _, c := range []byte(text[:tab+1]) {
+		if c != ' ' {
+			spaces = 0
+			sb.WriteByte(c)
+			continue
+		}
+		spaces++
+		if spaces == tabWidth {
+			sb.WriteByte('\t')
+			spaces = 0
+		}
+	}
+	return sb.String() + text[tab+1:] + cr
+}
+
+// The number of spaces a tab stands for
+const tabWidth = 8
+
+// Highlights whitespace errors
+var whitespaceErrorColor = color.New(color.BgRed)
+
+// Formats an added line with its whitespace errors highlighted
+func highlightWhitespaceErrors(col color.Color, text string, errs []WhitespaceError) string {
+	line := col.Sprint(string(INS))
+	pos := 0
+	for _, e := range errs {
+		start := max(e.Start, pos)
+		if start >= e.End {
+			continue
+		}
+		if pos < start {
+			line += col.Sprint(text[pos:start])
+		}
+		line += whitespaceErrorColor.Sprint(text[start:e.End])
+		pos = e.End
+	}
+	if pos < len(text) {
+		line += col.Sprint(text[pos:])
+	}
+	return line
+}
@@ -0,0 +1,61 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWhitespaceDiffer(t *testing.T) {
+	a := []byte("func f() {\n\treturn  x + y\n}  \r\n\nz\n")
+	b := []byte("func f() {\n    return x+y\n}\n\n\nz\n")
+	tests := []struct {
+		name string
+		opts WhitespaceOptions
+		want string
+	}{
+		{"none", WhitespaceOptions{}, "@@ -1,5 +1,6 @@\n func f() {\n-\treturn  x + y\n-}  \r\n+    return x+y\n+}\n \n+\n z\n"},
+		{"all space", WhitespaceOptions{IgnoreAllSpace: true}, "@@ -2,4 +2,5 @@\n \treturn  x + y\n }  \r\n \n+\n z\n"},
+		{"space change", WhitespaceOptions{IgnoreSpaceChange: true}, "@@ -1,5 +1,6 @@\n func f() {\n-\treturn  x + y\n+    return x+y\n }  \r\n \n+\n z\n"},
+		{"space at eol", WhitespaceOptions{IgnoreSpaceAtEOL: true}, "@@ -1,5 +1,6 @@\n func f() {\n-\treturn  x + y\n+    return x+y\n }  \r\n \n+\n z\n"},
+		{"cr at eol", WhitespaceOptions{IgnoreCRAtEOL: true}, "@@ -1,5 +1,6 @@\n func f() {\n-\treturn  x + y\n-}  \r\n+    return x+y\n+}\n \n+\n z\n"},
+		{"all space and blank lines", WhitespaceOptions{IgnoreAllSpace: true, IgnoreBlankLines: true}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bd := tt.opts.Differ(lcsDiffer{}).DiffBytes(a, b)
+			assert.Equal(t, tt.want, bd.Hunks(DefaultContext).String())
+		})
+	}
+}
+
+func TestIgnoreBlankLines(t *testing.T) {
+	a := []byte("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n")
+	b := []byte("1\n\n2\n3\n4\n5\n6\n7\n8\nnine\n10\n")
+	bd := WhitespaceOptions{IgnoreBlankLines: true}.Differ(lcsDiffer{}).DiffBytes(a, b)
+	// The blank line is only shown if it's in the hunk of another change
+	assert.Equal(t, "@@ -6,5 +7,5 @@\n 6\n 7\n 8\n-9\n+nine\n 10\n", bd.Hunks(3).String())
+	assert.Equal(t, "@@ -2,9 +2,10 @@\n+\n 2\n 3\n 4\n 5\n 6\n 7\n 8\n-9\n+nine\n 10\n", bd.Hunks(8).String())
+}
+
+func TestWhitespaceErrors(t *testing.T) {
+	assert.Empty(t, WhitespaceErrors("\tfine(x)\r"))
+	assert.Equal(t, []WhitespaceError{{TrailingWhitespace, 4, 6}}, WhitespaceErrors("x := \t"))
+	assert.Equal(t, []WhitespaceError{{SpaceBeforeTab, 1, 3}}, WhitespaceErrors("\t \tx"))
+	assert.Equal(t, []WhitespaceError{{SpaceBeforeTab, 0, 2}, {TrailingWhitespace, 0, 3}}, WhitespaceErrors(" \t "))
+}
+
+func TestFixWhitespace(t *testing.T) {
+	tests := []struct {
+		line, want string
+	}{
+		{"x := 1  ", "x := 1"},
+		{"\tx\t\r", "\tx\r"},
+		{"\t \tx", "\t\tx"},
+		{"         \tx ", "\t\tx"},
+		{"    x", "    x"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, FixWhitespace(tt.line), tt.line)
+	}
+}
@@ -0,0 +1,251 @@
+package diff
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/gookit/color"
+	"github.com/pkg/errors"
+)
+
+// How a word diff shows the words that changed
+type WordDiffMode string
+
+const (
+	// Deleted words are shown in red and inserted ones in green
+	WordDiffColor WordDiffMode = "color"
+	// Deleted words are shown as [-word-] and inserted ones as {+word+}
+	WordDiffPlain WordDiffMode = "plain"
+	// Every run of unchanged, deleted or inserted text is on a line of its
+	// own starting with ' ', '-' or '+', and each newline of the file is a
+	// line with only '~'
+	WordDiffPorcelain WordDiffMode = "porcelain"
+	// Patches are shown line by line
+	WordDiffNone WordDiffMode = "none"
+)
+
+func ParseWordDiffMode(s string) (WordDiffMode, error) {
+	switch mode := WordDiffMode(s); mode {
+	case WordDiffColor, WordDiffPlain, WordDiffPorcelain, WordDiffNone:
+		return mode, nil
+	}
+	return "", errors.Errorf("invalid word diff mode '%s', expected color, plain, porcelain or none", s)
+}
+
+// Matches the words of a word diff unless another expression is given: runs
+// of characters that aren't whitespace
+var DefaultWordRegex = regexp.MustCompile(`\S+`)
+
+// A run of text of a word diff, which is unchanged, deleted or inserted
+type WordEdit struct {
+	EditType EditType
+	Text     string
+}
+
+type WordDiff []WordEdit
+
+type word struct {
+	text       string
+	start, end int
+}
+
+// Splits text into the words that re matches, line by line so that no word
+// has a newline. Anything between the words is treated as whitespace.
+func splitWords(text string, re *regexp.Regexp) []word {
+	var words []word
+	offset := 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		for _, m := range re.FindAllStringIndex(strings.TrimSuffix(line, "\n"), -1) {
+			if m[0] < m[1] {
+				words = append(words, word{line[m[0]:m[1]], offset + m[0], offset + m[1]})
+			}
+		}
+		offset += len(line)
+	}
+	return words
+}
+
+// Diffs the words of two texts, which re matches, with the differ diffing
+// them as if each was a line. As in git, the whitespace around the words is
+// taken from b: only the whitespace between deleted words is shown from a.
+// Deleted words follow the word before them, or start the line if they
+// started one in a.
+func DiffWords(differ Differ, a, b string, re *regexp.Regexp) WordDiff {
+	aw, bw := splitWords(a, re), splitWords(b, re)
+	edits := differ.DiffBytes(joinWords(aw), joinWords(bw))
+	var wd WordDiff
+	// The ends of the text of a and b that has been diffed
+	aPos, pos := 0, 0
+	for i := 0; i < len(edits); {
+		if edits[i].EditType == EQL {
+			w := bw[edits[i].BLine]
+			wd = wd.add(EQL, b[pos:w.end])
+			pos = w.end
+			aPos = aw[edits[i].ALine].end
+			i++
+			continue
+		}
+		var dels, inss []word
+		for ; i < len(edits) && edits[i].EditType != EQL; i++ {
+			if edits[i].EditType == DEL {
+				dels = append(dels, aw[edits[i].ALine])
+			} else {
+				inss = append(inss, bw[edits[i].BLine])
+			}
+		}
+		switch {
+		case len(inss) > 0:
+			wd = wd.add(EQL, b[pos:inss[0].start])
+		case strings.Contains(a[aPos:dels[0].start], "\n"):
+			next := len(b)
+			if i < len(edits) {
+				next = bw[edits[i].BLine].start
+			}
+			if nl := strings.LastIndexByte(b[pos:next], '\n'); nl >= 0 {
+				wd = wd.add(EQL, b[pos:pos+nl+1])
+				pos += nl + 1
+			}
+		}
+		if len(dels) > 0 {
+			wd = wd.add(DEL, a[dels[0].start:dels[len(dels)-1].end])
+			aPos = dels[len(dels)-1].end
+		}
+		if len(inss) > 0 {
+			wd = wd.add(INS, b[inss[0].start:inss[len(inss)-1].end])
+			pos = inss[len(inss)-1].end
+		}
+	}
+	return wd.add(EQL, b[pos:])
+}
+
+func joinWords(words []word) []byte {
+	var sb strings.Builder
+	for _, w := range words {
+		sb.WriteString(w.text)
+		sb.WriteByte('\n')
+	}
+	return []byte(sb.String())
+}
+
+func (wd WordDiff) add(editType EditType, text string) WordDiff {
+	switch {
+	case text == "":
+		return wd
+	case len(wd) > 0 && wd[len(wd)-1].EditType == editType:
+		wd[len(wd)-1].Text += text
+		return wd
+	}
+	return append(wd, WordEdit{editType, text})
+}
+
+// Diffs the words of the lines the hunk removes and adds, with the
+// unchanged lines around them as context
+func (h Hunk) Words(differ Differ, re *regexp.Regexp) WordDiff {
+	var src, dst strings.Builder
+	for _, le := range h.Edits {
+		if le.EditType != INS {
+			src.WriteString(le.Text + "\n")
+		}
+		if le.EditType != DEL {
+			dst.WriteString(le.Text + "\n")
+		}
+	}
+	return DiffWords(differ, src.String(), dst.String(), re)
+}
+
+// Formats the word diff in the mode
+func (wd WordDiff) Format(mode WordDiffMode) string {
+	var sb strings.Builder
+	for _, we := range wd {
+		lines := strings.Split(we.Text, "\n")
+		for i, line := range lines {
+			if i > 0 {
+				if mode == WordDiffPorcelain {
+					sb.WriteString("~")
+				}
+				sb.WriteString("\n")
+			}
+			if line == "" {
+				continue
+			}
+			switch {
+			case mode == WordDiffPorcelain:
+				sb.WriteString(string(we.EditType) + line + "\n")
+			case we.EditType == EQL:
+				sb.WriteString(line)
+			case mode == WordDiffColor && we.EditType == DEL:
+				sb.WriteString(color.Red.Sprint(line))
+			case mode == WordDiffColor:
+				sb.WriteString(color.Green.Sprint(line))
+			case we.EditType == DEL:
+				sb.WriteString("[-" + line + "-]")
+			default:
+				sb.WriteString("{+" + line + "+}")
+			}
+		}
+	}
+	return sb.String()
+}
+
+// Writes the file like WritePatch, but with the hunks shown as word diffs in
+// the mode, diffing the words re matches with the differ
+func (fd *FileDiff) WriteWordDiff(w io.Writer, hs Hunks, differ Differ, re *regexp.Regexp, mode WordDiffMode) {
+	if !fd.writePatchHeader(w, hs) {
+		return
+	}
+	for _, h := range hs {
+		fmt.Fprintln(w, color.Cyan.Sprint(h.Header()))
+		fmt.Fprint(w, h.Words(differ, re).Format(mode))
+	}
+}
+
+// Highlights the parts of removed lines and the added lines that replace them
+var highlightDel, highlightIns = color.New(color.FgRed, color.OpReverse), color.New(color.FgGreen, color.OpReverse)
+
+// Returns the part of a removed line and of the added line replacing it that
+// changed, as the ends of the common prefix and suffix of the lines. The
+// parts start and end at word boundaries. They are both empty if the lines
+// have nothing in common.
+func changedParts(a, b string) (int, int, int, int) {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	for prefix > 0 && isWordByte(a[prefix-1]) &&
+		((prefix < len(a) && isWordByte(a[prefix])) || (prefix < len(b) && isWordByte(b[prefix]))) {
+		prefix--
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	for suffix > 0 && isWordByte(a[len(a)-suffix]) &&
+		((len(a)-suffix > prefix && isWordByte(a[len(a)-suffix-1])) || (len(b)-suffix > prefix && isWordByte(b[len(b)-suffix-1]))) {
+		suffix--
+	}
+	if prefix == 0 && suffix == 0 {
+		return 0, 0, 0, 0
+	}
+	return prefix, len(a) - suffix, prefix, len(b) - suffix
+}
+
+// Reports whether the byte is part of a word, counting the bytes of
+// multi-byte characters as such so that they aren't split
+func isWordByte(c byte) bool {
+	return c == '_' || c >= 0x80 || ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// Formats a removed or added line with the part from start to end
+// highlighted
+func highlightLine(le LineEdit, start, end int) string {
+	col, highlight := color.Red, highlightDel
+	if le.EditType == INS {
+		col, highlight = color.Green, highlightIns
+	}
+	if start == end || (le.EditType == INS && len(WhitespaceErrors(le.Text)) > 0) {
+		return le.String()
+	}
+	return col.Sprint(string(le.EditType)+le.Text[:start]) + highlight.Sprint(le.Text[start:end]) + col.Sprint(le.Text[end:])
+}
@@ -0,0 +1,112 @@
+package diff
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Diffs lines by their longest common subsequence, as the algorithms can't
+// be imported here
+type lcsDiffer struct{}
+
+func (lcsDiffer) DiffBytes(a, b []byte) BytesDiff {
+	al, bl := Lines(a), Lines(b)
+	lengths := make([][]int, len(al)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(bl)+1)
+	}
+	for i := len(al) - 1; i >= 0; i-- {
+		for j := len(bl) - 1; j >= 0; j-- {
+			if al[i] == bl[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+	var ops []EditType
+	i, j := 0, 0
+	for i < len(al) || j < len(bl) {
+		switch {
+		case i < len(al) && j < len(bl) && al[i] == bl[j]:
+			ops = append(ops, EQL)
+			i++
+			j++
+		case j == len(bl) || (i < len(al) && lengths[i+1][j] >= lengths[i][j+1]):
+			ops = append(ops, DEL)
+			i++
+		default:
+			ops = append(ops, INS)
+			j++
+		}
+	}
+	return NewBytesDiff(al, bl, ops)
+}
+
+func (lcsDiffer) FilesDiff(a, b []byte) bool {
+	return !bytes.Equal(a, b)
+}
+
+func (lcsDiffer) DiffFiles(a, b []byte) (FileEditType, error) {
+	return FileEditTypeInPlace, nil
+}
+
+func TestDiffWords(t *testing.T) {
+	a := "a b c\nx\nd e\nfoo bar\n"
+	b := "a c\nx\ne\nfoo baz qux\nnew line\n"
+	wd := DiffWords(lcsDiffer{}, a, b, DefaultWordRegex)
+
+	// The same as git diff --word-diff shows
+	assert.Equal(t, "a[-b-] c\nx\n[-d-]e\nfoo [-bar-]{+baz qux+}\n{+new line+}\n", wd.Format(WordDiffPlain))
+	assert.Equal(t, " a\n-b\n  c\n~\n x\n~\n-d\n e\n~\n foo \n-bar\n+baz qux\n~\n+new line\n~\n", wd.Format(WordDiffPorcelain))
+	assert.Equal(t, "ab c\nx\nde\nfoo barbaz qux\nnew line\n", wd.Format(WordDiffColor))
+
+	wd = DiffWords(lcsDiffer{}, "x d\n\ne\n", "x\n\n e\n", DefaultWordRegex)
+	assert.Equal(t, "x[-d-]\n\n e\n", wd.Format(WordDiffPlain))
+
+	wd = DiffWords(lcsDiffer{}, "a c\n", "a b c\n", DefaultWordRegex)
+	assert.Equal(t, "a {+b+} c\n", wd.Format(WordDiffPlain))
+
+	wd = DiffWords(lcsDiffer{}, "f(x, y)\n", "f(x, z)\n", regexp.MustCompile(`\w+|[^\w\s]`))
+	assert.Equal(t, "f(x, [-y-]{+z+})\n", wd.Format(WordDiffPlain))
+	wd = DiffWords(lcsDiffer{}, "f(x, y)\n", "f(x, z)\n", DefaultWordRegex)
+	assert.Equal(t, "f(x, [-y)-]{+z)+}\n", wd.Format(WordDiffPlain))
+}
+
+func TestWordDiffPatch(t *testing.T) {
+	a := []byte("one\ntwo three\nfour\n")
+	b := []byte("one\ntwo 3\nfour\n")
+	fd := &FileDiff{EditType: FileEditTypeInPlace, SrcPath: "f", DstPath: "f", SrcPerm: 0644, DstPerm: 0644, SrcID: "aaaaaaaa", DstID: "bbbbbbbb"}
+	buf := bytes.NewBuffer(nil)
+	fd.WriteWordDiff(buf, lcsDiffer{}.DiffBytes(a, b).Hunks(DefaultContext), lcsDiffer{}, DefaultWordRegex, WordDiffPlain)
+	assert.Equal(t, "diff --got a/f b/f\nindex aaaaaaa..bbbbbbb 100644\n--- a/f\n+++ b/f\n@@ -1,3 +1,3 @@\none\ntwo [-three-]{+3+}\nfour\n", buf.String())
+}
+
+func TestChangedParts(t *testing.T) {
+	tests := []struct {
+		a, b               string
+		changedA, changedB string
+	}{
+		{"return foo(a, b)", "return foo(a, c)", "b", "c"},
+		{"name := oldName", "name := newName", "oldName", "newName"},
+		{"x = 1", "x = 10", "1", "10"},
+		{"abc", "xyz", "", ""},
+		{"same prefix", "same prefix and more", "", " and more"},
+	}
+	for _, tt := range tests {
+		aStart, aEnd, bStart, bEnd := changedParts(tt.a, tt.b)
+		assert.Equal(t, tt.changedA, tt.a[aStart:aEnd], tt.a)
+		assert.Equal(t, tt.changedB, tt.b[bStart:bEnd], tt.b)
+	}
+}
+
+func TestParseWordDiffMode(t *testing.T) {
+	mode, err := ParseWordDiffMode("porcelain")
+	assert.NoError(t, err)
+	assert.Equal(t, WordDiffPorcelain, mode)
+	_, err = ParseWordDiffMode("words")
+	assert.Error(t, err)
+}
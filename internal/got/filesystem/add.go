@@ -5,42 +5,71 @@ import (
 	"path/filepath"
 
 	"github.com/pkg/errors"
+
+	"got/internal/index"
+	"got/internal/objects"
 )
 
+// Stages the files in the working tree that match the pathspecs, which are
+// relative to the working directory. Tracked files that match but were
+// deleted from the working tree are removed from the index.
 func (g *Got) AddPath(paths ...string) error {
+	ps, err := g.pathspec(paths)
+	if err != nil {
+		return errors.Wrap(err, "couldn't add paths")
+	}
 	var matches []string
-	for _, p := range paths {
-		ms, err := filepath.Glob(p)
-		if err != nil {
-			return errors.Wrapf(err, "couldn't add path %s", p)
+	err = g.forAllFilesInRepo(g.dir, func(path string, info os.FileInfo, err error) error {
+		if ps.Matches(path) {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "couldn't add paths")
+	}
+	var deleted []string
+	for _, e := range g.Index.SortedEntries() {
+		if !ps.Matches(e.Name) {
+			continue
 		}
-		matches = append(matches, ms...)
+		_, err := os.Lstat(filepath.Join(g.dir, e.Name))
+		if os.IsNotExist(err) {
+			deleted = append(deleted, e.Name)
+		}
+	}
+	if unmatched := ps.Unmatched(append(matches, deleted...)); len(unmatched) > 0 {
+		return errors.Errorf("pathspec '%s' did not match any files", unmatched[0])
 	}
 	for _, m := range matches {
-		err := g.forAllFilesInRepo(m, func(path string, info os.FileInfo, err error) error {
-			return g.addFile(path)
-		})
+		err := g.addFile(m)
 		if err != nil {
-			return errors.Wrapf(err, "couldn't add path %s", m)
+			return err
+		}
+	}
+	for _, d := range deleted {
+		err := g.Index.RemoveFile(d)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't add path %s", d)
 		}
 	}
 	return nil
 }
 
-func (g *Got) addFile(filename string) error {
-	rel, err := g.repoRel(filename)
+// Stores the file at the repository relative path and stages it
+func (g *Got) addFile(rel string) error {
+	abs := filepath.Join(g.dir, rel)
+	info, err := os.Stat(abs)
 	if err != nil {
-		return errors.Wrapf(err, "couldn't add path %s", filename)
+		return errors.Wrapf(err, "couldn't add path %s", rel)
 	}
-
-	hash, err := g.HashFile(rel, true)
+	hash, err := g.hashWorkingTreeFile(rel, true)
 	if err != nil {
-		return errors.Wrapf(err, "couldn't add path %s", filename)
+		return errors.Wrapf(err, "couldn't add path %s", rel)
 	}
-
-	err = g.Index.AddFile(rel, hash)
+	err = g.Index.AddEntry(index.NewEntry(info.Mode(), objects.TypeBlob, hash, rel))
 	if err != nil {
-		return errors.Wrapf(err, "couldn't add path %s", filename)
+		return errors.Wrapf(err, "couldn't add path %s", rel)
 	}
 	return nil
 }
@@ -0,0 +1,106 @@
+package filesystem
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"got/internal/diff"
+	"got/internal/index"
+	"got/internal/objects"
+)
+
+// Picks the hunks of the diff of a file to act on
+type HunkSelector func(fd *diff.FileDiff, hunks diff.Hunks) (diff.Hunks, error)
+
+// Stages the hunks of the changes in the working tree that the selector
+// picks, leaving the rest of the changes unstaged. Only files modified in
+// place are offered.
+func (g *Got) AddPatch(paths []string, sel HunkSelector) error {
+	wt, err := g.workingTreeSnapshot()
+	if err != nil {
+		return errors.Wrap(err, "couldn't add patch")
+	}
+	return g.selectHunks(g.indexSnapshot(), wt, paths, sel, func(fd *diff.FileDiff, src, _ []byte, selected diff.Hunks) error {
+		return g.writeIndexContents(fd.Path(), fd.SrcPerm, diff.ApplyHunks(src, selected))
+	})
+}
+
+// Discards the hunks of the changes in the working tree that the selector
+// picks, or with staged unstages the hunks of the changes in the index.
+// Only files modified in place are offered.
+func (g *Got) RestorePatch(paths []string, staged bool, sel HunkSelector) error {
+	if staged {
+		head, err := g.headSnapshot()
+		if err != nil {
+			return errors.Wrap(err, "couldn't restore patch")
+		}
+		return g.selectHunks(head, g.indexSnapshot(), paths, sel, func(fd *diff.FileDiff, _, dst []byte, selected diff.Hunks) error {
+			return g.writeIndexContents(fd.Path(), fd.DstPerm, diff.ApplyHunks(dst, reverseHunks(selected)))
+		})
+	}
+	wt, err := g.workingTreeSnapshot()
+	if err != nil {
+		return errors.Wrap(err, "couldn't restore patch")
+	}
+	return g.selectHunks(g.indexSnapshot(), wt, paths, sel, func(fd *diff.FileDiff, _, dst []byte, selected diff.Hunks) error {
+		return g.writeWorkingTreeFile(fd.Path(), diff.ApplyHunks(dst, reverseHunks(selected)), fd.DstPerm)
+	})
+}
+
+// Offers the hunks of every file modified in place between the snapshots
+// and matching the paths to the selector, and calls apply with the contents
+// of the file in both snapshots and the hunks that were picked, if any
+func (g *Got) selectHunks(src, dst *snapshot, paths []string, sel HunkSelector, apply func(fd *diff.FileDiff, src, dst []byte, selected diff.Hunks) error) error {
+	ps, err := g.pathspec(paths)
+	if err != nil {
+		return errors.Wrap(err, "couldn't select hunks")
+	}
+	for _, fd := range diffEntries(src.entries, dst.entries) {
+		if fd.EditType != diff.FileEditTypeInPlace || !ps.Matches(fd.Path()) {
+			continue
+		}
+		a, err := g.snapshotContents(src, fd.SrcPath)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't select hunks of %s", fd.Path())
+		}
+		b, err := g.snapshotContents(dst, fd.Path())
+		if err != nil {
+			return errors.Wrapf(err, "couldn't select hunks of %s", fd.Path())
+		}
+		hunks := g.Differ.DiffBytes(a, b).Hunks(g.DiffContext)
+		if len(hunks) == 0 {
+			continue
+		}
+		selected, err := sel(fd, hunks)
+		if err != nil {
+			return err
+		}
+		if len(selected) == 0 {
+			continue
+		}
+		err = apply(fd, a, b, selected)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't apply hunks to %s", fd.Path())
+		}
+	}
+	return nil
+}
+
+func reverseHunks(hs diff.Hunks) diff.Hunks {
+	reversed := make(diff.Hunks, len(hs))
+	for i, h := range hs {
+		reversed[i] = h.Reverse()
+	}
+	return reversed
+}
+
+// Stores the contents as a blob and stages it for the file
+func (g *Got) writeIndexContents(path string, perm os.FileMode, contents []byte) error {
+	id, err := g.Objects.StoreBlobFrom(bytes.NewReader(contents))
+	if err != nil {
+		return err
+	}
+	return g.Index.AddEntry(index.NewEntry(perm, objects.TypeBlob, id, path))
+}
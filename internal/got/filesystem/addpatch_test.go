@@ -0,0 +1,82 @@
+package filesystem
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"got/internal/interactive"
+)
+
+const (
+	patchBase    = "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	patchChanged = "one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n"
+)
+
+// Returns a hunk selector answering the questions with the answers
+func answering(answers string) HunkSelector {
+	return interactive.NewSession(strings.NewReader(answers), ioutil.Discard, "Stage this hunk").Select
+}
+
+func indexContents(t *testing.T, g *Got, path string) string {
+	id, err := g.Index.GetEntrySum(path)
+	assert.Nil(t, err)
+	bs, err := g.blobContents(id)
+	assert.Nil(t, err)
+	return string(bs)
+}
+
+func TestAddPatch(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	commitFiles(t, g, "first", map[string]string{"a.txt": patchBase})
+	writeFiles(t, g, map[string]string{"a.txt": patchChanged})
+
+	assert.Nil(t, g.AddPatch(nil, answering("y\nn\n")))
+	assert.Equal(t, "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n", indexContents(t, g, "a.txt"))
+	assert.Equal(t, patchChanged, readFile(t, g, "a.txt"))
+
+	// Only the hunk that's still unstaged is offered
+	assert.Nil(t, g.AddPatch([]string{"a.txt"}, answering("y\n")))
+	assert.Equal(t, patchChanged, indexContents(t, g, "a.txt"))
+}
+
+func TestAddPatchEdit(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	commitFiles(t, g, "first", map[string]string{"a.txt": "a\n"})
+	writeFiles(t, g, map[string]string{"a.txt": "b\nc\n"})
+
+	s := interactive.NewSession(strings.NewReader("e\n"), ioutil.Discard, "Stage this hunk")
+	s.Edit = func(text string) (string, error) {
+		return strings.Replace(text, "+c\n", "", 1), nil
+	}
+	assert.Nil(t, g.AddPatch(nil, s.Select))
+	assert.Equal(t, "b\n", indexContents(t, g, "a.txt"))
+	assert.Equal(t, "b\nc\n", readFile(t, g, "a.txt"))
+}
+
+func TestRestorePatch(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	commitFiles(t, g, "first", map[string]string{"a.txt": patchBase})
+	writeFiles(t, g, map[string]string{"a.txt": patchChanged})
+
+	// Discarding from the working tree
+	assert.Nil(t, g.RestorePatch(nil, false, answering("n\ny\n")))
+	assert.Equal(t, "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n", readFile(t, g, "a.txt"))
+	assert.Equal(t, patchBase, indexContents(t, g, "a.txt"))
+
+	// Unstaging
+	writeFiles(t, g, map[string]string{"a.txt": patchChanged})
+	assert.Nil(t, g.AddPath("a.txt"))
+	assert.Nil(t, g.RestorePatch([]string{"a.txt"}, true, answering("y\nn\n")))
+	assert.Equal(t, "1\n2\n3\n4\n5\n6\n7\n8\n9\nten\n", indexContents(t, g, "a.txt"))
+	assert.Equal(t, patchChanged, readFile(t, g, "a.txt"))
+
+	// Quitting leaves everything as it is
+	assert.Nil(t, g.RestorePatch(nil, false, answering("q\n")))
+	assert.Equal(t, patchChanged, readFile(t, g, "a.txt"))
+}
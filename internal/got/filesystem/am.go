@@ -0,0 +1,352 @@
+package filesystem
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"got/internal/diff"
+	"got/internal/index"
+	"got/internal/mbox"
+	"got/internal/objects"
+	"got/internal/patch"
+	"got/internal/pathspec"
+)
+
+// The directory holding the state of an am session: a file per mail named
+// by its number, the numbers of the 'next' and 'last' mails and the commit
+// HEAD was on when the session started in 'orig-head'.
+const amDir = "rebase-apply"
+
+// Reports whether an am session has stopped at a patch that didn't apply
+func (g *Got) AmInProgress() bool {
+	_, err := os.Stat(filepath.Join(g.gotDir, amDir))
+	return err == nil
+}
+
+// Applies the mails of the mailboxes as commits on top of HEAD, keeping
+// their authors and dates. If a patch doesn't apply the session stops,
+// leaving the patch to be resolved and the session to be continued with
+// AmContinue, or the patch to be dropped with AmSkip, or the whole session to
+// be undone with AmAbort.
+func (g *Got) Am(mailboxes ...io.Reader) error {
+	if g.AmInProgress() {
+		return errors.New("an am session is already in progress, use --continue, --skip or --abort")
+	}
+	staged, err := g.hasStagedChanges()
+	if err != nil {
+		return errors.Wrap(err, "couldn't apply mails")
+	}
+	if staged {
+		return errors.New("your index has uncommitted changes, commit them first")
+	}
+	var mails []string
+	for _, r := range mailboxes {
+		ms, err := mbox.Split(r)
+		if err != nil {
+			return errors.Wrap(err, "couldn't apply mails")
+		}
+		for _, m := range ms {
+			_, err = mbox.Parse(m)
+			if err != nil {
+				return errors.Wrapf(err, "couldn't apply mail %d", len(mails)+1)
+			}
+			mails = append(mails, m)
+		}
+	}
+	if len(mails) == 0 {
+		return errors.New("no patches found")
+	}
+	head, err := g.idAtHead()
+	if err != nil {
+		return errors.Wrap(err, "couldn't apply mails")
+	}
+	origHead := ""
+	if head != nil {
+		origHead = string(*head)
+	}
+
+	err = os.MkdirAll(filepath.Join(g.gotDir, amDir), os.ModePerm)
+	if err != nil {
+		return errors.Wrap(err, "couldn't start am session")
+	}
+	for i, m := range mails {
+		err = g.writeAmFile(fmt.Sprintf("%04d", i+1), m)
+		if err != nil {
+			return err
+		}
+	}
+	for name, value := range map[string]string{"next": "1", "last": strconv.Itoa(len(mails)), "orig-head": origHead} {
+		err = g.writeAmFile(name, value)
+		if err != nil {
+			return err
+		}
+	}
+	return g.amRun()
+}
+
+// Commits the current patch of the session, which has been resolved and
+// added to the index, and goes on with the rest
+func (g *Got) AmContinue() error {
+	if !g.AmInProgress() {
+		return errors.New("no am session in progress")
+	}
+	next, m, err := g.amCurrent()
+	if err != nil {
+		return err
+	}
+	staged, err := g.hasStagedChanges()
+	if err != nil {
+		return errors.Wrap(err, "couldn't continue am session")
+	}
+	if !staged {
+		return errors.New("no changes staged, did you forget to use 'got add'? Use --skip to drop the patch")
+	}
+	_, err = g.commitAs(m.CommitMessage(), formatAuthor(m.AuthorName, m.AuthorEmail, m.Date))
+	if err != nil {
+		return errors.Wrap(err, "couldn't continue am session")
+	}
+	err = g.writeAmFile("next", strconv.Itoa(next+1))
+	if err != nil {
+		return err
+	}
+	return g.amRun()
+}
+
+// Drops the current patch of the session, resetting the files it touches to
+// HEAD, and goes on with the rest
+func (g *Got) AmSkip() error {
+	if !g.AmInProgress() {
+		return errors.New("no am session in progress")
+	}
+	next, m, err := g.amCurrent()
+	if err != nil {
+		return err
+	}
+	headTree, err := g.headTree()
+	if err != nil {
+		return errors.Wrap(err, "couldn't skip patch")
+	}
+	err = g.resetPaths(headTree, patchPaths(m))
+	if err != nil {
+		return errors.Wrap(err, "couldn't skip patch")
+	}
+	err = g.writeAmFile("next", strconv.Itoa(next+1))
+	if err != nil {
+		return err
+	}
+	return g.amRun()
+}
+
+// Ends the session, moving HEAD back to where it was when the session
+// started and resetting the files the session changed
+func (g *Got) AmAbort() error {
+	if !g.AmInProgress() {
+		return errors.New("no am session in progress")
+	}
+	_, m, err := g.amCurrent()
+	if err != nil {
+		return err
+	}
+	origHead, err := g.readAmFile("orig-head")
+	if err != nil {
+		return err
+	}
+	var origTree *objects.Tree
+	if origHead != "" {
+		origTree, err = g.commitTree(objects.ID(origHead))
+		if err != nil {
+			return errors.Wrap(err, "couldn't abort am session")
+		}
+	}
+	headTree, err := g.headTree()
+	if err != nil {
+		return errors.Wrap(err, "couldn't abort am session")
+	}
+	paths := patchPaths(m)
+	for _, fd := range diffTrees(origTree, headTree) {
+		paths = append(paths, fd.Path())
+	}
+	err = g.resetPaths(origTree, paths)
+	if err != nil {
+		return errors.Wrap(err, "couldn't abort am session")
+	}
+
+	headType, err := g.HeadType()
+	if err != nil {
+		return errors.Wrap(err, "couldn't abort am session")
+	}
+	if headType == HeadTypeRef {
+		ref, err := g.HeadAsRef()
+		if err != nil {
+			return errors.Wrap(err, "couldn't abort am session")
+		}
+		if origHead == "" {
+			err = g.Refs.DeleteRef(ref.Name())
+			if err == nil {
+				err = ioutil.WriteFile(filepath.Join(g.gotDir, headFile), nil, os.ModePerm)
+			}
+		} else {
+			err = g.Refs.UpdateRef(ref, objects.ID(origHead))
+		}
+		if err != nil {
+			return errors.Wrap(err, "couldn't abort am session")
+		}
+	}
+	return os.RemoveAll(filepath.Join(g.gotDir, amDir))
+}
+
+// Applies and commits the mails from the next one on, stopping at the first
+// that doesn't apply
+func (g *Got) amRun() error {
+	for {
+		last, err := g.readAmNumber("last")
+		if err != nil {
+			return err
+		}
+		next, m, err := g.amCurrent()
+		if err != nil {
+			return err
+		}
+		if next > last {
+			return os.RemoveAll(filepath.Join(g.gotDir, amDir))
+		}
+		fmt.Printf("Applying: %s\n", m.Subject)
+		_, err = g.Apply(strings.NewReader(m.Patch), ApplyOptions{Index: true})
+		if err != nil {
+			return errors.Wrapf(err, "patch failed at %04d %s", next, m.Subject)
+		}
+		_, err = g.commitAs(m.CommitMessage(), formatAuthor(m.AuthorName, m.AuthorEmail, m.Date))
+		if err != nil {
+			return err
+		}
+		err = g.writeAmFile("next", strconv.Itoa(next+1))
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Returns the number and the mail of the patch the session is at. The mail is
+// empty if the session is past its last patch.
+func (g *Got) amCurrent() (int, mbox.Message, error) {
+	next, err := g.readAmNumber("next")
+	if err != nil {
+		return 0, mbox.Message{}, err
+	}
+	raw, err := g.readAmFile(fmt.Sprintf("%04d", next))
+	if os.IsNotExist(errors.Cause(err)) {
+		return next, mbox.Message{}, nil
+	}
+	if err != nil {
+		return 0, mbox.Message{}, err
+	}
+	m, err := mbox.Parse(raw)
+	if err != nil {
+		return 0, mbox.Message{}, errors.Wrapf(err, "couldn't read patch %04d", next)
+	}
+	return next, m, nil
+}
+
+func (g *Got) readAmFile(name string) (string, error) {
+	bs, err := ioutil.ReadFile(filepath.Join(g.gotDir, amDir, name))
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't read am session")
+	}
+	return string(bs), nil
+}
+
+func (g *Got) readAmNumber(name string) (int, error) {
+	s, err := g.readAmFile(name)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, errors.Wrapf(err, "couldn't read am session")
+	}
+	return n, nil
+}
+
+func (g *Got) writeAmFile(name, contents string) error {
+	err := ioutil.WriteFile(filepath.Join(g.gotDir, amDir, name), []byte(contents), os.ModePerm)
+	if err != nil {
+		return errors.Wrap(err, "couldn't write am session")
+	}
+	return nil
+}
+
+// Returns the paths the patch of the mail touches
+func patchPaths(m mbox.Message) []string {
+	fps, err := patch.Parse(strings.NewReader(m.Patch))
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, fp := range fps {
+		for _, p := range []string{fp.OldPath, fp.NewPath} {
+			if p != "" {
+				paths = append(paths, p)
+			}
+		}
+	}
+	return paths
+}
+
+// Reports whether the index differs from HEAD
+func (g *Got) hasStagedChanges() (bool, error) {
+	diffs, err := g.diffHead()
+	if err != nil {
+		return false, err
+	}
+	for _, fd := range diffs {
+		if fd.EditType != diff.FileEditTypeUnmodified {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Makes the given paths in the index and working tree match the tree, which
+// is empty if nil. Nothing is done if there are no paths.
+func (g *Got) resetPaths(tree *objects.Tree, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	entries := treeEntryMap(tree)
+	ps := pathspec.Paths(paths...)
+	for _, ie := range g.Index.SortedEntries() {
+		if _, ok := entries[ie.Name]; ok || !ps.Matches(ie.Name) {
+			continue
+		}
+		err := g.Index.RemoveFile(ie.Name)
+		if err != nil {
+			return err
+		}
+	}
+	for _, p := range paths {
+		te, ok := entries[p]
+		if !ok {
+			err := os.Remove(filepath.Join(g.dir, p))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		err := g.Index.AddEntry(index.NewEntry(te.Mode, te.Type, te.ID, te.Name))
+		if err != nil {
+			return err
+		}
+		err = g.writeBlobToFile(te.ID, te.Name, te.Mode)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package filesystem
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"got/internal/objects"
+)
+
+func headCommit(t *testing.T, g *Got) objects.Commit {
+	id, err := g.ResolveRevision("HEAD")
+	assert.Nil(t, err)
+	c, err := g.Objects.GetCommit(id)
+	assert.Nil(t, err)
+	return c
+}
+
+func TestFormatPatchAndAmRoundTrip(t *testing.T) {
+	base := map[string]string{"a.txt": "one\ntwo\n", "crlf.txt": "one\r\ntwo\r\nthree\r\n"}
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	commitFiles(t, g, "base", base)
+	baseID, err := g.ResolveRevision("HEAD")
+	assert.Nil(t, err)
+
+	writeFiles(t, g, map[string]string{"a.txt": "one\n2\n", "crlf.txt": "one\r\n2\r\nthree\r\n", "new.txt": "new\n"})
+	for _, path := range []string{"a.txt", "crlf.txt", "new.txt"} {
+		assert.Nil(t, g.AddPath(path))
+	}
+	message := "Change the numbers\n\nFrom now on they're digits.\n>From a quote."
+	author := formatAuthor("Jane Doe", "jane@doe.com", time.Date(2020, 11, 3, 14, 5, 9, 0, time.FixedZone("", -7*60*60)))
+	_, err = g.commitAs(message, author)
+	assert.Nil(t, err)
+	sent := headCommit(t, g)
+
+	mails, err := g.FormatPatch(string(baseID))
+	assert.Nil(t, err)
+	assert.Len(t, mails, 1)
+	buf := bytes.NewBuffer(nil)
+	assert.Nil(t, mails[0].Write(buf, 1, 1))
+	assert.Contains(t, buf.String(), "\n>From now on")
+
+	other, cleanupOther := newTestRepo(t)
+	defer cleanupOther()
+	commitFiles(t, other, "base", base)
+	assert.Nil(t, other.Am(buf))
+
+	applied := headCommit(t, other)
+	assert.Equal(t, sent.Author, applied.Author)
+	assert.Equal(t, sent.Message, applied.Message)
+	assert.Equal(t, sent.TreeID, applied.TreeID)
+	assert.Equal(t, "one\r\n2\r\nthree\r\n", readFile(t, other, "crlf.txt"))
+	assert.False(t, other.AmInProgress())
+}
@@ -0,0 +1,403 @@
+package filesystem
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"got/internal/index"
+	"got/internal/merge"
+	"got/internal/objects"
+	"got/internal/patch"
+)
+
+type ApplyOptions struct {
+	// Apply the patch to the index instead of the working tree
+	Cached bool
+	// Apply the patch to both the index and the working tree, which has to
+	// match the index for the patched files
+	Index bool
+	// Only check that the patch applies
+	Check   bool
+	Reverse bool
+	// Fall back to a three-way merge with the blobs named in the patch when
+	// hunks don't apply
+	ThreeWay bool
+	// What is done about whitespace errors in the lines the patches add.
+	// They aren't looked for if it's empty.
+	Whitespace WhitespaceAction
+}
+
+// What applying patches does about whitespace errors, i.e. trailing
+// whitespace and spaces before tabs, in the lines they add
+type WhitespaceAction string
+
+const (
+	// The errors aren't looked for
+	WhitespaceNoWarn WhitespaceAction = "nowarn"
+	// The errors are reported
+	WhitespaceWarn WhitespaceAction = "warn"
+	// The errors are fixed before the patches are applied, and the lines
+	// that were fixed are reported
+	WhitespaceFix WhitespaceAction = "fix"
+	// The errors are reported and the patches aren't applied
+	WhitespaceError WhitespaceAction = "error"
+)
+
+func ParseWhitespaceAction(s string) (WhitespaceAction, error) {
+	switch action := WhitespaceAction(s); action {
+	case WhitespaceNoWarn, WhitespaceWarn, WhitespaceFix, WhitespaceError:
+		return action, nil
+	case "strip":
+		return WhitespaceFix, nil
+	}
+	return "", errors.Errorf("unrecognized whitespace option '%s'", s)
+}
+
+type ApplyResult struct {
+	// The files that a three-way merge left conflict markers in
+	Conflicts []string
+	// The whitespace errors of the lines the patches add, one for each line
+	// that has any, which were fixed if the action was fix
+	WhitespaceErrors []string
+}
+
+// A file as the patches applied so far leave it
+type patchedFile struct {
+	contents []byte
+	mode     os.FileMode
+	// Unset if the file doesn't exist
+	exists bool
+}
+
+// Applies the patches read from r. Either all of them are applied or none:
+// if writing a file fails the files written before it are restored.
+// The result is returned along with the error if whitespace errors stop the
+// patches from being applied.
+func (g *Got) Apply(r io.Reader, opts ApplyOptions) (*ApplyResult, error) {
+	patches, err := patch.Parse(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't parse patch")
+	}
+	if len(patches) == 0 {
+		return nil, errors.New("no valid patches in input")
+	}
+	files := make(map[string]*patchedFile)
+	result := &ApplyResult{}
+	for _, fp := range patches {
+		if opts.Reverse {
+			fp = fp.Reverse()
+		}
+		if opts.Whitespace != "" && opts.Whitespace != WhitespaceNoWarn {
+			result.WhitespaceErrors = append(result.WhitespaceErrors, fp.WhitespaceErrors()...)
+		}
+		if opts.Whitespace == WhitespaceFix {
+			fp.FixWhitespace()
+		}
+		conflict, err := g.applyFilePatch(fp, files, opts)
+		if err != nil {
+			return nil, err
+		}
+		if conflict {
+			result.Conflicts = append(result.Conflicts, fp.Path())
+		}
+	}
+	if n := len(result.WhitespaceErrors); n > 0 && opts.Whitespace == WhitespaceError {
+		if n == 1 {
+			return result, errors.New("1 line adds whitespace errors")
+		}
+		return result, errors.Errorf("%d lines add whitespace errors", n)
+	}
+	var paths []string
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	if opts.Index {
+		for _, path := range paths {
+			err = g.checkWorkingTreeMatchesIndex(path)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if opts.Check {
+		return result, nil
+	}
+
+	var saved []*savedFile
+	for _, path := range paths {
+		f, err := g.saveFile(path)
+		if err == nil {
+			saved = append(saved, f)
+			err = g.writePatched(path, files[path], opts)
+		}
+		if err != nil {
+			// The file that failed may be half written too
+			for i := len(saved) - 1; i >= 0; i-- {
+				_ = g.restoreFile(saved[i])
+			}
+			return nil, errors.Wrapf(err, "couldn't apply patch to %s", path)
+		}
+	}
+	return result, nil
+}
+
+func (g *Got) writePatched(path string, f *patchedFile, opts ApplyOptions) error {
+	if opts.Cached || opts.Index {
+		err := g.writePatchedIndexEntry(path, f)
+		if err != nil {
+			return err
+		}
+	}
+	if !opts.Cached {
+		return g.writePatchedFile(path, f)
+	}
+	return nil
+}
+
+// A file of the index and the working tree as it was before patches were
+// written to it
+type savedFile struct {
+	path     string
+	entry    index.Entry
+	inIndex  bool
+	contents []byte
+	mode     os.FileMode
+	// Unset if the file doesn't exist in the working tree
+	exists bool
+}
+
+func (g *Got) saveFile(path string) (*savedFile, error) {
+	f := &savedFile{path: path}
+	f.entry, f.inIndex = g.indexEntry(path)
+	info, err := os.Lstat(filepath.Join(g.dir, path))
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err == nil {
+		f.mode, f.exists = info.Mode().Perm(), true
+		f.contents, err = ioutil.ReadFile(filepath.Join(g.dir, path))
+	}
+	return f, err
+}
+
+// Puts a saved file back into the index and the working tree
+func (g *Got) restoreFile(f *savedFile) error {
+	var err error
+	switch {
+	case f.inIndex:
+		err = g.Index.AddEntry(f.entry)
+	case g.Index.HasEntryFor(f.path):
+		err = g.Index.RemoveFile(f.path)
+	}
+	if err != nil {
+		return err
+	}
+	abs := filepath.Join(g.dir, f.path)
+	if !f.exists {
+		err = os.Remove(abs)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	err = ioutil.WriteFile(abs, f.contents, f.mode)
+	if err != nil {
+		return err
+	}
+	return os.Chmod(abs, f.mode)
+}
+
+// Applies the patch of a single file on top of the files patched so far
+func (g *Got) applyFilePatch(fp *patch.FilePatch, files map[string]*patchedFile, opts ApplyOptions) (bool, error) {
+	cached := opts.Cached || opts.Index
+	target := "working tree"
+	if cached {
+		target = "index"
+	}
+	old := &patchedFile{}
+	if !fp.IsNew() {
+		var err error
+		old, err = g.patchTarget(fp.OldPath, files, cached)
+		if err != nil {
+			return false, err
+		}
+		if !old.exists {
+			return false, errors.Errorf("%s: does not exist in %s", fp.OldPath, target)
+		}
+	}
+	if !fp.IsDelete() && fp.NewPath != fp.OldPath {
+		existing, err := g.patchTarget(fp.NewPath, files, cached)
+		if err != nil {
+			return false, err
+		}
+		if existing.exists {
+			return false, errors.Errorf("%s: already exists in %s", fp.NewPath, target)
+		}
+	}
+
+	if fp.Binary {
+		contents, err := patch.ApplyBinary(fp, old.contents)
+		if err != nil {
+			return false, errors.Wrapf(err, "patch failed: %s", fp.Path())
+		}
+		return false, g.recordPatchedFile(fp, old, contents, files)
+	}
+	contents, err := patch.Apply(old.contents, fp.Hunks)
+	conflict := false
+	if err != nil {
+		if !opts.ThreeWay || fp.OldID == "" {
+			return false, errors.Wrapf(err, "patch failed: %s", fp.Path())
+		}
+		contents, conflict, err = g.threeWayApply(fp, old.contents)
+		if err != nil {
+			return false, err
+		}
+		if conflict && cached {
+			return false, errors.Errorf("%s: can't record conflicts in the index", fp.Path())
+		}
+	}
+
+	return conflict, g.recordPatchedFile(fp, old, contents, files)
+}
+
+// Records the contents the patch leaves its file with, which was old before
+// the patch
+func (g *Got) recordPatchedFile(fp *patch.FilePatch, old *patchedFile, contents []byte, files map[string]*patchedFile) error {
+	if fp.IsDelete() {
+		if len(contents) > 0 {
+			return errors.Errorf("%s: removal patch leaves file contents", fp.OldPath)
+		}
+		files[fp.OldPath] = &patchedFile{}
+		return nil
+	}
+	mode := old.mode
+	if fp.NewMode != 0 {
+		mode = fp.NewMode
+	} else if mode == 0 {
+		mode = 0644
+	}
+	if fp.Rename {
+		files[fp.OldPath] = &patchedFile{}
+	}
+	files[fp.NewPath] = &patchedFile{contents: contents, mode: mode, exists: true}
+	return nil
+}
+
+// Merges the changes the patch makes to the blob it was made against into
+// contents
+func (g *Got) threeWayApply(fp *patch.FilePatch, contents []byte) ([]byte, bool, error) {
+	id, err := g.Objects.ExpandID(fp.OldID)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "patch failed: %s: no blob %s to merge with", fp.Path(), fp.OldID)
+	}
+	base, err := g.blobContents(id)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "patch failed: %s", fp.Path())
+	}
+	theirs, err := patch.Apply(base, fp.Hunks)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "patch failed: %s", fp.Path())
+	}
+	merged, conflict := merge.Merge(g.Differ, base, contents, theirs, "ours", "theirs")
+	return merged, conflict, nil
+}
+
+// Returns a file as the patches applied so far leave it, or as it is in the
+// index or working tree if no patch has touched it
+func (g *Got) patchTarget(path string, files map[string]*patchedFile, cached bool) (*patchedFile, error) {
+	if f, ok := files[path]; ok {
+		return f, nil
+	}
+	if cached {
+		e, ok := g.indexEntry(path)
+		if !ok {
+			return &patchedFile{}, nil
+		}
+		contents, err := g.blobContents(e.ID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "couldn't read %s from index", path)
+		}
+		return &patchedFile{contents: contents, mode: e.Perm, exists: true}, nil
+	}
+	abs := filepath.Join(g.dir, path)
+	info, err := os.Stat(abs)
+	if os.IsNotExist(err) {
+		return &patchedFile{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't read %s", path)
+	}
+	contents, err := g.readWorkingTreeFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't read %s", path)
+	}
+	return &patchedFile{contents: contents, mode: info.Mode(), exists: true}, nil
+}
+
+// Returns an error unless the file in the working tree is the same as in the
+// index, or missing from both
+func (g *Got) checkWorkingTreeMatchesIndex(path string) error {
+	e, ok := g.indexEntry(path)
+	if !ok {
+		_, err := os.Stat(filepath.Join(g.dir, path))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Errorf("%s: already exists in working tree", path)
+	}
+	fd, _, err := g.diffWorkingTreeFile(path, e.Perm, e.ID)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't compare %s with the index", path)
+	}
+	if fd != nil {
+		return errors.Errorf("%s: does not match index", path)
+	}
+	return nil
+}
+
+func (g *Got) writePatchedIndexEntry(path string, f *patchedFile) error {
+	if !f.exists {
+		if !g.Index.HasEntryFor(path) {
+			return nil
+		}
+		return g.Index.RemoveFile(path)
+	}
+	id, err := g.Objects.StoreBlobFrom(bytes.NewReader(f.contents))
+	if err != nil {
+		return err
+	}
+	return g.Index.AddEntry(index.NewEntry(f.mode.Perm(), objects.TypeBlob, id, path))
+}
+
+func (g *Got) writePatchedFile(path string, f *patchedFile) error {
+	abs := filepath.Join(g.dir, path)
+	if !f.exists {
+		err := os.Remove(abs)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	err := g.writeWorkingTreeFile(path, f.contents, f.mode.Perm())
+	if err != nil {
+		return err
+	}
+	return os.Chmod(abs, f.mode.Perm())
+}
+
+// Returns the index entry of the file
+func (g *Got) indexEntry(path string) (index.Entry, bool) {
+	for _, e := range g.Index.SortedEntries() {
+		if e.Name == path {
+			return e, true
+		}
+	}
+	return index.Entry{}, false
+}
@@ -0,0 +1,63 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const applyPatch = `diff --got a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -1 +1 @@
+-a
++A
+diff --got a/new.txt b/new.txt
+new file mode 100644
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1 @@
++new
+diff --got a/z.txt b/z.txt
+--- a/z.txt
++++ b/z.txt
+@@ -1 +1 @@
+-z
++Z
+`
+
+func TestApply(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	commitFiles(t, g, "first", map[string]string{"a.txt": "a\n", "z.txt": "z\n"})
+
+	_, err := g.Apply(strings.NewReader(applyPatch), ApplyOptions{Index: true})
+	assert.Nil(t, err)
+	assert.Equal(t, "A\n", readFile(t, g, "a.txt"))
+	assert.Equal(t, "new\n", readFile(t, g, "new.txt"))
+	assert.Equal(t, "Z\n", indexContents(t, g, "z.txt"))
+}
+
+func TestApplyRestoresFilesWhenWritingFails(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	commitFiles(t, g, "first", map[string]string{".gotattributes": "z.txt filter=fail\n", "a.txt": "a\n", "z.txt": "z\n"})
+	// Checking z.txt out fails once it's patched
+	assert.Nil(t, g.Config.Set("filter.fail.clean", "cat"))
+	assert.Nil(t, g.Config.Set("filter.fail.smudge", "false"))
+	assert.Nil(t, g.Config.Set("filter.fail.required", "true"))
+
+	_, err := g.Apply(strings.NewReader(applyPatch), ApplyOptions{Index: true})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "couldn't apply patch to z.txt")
+	assert.Equal(t, "a\n", readFile(t, g, "a.txt"))
+	assert.Equal(t, "a\n", indexContents(t, g, "a.txt"))
+	assert.Equal(t, "z\n", readFile(t, g, "z.txt"))
+	assert.Equal(t, "z\n", indexContents(t, g, "z.txt"))
+	assert.False(t, g.Index.HasEntryFor("new.txt"))
+	_, err = os.Stat(filepath.Join(g.dir, "new.txt"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,76 @@
+package filesystem
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"got/internal/attributes"
+)
+
+// The attributes file in the got directory, which takes precedence over the
+// .gotattributes files of the working tree
+const infoAttributesFile = "info/attributes"
+
+// Returns the attributes of the files of the repository, which are read
+// when first needed. Bare repositories only have the info attributes.
+func (g *Got) attributes() (*attributes.Attributes, error) {
+	if g.attrs != nil {
+		return g.attrs, nil
+	}
+	info, err := ioutil.ReadFile(filepath.Join(g.gotDir, infoAttributesFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "couldn't read attributes")
+	}
+	var read attributes.Reader
+	if !g.IsBare() {
+		read = func(dir string) ([]byte, error) {
+			bs, err := ioutil.ReadFile(filepath.Join(g.dir, filepath.FromSlash(dir), attributes.FileName))
+			if os.IsNotExist(err) {
+				return nil, nil
+			}
+			return bs, err
+		}
+	}
+	g.attrs = attributes.New(read, info)
+	return g.attrs, nil
+}
+
+// Returns the attributes that are set, unset or have a value for the
+// repository relative path
+func (g *Got) pathAttributes(path string) (map[string]attributes.Value, error) {
+	attrs, err := g.attributes()
+	if err != nil {
+		return nil, err
+	}
+	values, err := attrs.Lookup(filepath.ToSlash(path))
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't read attributes of %s", path)
+	}
+	return values, nil
+}
+
+// Returns the attributes of a path relative to the working directory. With
+// no names all the attributes that are set, unset or have a value are
+// returned, otherwise the named ones, which may be unspecified.
+func (g *Got) CheckAttr(path string, names []string) (map[string]attributes.Value, error) {
+	rel := path
+	if !g.IsBare() {
+		var err error
+		rel, err = g.repoRel(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "couldn't check attributes of %s", path)
+		}
+	}
+	values, err := g.pathAttributes(rel)
+	if err != nil || len(names) == 0 {
+		return values, err
+	}
+	named := make(map[string]attributes.Value)
+	for _, name := range names {
+		named[name] = values[name]
+	}
+	return named, nil
+}
@@ -46,9 +46,14 @@ func (g *Got) ListBranches() (Branches, error) {
 	if err != nil {
 		return Branches{}, errors.Wrapf(err, "couldn't list branches")
 	}
-	headRef, err := g.HeadAsRef()
-	if err != nil {
-		return Branches{}, errors.Wrapf(err, "couldn't list branches")
+	// No branch is current if HEAD is detached or empty, as it often is in
+	// bare repositories
+	var headRef refs.Ref
+	if headType, err := g.HeadType(); err == nil && headType == HeadTypeRef {
+		headRef, err = g.HeadAsRef()
+		if err != nil {
+			return Branches{}, errors.Wrapf(err, "couldn't list branches")
+		}
 	}
 	return Branches{branches, headRef}, nil
 }
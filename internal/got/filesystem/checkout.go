@@ -1,9 +1,6 @@
 package filesystem
 
 import (
-	"io/ioutil"
-	"path/filepath"
-
 	"github.com/pkg/errors"
 )
 
@@ -18,6 +15,10 @@ func (g *Got) Checkout(branchName string, create bool) error {
 		return errors.New("cannot checkout branch with uncommitted changes")
 	}
 
+	oldID := nullID
+	if id, err := g.idAtHead(); err == nil && id != nil {
+		oldID = string(*id)
+	}
 	if create {
 		id, err := g.idAtHead()
 		if err != nil {
@@ -53,11 +54,7 @@ func (g *Got) Checkout(branchName string, create bool) error {
 	// this gets an error midway through. Until then a manual restore should
 	// do the trick.
 	for _, te := range commitTree.Entries {
-		blob, err := g.Objects.GetBlob(te.ID)
-		if err != nil {
-			return errors.Wrapf(err, "couldn't checkout branch %s", branchName)
-		}
-		err = ioutil.WriteFile(filepath.Join(g.dir, te.Name), []byte(blob.Contents), te.Mode)
+		err = g.writeBlobToFile(te.ID, te.Name, te.Mode)
 		if err != nil {
 			return errors.Wrapf(err, "couldn't checkout branch %s", branchName)
 		}
@@ -71,5 +68,7 @@ func (g *Got) Checkout(branchName string, create bool) error {
 	if err != nil {
 		return errors.Wrapf(err, "couldn't checkout branch %s", branchName)
 	}
-	return nil
+	// The checkout is done whatever the hook returns, but its failure is
+	// still reported
+	return g.runHook(hookPostCheckout, nil, oldID, string(id), "1")
 }
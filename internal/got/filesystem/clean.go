@@ -0,0 +1,149 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type CleanOptions struct {
+	// Remove untracked directories as a whole too
+	Directories bool
+	// Remove ignored files as well as untracked ones
+	Ignored bool
+	// Only remove ignored files
+	OnlyIgnored bool
+	// Only remove files matching these pathspecs, relative to the working
+	// directory
+	Paths []string
+}
+
+// Returns the repository relative paths of the files that Clean would remove
+// with the given options. Directories end in a separator. Without
+// Directories untracked directories are left alone unless paths are given,
+// in which case the files inside them are returned. Nested repositories are
+// never removed, and neither are ignored files unless asked to, so
+// directories containing either are cleaned file by file.
+func (g *Got) CleanCandidates(opts CleanOptions) ([]string, error) {
+	paths, err := g.pathspec(opts.Paths)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't clean")
+	}
+	tracked := make(map[string]bool)
+	for _, e := range g.Index.SortedEntries() {
+		for p := e.Name; p != "."; p = filepath.Dir(p) {
+			tracked[p] = true
+		}
+	}
+
+	var candidates []string
+	err = filepath.Walk(g.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := g.repoRel(path)
+		if err != nil {
+			return err
+		}
+		if rel == "." || (tracked[rel] && !info.IsDir()) {
+			return nil
+		}
+		if rel == rootDir {
+			return filepath.SkipDir
+		}
+		ignored := g.isIgnored(rel)
+		if (ignored && !opts.Ignored && !opts.OnlyIgnored) || (!ignored && opts.OnlyIgnored && !info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() {
+			if paths.Matches(rel) {
+				candidates = append(candidates, rel)
+			}
+			return nil
+		}
+		if tracked[rel] {
+			return nil
+		}
+		if hasGotDir(path) {
+			return filepath.SkipDir
+		}
+		if opts.Directories && (ignored || !opts.OnlyIgnored) {
+			if paths.Matches(rel) {
+				whole, err := g.removableDir(path, !opts.Ignored && !opts.OnlyIgnored)
+				if err != nil || !whole {
+					// The files inside are cleaned one by one instead
+					return err
+				}
+				candidates = append(candidates, rel+string(filepath.Separator))
+				return filepath.SkipDir
+			}
+			// Files inside the directory may still match
+			if paths.Empty() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if paths.Empty() && !opts.OnlyIgnored {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't clean")
+	}
+	return candidates, nil
+}
+
+// Reports whether an untracked directory can be removed as a whole, which it
+// can't if there's a nested repository anywhere inside it or, with
+// keepIgnored, an ignored file
+func (g *Got) removableDir(dir string, keepIgnored bool) (bool, error) {
+	removable := true
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && info.Name() == rootDir {
+			removable = false
+			return errStopWalk
+		}
+		if !keepIgnored {
+			return nil
+		}
+		rel, err := g.repoRel(path)
+		if err != nil {
+			return err
+		}
+		if g.isIgnored(rel) {
+			removable = false
+			return errStopWalk
+		}
+		return nil
+	})
+	if err != nil && err != errStopWalk {
+		return false, err
+	}
+	return removable, nil
+}
+
+// Removes the files and directories returned by CleanCandidates
+func (g *Got) Clean(candidates []string) error {
+	for _, c := range candidates {
+		abs := filepath.Join(g.dir, c)
+		var err error
+		if strings.HasSuffix(c, string(filepath.Separator)) {
+			err = os.RemoveAll(abs)
+		} else {
+			err = os.Remove(abs)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "couldn't remove %s", c)
+		}
+	}
+	return nil
+}
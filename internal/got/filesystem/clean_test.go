@@ -0,0 +1,89 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCleanCandidatesFromSubdirectory(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	commitFiles(t, g, "first", map[string]string{".gitignore": "build/\n*.log\n", "sub/tracked.txt": "tracked\n"})
+	writeFiles(t, g, map[string]string{
+		"untracked.txt":   "",
+		"x.log":           "",
+		"build/out":       "",
+		"sub/untracked":   "",
+		"sub/y.log":       "",
+		"sub/build/out":   "",
+		"sub/new/file.go": "",
+	})
+	assert.Nil(t, os.Chdir(filepath.Join(g.dir, "sub")))
+	g, err := NewGot()
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name string
+		opts CleanOptions
+		want []string
+	}{
+		{"untracked", CleanOptions{Directories: true}, []string{"sub/new/", "sub/untracked", "untracked.txt"}},
+		{"with ignored", CleanOptions{Directories: true, Ignored: true}, []string{"build/", "sub/build/", "sub/new/", "sub/untracked", "sub/y.log", "untracked.txt", "x.log"}},
+		{"only ignored", CleanOptions{Directories: true, OnlyIgnored: true}, []string{"build/", "sub/build/", "sub/y.log", "x.log"}},
+		{"paths", CleanOptions{Ignored: true, Paths: []string{"."}}, []string{"sub/build/out", "sub/new/file.go", "sub/untracked", "sub/y.log"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates, err := g.CleanCandidates(tt.opts)
+			assert.Nil(t, err)
+			assert.ElementsMatch(t, tt.want, candidates)
+		})
+	}
+}
+
+func TestCleanKeepsNestedRepositoriesAndIgnoredFiles(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	commitFiles(t, g, "first", map[string]string{".gitignore": "*.o\n"})
+	writeFiles(t, g, map[string]string{
+		"build/out.o":          "",
+		"build/main.go":        "",
+		"build/sub/a.txt":      "",
+		"vendor/lib/.got/HEAD": "",
+		"vendor/lib/lib.go":    "",
+		"vendor/other/b.txt":   "",
+		"tmp/c.txt":            "",
+	})
+	// The ignore patterns are read when the repository is opened
+	g, err := NewGot()
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name string
+		opts CleanOptions
+		want []string
+	}{
+		{"untracked", CleanOptions{Directories: true}, []string{"build/main.go", "build/sub/", "tmp/", "vendor/other/"}},
+		{"with ignored", CleanOptions{Directories: true, Ignored: true}, []string{"build/", "tmp/", "vendor/other/"}},
+		{"only ignored", CleanOptions{Directories: true, OnlyIgnored: true}, []string{"build/out.o"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates, err := g.CleanCandidates(tt.opts)
+			assert.Nil(t, err)
+			assert.ElementsMatch(t, tt.want, candidates)
+		})
+	}
+
+	candidates, err := g.CleanCandidates(CleanOptions{Directories: true})
+	assert.Nil(t, err)
+	assert.Nil(t, g.Clean(candidates))
+	assert.FileExists(t, filepath.Join(g.dir, "build", "out.o"))
+	assert.FileExists(t, filepath.Join(g.dir, "vendor", "lib", ".got", "HEAD"))
+	assert.FileExists(t, filepath.Join(g.dir, "vendor", "lib", "lib.go"))
+	_, err = os.Stat(filepath.Join(g.dir, "tmp"))
+	assert.True(t, os.IsNotExist(err))
+}
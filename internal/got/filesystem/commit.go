@@ -2,79 +2,141 @@ package filesystem
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"got/internal/objects"
 )
 
-func (g *Got) Commit(message string) error {
+type CommitOptions struct {
+	// Skip the pre-commit and commit-msg hooks
+	NoVerify bool
+}
+
+// Commits the index on top of HEAD, running the commit hooks around it
+func (g *Got) Commit(message string, opts CommitOptions) error {
+	if !opts.NoVerify {
+		err := g.runHook(hookPreCommit, nil)
+		if err != nil {
+			return err
+		}
+		// The hook may have staged changes, e.g. by formatting files
+		g.Index, err = readIndex(g.gotDir, g.dir)
+		if err != nil {
+			return errors.Wrap(err, "couldn't perform commit")
+		}
+		message, err = g.runCommitMsgHook(message)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := g.commitAs(message, g.author(time.Now()))
+	if err != nil {
+		return err
+	}
+	// The commit is made whatever the hook returns
+	_ = g.runHook(hookPostCommit, nil)
+	return nil
+}
+
+// Commits the index on top of HEAD with the given author and moves the
+// branch HEAD is on to the new commit
+func (g *Got) commitAs(message, author string) (objects.ID, error) {
 	headType, err := g.HeadType()
 	if err != nil {
-		return errors.Wrap(err, "couldn't perform commit")
+		return "", errors.Wrap(err, "couldn't perform commit")
 	}
 	if headType == HeadTypeRef {
-		return g.commitAtRef(message)
+		return g.commitAtRef(message, author)
 	}
-	return g.firstCommit(message)
+	return g.firstCommit(message, author)
 }
 
-func (g *Got) commitAtRef(message string) error {
+func (g *Got) commitAtRef(message, author string) (objects.ID, error) {
 	ref, err := g.HeadAsRef()
 	if err != nil {
-		return errors.Wrap(err, "couldn't perform commit")
+		return "", errors.Wrap(err, "couldn't perform commit")
 	}
 	currentCommitID, err := g.Refs.IDFromRef(ref)
 	if err != nil {
-		return errors.Wrap(err, "couldn't perform commit")
+		return "", errors.Wrap(err, "couldn't perform commit")
 	}
 	treeID, err := g.WriteTree()
 	if err != nil {
-		return errors.Wrap(err, "couldn't perform commit")
+		return "", errors.Wrap(err, "couldn't perform commit")
+	}
+	// A merge with conflicts is concluded by committing its resolution
+	parents := []objects.ID{currentCommitID}
+	mergeHead, err := g.mergeHead()
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't perform commit")
+	}
+	if mergeHead != "" {
+		parents = append(parents, mergeHead)
 	}
 	// Update branch head if it exists
-	newCommitID, err := g.CommitTree(message, treeID, &currentCommitID)
+	newCommitID, err := g.commitParentsAs(message, treeID, parents, author)
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't perform commit")
+	}
 	err = g.Refs.UpdateRef(ref, newCommitID)
 	if err != nil {
-		return errors.Wrap(err, "couldn't perform commit")
+		return "", errors.Wrap(err, "couldn't perform commit")
 	}
-	return nil
+	if mergeHead != "" {
+		err = os.Remove(filepath.Join(g.gotDir, mergeHeadFile))
+		if err != nil {
+			return "", errors.Wrap(err, "couldn't perform commit")
+		}
+	}
+	return newCommitID, nil
 }
 
-func (g *Got) firstCommit(message string) error {
+func (g *Got) firstCommit(message, author string) (objects.ID, error) {
 	treeID, err := g.WriteTree()
 	if err != nil {
-		return errors.Wrap(err, "couldn't perform commit")
+		return "", errors.Wrap(err, "couldn't perform commit")
 	}
-	newCommitID, err := g.CommitTree(message, treeID, nil)
+	newCommitID, err := g.commitTreeAs(message, treeID, nil, author)
 	if err != nil {
-		return errors.Wrap(err, "couldn't perform commit")
+		return "", errors.Wrap(err, "couldn't perform commit")
 	}
-	ref, err := g.Refs.CreateBranchAt("master", newCommitID)
+	ref, err := g.Refs.CreateBranchAt(g.initialBranch(), newCommitID)
 	if err != nil {
-		return errors.Wrap(err, "couldn't perform commit")
+		return "", errors.Wrap(err, "couldn't perform commit")
 	}
 	err = g.updateHeadWithRef(ref)
 	if err != nil {
-		return errors.Wrap(err, "couldn't perform commit")
+		return "", errors.Wrap(err, "couldn't perform commit")
 	}
-	return nil
+	return newCommitID, nil
 }
 
 func (g *Got) CommitTree(msg string, treeID objects.ID, parentID *objects.ID) (objects.ID, error) {
-	commit := objects.NewCommit(treeID, parentID, "John Doe <john@doe.com> 0123456789 +0000", msg)
+	return g.commitTreeAs(msg, treeID, parentID, g.author(time.Now()))
+}
+
+func (g *Got) commitTreeAs(msg string, treeID objects.ID, parentID *objects.ID, author string) (objects.ID, error) {
+	commit := objects.NewCommit(treeID, parentID, author, msg)
 	fmt.Printf("Committing %s", treeID)
 	if parentID != nil {
 		fmt.Printf(" with parent %s", *parentID)
 	}
 	fmt.Println("...")
-	var buf string
-	buf += fmt.Sprintf("tree %s\n", treeID)
-	if parentID != nil {
-		buf += fmt.Sprintf("parent %s\n", *parentID)
-	}
-	buf += fmt.Sprintln("author John Doe <john@doe.com> 0123456789 +0000")
-	buf += fmt.Sprintln("committer John Doe <john@doe.com> 0123456789 +0000")
-	buf += fmt.Sprintf("\n%s", msg)
 	return commit.ID(), g.Objects.Store(commit)
 }
+
+// Returns the author line of a commit made at the given time by the user
+// configured with user.name and user.email
+func (g *Got) author(t time.Time) string {
+	name := g.Config.GetString("user.name", "John Doe")
+	email := g.Config.GetString("user.email", "john@doe.com")
+	return formatAuthor(name, email, t)
+}
+
+func formatAuthor(name, email string, t time.Time) string {
+	return fmt.Sprintf("%s <%s> %d %s", name, email, t.Unix(), t.Format("-0700"))
+}
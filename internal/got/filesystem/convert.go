@@ -0,0 +1,252 @@
+package filesystem
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"got/internal/attributes"
+	"got/internal/diff"
+	"got/internal/objects"
+)
+
+// How the contents of a file are converted between the working tree and its
+// blob. Text files, as set by the 'text' and 'eol' attributes or by
+// core.autocrlf, are stored with LF line endings and checked out with CRLF
+// ones if 'eol=crlf', core.autocrlf=true or core.eol=crlf says so. A filter
+// driver set by 'filter=<driver>' runs before the line endings are
+// normalized when storing and after when checking out.
+type conversion struct {
+	text bool
+	// Set for 'text=auto' and core.autocrlf, which only convert files that
+	// don't look binary
+	auto bool
+	// Set for auto text files whose blob in the index has CRLF line
+	// endings, which are kept as they are so that they don't show as
+	// changed
+	keepCRLF bool
+	crlf     bool
+	filter   *filterDriver
+}
+
+// A command pair from the filter.<driver> config that converts the contents
+// of files with the 'filter=<driver>' attribute. The commands read the
+// contents from stdin and write the converted ones to stdout, with %f
+// standing for the path of the file.
+type filterDriver struct {
+	name   string
+	clean  string
+	smudge string
+	// Set if the filter has to succeed, otherwise the contents of files it
+	// fails on are left as they are
+	required bool
+}
+
+func (g *Got) conversionFor(path string) (conversion, error) {
+	attrs, err := g.pathAttributes(path)
+	if err != nil {
+		return conversion{}, err
+	}
+	var c conversion
+	if name := attrs["filter"]; name.IsValue() {
+		f := &filterDriver{
+			name:     string(name),
+			clean:    g.Config.GetString("filter."+string(name)+".clean", ""),
+			smudge:   g.Config.GetString("filter."+string(name)+".smudge", ""),
+			required: g.Config.GetBool("filter."+string(name)+".required", false),
+		}
+		if f.clean != "" || f.smudge != "" || f.required {
+			c.filter = f
+		}
+	}
+
+	autocrlf := strings.ToLower(g.Config.GetString("core.autocrlf", "false"))
+	if autocrlf != "input" && g.Config.GetBool("core.autocrlf", false) {
+		autocrlf = "true"
+	}
+	eol := attrs["eol"]
+	switch attrs["text"] {
+	case attributes.Set:
+		c.text = true
+	case attributes.Unset:
+		return c, nil
+	case "auto":
+		c.text, c.auto = true, true
+	default:
+		// Giving the line endings makes a file text, and core.autocrlf
+		// makes files text that don't look binary
+		c.text = eol.IsValue()
+		if !c.text && (autocrlf == "true" || autocrlf == "input") {
+			c.text, c.auto = true, true
+		}
+	}
+	switch {
+	case eol.IsValue():
+		c.crlf = eol == "crlf"
+	case autocrlf == "true":
+		c.crlf = true
+	case autocrlf == "input":
+	default:
+		c.crlf = g.Config.GetString("core.eol", "native") == "crlf"
+	}
+	if c.auto {
+		c.keepCRLF, err = g.indexHasCRLF(path)
+	}
+	return c, err
+}
+
+// Reports whether the blob of the path in the index has CRLF line endings.
+// Each blob is only read once.
+func (g *Got) indexHasCRLF(path string) (bool, error) {
+	if !g.Index.HasEntryFor(path) {
+		return false, nil
+	}
+	id, err := g.Index.GetEntrySum(path)
+	if err != nil {
+		return false, err
+	}
+	if crlf, ok := g.crlfBlobs[id]; ok {
+		return crlf, nil
+	}
+	bs, err := g.blobContents(id)
+	if err != nil {
+		return false, err
+	}
+	if g.crlfBlobs == nil {
+		g.crlfBlobs = make(map[objects.ID]bool)
+	}
+	g.crlfBlobs[id] = bytes.Contains(bs, []byte("\r\n"))
+	return g.crlfBlobs[id], nil
+}
+
+// Reports whether the contents are stored as they are in the working tree
+func (c conversion) none() bool {
+	return !c.text && c.filter == nil
+}
+
+// Converts the contents of a working tree file at the path to those of its
+// blob
+func (g *Got) clean(c conversion, path string, bs []byte) ([]byte, error) {
+	bs, err := g.runFilter(c.filter, c.filter.cleanCommand(), path, bs)
+	if err != nil {
+		return nil, err
+	}
+	if !c.text || c.keepCRLF || (c.auto && diff.IsBinary(bs)) {
+		return bs, nil
+	}
+	return bytes.ReplaceAll(bs, []byte("\r\n"), []byte("\n")), nil
+}
+
+// Converts the contents of a blob to those of its working tree file at the
+// path. Lines that already end in CRLF are kept.
+func (g *Got) smudge(c conversion, path string, bs []byte) ([]byte, error) {
+	if c.text && c.crlf && !(c.auto && (diff.IsBinary(bs) || bytes.IndexByte(bs, '\r') >= 0)) {
+		buf := bytes.NewBuffer(make([]byte, 0, len(bs)+len(bs)/32))
+		for i, b := range bs {
+			if b == '\n' && (i == 0 || bs[i-1] != '\r') {
+				buf.WriteByte('\r')
+			}
+			buf.WriteByte(b)
+		}
+		bs = buf.Bytes()
+	}
+	return g.runFilter(c.filter, c.filter.smudgeCommand(), path, bs)
+}
+
+func (f *filterDriver) cleanCommand() string {
+	if f == nil {
+		return ""
+	}
+	return f.clean
+}
+
+func (f *filterDriver) smudgeCommand() string {
+	if f == nil {
+		return ""
+	}
+	return f.smudge
+}
+
+// Runs a command of the filter with the contents on stdin and returns its
+// output. Contents are passed through if there's no command, or if it fails
+// and the filter isn't required.
+func (g *Got) runFilter(f *filterDriver, command, path string, contents []byte) ([]byte, error) {
+	if command == "" {
+		if f != nil && f.required {
+			return nil, errors.Errorf("%s: filter '%s' is required but has no command", path, f.name)
+		}
+		return contents, nil
+	}
+	cmd := g.shellCommand(strings.ReplaceAll(command, "%f", shellQuote(path)))
+	cmd.Stdin = bytes.NewReader(contents)
+	out, err := cmd.Output()
+	if err != nil {
+		if f.required {
+			return nil, errors.Wrapf(err, "%s: filter '%s' failed", path, f.name)
+		}
+		return contents, nil
+	}
+	return out, nil
+}
+
+// Returns the contents of a file of the working tree as they're stored
+func (g *Got) readWorkingTreeFile(path string) ([]byte, error) {
+	c, err := g.conversionFor(path)
+	if err != nil {
+		return nil, err
+	}
+	bs, err := ioutil.ReadFile(filepath.Join(g.dir, path))
+	if err != nil || c.none() {
+		return bs, err
+	}
+	return g.clean(c, path, bs)
+}
+
+// Hashes a file of the working tree as it's stored, storing it as a blob if
+// store is set
+func (g *Got) hashWorkingTreeFile(path string, store bool) (objects.ID, error) {
+	c, err := g.conversionFor(path)
+	if err != nil {
+		return "", err
+	}
+	if c.none() {
+		return g.HashFile(filepath.Join(g.dir, path), store)
+	}
+	bs, err := ioutil.ReadFile(filepath.Join(g.dir, path))
+	if err != nil {
+		return "", errors.Wrapf(err, "couldn't hash file %s", path)
+	}
+	bs, err = g.clean(c, path, bs)
+	if err != nil {
+		return "", err
+	}
+	if !store {
+		return objects.NewBlob(bs).ID(), nil
+	}
+	return g.Objects.StoreBlobFrom(bytes.NewReader(bs))
+}
+
+// Writes stored contents to a file of the working tree, converting them as
+// its attributes and the config say
+func (g *Got) writeWorkingTreeFile(path string, contents []byte, perm os.FileMode) error {
+	c, err := g.conversionFor(path)
+	if err != nil {
+		return err
+	}
+	if !c.none() {
+		contents, err = g.smudge(c, path, contents)
+		if err != nil {
+			return err
+		}
+	}
+	abs := filepath.Join(g.dir, path)
+	err = os.MkdirAll(filepath.Dir(abs), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(abs, contents, perm)
+}
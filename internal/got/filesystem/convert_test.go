@@ -0,0 +1,115 @@
+package filesystem
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"got/internal/objects"
+)
+
+func TestLineEndingConversion(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     map[string]string
+		attributes string
+		path       string
+		// The contents of the working tree file, as they're stored and as
+		// they're checked out again
+		worktree, stored, checkedOut string
+	}{
+		{"none", nil, "", "a.txt", "a\r\nb\n", "a\r\nb\n", "a\r\nb\n"},
+		{"autocrlf", map[string]string{"core.autocrlf": "true"}, "", "a.txt", "a\r\nb\n", "a\nb\n", "a\r\nb\r\n"},
+		{"autocrlf input", map[string]string{"core.autocrlf": "input"}, "", "a.txt", "a\r\nb\n", "a\nb\n", "a\nb\n"},
+		{"autocrlf binary", map[string]string{"core.autocrlf": "true"}, "", "a.bin", "a\x00\r\n", "a\x00\r\n", "a\x00\r\n"},
+		{"autocrlf -text", map[string]string{"core.autocrlf": "true"}, "*.txt -text\n", "a.txt", "a\r\n", "a\r\n", "a\r\n"},
+		{"text eol lf", map[string]string{"core.autocrlf": "true"}, "*.txt text eol=lf\n", "a.txt", "a\r\nb\n", "a\nb\n", "a\nb\n"},
+		{"text eol crlf", nil, "*.txt eol=crlf\n", "a.txt", "a\nb\r\n", "a\nb\n", "a\r\nb\r\n"},
+		{"core.eol", map[string]string{"core.eol": "crlf"}, "*.txt text\n", "a.txt", "a\r\nb\n", "a\nb\n", "a\r\nb\r\n"},
+		{"core.eol without text", map[string]string{"core.eol": "crlf"}, "", "a.txt", "a\nb\n", "a\nb\n", "a\nb\n"},
+		{"text auto", map[string]string{"core.eol": "crlf"}, "* text=auto\n", "a.txt", "a\r\n", "a\n", "a\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, cleanup := newTestRepo(t)
+			defer cleanup()
+			for key, value := range tt.config {
+				assert.Nil(t, g.Config.Set(key, value))
+			}
+			writeFiles(t, g, map[string]string{".gotattributes": tt.attributes, tt.path: tt.worktree})
+
+			stored, err := g.readWorkingTreeFile(tt.path)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.stored, string(stored))
+			id, err := g.hashWorkingTreeFile(tt.path, true)
+			assert.Nil(t, err)
+			contents, err := g.blobContents(id)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.stored, string(contents))
+
+			assert.Nil(t, g.writeBlobToFile(id, tt.path, 0644))
+			assert.Equal(t, tt.checkedOut, readFile(t, g, tt.path))
+		})
+	}
+}
+
+func TestAutoTextKeepsCRLFOfIndex(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	commitFiles(t, g, "crlf", map[string]string{"a.txt": "a\r\n"})
+	assert.Nil(t, g.Config.Set("core.autocrlf", "true"))
+
+	writeFiles(t, g, map[string]string{"a.txt": "a\r\nb\r\n"})
+	stored, err := g.readWorkingTreeFile("a.txt")
+	assert.Nil(t, err)
+	assert.Equal(t, "a\r\nb\r\n", string(stored))
+	id, err := g.Index.GetEntrySum("a.txt")
+	assert.Nil(t, err)
+	assert.Equal(t, map[objects.ID]bool{id: true}, g.crlfBlobs)
+
+	// Files that are new get converted
+	writeFiles(t, g, map[string]string{"b.txt": "b\r\n"})
+	stored, err = g.readWorkingTreeFile("b.txt")
+	assert.Nil(t, err)
+	assert.Equal(t, "b\n", string(stored))
+}
+
+func TestFilters(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]string
+		// The contents as they're stored and checked out, empty for an
+		// error
+		stored, checkedOut string
+	}{
+		{"clean and smudge", map[string]string{"filter.case.clean": "tr a-z A-Z", "filter.case.smudge": "tr A-Z a-z"}, "ABC\n", "abc\n"},
+		{"path", map[string]string{"filter.case.clean": "cat && echo %f"}, "abc\na.txt\n", "abc\na.txt\n"},
+		{"failing", map[string]string{"filter.case.clean": "false", "filter.case.smudge": "false"}, "abc\n", "abc\n"},
+		{"required failing", map[string]string{"filter.case.clean": "false", "filter.case.required": "true"}, "", ""},
+		{"required without command", map[string]string{"filter.case.required": "true"}, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, cleanup := newTestRepo(t)
+			defer cleanup()
+			for key, value := range tt.config {
+				assert.Nil(t, g.Config.Set(key, value))
+			}
+			writeFiles(t, g, map[string]string{".gotattributes": "*.txt filter=case\n", "a.txt": "abc\n"})
+
+			id, err := g.hashWorkingTreeFile("a.txt", true)
+			if tt.stored == "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "a.txt: filter 'case'")
+				return
+			}
+			assert.Nil(t, err)
+			contents, err := g.blobContents(id)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.stored, string(contents))
+
+			assert.Nil(t, g.writeBlobToFile(id, "a.txt", 0644))
+			assert.Equal(t, tt.checkedOut, readFile(t, g, "a.txt"))
+		})
+	}
+}
@@ -3,167 +3,497 @@ package filesystem
 import (
 	"bytes"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 
-	"github.com/gookit/color"
 	"github.com/pkg/errors"
 
 	"got/internal/diff"
 	"got/internal/objects"
-	"got/internal/pkg/filesystem"
+	"got/internal/pathspec"
+	"got/internal/pretty"
 )
 
-func (g *Got) DiffIndexPath(paths ...string) (string, error) {
-	buf := bytes.NewBuffer(nil)
-	for _, p := range paths {
-		err := g.forAllFilesInRepo(p, func(path string, info os.FileInfo, err error) error {
-			hs, err := g.diffIndexPath(path)
-			if err != nil {
-				return err
-			}
+// How the differences between two snapshots are shown
+type DiffFormat string
 
-			if hs != nil {
-				fmt.Fprintf(buf, color.OpBold.Sprintf("--- a/%s\n", path))
-				fmt.Fprintf(buf, color.OpBold.Sprintf("+++ b/%s\n", path))
-				fmt.Fprint(buf, hs)
-			}
+const (
+	DiffFormatPatch      DiffFormat = "patch"
+	DiffFormatNameOnly   DiffFormat = "name-only"
+	DiffFormatNameStatus DiffFormat = "name-status"
+	DiffFormatStat       DiffFormat = "stat"
+	DiffFormatNumstat    DiffFormat = "numstat"
+	DiffFormatRaw        DiffFormat = "raw"
+)
 
-			return nil
-		})
+type DiffOptions struct {
+	// The commits to compare. With none the working tree is compared with
+	// the index. With one, or a range such as 'a..b' or 'a...b', the working
+	// tree is compared with the commit.
+	Revisions []string
+	// Compare the index instead of the working tree, with HEAD if no
+	// revision is given
+	Cached bool
+	// Only compare the paths matching these pathspecs, relative to the
+	// working directory
+	Paths  []string
+	Format DiffFormat
+}
+
+// The files on one side of a diff
+type snapshot struct {
+	entries map[string]objects.TreeEntry
+	// Set if the contents of the files have to be read from the working tree
+	// as they aren't necessarily stored as blobs
+	workingTree bool
+}
+
+// Shows the differences between two snapshots of the repository as selected
+// by the options
+func (g *Got) Diff(opts DiffOptions) (string, error) {
+	src, dst, err := g.diffSnapshots(opts.Revisions, opts.Cached)
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't diff")
+	}
+	paths, err := g.pathspec(opts.Paths)
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't diff")
+	}
+	buf := bytes.NewBuffer(nil)
+	err = g.writeDiff(buf, src, dst, paths, opts.Format)
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't diff")
+	}
+	return buf.String(), nil
+}
+
+// Compares two commits, or a commit with its first parent if only one is
+// given. The output is raw by default.
+func (g *Got) DiffTree(revs []string, paths []string, format DiffFormat) (string, error) {
+	if format == "" {
+		format = DiffFormatRaw
+	}
+	buf := bytes.NewBuffer(nil)
+	var src, dst *snapshot
+	var err error
+	switch len(revs) {
+	case 1:
+		var id objects.ID
+		id, err = g.ResolveRevision(revs[0])
 		if err != nil {
-			return "", errors.Wrapf(err, "couldn't diff index and HEAD %s", p)
+			return "", errors.Wrap(err, "couldn't diff tree")
 		}
+		fmt.Fprintln(buf, id)
+		src, dst, err = g.parentSnapshots(id)
+	case 2:
+		src, dst, err = g.diffSnapshots(revs, false)
+	default:
+		return "", errors.New("diff-tree needs one or two commits")
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't diff tree")
+	}
+	ps, err := g.pathspec(paths)
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't diff tree")
+	}
+	err = g.writeDiff(buf, src, dst, ps, format)
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't diff tree")
 	}
 	return buf.String(), nil
 }
 
-func (g *Got) diffIndexPath(path string) (diff.Hunks, error) {
-	idx, err := g.getContentsFromIndex(path)
+// Shows a commit and the changes it made compared to its first parent
+func (g *Got) Show(rev string, paths []string, format DiffFormat) (string, error) {
+	id, err := g.ResolveRevision(rev)
 	if err != nil {
-		return nil, errors.Wrapf(err, "couldn't diff index with HEAD %s", path)
+		return "", errors.Wrapf(err, "couldn't show %s", rev)
 	}
-	headType, err := g.HeadType()
+	c, err := g.Objects.GetCommit(id)
 	if err != nil {
-		return nil, errors.Wrapf(err, "couldn't diff index with HEAD %s", path)
+		return "", errors.Wrapf(err, "couldn't show %s", rev)
 	}
-	if headType == HeadTypeEmpty {
-		return nil, errors.New("no head to diff against")
+	decorations, err := g.decorations()
+	if err != nil {
+		return "", errors.Wrapf(err, "couldn't show %s", rev)
 	}
-	ref, err := g.HeadAsRef()
+	src, dst, err := g.parentSnapshots(id)
 	if err != nil {
-		return nil, errors.Wrapf(err, "couldn't diff index with HEAD %s", path)
+		return "", errors.Wrapf(err, "couldn't show %s", rev)
 	}
-	headID, err := g.Refs.IDFromRef(ref)
+	ps, err := g.pathspec(paths)
 	if err != nil {
-		return nil, errors.Wrapf(err, "couldn't diff index with HEAD %s", path)
+		return "", errors.Wrapf(err, "couldn't show %s", rev)
 	}
-	hd, err := g.getContentsOfPathFromCommit(path, headID)
+	buf := bytes.NewBuffer(nil)
+	header := pretty.Format(pretty.Medium, pretty.Entry{ID: id, Commit: c, Decorations: decorations[id]})
+	fmt.Fprint(buf, strings.TrimRight(header, "\n")+"\n\n")
+	err = g.writeDiff(buf, src, dst, ps, format)
 	if err != nil {
-		return nil, errors.Wrapf(err, "couldn't diff index with HEAD %s", path)
+		return "", errors.Wrapf(err, "couldn't show %s", rev)
 	}
-	return g.Differ.DiffBytes(hd, idx).Strip(), nil
+	return buf.String(), nil
+}
+
+func (g *Got) DiffIndexPath(paths ...string) (string, error) {
+	return g.Diff(DiffOptions{Cached: true, Paths: paths, Format: DiffFormatPatch})
 }
 
 func (g *Got) DiffPathSpec(pathspecs ...string) (string, error) {
-	var matches []string
-	for _, ps := range pathspecs {
-		ms, err := filepath.Glob(ps)
-		if err != nil {
-			return "", errors.Wrapf(err, "couldn't diff path %s", ps)
-		}
-		matches = append(matches, ms...)
-	}
-	return g.DiffPath(matches...)
+	return g.DiffPath(pathspecs...)
 }
 
 func (g *Got) DiffPath(paths ...string) (string, error) {
-	buf := bytes.NewBuffer(nil)
-	for _, p := range paths {
-		err := g.forAllFilesInRepo(p, func(path string, info os.FileInfo, err error) error {
-			hs, err := g.diffPath(path)
-			if err != nil {
-				return err
-			}
-			if hs != nil {
-				fmt.Fprintf(buf, color.OpBold.Sprintf("--- a/%s\n", path))
-				fmt.Fprintf(buf, color.OpBold.Sprintf("+++ b/%s\n", path))
-				fmt.Fprint(buf, hs)
-			}
-			return nil
-		})
+	return g.Diff(DiffOptions{Paths: paths, Format: DiffFormatPatch})
+}
+
+// Returns the two sides of a diff of the given revisions, see DiffOptions
+func (g *Got) diffSnapshots(revs []string, cached bool) (*snapshot, *snapshot, error) {
+	var err error
+	if len(revs) == 1 && strings.Contains(revs[0], "..") {
+		revs, err = g.splitRange(revs[0])
 		if err != nil {
-			return "", errors.Wrapf(err, "couldn't diff path %s", p)
+			return nil, nil, err
 		}
 	}
-	return buf.String(), nil
+	switch len(revs) {
+	case 0:
+		if cached {
+			head, err := g.headSnapshot()
+			return head, g.indexSnapshot(), err
+		}
+		wt, err := g.workingTreeSnapshot()
+		return g.indexSnapshot(), wt, err
+	case 1:
+		src, err := g.revisionSnapshot(revs[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		if cached {
+			return src, g.indexSnapshot(), nil
+		}
+		wt, err := g.workingTreeSnapshot()
+		return src, wt, err
+	case 2:
+		src, err := g.revisionSnapshot(revs[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		dst, err := g.revisionSnapshot(revs[1])
+		return src, dst, err
+	}
+	return nil, nil, errors.New("too many revisions to diff")
 }
 
-func (g *Got) diffPath(path string) (diff.Hunks, error) {
-	abs := filepath.Join(g.dir, path)
-	wt, err := g.getContentsFromWorkingTree(abs)
+// Splits 'a..b' into a and b, and 'a...b' into the merge base of a and b
+// and b. A missing side stands for HEAD.
+func (g *Got) splitRange(r string) ([]string, error) {
+	symmetric := strings.Contains(r, "...")
+	sep := ".."
+	if symmetric {
+		sep = "..."
+	}
+	parts := strings.SplitN(r, sep, 2)
+	for i := range parts {
+		if parts[i] == "" {
+			parts[i] = "HEAD"
+		}
+	}
+	if !symmetric {
+		return parts, nil
+	}
+	bases, err := g.MergeBases(parts[0], parts[1])
 	if err != nil {
-		return nil, errors.Wrapf(err, "couldn't diff path %s", abs)
+		return nil, err
+	}
+	if len(bases) == 0 {
+		return nil, errors.Errorf("%s and %s have no merge base", parts[0], parts[1])
 	}
+	return []string{string(bases[0]), parts[1]}, nil
+}
 
-	idx, err := g.getContentsFromIndex(path)
+// Returns the snapshots of the first parent of a commit, empty for a root
+// commit, and of the commit itself
+func (g *Got) parentSnapshots(id objects.ID) (*snapshot, *snapshot, error) {
+	c, err := g.Objects.GetCommit(id)
 	if err != nil {
-		return nil, errors.Wrapf(err, "couldn't diff path %s", path)
+		return nil, nil, err
 	}
+	src := &snapshot{entries: treeEntryMap(nil)}
+	if c.ParentID != nil {
+		src, err = g.commitSnapshot(*c.ParentID)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	dst, err := g.commitSnapshot(id)
+	return src, dst, err
+}
 
-	return g.Differ.DiffBytes(idx, wt).Strip(), nil
+func (g *Got) revisionSnapshot(rev string) (*snapshot, error) {
+	id, err := g.ResolveRevision(rev)
+	if err != nil {
+		return nil, err
+	}
+	return g.commitSnapshot(id)
 }
 
-func (g *Got) getContentsOfPathFromCommit(path string, commitID objects.ID) ([]byte, error) {
-	commit, err := g.Objects.GetCommit(commitID)
+func (g *Got) commitSnapshot(id objects.ID) (*snapshot, error) {
+	tree, err := g.commitTree(id)
 	if err != nil {
-		return nil, errors.Wrapf(err, "couldn't get contents for path %s in commit %s", path, commitID)
+		return nil, err
 	}
-	tree, err := g.Objects.GetTree(commit.TreeID)
+	return &snapshot{entries: treeEntryMap(tree)}, nil
+}
+
+// Returns the snapshot of HEAD, which is empty before the first commit
+func (g *Got) headSnapshot() (*snapshot, error) {
+	tree, err := g.headTree()
 	if err != nil {
-		return nil, errors.Wrapf(err, "couldn't get contents for path %s in commit %s", path, commitID)
+		return nil, err
 	}
+	return &snapshot{entries: treeEntryMap(tree)}, nil
+}
 
-	for _, te := range tree.Entries {
-		if te.Name == path {
-			blob, err := g.Objects.GetBlob(te.ID)
-			if err != nil {
-				return nil, errors.Wrapf(err, "couldn't get contents for path %s in commit %s", path, commitID)
-			}
-			return []byte(blob.Content()), nil
+func (g *Got) indexSnapshot() *snapshot {
+	entries := make(map[string]objects.TreeEntry)
+	for _, e := range g.Index.SortedEntries() {
+		entries[e.Name] = objects.TreeEntry{Mode: e.Perm, Type: e.EntryType, Name: e.Name, ID: e.ID}
+	}
+	return &snapshot{entries: entries}
+}
+
+// Returns the snapshot of the files in the working tree that are tracked in
+// the index
+func (g *Got) workingTreeSnapshot() (*snapshot, error) {
+	entries := make(map[string]objects.TreeEntry)
+	for _, e := range g.Index.SortedEntries() {
+		abs := filepath.Join(g.dir, e.Name)
+		info, err := os.Stat(abs)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		id, err := g.hashWorkingTreeFile(e.Name, false)
+		if err != nil {
+			return nil, err
 		}
+		entries[e.Name] = objects.TreeEntry{Mode: info.Mode(), Type: objects.TypeBlob, Name: e.Name, ID: id}
 	}
-	return nil, nil
+	return &snapshot{entries: entries, workingTree: true}, nil
 }
 
-func (g *Got) getContentsFromWorkingTree(path string) ([]byte, error) {
-	if !filesystem.FileExists(path) {
+// Returns the contents of a file of the snapshot, nil if there's no such
+// file
+func (g *Got) snapshotContents(s *snapshot, path string) ([]byte, error) {
+	e, ok := s.entries[path]
+	if !ok {
 		return nil, nil
 	}
-
-	bs, err := ioutil.ReadFile(path)
-	if err != nil {
-		return nil, errors.Wrapf(err, "couldn't diff path %s", path)
+	if s.workingTree {
+		return g.readWorkingTreeFile(path)
 	}
+	return g.blobContents(e.ID)
+}
 
-	return bs, nil
+// Returns the differences between the snapshots, limited to the paths that
+// match the pathspec, with renames and copies detected as configured
+func (g *Got) diffSnapshotFiles(src, dst *snapshot, paths *pathspec.Pathspec) ([]*diff.FileDiff, error) {
+	var diffs []*diff.FileDiff
+	for _, fd := range diffEntries(src.entries, dst.entries) {
+		if paths.Matches(fd.Path()) {
+			diffs = append(diffs, fd)
+		}
+	}
+	return g.detectRenames(diffs, src, dst, g.Renames)
 }
 
-func (g *Got) getContentsFromIndex(path string) ([]byte, error) {
-	if !g.Index.HasEntryFor(path) {
-		return nil, nil
+// Pairs up the created and deleted files of the diffs between the snapshots
+// as renames, and with copies as copies of any file of src that dst keeps
+func (g *Got) detectRenames(diffs []*diff.FileDiff, src, dst *snapshot, opts diff.RenameOptions) ([]*diff.FileDiff, error) {
+	var sources []*diff.FileDiff
+	if opts.Copies {
+		for name, se := range src.entries {
+			if _, ok := dst.entries[name]; ok {
+				sources = append(sources, diff.NewUnmodifiedFileDiff(se.Mode, se.ID, name))
+			}
+		}
+		sort.Slice(sources, func(i, j int) bool {
+			return sources[i].SrcPath < sources[j].SrcPath
+		})
 	}
+	return diff.DetectRenames(diffs, sources, func(path string, fromSrc bool) ([]byte, error) {
+		if fromSrc {
+			return g.snapshotContents(src, path)
+		}
+		return g.snapshotContents(dst, path)
+	}, opts)
+}
 
-	hash, err := g.Index.GetEntrySum(path)
+// Writes the differences between the snapshots, limited to the given
+// repository relative paths, in the given format
+func (g *Got) writeDiff(w io.Writer, src, dst *snapshot, paths *pathspec.Pathspec, format DiffFormat) error {
+	diffs, err := g.diffSnapshotFiles(src, dst, paths)
 	if err != nil {
-		return nil, errors.Wrapf(err, "couldn't diff path %s", path)
+		return err
+	}
+	var stats diff.Stats
+	for _, fd := range diffs {
+		switch format {
+		case DiffFormatNameOnly:
+			fmt.Fprintln(w, fd.Path())
+			continue
+		case DiffFormatNameStatus:
+			if fd.EditType == diff.FileEditTypeRename || fd.EditType == diff.FileEditTypeCopy {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", fd.StatusLetter(), fd.SrcPath, fd.DstPath)
+			} else {
+				fmt.Fprintf(w, "%s\t%s\n", fd.StatusLetter(), fd.Path())
+			}
+			continue
+		case DiffFormatRaw:
+			fmt.Fprintln(w, fd.Raw())
+			continue
+		}
+		a, err := g.snapshotContents(src, fd.SrcPath)
+		if err != nil {
+			return err
+		}
+		b, err := g.snapshotContents(dst, fd.Path())
+		if err != nil {
+			return err
+		}
+		cd, err := g.diffContents(fd, a, b)
+		if err != nil {
+			return err
+		}
+		switch format {
+		case DiffFormatStat:
+			stats = append(stats, cd.stat())
+		case DiffFormatNumstat:
+			s := cd.stat()
+			if s.Binary {
+				fmt.Fprintf(w, "-\t-\t%s\n", s.Path)
+			} else {
+				fmt.Fprintf(w, "%d\t%d\t%s\n", s.Insertions, s.Deletions, s.Path)
+			}
+		default:
+			cd.writePatch(w)
+		}
 	}
+	if format == DiffFormatStat && len(stats) > 0 {
+		fmt.Fprint(w, stats)
+	}
+	return nil
+}
+
+// The differences between the two sides of a file as its diff driver shows
+// them
+type contentDiff struct {
+	fd *diff.FileDiff
+	// Set for binary files, whose lines aren't diffed. Their patches only
+	// say that they differ unless binaryPatch is set.
+	binary      bool
+	binaryPatch bool
+	src, dst    []byte
+	lines       diff.BytesDiff
+	hunks       diff.Hunks
+	// The mode of word diffs and the differ and expression they're diffed
+	// with, if the hunks are shown as word diffs
+	wordDiff  diff.WordDiffMode
+	differ    diff.Differ
+	wordRegex *regexp.Regexp
+}
 
-	blob, err := g.Objects.GetBlob(hash)
+// Diffs the contents of the two sides of a file, which are nil where the
+// file is missing. Files are binary if their diff driver says so or if
+// either side looks binary.
+func (g *Got) diffContents(fd *diff.FileDiff, a, b []byte) (*contentDiff, error) {
+	d, err := g.diffDriverFor(fd.Path())
 	if err != nil {
-		return nil, errors.Wrapf(err, "couldn't diff path %s", path)
+		return nil, err
 	}
+	cd := &contentDiff{fd: fd, binary: d.binary, binaryPatch: g.BinaryPatches, src: a, dst: b}
+	if !cd.binary && !d.text && d.textconv == "" {
+		cd.binary = diff.IsBinary(a) || diff.IsBinary(b)
+	}
+	if cd.binary {
+		return cd, nil
+	}
+	a, err = g.diffText(d, a)
+	if err != nil {
+		return nil, err
+	}
+	b, err = g.diffText(d, b)
+	if err != nil {
+		return nil, err
+	}
+	cd.lines = g.Differ.DiffBytes(a, b)
+	cd.hunks = cd.lines.Hunks(g.DiffContext)
+	if d.funcname != nil {
+		cd.hunks.SetFunctions(a, d.funcname)
+	}
+	if g.WordDiff != "" && g.WordDiff != diff.WordDiffNone {
+		cd.wordDiff, cd.differ, cd.wordRegex = g.WordDiff, g.Differ, diff.DefaultWordRegex
+		switch {
+		case g.WordRegex != nil:
+			cd.wordRegex = g.WordRegex
+		case d.wordRegex != nil:
+			cd.wordRegex = d.wordRegex
+		}
+	}
+	return cd, nil
+}
+
+func (cd *contentDiff) stat() diff.FileStat {
+	if cd.binary {
+		return diff.FileStat{Path: cd.fd.DisplayPath(), Binary: true}
+	}
+	return diff.NewFileStat(cd.fd.DisplayPath(), cd.lines)
+}
+
+func (cd *contentDiff) writePatch(w io.Writer) {
+	switch {
+	case cd.binary && cd.binaryPatch:
+		cd.fd.WriteBinaryData(w, cd.src, cd.dst)
+		return
+	case cd.binary:
+		cd.fd.WriteBinaryPatch(w)
+		return
+	case cd.wordDiff != "":
+		cd.fd.WriteWordDiff(w, cd.hunks, cd.differ, cd.wordRegex, cd.wordDiff)
+		return
+	}
+	cd.fd.WritePatch(w, cd.hunks)
+}
 
-	return []byte(blob.Contents), nil
+// Compares a tracked file in the working tree with the given version of it
+// and returns the diff together with the contents of the file in the working
+// tree. The diff is nil if the file is unchanged.
+func (g *Got) diffWorkingTreeFile(path string, perm os.FileMode, id objects.ID) (*diff.FileDiff, []byte, error) {
+	abs := filepath.Join(g.dir, path)
+	info, err := os.Stat(abs)
+	if os.IsNotExist(err) {
+		return diff.NewDeleteFileDiff(perm, id, path), nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	wt, err := g.readWorkingTreeFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	wtID := objects.NewBlob(wt).ID()
+	if wtID == id && diff.ModeString(info.Mode()) == diff.ModeString(perm) {
+		return nil, nil, nil
+	}
+	fd := diff.NewInPlaceFileDiff(perm, info.Mode(), id, wtID, path)
+	fd.DstPath = path
+	return fd, wt, nil
 }
@@ -0,0 +1,195 @@
+package filesystem
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"got/internal/attributes"
+	"got/internal/diff"
+	"got/internal/merge"
+)
+
+// The expressions matching the lines hunk headers name as the function a
+// hunk is in, for the diff drivers that don't configure diff.<driver>.xfuncname.
+// As in xfuncname, alternatives are on separate lines.
+var builtinFuncnames = map[string]string{
+	"golang":   "^[ \t]*(func[ \t]*.*(\\{[ \t]*)?)$\n^[ \t]*(type[ \t].*(struct|interface)[ \t]*(\\{[ \t]*)?)$",
+	"python":   "^[ \t]*((class|(async[ \t]+)?def)[ \t].*)$",
+	"java":     "^[ \t]*(([A-Za-z_][A-Za-z_0-9]*[ \t]+)+[A-Za-z_][A-Za-z_0-9]*[ \t]*\\([^;]*)$",
+	"cpp":      "^((::[ \t]*)?[A-Za-z_].*)$",
+	"rust":     "^[\t ]*((pub(\\([^\\)]+\\))?[\t ]+)?((async|const|unsafe|extern([\t ]+\"[^\"]+\"))[\t ]+)?(struct|enum|union|mod|trait|fn|impl|macro_rules!)[< \t]+[^;]*)$",
+	"bash":     "^[ \t]*((function[ \t]+)?[a-zA-Z_][a-zA-Z0-9_]*[ \t]*(\\([ \t]*\\))?[ \t]*\\{.*)$",
+	"markdown": "^ {0,3}#{1,6}[ \t].*",
+}
+
+// How a file is diffed, as chosen by its 'diff' attribute. '-diff' shows it
+// as binary, 'diff' as text even if it looks binary, and 'diff=<driver>'
+// uses the diff.<driver> config.
+type diffDriver struct {
+	// Set if the differences aren't shown, only that the file differs
+	binary bool
+	// Set if the file is diffed as text whatever its contents
+	text bool
+	// A command that converts the contents to text before they're diffed,
+	// given a file with the contents
+	textconv string
+	// Matches the lines hunk headers name as the function a hunk is in,
+	// nil to name none
+	funcname *regexp.Regexp
+	// Matches the words of word diffs, nil for the default
+	wordRegex *regexp.Regexp
+}
+
+func (g *Got) diffDriverFor(path string) (*diffDriver, error) {
+	attrs, err := g.pathAttributes(path)
+	if err != nil {
+		return nil, err
+	}
+	d := &diffDriver{}
+	if pattern := g.Config.GetString("diff.wordRegex", ""); pattern != "" {
+		d.wordRegex, err = regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid diff.wordRegex")
+		}
+	}
+	value := attrs["diff"]
+	switch {
+	case value == attributes.Unset:
+		d.binary = true
+	case value == attributes.Set:
+		d.text = true
+	case value.IsValue():
+		name := string(value)
+		d.binary = g.Config.GetBool("diff."+name+".binary", false)
+		d.textconv = g.Config.GetString("diff."+name+".textconv", "")
+		if pattern := g.Config.GetString("diff."+name+".xfuncname", builtinFuncnames[name]); pattern != "" {
+			d.funcname, err = regexp.Compile(strings.Join(strings.Split(pattern, "\n"), "|"))
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid funcname of diff driver %s", name)
+			}
+		}
+		if pattern := g.Config.GetString("diff."+name+".wordRegex", ""); pattern != "" {
+			d.wordRegex, err = regexp.Compile(pattern)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid wordRegex of diff driver %s", name)
+			}
+		}
+	}
+	return d, nil
+}
+
+// Returns the contents as they're diffed, converted by the textconv command
+// if there is one
+func (g *Got) diffText(d *diffDriver, contents []byte) ([]byte, error) {
+	if d.textconv == "" || contents == nil {
+		return contents, nil
+	}
+	f, err := ioutil.TempFile("", "got-textconv-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(contents)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	out, err := g.shellCommand(d.textconv, f.Name()).Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "textconv %s failed", d.textconv)
+	}
+	return out, nil
+}
+
+// Returns a command that runs a configured command line with the shell in
+// the root of the working tree, with the arguments appended. Its stderr
+// goes to the stderr of got.
+func (g *Got) shellCommand(command string, args ...string) *exec.Cmd {
+	cmd := exec.Command("sh", append([]string{"-c", command + ` "$@"`, command}, args...)...)
+	cmd.Dir = g.dir
+	if g.IsBare() {
+		cmd.Dir = g.gotDir
+	}
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+// Merges the contents of a file that both sides changed as its 'merge'
+// attribute says. A merge.<driver>.driver command takes precedence over the
+// built-in drivers: 'text', the default, merges line by line, 'binary' or
+// '-merge' keeps ours as a conflict, 'union' keeps the lines of both sides
+// and 'ours' keeps ours without a conflict. Returns the merged contents,
+// nil for a conflict without conflict markers, and whether there's a
+// conflict.
+func (g *Got) mergeFile(path string, base, ours, theirs []byte, theirsName string) ([]byte, bool, error) {
+	attrs, err := g.pathAttributes(path)
+	if err != nil {
+		return nil, false, err
+	}
+	value := attrs["merge"]
+	if value.IsValue() {
+		if command := g.Config.GetString("merge."+string(value)+".driver", ""); command != "" {
+			return g.runMergeDriver(command, path, base, ours, theirs)
+		}
+	}
+	switch {
+	case value == attributes.Unset, value == "binary":
+		return nil, true, nil
+	case value == "union":
+		return merge.Union(g.Differ, base, ours, theirs), false, nil
+	case value == "ours":
+		return ours, false, nil
+	case value != attributes.Set && value != "text" && (diff.IsBinary(ours) || diff.IsBinary(theirs)):
+		// Files of no merge driver are merged as text unless they look
+		// binary
+		return nil, true, nil
+	}
+	contents, conflict := merge.Merge(g.Differ, base, ours, theirs, "HEAD", theirsName)
+	return contents, conflict, nil
+}
+
+// Runs a merge driver command, in which %O, %A and %B stand for files with
+// the base, ours and theirs, %P for the path and %L for the length of
+// conflict markers. The driver leaves the result in the file of ours and
+// exits with a non-zero status if there's a conflict.
+func (g *Got) runMergeDriver(command, path string, base, ours, theirs []byte) ([]byte, bool, error) {
+	dir, err := ioutil.TempDir("", "got-merge-")
+	if err != nil {
+		return nil, false, err
+	}
+	defer os.RemoveAll(dir)
+	files := map[string][]byte{"%O": base, "%A": ours, "%B": theirs}
+	var replacements []string
+	for placeholder, contents := range files {
+		file := dir + "/" + placeholder[1:]
+		err = ioutil.WriteFile(file, contents, 0600)
+		if err != nil {
+			return nil, false, err
+		}
+		replacements = append(replacements, placeholder, shellQuote(file))
+	}
+	replacements = append(replacements, "%P", shellQuote(path), "%L", "7", "%%", "%")
+	cmd := g.shellCommand(strings.NewReplacer(replacements...).Replace(command))
+	cmd.Stdout = os.Stderr
+	err = cmd.Run()
+	if _, ok := err.(*exec.ExitError); !ok && err != nil {
+		return nil, false, errors.Wrapf(err, "couldn't run merge driver for %s", path)
+	}
+	merged, readErr := ioutil.ReadFile(dir + "/A")
+	if readErr != nil {
+		return nil, false, readErr
+	}
+	return merged, err != nil, nil
+}
+
+// Quotes s as a single word for the shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,44 @@
+package filesystem
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"got/internal/mbox"
+	"got/internal/revwalk"
+)
+
+// Returns the commits selected by the revision range as mails, oldest first.
+// A single revision selects the commits since it, i.e. '<rev>..HEAD'. Merge
+// commits are left out as they can't be represented as a single patch.
+func (g *Got) FormatPatch(revRange string) ([]mbox.Message, error) {
+	if !strings.Contains(revRange, "..") && !strings.HasPrefix(revRange, "^") {
+		revRange += "..HEAD"
+	}
+	log, err := g.Log(LogOptions{
+		Revisions: []string{revRange},
+		Sort:      revwalk.SortTopological | revwalk.SortReverse,
+		Stat:      true,
+		Patch:     true,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't format patches for %s", revRange)
+	}
+	var mails []mbox.Message
+	for _, le := range log {
+		if len(le.Commit.Parents()) > 1 {
+			continue
+		}
+		mails = append(mails, mbox.Message{
+			ID:          string(le.ID),
+			AuthorName:  le.Commit.AuthorName(),
+			AuthorEmail: le.Commit.AuthorEmail(),
+			Date:        le.Commit.Time(),
+			Subject:     le.Commit.Subject(),
+			Body:        le.Commit.Body(),
+			Patch:       le.Stat.String() + "\n" + le.Patch,
+		})
+	}
+	return mails, nil
+}
@@ -2,14 +2,19 @@ package filesystem
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
+	"got/internal/attributes"
+	"got/internal/commitgraph"
 	"got/internal/refs"
 
-	"got/internal/diff/simple"
+	"got/internal/config"
+	"got/internal/diff/algorithm"
 
 	"got/internal/diff"
 
@@ -19,6 +24,7 @@ import (
 	"got/internal/index/file"
 	"got/internal/objects"
 	"got/internal/objects/disk"
+	"got/internal/pathspec"
 	"got/internal/pkg/filesystem"
 )
 
@@ -26,63 +32,262 @@ const (
 	rootDir = ".got"
 )
 
-var objectsDir = filepath.Join(rootDir, disk.ObjectsDir)
-var indexFile = filepath.Join(rootDir, file.IndexFile)
-var headFile = filepath.Join(rootDir, "HEAD")
+// Environment variables that change the repository commands use, which the
+// global options --got-dir and --work-tree set too
+const (
+	// The got directory of the repository, used instead of searching for one
+	EnvGotDir = "GOT_DIR"
+	// The working tree of the repository
+	EnvWorkTree = "GOT_WORK_TREE"
+	// Absolute paths, separated by colons, of directories that the search
+	// for a repository doesn't go up into, e.g. slow network mounts
+	EnvCeilingDirectories = "GOT_CEILING_DIRECTORIES"
+	// The index file used instead of the one in the got directory
+	EnvIndexFile = "GOT_INDEX_FILE"
+)
+
+// Files in the got directory, which is the .got directory of the working
+// tree or the directory of a bare repository
+const headFile = "HEAD"
 
 type Got struct {
-	gotDir  string
+	gotDir string
+	// The working tree, empty if the repository is bare
 	dir     string
 	Objects objects.Objects
 	Index   index.Index
-	Ignores map[string]bool
+	// The patterns of the .gitignore file at the root of the working tree
+	Ignores []string
 	Differ  diff.Differ
 	Refs    *refs.Refs
+	Config  *config.Config
+	// The number of unchanged lines shown around changes in patches
+	DiffContext int
+	// How renames and copies are detected in diffs
+	Renames diff.RenameOptions
+	// Write changed binary files as git binary patches, which can be
+	// applied, instead of only saying that they differ
+	BinaryPatches bool
+	// Show the hunks of patches as word diffs in this mode, unless it's
+	// empty or none
+	WordDiff diff.WordDiffMode
+	// Matches the words of word diffs instead of the diff drivers' wordRegex
+	// if set
+	WordRegex *regexp.Regexp
+	graph     *commitgraph.Graph
+	// The attributes of the files, read when first needed
+	attrs *attributes.Attributes
+	// Whether blobs have CRLF line endings, by their IDs, as far as it has
+	// been looked up
+	crlfBlobs map[objects.ID]bool
 }
 
+// Opens the repository the working directory is in, which must have a
+// working tree
 func NewGot() (*Got, error) {
-	dir, err := getRepositoryRoot()
+	g, err := NewGotAllowBare()
 	if err != nil {
-		return nil, errors.New("repository not initialized")
+		return nil, err
 	}
-	gotDir := filepath.Join(dir, rootDir)
-	if !IsInitialized(dir) {
-		return nil, errors.New("repository not initialized")
+	if g.IsBare() {
+		return nil, errors.New("this operation must be run in a work tree")
+	}
+	return g, nil
+}
+
+// Opens the repository the working directory is in, which may be bare, for
+// commands that only need objects and refs
+func NewGotAllowBare() (*Got, error) {
+	gotDir, worktree, err := findRepository()
+	if err != nil {
+		return nil, err
+	}
+	return openAt(gotDir, worktree)
+}
+
+// Opens the repository whose working tree is dir, or the bare repository
+// dir. A bare repository has an empty index and nothing is ignored.
+func Open(dir string) (*Got, error) {
+	if IsInitialized(dir) {
+		return openAt(filepath.Join(dir, rootDir), dir)
+	}
+	if IsBare(dir) {
+		return openAt(dir, "")
+	}
+	return nil, errors.New("repository not initialized")
+}
+
+// Returns the got directory and the working tree of the repository that
+// commands use: the one GOT_DIR names, whose working tree is the working
+// directory unless it's bare, or else the closest one the working directory
+// is in. GOT_WORK_TREE overrides the working tree of either.
+func findRepository() (string, string, error) {
+	gotDir, worktree := os.Getenv(EnvGotDir), ""
+	if gotDir != "" {
+		if !isGotDir(gotDir) {
+			return "", "", errors.Errorf("not a got repository: '%s'", gotDir)
+		}
+		if !IsBare(gotDir) {
+			wd, err := os.Getwd()
+			if err != nil {
+				return "", "", errors.Wrap(err, "couldn't get working directory")
+			}
+			worktree = wd
+		}
+	} else {
+		dir, err := getRepositoryRoot()
+		if err != nil {
+			return "", "", errors.New("repository not initialized")
+		}
+		gotDir, worktree = filepath.Join(dir, rootDir), dir
+		if !IsInitialized(dir) {
+			gotDir, worktree = dir, ""
+		}
 	}
-	i, err := file.ReadFromFile(gotDir)
+	if w := os.Getenv(EnvWorkTree); w != "" {
+		worktree = w
+	}
+
+	gotDir, err := filepath.Abs(gotDir)
+	if err == nil && worktree != "" {
+		worktree, err = filepath.Abs(worktree)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	return gotDir, worktree, nil
+}
+
+// Opens the repository in gotDir with the working tree, which is empty for
+// bare repositories
+func openAt(gotDir, worktree string) (*Got, error) {
+	i, err := readIndex(gotDir, worktree)
 	if err != nil {
 		return nil, err
 	}
-	ignores, err := readIgnores(dir)
+	var ignores []string
+	if worktree != "" {
+		ignores, err = readIgnores(worktree)
+		if err != nil {
+			return nil, err
+		}
+	}
+	conf, err := config.Read(gotDir)
 	if err != nil {
 		return nil, err
 	}
+	// An unknown algorithm falls back to the default so that the config can
+	// still be fixed with got config
+	differ, err := algorithm.ByName(conf.GetString("diff.algorithm", algorithm.Default))
+	if err != nil {
+		differ = algorithm.Myers{}
+	}
+	renames := conf.GetString("diff.renames", "true")
+	renameOptions := diff.RenameOptions{
+		Detect:    renames == "copies" || conf.GetBool("diff.renames", true),
+		Threshold: diff.DefaultRenameThreshold,
+		Copies:    renames == "copies",
+	}
 
 	return &Got{
-		gotDir:  gotDir,
-		dir:     dir,
-		Objects: disk.NewObjects(gotDir),
-		Index:   i,
-		Ignores: ignores,
-		Differ:  simple.Diff{},
-		Refs:    refs.NewRefs(gotDir),
+		gotDir:      gotDir,
+		dir:         worktree,
+		Objects:     disk.NewObjects(gotDir),
+		Index:       i,
+		Ignores:     ignores,
+		Differ:      differ,
+		Refs:        refs.NewRefs(gotDir),
+		Config:      conf,
+		DiffContext: conf.GetInt("diff.context", diff.DefaultContext),
+		Renames:     renameOptions,
 	}, nil
 }
 
+// Reads the index of the repository in gotDir with the working tree: the
+// file GOT_INDEX_FILE names, or else the index file of the got directory.
+// Bare repositories without a working tree have an empty index.
+func readIndex(gotDir, worktree string) (index.Index, error) {
+	switch {
+	case os.Getenv(EnvIndexFile) != "":
+		indexFile, err := filepath.Abs(os.Getenv(EnvIndexFile))
+		if err != nil {
+			return nil, err
+		}
+		return file.ReadFromFileAt(gotDir, indexFile)
+	case worktree != "" && IsBare(gotDir):
+		// A bare repository given a working tree gets an index once
+		// something is staged
+		return file.ReadFromFileAt(gotDir, filepath.Join(gotDir, file.IndexFile))
+	case worktree != "":
+		return file.ReadFromFile(gotDir)
+	}
+	return file.NewIndex(gotDir), nil
+}
+
 func (g *Got) HashFile(filename string, store bool) (objects.ID, error) {
-	bs, err := ioutil.ReadFile(filename)
+	f, err := os.Open(filename)
 	if err != nil {
 		return "", errors.Wrapf(err, "couldn't hash file %s", filename)
 	}
-	blob := objects.NewBlob(bs)
-	sum := blob.ID()
+	defer f.Close()
+	var sum objects.ID
 	if store {
-		err = g.Objects.Store(blob)
+		sum, err = g.Objects.StoreBlobFrom(f)
+	} else {
+		sum, _, err = objects.HashBlob(f)
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "couldn't hash file %s", filename)
+	}
+	return sum, nil
+}
+
+// Writes the contents of the blob with the given ID to the file of the
+// working tree at the repository relative path, copying through a fixed size
+// buffer unless the attributes of the file ask for its contents to be
+// converted.
+func (g *Got) writeBlobToFile(id objects.ID, path string, perm os.FileMode) error {
+	c, err := g.conversionFor(path)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't write blob %s to %s", id, path)
+	}
+	if !c.none() {
+		bs, err := g.blobContents(id)
+		if err == nil {
+			err = g.writeWorkingTreeFile(path, bs, perm)
+		}
 		if err != nil {
-			return "", errors.Wrapf(err, "couldn't hash file %s", filename)
+			return errors.Wrapf(err, "couldn't write blob %s to %s", id, path)
 		}
+		return nil
 	}
-	return sum, nil
+	filename := filepath.Join(g.dir, path)
+	r, _, err := g.Objects.OpenBlob(id)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't write blob %s to %s", id, filename)
+	}
+	defer r.Close()
+	err = os.MkdirAll(filepath.Dir(filename), os.ModePerm)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't write blob %s to %s", id, filename)
+	}
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't write blob %s to %s", id, filename)
+	}
+	_, err = io.CopyBuffer(f, r, make([]byte, objects.BufferSize))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrapf(err, "couldn't write blob %s to %s", id, filename)
+	}
+	return nil
+}
+
+// Reports whether the repository has no working tree
+func (g *Got) IsBare() bool {
+	return g.dir == ""
 }
 
 func (g *Got) repoRel(path string) (string, error) {
@@ -97,6 +302,41 @@ func (g *Got) repoRel(path string) (string, error) {
 	return repoRel, nil
 }
 
+// Parses pathspecs given relative to the working directory. Absolute paths
+// inside the working tree are made relative to its root.
+func (g *Got) pathspec(patterns []string) (*pathspec.Pathspec, error) {
+	if g.IsBare() {
+		return pathspec.Parse("", patterns...)
+	}
+	prefix, err := g.repoRel(".")
+	if err != nil {
+		return nil, err
+	}
+	// Outside of the working tree pathspecs are relative to its root
+	if prefix == "." || isOutside(prefix) {
+		prefix = ""
+	}
+	var converted []string
+	for _, p := range patterns {
+		if filepath.IsAbs(p) {
+			// Paths outside are left to be rejected by the parser
+			if rel, err := filepath.Rel(g.dir, p); err == nil && !isOutside(rel) {
+				if strings.HasSuffix(p, string(filepath.Separator)) {
+					rel += string(filepath.Separator)
+				}
+				p = ":/" + rel
+			}
+		}
+		converted = append(converted, p)
+	}
+	return pathspec.Parse(prefix, converted...)
+}
+
+// Reports whether a path relative to the working tree isn't inside it
+func isOutside(rel string) bool {
+	return rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // The path parameter in f is relative to the repository root
 func (g *Got) forAllInRepo(dir string, f func(path string, info os.FileInfo, err error) error) error {
 	if g.isIgnored(dir) {
@@ -139,35 +379,70 @@ func (g *Got) forAllFilesInRepo(dir string, f func(path string, info os.FileInfo
 	})
 }
 
+// Reports whether the repository relative path or a directory it's in
+// matches an ignore pattern. Patterns with a separator, other than one at
+// their end, match paths relative to the root of the working tree, and
+// others match names at any depth.
 func (g *Got) isIgnored(path string) bool {
-	if g.Ignores[path] {
-		return true
-	}
-	dirs := strings.Split(path, string(filepath.Separator))
-	for i := range dirs {
+	sep := string(filepath.Separator)
+	dirs := strings.Split(path, sep)
+	for i := 1; i <= len(dirs); i++ {
 		dir := filepath.Join(dirs[:i]...)
-		if g.Ignores[dir] || g.Ignores[dir+string(filepath.Separator)] {
-			return true
+		for _, pattern := range g.Ignores {
+			pattern = strings.TrimSuffix(pattern, sep)
+			if strings.Contains(pattern, sep) {
+				if pathspec.Wildmatch(strings.TrimPrefix(pattern, sep), dir, true) {
+					return true
+				}
+			} else if pathspec.Wildmatch(pattern, dirs[i-1], false) {
+				return true
+			}
 		}
 	}
 	return false
 }
 
-// Returns the closest ascendant that contains a '.got' directory
+// Returns the command used to edit text: $GOT_EDITOR, core.editor, $VISUAL
+// or $EDITOR, whichever is set first, or vi
+func (g *Got) Editor() string {
+	if e := os.Getenv("GOT_EDITOR"); e != "" {
+		return e
+	}
+	if e := g.Config.GetString("core.editor", ""); e != "" {
+		return e
+	}
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if e := os.Getenv(env); e != "" {
+			return e
+		}
+	}
+	return "vi"
+}
+
+// Returns the closest ascendant of the working directory, including itself
+// and the root, that contains a '.got' directory or is a bare repository.
+// The search doesn't go up into the directories in GOT_CEILING_DIRECTORIES.
 func getRepositoryRoot() (string, error) {
 	wd, err := os.Getwd()
 	if err != nil {
 		return "", errors.Wrap(err, "couldn't get working directory")
 	}
+	ceilings := make(map[string]bool)
+	for _, dir := range filepath.SplitList(os.Getenv(EnvCeilingDirectories)) {
+		if filepath.IsAbs(dir) {
+			ceilings[filepath.Clean(dir)] = true
+		}
+	}
 	currentDir := wd
 	for {
-		if hasGotDir(currentDir) {
+		if hasGotDir(currentDir) || IsBare(currentDir) {
 			return currentDir, nil
 		}
-		currentDir = filepath.Dir(currentDir)
-		if currentDir == "/" {
+		parent := filepath.Dir(currentDir)
+		if parent == currentDir || ceilings[parent] {
 			break
 		}
+		currentDir = parent
 	}
 	return "", errors.New("no repository found")
 }
@@ -185,70 +460,143 @@ func hasGotDir(dir string) bool {
 	return false
 }
 
+// Reports whether dir is a working tree with a repository
 func IsInitialized(dir string) bool {
-	return filesystem.DirExists(filepath.Join(dir, rootDir)) &&
-		filesystem.DirExists(filepath.Join(dir, objectsDir)) &&
-		filesystem.FileExists(filepath.Join(dir, indexFile)) &&
-		filesystem.FileExists(filepath.Join(dir, headFile)) &&
-		filesystem.DirExists(filepath.Join(dir, rootDir, refs.Dir)) &&
-		filesystem.DirExists(filepath.Join(dir, rootDir, refs.Dir, refs.HeadsDir))
+	gotDir := filepath.Join(dir, rootDir)
+	return isGotDir(gotDir) && filesystem.FileExists(filepath.Join(gotDir, file.IndexFile))
 }
 
-func Initialize(dir string) error {
-	if IsInitialized(dir) {
-		return fmt.Errorf("Repository already exists for %s\n", dir)
+// Reports whether dir is a bare repository, which has no working tree
+func IsBare(dir string) bool {
+	return isGotDir(dir) && !filesystem.FileExists(filepath.Join(dir, file.IndexFile))
+}
+
+func isGotDir(gotDir string) bool {
+	return filesystem.DirExists(filepath.Join(gotDir, disk.ObjectsDir)) &&
+		filesystem.FileExists(filepath.Join(gotDir, headFile)) &&
+		filesystem.DirExists(filepath.Join(gotDir, refs.Dir, refs.HeadsDir))
+}
+
+type InitOptions struct {
+	// Create a repository without a working tree, in dir itself instead of
+	// dir/.got
+	Bare bool
+	// The branch the first commit creates, init.defaultBranch or master if
+	// empty
+	InitialBranch string
+	// A directory whose files are copied into the new repository, e.g. a
+	// config. Existing files aren't overwritten.
+	Template string
+}
+
+// Creates a repository in dir, which is created if it doesn't exist, and
+// returns the directory of the repository
+func Initialize(dir string, opts InitOptions) (string, error) {
+	if IsInitialized(dir) || IsBare(dir) {
+		return "", fmt.Errorf("Repository already exists for %s", dir)
+	}
+	gotDir := filepath.Join(dir, rootDir)
+	if opts.Bare {
+		gotDir = dir
+	}
+	if opts.InitialBranch != "" && !refs.ValidBranchName(opts.InitialBranch) {
+		return "", errors.Errorf("invalid initial branch name '%s'", opts.InitialBranch)
 	}
-	err := filesystem.MkDirIfIsNotExist(filepath.Join(dir, rootDir), os.ModePerm)
+	err := os.MkdirAll(gotDir, os.ModePerm)
 	if err != nil {
-		return err
+		return "", err
+	}
+	if opts.Template != "" {
+		err = copyTemplate(opts.Template, gotDir)
+		if err != nil {
+			return "", errors.Wrapf(err, "couldn't copy template %s", opts.Template)
+		}
 	}
-	err = filesystem.MkDirIfIsNotExist(filepath.Join(dir, objectsDir), os.ModePerm)
+	err = filesystem.MkDirIfIsNotExist(filepath.Join(gotDir, disk.ObjectsDir), os.ModePerm)
 	if err != nil {
-		return err
+		return "", err
 	}
-	err = filesystem.MkFileIfIsNotExist(filepath.Join(dir, indexFile))
+	if !opts.Bare {
+		err = filesystem.MkFileIfIsNotExist(filepath.Join(gotDir, file.IndexFile))
+		if err != nil {
+			return "", err
+		}
+	}
+	err = filesystem.MkFileIfIsNotExist(filepath.Join(gotDir, headFile))
 	if err != nil {
-		return err
+		return "", err
 	}
-	err = filesystem.MkFileIfIsNotExist(filepath.Join(dir, headFile))
+	err = os.MkdirAll(filepath.Join(gotDir, refs.Dir, refs.HeadsDir), os.ModePerm)
 	if err != nil {
-		return err
+		return "", err
 	}
-	err = filesystem.MkDirIfIsNotExist(filepath.Join(dir, rootDir, refs.Dir), os.ModePerm)
+
+	if !opts.Bare && opts.InitialBranch == "" {
+		return gotDir, nil
+	}
+	conf, err := config.Read(gotDir)
 	if err != nil {
-		return err
+		return "", err
+	}
+	if opts.Bare {
+		err = conf.Set("core.bare", "true")
+	}
+	if err == nil && opts.InitialBranch != "" {
+		err = conf.Set("init.defaultBranch", opts.InitialBranch)
 	}
-	err = filesystem.MkDirIfIsNotExist(filepath.Join(dir, rootDir, refs.Dir, refs.HeadsDir), os.ModePerm)
 	if err != nil {
-		return err
+		return "", err
 	}
-	return nil
+	return gotDir, nil
+}
+
+// Copies the files of the template directory into the got directory,
+// keeping files that exist already
+func copyTemplate(template, gotDir string) error {
+	return filepath.Walk(template, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(template, path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(gotDir, rel)
+		if info.IsDir() {
+			return os.MkdirAll(dst, info.Mode().Perm()|0700)
+		}
+		if !info.Mode().IsRegular() || filesystem.FileExists(dst) {
+			return nil
+		}
+		bs, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(dst, bs, info.Mode().Perm())
+	})
 }
 
-func readIgnores(dir string) (map[string]bool, error) {
-	ignores := make(map[string]bool)
+// Reads the patterns of the .gitignore file at the root of the working tree
+// in dir
+func readIgnores(dir string) ([]string, error) {
 	if !filesystem.FileExists(filepath.Join(dir, ".gitignore")) {
-		return ignores, nil
+		return nil, nil
 	}
 	ignoreFile, err := ioutil.ReadFile(filepath.Join(dir, ".gitignore"))
 	if err != nil {
 		return nil, errors.Wrapf(err, "couldn't read ignorefile")
 	}
 
+	var ignores []string
 	for _, line := range strings.Split(string(ignoreFile), "\n") {
+		line = strings.TrimRight(line, " \r")
 		if len(line) == 0 {
 			continue
 		}
 		if line[0] == '#' {
 			continue
 		}
-		matches, err := filepath.Glob(line)
-		if err != nil {
-			return nil, errors.Wrapf(err, "couldn't read ignorefile")
-		}
-		for _, m := range matches {
-			ignores[m] = true
-		}
+		ignores = append(ignores, filepath.FromSlash(line))
 	}
 	return ignores, nil
 }
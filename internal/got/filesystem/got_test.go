@@ -0,0 +1,86 @@
+package filesystem
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gookit/color"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	color.Disable()
+}
+
+// Creates a repository in a temporary directory, which becomes the working
+// directory, and opens it. The returned function removes it again.
+func newTestRepo(t *testing.T) (*Got, func()) {
+	dir, err := ioutil.TempDir("", "got-filesystem")
+	assert.Nil(t, err)
+	dir, err = filepath.EvalSymlinks(dir)
+	assert.Nil(t, err)
+	wd, err := os.Getwd()
+	assert.Nil(t, err)
+	cleanup := func() {
+		_ = os.Chdir(wd)
+		os.RemoveAll(dir)
+	}
+	_, err = Initialize(dir, InitOptions{})
+	assert.Nil(t, err)
+	assert.Nil(t, os.Chdir(dir))
+	g, err := Open(dir)
+	assert.Nil(t, err)
+	assert.Nil(t, g.Config.Set("user.name", "A U Thor"))
+	assert.Nil(t, g.Config.Set("user.email", "author@example.com"))
+	return g, cleanup
+}
+
+// Writes the files, given by their repository relative paths, to the
+// working tree
+func writeFiles(t *testing.T, g *Got, files map[string]string) {
+	for path, contents := range files {
+		abs := filepath.Join(g.dir, filepath.FromSlash(path))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(abs), os.ModePerm))
+		assert.Nil(t, ioutil.WriteFile(abs, []byte(contents), 0644))
+	}
+}
+
+// Writes the files, adds them and commits them
+func commitFiles(t *testing.T, g *Got, message string, files map[string]string) {
+	writeFiles(t, g, files)
+	for path := range files {
+		assert.Nil(t, g.AddPath(":/"+path))
+	}
+	assert.Nil(t, g.Commit(message, CommitOptions{}))
+}
+
+// Reads a file of the working tree by its repository relative path
+func readFile(t *testing.T, g *Got, path string) string {
+	bs, err := ioutil.ReadFile(filepath.Join(g.dir, filepath.FromSlash(path)))
+	assert.Nil(t, err)
+	return string(bs)
+}
+
+func TestAbsolutePathspecs(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	writeFiles(t, g, map[string]string{"a.txt": "a\n", "sub/b.txt": "b\n", "sub/c.txt": "c\n"})
+	assert.Nil(t, os.Chdir(filepath.Join(g.dir, "sub")))
+
+	assert.Nil(t, g.AddPath(filepath.Join(g.dir, "a.txt")))
+	assert.Nil(t, g.AddPath(filepath.Join(g.dir, "sub")+string(filepath.Separator)))
+	var names []string
+	for _, e := range g.Index.SortedEntries() {
+		names = append(names, e.Name)
+	}
+	assert.Equal(t, []string{"a.txt", filepath.Join("sub", "b.txt"), filepath.Join("sub", "c.txt")}, names)
+
+	ps, err := g.pathspec([]string{g.dir})
+	assert.Nil(t, err)
+	assert.True(t, ps.Matches("a.txt"))
+
+	_, err = g.pathspec([]string{filepath.Dir(g.dir)})
+	assert.NotNil(t, err)
+}
@@ -21,7 +21,7 @@ const (
 )
 
 func (g *Got) HeadType() (HeadType, error) {
-	bs, err := ioutil.ReadFile(filepath.Join(g.dir, headFile))
+	bs, err := ioutil.ReadFile(filepath.Join(g.gotDir, headFile))
 	if err != nil {
 		return "", errors.Wrapf(err, "couldn't get head type")
 	}
@@ -40,7 +40,7 @@ func (g *Got) HeadType() (HeadType, error) {
 }
 
 func (g *Got) HeadAsID() (objects.ID, error) {
-	bs, err := ioutil.ReadFile(filepath.Join(g.dir, headFile))
+	bs, err := ioutil.ReadFile(filepath.Join(g.gotDir, headFile))
 	if err != nil {
 		return "", errors.Wrap(err, "couldn't get head as ID")
 	}
@@ -52,7 +52,7 @@ func (g *Got) HeadAsID() (objects.ID, error) {
 }
 
 func (g *Got) HeadAsRef() (refs.Ref, error) {
-	bs, err := ioutil.ReadFile(filepath.Join(g.dir, headFile))
+	bs, err := ioutil.ReadFile(filepath.Join(g.gotDir, headFile))
 	if err != nil {
 		return "", errors.Wrap(err, "couldn't get head as Ref")
 	}
@@ -69,7 +69,16 @@ func (g *Got) idAtHead() (*objects.ID, error) {
 		return nil, errors.Wrapf(err, "couldn't get id at HEAD")
 	}
 	if headType == HeadTypeEmpty {
-		return nil, nil
+		// Pushes to a bare repository create the initial branch without
+		// touching HEAD, which then stands for that branch
+		if !g.IsBare() || !g.Refs.BranchExists(g.initialBranch()) {
+			return nil, nil
+		}
+		id, err := g.Refs.IdAtBranch(g.initialBranch())
+		if err != nil {
+			return nil, errors.Wrapf(err, "couldn't get id at HEAD")
+		}
+		return &id, nil
 	}
 	ref, err := g.HeadAsRef()
 	if err != nil {
@@ -85,11 +94,11 @@ func (g *Got) idAtHead() (*objects.ID, error) {
 func (g *Got) headAtBranch(branchName string) (bool, error) {
 	ref, err := g.Refs.BranchRef(branchName)
 	if err != nil {
-		return false, errors.Wrapf(err, "couldn't determine if HEAD is at branch %s")
+		return false, errors.Wrapf(err, "couldn't determine if HEAD is at branch %s", branchName)
 	}
 	headRef, err := g.HeadAsRef()
 	if err != nil {
-		return false, errors.Wrapf(err, "couldn't determine if HEAD is at branch %s")
+		return false, errors.Wrapf(err, "couldn't determine if HEAD is at branch %s", branchName)
 	}
 	return ref == headRef, nil
 }
@@ -111,8 +120,13 @@ func (g *Got) headTree() (*objects.Tree, error) {
 
 }
 
+// Returns the branch the first commit creates while HEAD is empty
+func (g *Got) initialBranch() string {
+	return g.Config.GetString("init.defaultBranch", "master")
+}
+
 func (g *Got) updateHeadWithID(id objects.ID) error {
-	err := ioutil.WriteFile(filepath.Join(g.dir, headFile), []byte(id), os.ModePerm)
+	err := ioutil.WriteFile(filepath.Join(g.gotDir, headFile), []byte(id), os.ModePerm)
 	if err != nil {
 		return errors.Wrapf(err, "couldn't update HEAD with id %s", id)
 	}
@@ -120,7 +134,7 @@ func (g *Got) updateHeadWithID(id objects.ID) error {
 }
 
 func (g *Got) updateHeadWithRef(ref refs.Ref) error {
-	err := ioutil.WriteFile(filepath.Join(g.dir, headFile), []byte(ref), os.ModePerm)
+	err := ioutil.WriteFile(filepath.Join(g.gotDir, headFile), []byte(ref), os.ModePerm)
 	if err != nil {
 		return errors.Wrapf(err, "couldn't update HEAD with ref %s", ref)
 	}
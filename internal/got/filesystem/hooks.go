@@ -0,0 +1,136 @@
+package filesystem
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The hooks got runs, with the arguments and stdin Git gives them
+const (
+	// Before a commit is made, with no arguments. Failing aborts the commit.
+	hookPreCommit = "pre-commit"
+	// With the file holding the message of a commit, which the hook may
+	// edit. Failing aborts the commit.
+	hookCommitMsg = "commit-msg"
+	// After a commit is made, with no arguments
+	hookPostCommit = "post-commit"
+	// After HEAD is moved by a checkout or clone, with the commits HEAD was
+	// and is at and 1 for a branch checkout
+	hookPostCheckout = "post-checkout"
+	// Before a merge commit is made, with no arguments. Failing leaves the
+	// merge to be committed.
+	hookPreMergeCommit = "pre-merge-commit"
+	// After a merge, with 0 as merges are never squashed
+	hookPostMerge = "post-merge"
+	// Before anything is pushed, with the name and URL of the remote and a
+	// line '<local ref> <local id> <remote ref> <remote id>' per update on
+	// stdin. Failing aborts the push.
+	hookPrePush = "pre-push"
+)
+
+// The file the commit-msg hook gets the message in
+const commitMsgFile = "COMMIT_EDITMSG"
+
+// The ID hooks get for a commit that doesn't exist
+var nullID = strings.Repeat("0", 40)
+
+// Returns the directory hooks are in, core.hooksPath relative to the working
+// tree or hooks in the got directory
+func (g *Got) hooksDir() string {
+	dir := g.Config.GetString("core.hooksPath", "")
+	if dir == "" {
+		return filepath.Join(g.gotDir, "hooks")
+	}
+	if strings.HasPrefix(dir, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, dir[2:])
+		}
+	}
+	switch {
+	case filepath.IsAbs(dir):
+		return dir
+	case g.IsBare():
+		return filepath.Join(g.gotDir, dir)
+	}
+	return filepath.Join(g.dir, dir)
+}
+
+// Returns the path of the hook, empty if there is no executable hook of
+// that name
+func (g *Got) hookPath(name string) string {
+	path := filepath.Join(g.hooksDir(), name)
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+		return ""
+	}
+	return path
+}
+
+// Runs the hook in the root of the working tree with the arguments and
+// stdin, which may be nil. Its output goes to stderr so that it doesn't mix
+// with the output of the command. Hooks that don't exist succeed. The
+// repository is passed on in GOT_DIR, GOT_WORK_TREE and GOT_INDEX_FILE so
+// that got commands run by the hook use it wherever they run.
+func (g *Got) runHook(name string, stdin io.Reader, args ...string) error {
+	path := g.hookPath(name)
+	if path == "" {
+		return nil
+	}
+	cmd := exec.Command(path, args...)
+	cmd.Dir = g.dir
+	if g.IsBare() {
+		cmd.Dir = g.gotDir
+	}
+	cmd.Env = append(os.Environ(), EnvGotDir+"="+g.gotDir)
+	if g.dir != "" {
+		cmd.Env = append(cmd.Env, EnvWorkTree+"="+g.dir)
+	}
+	if indexFile := os.Getenv(EnvIndexFile); indexFile != "" {
+		abs, err := filepath.Abs(indexFile)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't run %s hook", name)
+		}
+		cmd.Env = append(cmd.Env, EnvIndexFile+"="+abs)
+	}
+	cmd.Stdin = stdin
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return errors.Errorf("%s hook exited with status %d", name, exitErr.ExitCode())
+	}
+	if err != nil {
+		return errors.Wrapf(err, "couldn't run %s hook", name)
+	}
+	return nil
+}
+
+// Runs the commit-msg hook on the message and returns the message as the
+// hook left it
+func (g *Got) runCommitMsgHook(message string) (string, error) {
+	if g.hookPath(hookCommitMsg) == "" {
+		return message, nil
+	}
+	file := filepath.Join(g.gotDir, commitMsgFile)
+	// Like files made by editors the message ends in a line break, which
+	// hooks appending trailers rely on
+	err := ioutil.WriteFile(file, []byte(strings.TrimRight(message, "\n")+"\n"), 0644)
+	if err != nil {
+		return "", errors.Wrapf(err, "couldn't write %s", file)
+	}
+	err = g.runHook(hookCommitMsg, nil, file)
+	if err != nil {
+		return "", err
+	}
+	bs, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", errors.Wrapf(err, "couldn't read %s", file)
+	}
+	return strings.TrimRight(string(bs), "\n"), nil
+}
@@ -0,0 +1,131 @@
+package filesystem
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Writes an executable hook with the script to the hooks directory
+func writeHook(t *testing.T, g *Got, name, script string) {
+	assert.Nil(t, os.MkdirAll(g.hooksDir(), os.ModePerm))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(g.hooksDir(), name), []byte("#!/bin/sh\n"+script), 0755))
+}
+
+func headMessage(t *testing.T, g *Got) string {
+	id, err := g.ResolveRevision("HEAD")
+	assert.Nil(t, err)
+	c, err := g.Objects.GetCommit(id)
+	assert.Nil(t, err)
+	return c.Message
+}
+
+func TestCommitHooks(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	commitFiles(t, g, "first", map[string]string{"a.txt": "a\n"})
+	writeFiles(t, g, map[string]string{"a.txt": "b\n"})
+	assert.Nil(t, g.AddPath("a.txt"))
+
+	writeHook(t, g, hookPreCommit, "exit 1\n")
+	assert.EqualError(t, g.Commit("second", CommitOptions{}), "pre-commit hook exited with status 1")
+	assert.Equal(t, "first", headMessage(t, g))
+
+	writeHook(t, g, hookPreCommit, "exit 0\n")
+	writeHook(t, g, hookCommitMsg, "grep -q JIRA- \"$1\"\n")
+	assert.EqualError(t, g.Commit("second", CommitOptions{}), "commit-msg hook exited with status 1")
+	assert.Equal(t, "first", headMessage(t, g))
+
+	writeHook(t, g, hookCommitMsg, "echo 'Signed-off-by: A U Thor <author@example.com>' >> \"$1\"\n")
+	writeHook(t, g, hookPostCommit, "touch post-commit-ran\nexit 1\n")
+	assert.Nil(t, g.Commit("second", CommitOptions{}))
+	assert.Equal(t, "second\nSigned-off-by: A U Thor <author@example.com>", headMessage(t, g))
+	assert.FileExists(t, filepath.Join(g.dir, "post-commit-ran"))
+}
+
+func TestPreCommitHookStagesChanges(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	commitFiles(t, g, "first", map[string]string{"a.txt": "a\n"})
+
+	// The hook stages the formatted file by putting back an index that has
+	// it staged, as 'got add' in the hook would
+	writeFiles(t, g, map[string]string{"a.txt": "formatted\n"})
+	assert.Nil(t, g.AddPath("a.txt"))
+	formatted := readFile(t, g, ".got/index")
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(g.gotDir, "index.formatted"), []byte(formatted), 0644))
+	writeFiles(t, g, map[string]string{"a.txt": "b\n"})
+	assert.Nil(t, g.AddPath("a.txt"))
+	writeHook(t, g, hookPreCommit, "echo formatted > a.txt\ncp .got/index.formatted .got/index\n")
+
+	assert.Nil(t, g.Commit("second", CommitOptions{}))
+	tree, err := g.headTree()
+	assert.Nil(t, err)
+	bs, err := g.blobContents(tree.Entries[0].ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "formatted\n", string(bs))
+	st, err := g.Status()
+	assert.Nil(t, err)
+	assert.Empty(t, st.Changes)
+}
+
+func TestHookEnvironment(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	indexFile := filepath.Join(g.gotDir, "other-index")
+	defer setenv(t, EnvIndexFile, indexFile)()
+	writeHook(t, g, hookPreCommit, "echo \"$GOT_DIR|$GOT_WORK_TREE|$GOT_INDEX_FILE\" > env.txt\n")
+	writeFiles(t, g, map[string]string{"a.txt": "a\n"})
+	assert.Nil(t, g.AddPath("a.txt"))
+
+	assert.Nil(t, g.Commit("first", CommitOptions{}))
+	assert.Equal(t, g.gotDir+"|"+g.dir+"|"+indexFile+"\n", readFile(t, g, "env.txt"))
+}
+
+func TestCommitNoVerify(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	writeHook(t, g, hookPreCommit, "exit 1\n")
+	writeHook(t, g, hookCommitMsg, "exit 1\n")
+	writeFiles(t, g, map[string]string{"a.txt": "a\n"})
+	assert.Nil(t, g.AddPath("a.txt"))
+
+	assert.Nil(t, g.Commit("first", CommitOptions{NoVerify: true}))
+	assert.Equal(t, "first", headMessage(t, g))
+}
+
+func TestHooksPath(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	writeHook(t, g, hookPreCommit, "exit 1\n")
+	assert.Nil(t, g.Config.Set("core.hooksPath", "myhooks"))
+	assert.Equal(t, filepath.Join(g.dir, "myhooks"), g.hooksDir())
+	writeHook(t, g, hookPreCommit, "touch pre-commit-ran\n")
+
+	commitFiles(t, g, "first", map[string]string{"a.txt": "a\n"})
+	assert.FileExists(t, filepath.Join(g.dir, "pre-commit-ran"))
+}
+
+func TestPrePushHook(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	remote := addTestRemote(t, g)
+	commitFiles(t, g, "first", map[string]string{"a.txt": "a\n"})
+	head, err := g.ResolveRevision("HEAD")
+	assert.Nil(t, err)
+
+	out := filepath.Join(g.dir, "pre-push.out")
+	writeHook(t, g, hookPrePush, "echo \"$@\" > "+out+"\ncat >> "+out+"\nexit 1\n")
+	_, err = g.Push("origin", []string{"master"}, PushOptions{})
+	assert.EqualError(t, err, "couldn't push to origin: pre-push hook exited with status 1")
+	assert.Equal(t, "origin "+remote.gotDir+"\nrefs/heads/master "+string(head)+" refs/heads/master "+nullID+"\n", readFile(t, g, "pre-push.out"))
+	assert.False(t, remote.Refs.BranchExists("master"))
+
+	pushed, err := g.Push("origin", []string{"master"}, PushOptions{NoVerify: true})
+	assert.Nil(t, err)
+	assert.Len(t, pushed, 1)
+	assert.True(t, remote.Refs.BranchExists("master"))
+}
@@ -0,0 +1,94 @@
+package filesystem
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "got-init")
+	assert.Nil(t, err)
+	dir, err = filepath.EvalSymlinks(dir)
+	assert.Nil(t, err)
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestInitialize(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	gotDir, err := Initialize(filepath.Join(dir, "repo"), InitOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "repo", rootDir), gotDir)
+	assert.True(t, IsInitialized(filepath.Join(dir, "repo")))
+	assert.False(t, IsBare(filepath.Join(dir, "repo")))
+	assert.False(t, IsBare(gotDir))
+	g, err := Open(filepath.Join(dir, "repo"))
+	assert.Nil(t, err)
+	assert.False(t, g.IsBare())
+	assert.Equal(t, "master", g.initialBranch())
+
+	_, err = Initialize(filepath.Join(dir, "repo"), InitOptions{})
+	assert.EqualError(t, err, "Repository already exists for "+filepath.Join(dir, "repo"))
+	_, err = Initialize(filepath.Join(dir, "other"), InitOptions{InitialBranch: "bad//name"})
+	assert.EqualError(t, err, "invalid initial branch name 'bad//name'")
+}
+
+func TestInitializeBare(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	gotDir, err := Initialize(dir, InitOptions{Bare: true, InitialBranch: "main"})
+	assert.Nil(t, err)
+	assert.Equal(t, dir, gotDir)
+	assert.True(t, IsBare(dir))
+	assert.False(t, IsInitialized(dir))
+	g, err := Open(dir)
+	assert.Nil(t, err)
+	assert.True(t, g.IsBare())
+	assert.True(t, g.Config.GetBool("core.bare", false))
+	assert.Equal(t, "main", g.initialBranch())
+	assert.Nil(t, g.Ignores)
+	assert.Empty(t, g.Index.SortedEntries())
+
+	// Commands that need a working tree refuse to run in it
+	wd, err := os.Getwd()
+	assert.Nil(t, err)
+	defer os.Chdir(wd)
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "refs", "sub"), os.ModePerm))
+	assert.Nil(t, os.Chdir(filepath.Join(dir, "refs", "sub")))
+	_, err = NewGot()
+	assert.EqualError(t, err, "this operation must be run in a work tree")
+	g, err = NewGotAllowBare()
+	assert.Nil(t, err)
+	assert.True(t, g.IsBare())
+	assert.Equal(t, dir, g.gotDir)
+}
+
+func TestInitializeTemplate(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	template := filepath.Join(dir, "template")
+	assert.Nil(t, os.MkdirAll(filepath.Join(template, "hooks"), os.ModePerm))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(template, "hooks", "pre-commit"), []byte("#!/bin/sh\n"), 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(template, "config"), []byte("core.hooksPath = hooks\n"), 0644))
+
+	gotDir, err := Initialize(filepath.Join(dir, "repo"), InitOptions{Template: template, InitialBranch: "main"})
+	assert.Nil(t, err)
+	info, err := os.Stat(filepath.Join(gotDir, "hooks", "pre-commit"))
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+	g, err := Open(filepath.Join(dir, "repo"))
+	assert.Nil(t, err)
+	// The template's config is kept and extended
+	assert.Equal(t, "hooks", g.Config.GetString("core.hooksPath", ""))
+	assert.Equal(t, "main", g.initialBranch())
+
+	_, err = Initialize(filepath.Join(dir, "other"), InitOptions{Template: filepath.Join(dir, "missing")})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "couldn't copy template")
+}
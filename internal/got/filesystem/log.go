@@ -2,64 +2,455 @@ package filesystem
 
 import (
 	"bytes"
-	"fmt"
-	"strings"
-
-	"github.com/gookit/color"
+	"regexp"
+	"time"
 
 	"github.com/pkg/errors"
 
+	"got/internal/diff"
 	"got/internal/objects"
+	"got/internal/pathspec"
+	"got/internal/pretty"
+	"got/internal/revwalk"
 )
 
+type LogOptions struct {
+	// Revision specifiers selecting the commits to show, HEAD if empty
+	Revisions []string
+	// Only show commits that change any of the paths matching these
+	// pathspecs
+	Paths    []string
+	MaxCount int
+	Sort     revwalk.Sort
+	// Only show commits whose author matches this regular expression
+	Author string
+	// Only show commits whose message matches this regular expression
+	Grep  string
+	Since time.Time
+	Until time.Time
+	// Add the names of the refs pointing at each commit
+	Decorate bool
+	// Add a diffstat of the changes made by each commit
+	Stat bool
+	// Add a patch of the changes made by each commit
+	Patch bool
+	// Follow the single path in Paths through renames
+	Follow bool
+}
+
 type Log []LogEntry
-type LogEntry objects.Commit
+
+type LogEntry struct {
+	pretty.Entry
+	// The nearest ancestors of the commit that are in the log, which the
+	// graph is drawn from
+	Parents []objects.ID
+	// Diffstat of the commit, if requested
+	Stat diff.Stats
+	// Patch of the commit, if requested
+	Patch string
+}
 
 func (l Log) String() string {
+	return l.Format(pretty.Medium, false)
+}
+
+// Formats every entry with the given --pretty format, optionally with an
+// ASCII graph of the history to the left.
+func (l Log) Format(format string, graph bool) string {
 	buf := bytes.NewBuffer(nil)
-	for _, le := range l {
-		fmt.Fprintln(buf, le)
+	g := pretty.NewGraph()
+	for i, le := range l {
+		text := le.Format(format)
+		// The multi-line formats are separated by a blank line
+		if i > 0 && isBuiltinFormat(format) && format != pretty.Oneline {
+			text = "\n" + text
+		}
+		if graph {
+			text = g.Next(le.ID, le.Parents, text)
+		}
+		buf.WriteString(text)
 	}
 	return buf.String()
 }
 
-func (le LogEntry) String() string {
-	buf := bytes.NewBuffer(nil)
-	fmt.Fprintf(buf, color.Yellow.Sprintf("commit %v\n", le.Checksum))
-	fmt.Fprintf(buf, "Author: %v\n", le.Author)
-	fmt.Fprintln(buf)
-	for _, line := range strings.Split(le.Message, "\n") {
-		fmt.Fprintf(buf, "    %s\n", line)
+func isBuiltinFormat(format string) bool {
+	switch format {
+	case pretty.Oneline, pretty.Short, pretty.Medium, pretty.Full, "":
+		return true
 	}
-	return buf.String()
+	return false
 }
 
-func (g *Got) Log(n int) (Log, error) {
-	headID, err := g.idAtHead()
-	if err != nil {
-		return nil, errors.Wrap(err, "couldn't show log")
+func (le LogEntry) Format(format string) string {
+	s := pretty.Format(format, le.Entry)
+	if le.Stat == nil && le.Patch == "" {
+		return s
+	}
+	if format != pretty.Oneline {
+		s += "\n"
 	}
-	if headID == nil {
-		return nil, nil
+	if le.Stat != nil {
+		s += le.Stat.String()
+	}
+	if le.Patch != "" {
+		if le.Stat != nil {
+			s += "\n"
+		}
+		s += le.Patch
 	}
-	commit, err := g.Objects.GetCommit(*headID)
+	return s
+}
+
+func (le LogEntry) String() string {
+	return le.Format(pretty.Medium)
+}
+
+func (g *Got) Log(opts LogOptions) (Log, error) {
+	revisions := opts.Revisions
+	if len(revisions) == 0 {
+		headID, err := g.idAtHead()
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't show log")
+		}
+		if headID == nil {
+			return nil, nil
+		}
+		revisions = []string{"HEAD"}
+	}
+	filter, err := newLogFilter(g, opts)
 	if err != nil {
 		return nil, errors.Wrap(err, "couldn't show log")
 	}
-
-	var log Log
-	log = append(log, LogEntry(commit))
-	n--
-	for commit.ParentID != nil {
-		if n == 0 {
-			break
+	var decorations map[objects.ID][]string
+	if opts.Decorate {
+		decorations, err = g.decorations()
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't show log")
 		}
-		commit, err = g.Objects.GetCommit(*commit.ParentID)
+	}
+
+	// The log is reversed once it's complete, so that a limited log has the
+	// newest commits
+	w := g.RevWalker()
+	w.Sorting(opts.Sort &^ revwalk.SortReverse)
+	for _, rev := range revisions {
+		err := w.PushSpec(rev, g.ResolveRevision)
 		if err != nil {
 			return nil, errors.Wrap(err, "couldn't show log")
 		}
-		log = append(log, LogEntry(commit))
-		n--
+	}
+
+	// Commits in date order are walked as they're reached, so stopping once
+	// enough commits are shown saves reading the rest of the history
+	var log Log
+	walked := make(map[objects.ID][]objects.ID)
+	err = w.Walk(func(id objects.ID, c objects.Commit) error {
+		walked[id] = c.Parents()
+		// Following a path changes it to the name it had before the commit
+		paths := filter.paths
+		ok, err := filter.matches(id, c)
+		if err != nil || !ok {
+			return err
+		}
+		le := LogEntry{Entry: pretty.Entry{ID: id, Commit: c, Decorations: decorations[id]}}
+		if opts.Stat || opts.Patch {
+			err = g.addChangesToLogEntry(&le, paths, opts)
+			if err != nil {
+				return err
+			}
+		}
+		log = append(log, le)
+		if len(log) == opts.MaxCount {
+			return errStopWalk
+		}
+		return nil
+	})
+	if err != nil && err != errStopWalk {
+		return nil, errors.Wrap(err, "couldn't show log")
+	}
+	log.rewriteParents(walked)
+	if opts.Sort&revwalk.SortReverse != 0 {
+		for i, j := 0, len(log)-1; i < j; i, j = i+1, j-1 {
+			log[i], log[j] = log[j], log[i]
+		}
 	}
 	return log, nil
 }
+
+// Sets the parents of every entry to its nearest ancestors in the log,
+// skipping the walked commits that were filtered out, so that the graph has
+// no lines waiting for commits that are never shown. Parents that weren't
+// walked, like those at the end of a range, are dropped.
+func (l Log) rewriteParents(walked map[objects.ID][]objects.ID) {
+	shown := make(map[objects.ID]bool)
+	for _, le := range l {
+		shown[le.ID] = true
+	}
+	nearest := make(map[objects.ID][]objects.ID)
+	var ancestors func(id objects.ID) []objects.ID
+	ancestors = func(id objects.ID) []objects.ID {
+		var found []objects.ID
+		for _, p := range walked[id] {
+			if shown[p] {
+				found = appendNew(found, p)
+				continue
+			}
+			ps, ok := nearest[p]
+			if !ok {
+				ps = ancestors(p)
+				nearest[p] = ps
+			}
+			found = appendNew(found, ps...)
+		}
+		return found
+	}
+	for i := range l {
+		l[i].Parents = ancestors(l[i].ID)
+	}
+}
+
+// Appends the IDs that aren't in ids yet
+func appendNew(ids []objects.ID, add ...objects.ID) []objects.ID {
+	for _, id := range add {
+		found := false
+		for _, known := range ids {
+			if known == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+var errStopWalk = errors.New("stop walk")
+
+// Decides which of the walked commits are shown in the log
+type logFilter struct {
+	g      *Got
+	paths  *pathspec.Pathspec
+	author *regexp.Regexp
+	grep   *regexp.Regexp
+	since  time.Time
+	until  time.Time
+	follow bool
+	// The repository relative path that is followed, which changes to the
+	// name the file had before it was renamed or copied
+	followed string
+}
+
+func newLogFilter(g *Got, opts LogOptions) (*logFilter, error) {
+	if opts.Follow && len(opts.Paths) != 1 {
+		return nil, errors.New("--follow requires exactly one path")
+	}
+	f := &logFilter{g: g, since: opts.Since, until: opts.Until, follow: opts.Follow}
+	var err error
+	if opts.Author != "" {
+		f.author, err = regexp.Compile(opts.Author)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid --author pattern %s", opts.Author)
+		}
+	}
+	if opts.Grep != "" {
+		f.grep, err = regexp.Compile(opts.Grep)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid --grep pattern %s", opts.Grep)
+		}
+	}
+	if opts.Follow {
+		f.followed, err = g.repoRel(opts.Paths[0])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid path %s", opts.Paths[0])
+		}
+		f.paths = pathspec.Paths(f.followed)
+		return f, nil
+	}
+	f.paths, err = g.pathspec(opts.Paths)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *logFilter) matches(id objects.ID, c objects.Commit) (bool, error) {
+	if f.author != nil && !f.author.MatchString(c.Author) {
+		return false, nil
+	}
+	if f.grep != nil && !f.grep.MatchString(c.Message) {
+		return false, nil
+	}
+	if !f.since.IsZero() && c.Time().Before(f.since) {
+		return false, nil
+	}
+	if !f.until.IsZero() && c.Time().After(f.until) {
+		return false, nil
+	}
+	if f.paths.Empty() {
+		return true, nil
+	}
+	if f.follow {
+		return f.changesFollowedPath(id, c)
+	}
+	return f.changesPaths(id, c)
+}
+
+// Returns true if the commit changes the followed path compared to its first
+// parent. If the commit renamed or copied the file to the path, the path it
+// had before is followed from then on.
+func (f *logFilter) changesFollowedPath(id objects.ID, c objects.Commit) (bool, error) {
+	src, dst, err := f.g.parentSnapshots(id)
+	if err != nil {
+		return false, err
+	}
+	opts := f.g.Renames
+	opts.Detect = true
+	diffs, err := f.g.detectRenames(diffEntries(src.entries, dst.entries), src, dst, opts)
+	if err != nil {
+		return false, err
+	}
+	for _, fd := range diffs {
+		if fd.Path() != f.followed {
+			continue
+		}
+		if fd.EditType == diff.FileEditTypeRename || fd.EditType == diff.FileEditTypeCopy {
+			f.followed = fd.SrcPath
+			f.paths = pathspec.Paths(f.followed)
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// Returns true if the commit changes any of the filtered paths compared to
+// every one of its parents. A merge that takes the paths from one of its
+// parents unchanged does not change them.
+func (f *logFilter) changesPaths(id objects.ID, c objects.Commit) (bool, error) {
+	tree, err := f.g.Objects.GetTree(c.TreeID)
+	if err != nil {
+		return false, err
+	}
+	parents := c.Parents()
+	if len(parents) == 0 {
+		return f.anyMatch(diffTrees(nil, &tree)), nil
+	}
+	for _, p := range parents {
+		parentTree, err := f.g.commitTree(p)
+		if err != nil {
+			return false, err
+		}
+		if !f.anyMatch(diffTrees(parentTree, &tree)) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (f *logFilter) anyMatch(diffs []*diff.FileDiff) bool {
+	for _, d := range diffs {
+		if f.paths.Matches(d.Path()) || (d.SrcPath != "" && f.paths.Matches(d.SrcPath)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Adds the diffstat and/or patch of the changes the commit made to the paths
+// compared to its first parent.
+func (g *Got) addChangesToLogEntry(le *LogEntry, paths *pathspec.Pathspec, opts LogOptions) error {
+	src, dst, err := g.parentSnapshots(le.ID)
+	if err != nil {
+		return err
+	}
+	diffs := diffEntries(src.entries, dst.entries)
+	if opts.Follow {
+		// The rename is part of the changes to the followed path
+		renames := g.Renames
+		renames.Detect = true
+		diffs, err = g.detectRenames(diffs, src, dst, renames)
+		if err != nil {
+			return err
+		}
+		var matching []*diff.FileDiff
+		for _, fd := range diffs {
+			if paths.Matches(fd.Path()) {
+				matching = append(matching, fd)
+			}
+		}
+		diffs = matching
+	} else {
+		diffs, err = g.diffSnapshotFiles(src, dst, paths)
+		if err != nil {
+			return err
+		}
+	}
+
+	le.Stat = diff.Stats{}
+	buf := bytes.NewBuffer(nil)
+	for _, fd := range diffs {
+		a, err := g.blobContents(fd.SrcID)
+		if err != nil {
+			return err
+		}
+		b, err := g.blobContents(fd.DstID)
+		if err != nil {
+			return err
+		}
+		cd, err := g.diffContents(fd, a, b)
+		if err != nil {
+			return err
+		}
+		le.Stat = append(le.Stat, cd.stat())
+		cd.writePatch(buf)
+	}
+	if !opts.Stat {
+		le.Stat = nil
+	}
+	if opts.Patch {
+		le.Patch = buf.String()
+	}
+	return nil
+}
+
+// Returns the names of the refs pointing at each commit
+func (g *Got) decorations() (map[objects.ID][]string, error) {
+	decorations := make(map[objects.ID][]string)
+	branches, err := g.Refs.Branches()
+	if err != nil {
+		return nil, err
+	}
+	headType, err := g.HeadType()
+	if err != nil {
+		return nil, err
+	}
+	var headBranch string
+	switch headType {
+	case HeadTypeRef:
+		ref, err := g.HeadAsRef()
+		if err != nil {
+			return nil, err
+		}
+		headBranch = ref.Name()
+	case HeadTypeID:
+		id, err := g.HeadAsID()
+		if err != nil {
+			return nil, err
+		}
+		decorations[id] = append(decorations[id], "HEAD")
+	}
+	for _, b := range branches {
+		id, err := g.Refs.IdAtBranch(b)
+		if err != nil {
+			return nil, err
+		}
+		if b == headBranch {
+			decorations[id] = append([]string{"HEAD -> " + b}, decorations[id]...)
+			continue
+		}
+		decorations[id] = append(decorations[id], b)
+	}
+	return decorations, nil
+}
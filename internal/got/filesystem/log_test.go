@@ -0,0 +1,183 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"got/internal/objects"
+	"got/internal/objects/disk"
+	"got/internal/revwalk"
+)
+
+func logMessages(log Log) []string {
+	var messages []string
+	for _, le := range log {
+		messages = append(messages, le.Commit.Message)
+	}
+	return messages
+}
+
+func TestLogMaxCountStopsWalk(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	commitFiles(t, g, "first", map[string]string{"a.txt": "1"})
+	first, err := g.ResolveRevision("HEAD")
+	assert.Nil(t, err)
+	commitFiles(t, g, "second", map[string]string{"a.txt": "2"})
+	commitFiles(t, g, "third", map[string]string{"a.txt": "3"})
+	commitFiles(t, g, "fourth", map[string]string{"a.txt": "4"})
+
+	// Reading the first commit fails, so only a walk that stops early
+	// succeeds
+	assert.Nil(t, os.Remove(objectFile(g, first)))
+
+	log, err := g.Log(LogOptions{MaxCount: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"fourth", "third"}, logMessages(log))
+
+	log, err = g.Log(LogOptions{MaxCount: 1, Grep: "^s"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"second"}, logMessages(log))
+
+	_, err = g.Log(LogOptions{})
+	assert.NotNil(t, err)
+}
+
+func objectFile(g *Got, id objects.ID) string {
+	return filepath.Join(g.gotDir, disk.ObjectsDir, string(id)[:2], string(id)[2:])
+}
+
+// Stores a commit of a tree with the given files, made by the author at the
+// given Unix time, without touching the index or the working tree
+func storeCommit(t *testing.T, g *Got, message, author string, secs int64, files map[string]string, parents ...objects.ID) objects.ID {
+	var tree objects.Tree
+	for path, contents := range files {
+		id, err := g.Objects.StoreBlobFrom(strings.NewReader(contents))
+		assert.Nil(t, err)
+		tree.Entries = append(tree.Entries, objects.TreeEntry{Mode: 0644, Type: objects.TypeBlob, Name: path, ID: id})
+	}
+	sort.Slice(tree.Entries, func(i, j int) bool { return tree.Entries[i].Name < tree.Entries[j].Name })
+	assert.Nil(t, g.Objects.Store(tree))
+	id, err := g.commitParentsAs(message, tree.ID(), parents, formatAuthor(author, strings.ToLower(author)+"@example.com", time.Unix(secs, 0).UTC()))
+	assert.Nil(t, err)
+	return id
+}
+
+// Builds the history
+//
+//	add a - add b - change a - merge side
+//	             \            /
+//	              side c -----
+//
+// where the merge also adds d.txt, which neither of its parents has.
+func logHistory(t *testing.T, g *Got) map[string]objects.ID {
+	ids := make(map[string]objects.ID)
+	ids["add a"] = storeCommit(t, g, "add a", "Alice", 1000, map[string]string{"a.txt": "1"})
+	ids["add b"] = storeCommit(t, g, "add b", "Bob", 2000, map[string]string{"a.txt": "1", "b.txt": "1"}, ids["add a"])
+	ids["side c"] = storeCommit(t, g, "side c", "Bob", 2500, map[string]string{"a.txt": "1", "b.txt": "1", "c.txt": "1"}, ids["add b"])
+	ids["change a"] = storeCommit(t, g, "change a", "Alice", 3000, map[string]string{"a.txt": "2", "b.txt": "1"}, ids["add b"])
+	ids["merge side"] = storeCommit(t, g, "merge side", "Alice", 4000,
+		map[string]string{"a.txt": "2", "b.txt": "1", "c.txt": "1", "d.txt": "1"}, ids["change a"], ids["side c"])
+	master, err := g.Refs.CreateBranchAt("master", ids["merge side"])
+	assert.Nil(t, err)
+	assert.Nil(t, g.updateHeadWithRef(master))
+	_, err = g.Refs.CreateBranchAt("side", ids["side c"])
+	assert.Nil(t, err)
+	return ids
+}
+
+func TestLogFilters(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	ids := logHistory(t, g)
+
+	tests := []struct {
+		name     string
+		opts     LogOptions
+		expected []string
+	}{
+		{"all", LogOptions{}, []string{"merge side", "change a", "side c", "add b", "add a"}},
+		{"range", LogOptions{Revisions: []string{string(ids["change a"]) + "..master"}}, []string{"merge side", "side c"}},
+		{"exclude", LogOptions{Revisions: []string{"master", "^side"}}, []string{"merge side", "change a"}},
+		{"path", LogOptions{Paths: []string{"a.txt"}}, []string{"change a", "add a"}},
+		// The merge takes c.txt from the side branch unchanged
+		{"path from a merged branch", LogOptions{Paths: []string{"c.txt"}}, []string{"side c"}},
+		// but d.txt differs from both of its parents
+		{"path added by a merge", LogOptions{Paths: []string{"d.txt"}}, []string{"merge side"}},
+		{"author", LogOptions{Author: "Bob"}, []string{"side c", "add b"}},
+		{"grep", LogOptions{Grep: "^add"}, []string{"add b", "add a"}},
+		{"since", LogOptions{Since: time.Unix(2500, 0)}, []string{"merge side", "change a", "side c"}},
+		{"until", LogOptions{Until: time.Unix(2000, 0)}, []string{"add b", "add a"}},
+		{"author and path", LogOptions{Author: "Alice", Paths: []string{"a.txt"}}, []string{"change a", "add a"}},
+	}
+	for _, test := range tests {
+		log, err := g.Log(test.opts)
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.expected, logMessages(log), test.name)
+	}
+}
+
+func TestLogDecorate(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	ids := logHistory(t, g)
+
+	log, err := g.Log(LogOptions{Decorate: true})
+	assert.Nil(t, err)
+	decorations := make(map[objects.ID][]string)
+	for _, le := range log {
+		if le.Decorations != nil {
+			decorations[le.ID] = le.Decorations
+		}
+	}
+	assert.Equal(t, map[objects.ID][]string{
+		ids["merge side"]: {"HEAD -> master"},
+		ids["side c"]:     {"side"},
+	}, decorations)
+}
+
+func TestLogMaxCountReverse(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	commitFiles(t, g, "first", map[string]string{"a.txt": "1"})
+	commitFiles(t, g, "second", map[string]string{"a.txt": "2"})
+	commitFiles(t, g, "third", map[string]string{"a.txt": "3"})
+
+	log, err := g.Log(LogOptions{MaxCount: 2, Sort: revwalk.SortDate | revwalk.SortReverse})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"second", "third"}, logMessages(log))
+
+	log, err = g.Log(LogOptions{MaxCount: 1, Sort: revwalk.SortTopological | revwalk.SortReverse})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"third"}, logMessages(log))
+}
+
+func TestLogGraphOfFilteredLog(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	commitFiles(t, g, "first", map[string]string{"a.txt": "1"})
+	commitFiles(t, g, "second", map[string]string{"a.txt": "2"})
+	commitFiles(t, g, "third", map[string]string{"a.txt": "3"})
+	commitFiles(t, g, "fourth", map[string]string{"b.txt": "1"})
+	commitFiles(t, g, "fifth", map[string]string{"a.txt": "4"})
+
+	log, err := g.Log(LogOptions{Paths: []string{"a.txt"}, Sort: revwalk.SortTopological})
+	assert.Nil(t, err)
+	assert.Equal(t, "* fifth\n* third\n* second\n* first\n", log.Format("tformat:%s", true))
+
+	// Commits that aren't shown are skipped over to their ancestors, which
+	// keeps the lines of the merged branch
+	g2, cleanup2 := newTestRepo(t)
+	defer cleanup2()
+	ids := logHistory(t, g2)
+	log, err = g2.Log(LogOptions{Grep: "^(add|merge|side)", Sort: revwalk.SortTopological})
+	assert.Nil(t, err)
+	assert.Equal(t, []objects.ID{ids["add b"], ids["side c"]}, log[0].Parents)
+	assert.Equal(t, "* merge side\n|\\\n| * side c\n|/\n* add b\n* add a\n", log.Format("tformat:%s", true))
+}
@@ -0,0 +1,334 @@
+package filesystem
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"got/internal/objects"
+)
+
+// The commit being merged while a merge with conflicts is resolved. The next
+// commit becomes a merge commit with it as the second parent.
+const mergeHeadFile = "MERGE_HEAD"
+
+type MergeResult struct {
+	// Set if HEAD already contained the merged commit
+	UpToDate bool
+	// Set if HEAD was moved to the merged commit as that contained HEAD
+	FastForward bool
+	// The commit HEAD is at after the merge
+	ID objects.ID
+	// The files with conflicts, which are left in the working tree with
+	// conflict markers to be resolved and committed
+	Conflicts []string
+}
+
+// Merges the commit into HEAD, fast-forwarding if possible and otherwise
+// committing the merge with the given message. Conflicts are left in the
+// working tree instead. theirs names the commit in conflict markers.
+// noVerify skips the pre-merge-commit hook.
+func (g *Got) merge(id objects.ID, theirs, message string, noVerify bool) (*MergeResult, error) {
+	result, err := g.mergeCommit(id, theirs, message, noVerify)
+	if err != nil || result.UpToDate || len(result.Conflicts) > 0 {
+		return result, err
+	}
+	// The merge is done whatever the hook returns
+	_ = g.runHook(hookPostMerge, nil, "0")
+	return result, nil
+}
+
+func (g *Got) mergeCommit(id objects.ID, theirs, message string, noVerify bool) (*MergeResult, error) {
+	statusTree, err := g.statusTree()
+	if err != nil {
+		return nil, err
+	}
+	if statusTree.HasChanges() {
+		return nil, errors.New("your local changes would be overwritten by merge, commit them first")
+	}
+	headID, err := g.idAtHead()
+	if err != nil {
+		return nil, err
+	}
+	theirsTree, err := g.commitTree(id)
+	if err != nil {
+		return nil, err
+	}
+	if headID == nil {
+		err = g.switchTrees(nil, treeEntryMap(theirsTree))
+		if err == nil {
+			err = g.moveHead(id)
+		}
+		return &MergeResult{FastForward: true, ID: id}, err
+	}
+
+	w := g.RevWalker()
+	if *headID == id {
+		return &MergeResult{UpToDate: true, ID: id}, nil
+	}
+	if ok, err := w.IsAncestor(id, *headID); err != nil || ok {
+		return &MergeResult{UpToDate: true, ID: *headID}, err
+	}
+	oursTree, err := g.commitTree(*headID)
+	if err != nil {
+		return nil, err
+	}
+	ours := treeEntryMap(oursTree)
+	if ok, err := w.IsAncestor(*headID, id); err != nil {
+		return nil, err
+	} else if ok {
+		err = g.switchTrees(ours, treeEntryMap(theirsTree))
+		if err == nil {
+			err = g.moveHead(id)
+		}
+		return &MergeResult{FastForward: true, ID: id}, err
+	}
+
+	var base map[string]objects.TreeEntry
+	bases, err := w.MergeBases(*headID, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(bases) > 0 {
+		baseTree, err := g.commitTree(bases[0])
+		if err != nil {
+			return nil, err
+		}
+		base = treeEntryMap(baseTree)
+	}
+	merged, conflicts, err := g.mergeTrees(base, ours, treeEntryMap(theirsTree), theirs)
+	if err != nil {
+		return nil, err
+	}
+	err = g.switchTrees(ours, merged)
+	if err != nil {
+		return nil, err
+	}
+	result := &MergeResult{ID: *headID}
+	if len(conflicts) > 0 {
+		for path, contents := range conflicts {
+			result.Conflicts = append(result.Conflicts, path)
+			if contents == nil {
+				continue
+			}
+			err = g.writeWorkingTreeFile(path, contents, merged[path].Mode)
+			if err != nil {
+				return nil, err
+			}
+		}
+		sort.Strings(result.Conflicts)
+		err = ioutil.WriteFile(filepath.Join(g.gotDir, mergeHeadFile), []byte(id), os.ModePerm)
+		return result, err
+	}
+	if !noVerify {
+		err = g.runHook(hookPreMergeCommit, nil)
+		if err != nil {
+			// Like a merge with conflicts the merged files are left to be
+			// committed
+			if err := ioutil.WriteFile(filepath.Join(g.gotDir, mergeHeadFile), []byte(id), os.ModePerm); err != nil {
+				return nil, err
+			}
+			return nil, errors.Wrap(err, "not committing merge, commit to conclude it")
+		}
+	}
+	treeID, err := g.WriteTree()
+	if err != nil {
+		return nil, err
+	}
+	result.ID, err = g.commitParentsAs(message, treeID, []objects.ID{*headID, id}, g.author(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	return result, g.moveHead(result.ID)
+}
+
+// Merges the changes that ours and theirs made to the files of base.
+// Returns the merged files and the conflicts by path. The contents of a
+// conflict are the file with conflict markers, or nil if one side deleted
+// the file the other modified, in which case the modified file is kept, or
+// if the merge driver of the file doesn't mark conflicts. Conflicting files
+// are merged as ours.
+func (g *Got) mergeTrees(base, ours, theirs map[string]objects.TreeEntry, theirsName string) (map[string]objects.TreeEntry, map[string][]byte, error) {
+	merged := make(map[string]objects.TreeEntry)
+	conflicts := make(map[string][]byte)
+	paths := make(map[string]bool)
+	for _, entries := range []map[string]objects.TreeEntry{base, ours, theirs} {
+		for path := range entries {
+			paths[path] = true
+		}
+	}
+	for path := range paths {
+		b, inBase := base[path]
+		o, inOurs := ours[path]
+		t, inTheirs := theirs[path]
+		switch {
+		case sameEntry(o, inOurs, t, inTheirs), sameEntry(b, inBase, t, inTheirs):
+			if inOurs {
+				merged[path] = o
+			}
+		case sameEntry(b, inBase, o, inOurs):
+			if inTheirs {
+				merged[path] = t
+			}
+		case !inOurs || !inTheirs:
+			// Deleted on one side and modified on the other
+			if inOurs {
+				merged[path] = o
+			} else {
+				merged[path] = t
+			}
+			conflicts[path] = nil
+		default:
+			var baseContents []byte
+			var err error
+			if inBase {
+				baseContents, err = g.blobContents(b.ID)
+				if err != nil {
+					return nil, nil, err
+				}
+			}
+			oursContents, err := g.blobContents(o.ID)
+			if err != nil {
+				return nil, nil, err
+			}
+			theirsContents, err := g.blobContents(t.ID)
+			if err != nil {
+				return nil, nil, err
+			}
+			contents, conflict, err := g.mergeFile(path, baseContents, oursContents, theirsContents, theirsName)
+			if err != nil {
+				return nil, nil, err
+			}
+			if conflict {
+				merged[path] = o
+				conflicts[path] = contents
+				continue
+			}
+			id, err := g.Objects.StoreBlobFrom(bytes.NewReader(contents))
+			if err != nil {
+				return nil, nil, err
+			}
+			e := o
+			if inBase && o.Mode == b.Mode {
+				e.Mode = t.Mode
+			}
+			e.ID = id
+			merged[path] = e
+		}
+	}
+	return merged, conflicts, nil
+}
+
+func sameEntry(a objects.TreeEntry, aExists bool, b objects.TreeEntry, bExists bool) bool {
+	if !aExists || !bExists {
+		return aExists == bExists
+	}
+	return a.ID == b.ID && a.Mode == b.Mode
+}
+
+// Makes the index and the working tree, which match the files from, match
+// the files to, only writing the files that differ. Untracked files in the
+// way aren't overwritten.
+func (g *Got) switchTrees(from, to map[string]objects.TreeEntry) error {
+	var changed []objects.TreeEntry
+	for path, te := range to {
+		if fe, ok := from[path]; ok && fe.ID == te.ID && fe.Mode == te.Mode {
+			continue
+		}
+		if _, tracked := from[path]; !tracked {
+			if _, err := os.Lstat(filepath.Join(g.dir, path)); err == nil {
+				return errors.Errorf("untracked working tree file '%s' would be overwritten", path)
+			}
+		}
+		changed = append(changed, te)
+	}
+	for path := range from {
+		if _, ok := to[path]; ok {
+			continue
+		}
+		err := g.Index.RemoveFile(path)
+		if err == nil {
+			err = g.removeFromWorkingTree(path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, te := range changed {
+		err := g.writeBlobToFile(te.ID, te.Name, te.Mode)
+		if err != nil {
+			return err
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	return g.Index.AddTreeContents(objects.Tree{Entries: changed})
+}
+
+// Moves the branch HEAD is on, or HEAD itself if it's detached, to the
+// commit. Before the first commit the initial branch is created.
+func (g *Got) moveHead(id objects.ID) error {
+	headType, err := g.HeadType()
+	if err != nil {
+		return err
+	}
+	switch headType {
+	case HeadTypeRef:
+		ref, err := g.HeadAsRef()
+		if err != nil {
+			return err
+		}
+		return g.Refs.UpdateRef(ref, id)
+	case HeadTypeID:
+		return g.updateHeadWithID(id)
+	}
+	ref, err := g.Refs.CreateBranchAt(g.initialBranch(), id)
+	if err != nil {
+		return err
+	}
+	return g.updateHeadWithRef(ref)
+}
+
+// Returns the commit of a merge with conflicts that's being resolved, empty
+// if there is none
+func (g *Got) mergeHead() (objects.ID, error) {
+	bs, err := ioutil.ReadFile(filepath.Join(g.gotDir, mergeHeadFile))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return objects.IdFromString(strings.TrimSpace(string(bs)))
+}
+
+// Stores a commit of the tree with the given parents, the first parent
+// first
+func (g *Got) commitParentsAs(msg string, treeID objects.ID, parents []objects.ID, author string) (objects.ID, error) {
+	if len(parents) < 2 {
+		var parentID *objects.ID
+		if len(parents) == 1 {
+			parentID = &parents[0]
+		}
+		return g.commitTreeAs(msg, treeID, parentID, author)
+	}
+	commit := objects.NewMergeCommit(treeID, parents, author, msg)
+	fmt.Printf("Committing %s with parents %s\n", treeID, strings.Join(idStrings(parents), " "))
+	return commit.ID(), g.Objects.Store(commit)
+}
+
+func idStrings(ids []objects.ID) []string {
+	var ss []string
+	for _, id := range ids {
+		ss = append(ss, string(id))
+	}
+	return ss
+}
@@ -0,0 +1,95 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"got/internal/index"
+)
+
+// Moves or renames tracked files and directories in the working tree and
+// the index. With a single source dst is its new name, unless dst is an
+// existing directory, which the sources are moved into. An existing file at
+// the destination is only overwritten if forced. Paths are relative to the
+// working directory.
+func (g *Got) Move(srcs []string, dst string, force bool) error {
+	dstRel, err := g.repoRel(dst)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't move to %s", dst)
+	}
+	info, err := os.Stat(filepath.Join(g.dir, dstRel))
+	intoDir := err == nil && info.IsDir()
+	if len(srcs) > 1 && !intoDir {
+		return errors.Errorf("destination '%s' is not a directory", dst)
+	}
+	for _, src := range srcs {
+		srcRel, err := g.repoRel(src)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't move %s", src)
+		}
+		target := dstRel
+		if intoDir {
+			target = filepath.Join(dstRel, filepath.Base(srcRel))
+		}
+		err = g.moveOne(srcRel, target, force)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't move %s to %s", srcRel, target)
+		}
+	}
+	return nil
+}
+
+// Moves a tracked file, or a directory with tracked files, from one
+// repository relative path to another
+func (g *Got) moveOne(src, dst string, force bool) error {
+	srcInfo, err := os.Stat(filepath.Join(g.dir, src))
+	if err != nil {
+		return errors.New("bad source")
+	}
+	if src == dst || strings.HasPrefix(dst, src+string(filepath.Separator)) {
+		return errors.New("can't move a directory into itself")
+	}
+	var entries []index.Entry
+	for _, e := range g.Index.SortedEntries() {
+		if e.Name == src || strings.HasPrefix(e.Name, src+string(filepath.Separator)) {
+			entries = append(entries, e)
+		}
+	}
+	if len(entries) == 0 {
+		return errors.New("not under version control")
+	}
+
+	dstInfo, err := os.Stat(filepath.Join(g.dir, dst))
+	if err == nil {
+		if srcInfo.IsDir() || dstInfo.IsDir() {
+			return errors.New("destination already exists")
+		}
+		if !force {
+			return errors.New("destination exists, use -f to overwrite it")
+		}
+	}
+	if _, err := os.Stat(filepath.Dir(filepath.Join(g.dir, dst))); err != nil {
+		return errors.New("destination directory does not exist")
+	}
+	err = os.Rename(filepath.Join(g.dir, src), filepath.Join(g.dir, dst))
+	if err != nil {
+		return err
+	}
+
+	// The entries keep what's staged for them, only their names change
+	for _, e := range entries {
+		err = g.Index.RemoveFile(e.Name)
+		if err != nil {
+			return err
+		}
+		e.Name = dst + strings.TrimPrefix(e.Name, src)
+		err = g.Index.AddEntry(e)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
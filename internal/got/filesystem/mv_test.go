@@ -0,0 +1,56 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMove(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	commitFiles(t, g, "first", map[string]string{"a.txt": "a\n", "b.txt": "b\n", "dir/c.txt": "c\n"})
+
+	assert.Nil(t, g.Move([]string{"a.txt"}, "renamed.txt", false))
+	assert.Equal(t, "a\n", readFile(t, g, "renamed.txt"))
+	assert.True(t, g.Index.HasEntryFor("renamed.txt"))
+	assert.False(t, g.Index.HasEntryFor("a.txt"))
+
+	assert.Nil(t, g.Move([]string{"renamed.txt", "b.txt"}, "dir", false))
+	assert.True(t, g.Index.HasEntryFor(filepath.Join("dir", "renamed.txt")))
+	assert.True(t, g.Index.HasEntryFor(filepath.Join("dir", "b.txt")))
+
+	assert.Nil(t, g.Move([]string{"dir"}, "moved", false))
+	assert.True(t, g.Index.HasEntryFor(filepath.Join("moved", "c.txt")))
+	assert.Equal(t, "c\n", readFile(t, g, "moved/c.txt"))
+}
+
+func TestMoveErrors(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	commitFiles(t, g, "first", map[string]string{"a.txt": "a\n", "b.txt": "b\n", "dir/c.txt": "c\n"})
+	writeFiles(t, g, map[string]string{"untracked.txt": ""})
+
+	tests := []struct {
+		srcs []string
+		dst  string
+		err  string
+	}{
+		{[]string{"a.txt"}, "missing/a.txt", "couldn't move a.txt to missing/a.txt: destination directory does not exist"},
+		{[]string{"a.txt"}, "b.txt", "couldn't move a.txt to b.txt: destination exists, use -f to overwrite it"},
+		{[]string{"missing.txt"}, "x.txt", "couldn't move missing.txt to x.txt: bad source"},
+		{[]string{"untracked.txt"}, "x.txt", "couldn't move untracked.txt to x.txt: not under version control"},
+		{[]string{"dir"}, "dir/sub", "couldn't move dir to dir/sub: can't move a directory into itself"},
+		{[]string{"a.txt", "b.txt"}, "x.txt", "destination 'x.txt' is not a directory"},
+	}
+	for _, tt := range tests {
+		assert.EqualError(t, g.Move(tt.srcs, tt.dst, false), tt.err)
+	}
+	_, err := os.Stat(filepath.Join(g.dir, "missing"))
+	assert.True(t, os.IsNotExist(err))
+
+	assert.Nil(t, g.Move([]string{"a.txt"}, "b.txt", true))
+	assert.Equal(t, "a\n", readFile(t, g, "b.txt"))
+}
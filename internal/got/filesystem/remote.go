@@ -0,0 +1,500 @@
+package filesystem
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"got/internal/objects"
+	"got/internal/objects/disk"
+	"got/internal/refs"
+	"got/internal/transport"
+	"got/internal/transport/http"
+	"got/internal/transport/local"
+)
+
+// Adds a remote repository under a name, whose branches are fetched into
+// the remote-tracking branches refs/remotes/<name>/*
+func (g *Got) RemoteAdd(name, url string) error {
+	if g.RemoteURL(name) != "" {
+		return errors.Errorf("remote %s already exists", name)
+	}
+	if name == "" || strings.ContainsAny(name, "/. \t") {
+		return errors.Errorf("'%s' is not a valid remote name", name)
+	}
+	err := g.Config.Set("remote."+name+".url", url)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't add remote %s", name)
+	}
+	err = g.Config.Set("remote."+name+".fetch", "+refs/heads/*:refs/remotes/"+name+"/*")
+	if err != nil {
+		return errors.Wrapf(err, "couldn't add remote %s", name)
+	}
+	return nil
+}
+
+// Removes a remote together with its remote-tracking branches and the
+// upstream configuration of the branches that track it
+func (g *Got) RemoteRemove(name string) error {
+	if g.RemoteURL(name) == "" {
+		return errors.Errorf("no such remote: '%s'", name)
+	}
+	for _, branch := range g.Config.Subsections("branch") {
+		if g.Config.GetString("branch."+branch+".remote", "") != name {
+			continue
+		}
+		err := g.Config.Unset("branch." + branch + ".remote")
+		if err == nil {
+			err = g.Config.Unset("branch." + branch + ".merge")
+		}
+		if err != nil {
+			return errors.Wrapf(err, "couldn't remove remote %s", name)
+		}
+	}
+	err := g.Config.RemoveSection("remote." + name)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't remove remote %s", name)
+	}
+	return g.Refs.DeleteRemoteRefs(name)
+}
+
+// Returns the names of the remotes, sorted
+func (g *Got) Remotes() []string {
+	var names []string
+	for _, name := range g.Config.Subsections("remote") {
+		if g.RemoteURL(name) != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Returns the URL of a remote, empty if there is no such remote
+func (g *Got) RemoteURL(name string) string {
+	return g.Config.GetString("remote."+name+".url", "")
+}
+
+// Returns the remote the checked out branch has as its upstream, origin if
+// it has none
+func (g *Got) DefaultRemote() string {
+	if ref, err := g.HeadAsRef(); err == nil {
+		if remote := g.Config.GetString("branch."+ref.Name()+".remote", ""); remote != "" && remote != "." {
+			return remote
+		}
+	}
+	return "origin"
+}
+
+// Connects to the repository at url, a path or a URL served by got serve
+func openRemote(url string) (transport.Remote, error) {
+	if http.IsURL(url) {
+		return http.Open(url), nil
+	}
+	return local.Open(url)
+}
+
+// Returns the objects of the repository as they're copied to and from
+// remotes
+func (g *Got) store() transport.Store {
+	return disk.NewObjects(g.gotDir)
+}
+
+// A remote-tracking branch changed by a fetch
+type FetchedRef struct {
+	// The name of the branch on the remote, e.g. 'master'
+	Branch string
+	// The name of the remote-tracking branch, e.g. 'origin/master'
+	Tracking string
+	// Where the remote-tracking branch was before, empty if it's new, and
+	// where it is now, empty if it was pruned
+	Old, New objects.ID
+	// Set if New doesn't contain Old
+	Forced bool
+}
+
+// Copies the objects of the branches of the remote that are missing and
+// updates the remote-tracking branches. With prune the remote-tracking
+// branches of branches that no longer exist are removed.
+func (g *Got) Fetch(remote string, prune bool) ([]FetchedRef, error) {
+	fetched, _, err := g.fetch(remote, prune)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't fetch %s", remote)
+	}
+	return fetched, nil
+}
+
+func (g *Got) fetch(remote string, prune bool) ([]FetchedRef, *transport.Advertisement, error) {
+	url := g.RemoteURL(remote)
+	if url == "" {
+		return nil, nil, errors.Errorf("'%s' does not appear to be a got repository", remote)
+	}
+	r, err := openRemote(url)
+	if err != nil {
+		return nil, nil, err
+	}
+	ad, err := r.Advertise()
+	if err != nil {
+		return nil, nil, err
+	}
+	tracking, err := g.Refs.RemoteBranches(remote)
+	if err != nil {
+		return nil, nil, err
+	}
+	store := g.store()
+	var wants, haves []objects.ID
+	for _, id := range ad.Branches {
+		if !store.Has(id) {
+			wants = append(wants, id)
+		}
+	}
+	for _, id := range tracking {
+		haves = append(haves, id)
+	}
+	if branches, err := g.Refs.Branches(); err == nil {
+		for _, b := range branches {
+			if id, err := g.Refs.IdAtBranch(b); err == nil {
+				haves = append(haves, id)
+			}
+		}
+	}
+	if len(wants) > 0 {
+		err = r.Fetch(store, wants, haves)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var fetched []FetchedRef
+	for branch, id := range ad.Branches {
+		old := tracking[branch]
+		if old == id {
+			continue
+		}
+		f := FetchedRef{Branch: branch, Tracking: remote + "/" + branch, Old: old, New: id}
+		if old != "" {
+			ok, err := g.RevWalker().IsAncestor(old, id)
+			f.Forced = err != nil || !ok
+		}
+		err = g.Refs.UpdateRef(refs.RemoteRef(remote, branch), id)
+		if err != nil {
+			return nil, nil, err
+		}
+		fetched = append(fetched, f)
+	}
+	if prune {
+		for branch, old := range tracking {
+			if _, ok := ad.Branches[branch]; ok {
+				continue
+			}
+			err = g.Refs.DeleteRemoteRef(remote, branch)
+			if err != nil {
+				return nil, nil, err
+			}
+			fetched = append(fetched, FetchedRef{Branch: branch, Tracking: remote + "/" + branch, Old: old})
+		}
+	}
+	sort.Slice(fetched, func(i, j int) bool {
+		return fetched[i].Branch < fetched[j].Branch
+	})
+	return fetched, ad, nil
+}
+
+type PushOptions struct {
+	// Update the branches of the remote even if that loses commits
+	Force bool
+	// Make the remote branches the upstreams of the pushed branches
+	SetUpstream bool
+	// Skip the pre-push hook
+	NoVerify bool
+}
+
+// A branch of the remote that was pushed to
+type PushedRef struct {
+	// The pushed revision, e.g. 'master'
+	Src string
+	transport.Result
+}
+
+// Reports whether the branch already was at the pushed commit
+func (p PushedRef) UpToDate() bool {
+	return p.Old == p.New
+}
+
+// Pushes revisions to branches of the remote. Refspecs are of the form
+// '[+]<src>[:<dst>]', where src is a revision that is pushed to the branch
+// dst, which is src if omitted, and '+' forces the update. A dst of HEAD
+// stands for the checked out branch. Without refspecs the checked out branch
+// is pushed to its upstream branch or the branch of the same name. Branches
+// are only updated if that doesn't lose commits on them, unless forced.
+func (g *Got) Push(remote string, refspecs []string, opts PushOptions) ([]PushedRef, error) {
+	pushed, err := g.push(remote, refspecs, opts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't push to %s", remote)
+	}
+	return pushed, nil
+}
+
+func (g *Got) push(remote string, refspecs []string, opts PushOptions) ([]PushedRef, error) {
+	url := g.RemoteURL(remote)
+	if url == "" {
+		return nil, errors.Errorf("'%s' does not appear to be a got repository", remote)
+	}
+	if len(refspecs) == 0 {
+		ref, err := g.HeadAsRef()
+		if err != nil {
+			return nil, errors.New("you are not currently on a branch")
+		}
+		refspec := ref.Name()
+		if g.Config.GetString("branch."+ref.Name()+".remote", "") == remote {
+			merge := g.Config.GetString("branch."+ref.Name()+".merge", "")
+			if merge != "" {
+				refspec += ":" + strings.TrimPrefix(merge, refs.Dir+"/"+refs.HeadsDir+"/")
+			}
+		}
+		refspecs = []string{refspec}
+	}
+	r, err := openRemote(url)
+	if err != nil {
+		return nil, err
+	}
+	ad, err := r.Advertise()
+	if err != nil {
+		return nil, err
+	}
+
+	var pushed []PushedRef
+	var updates []transport.Update
+	for _, spec := range refspecs {
+		force := opts.Force || strings.HasPrefix(spec, "+")
+		spec = strings.TrimPrefix(spec, "+")
+		src, dst := spec, spec
+		if i := strings.Index(spec, ":"); i >= 0 {
+			src, dst = spec[:i], spec[i+1:]
+		}
+		dst = strings.TrimPrefix(dst, refs.Dir+"/"+refs.HeadsDir+"/")
+		if dst == "HEAD" {
+			// HEAD is pushed to the branch it's on
+			ref, err := g.HeadAsRef()
+			if err != nil {
+				return nil, errors.New("HEAD does not refer to a branch")
+			}
+			dst = ref.Name()
+		}
+		id, err := g.ResolveRevision(src)
+		if err != nil {
+			return nil, errors.Errorf("src refspec %s does not match any", src)
+		}
+		u := transport.Update{Branch: dst, Old: ad.Branches[dst], New: id, Force: force}
+		p := PushedRef{Src: src, Result: transport.Result{Update: u}}
+		if u.Old != u.New {
+			updates = append(updates, u)
+		}
+		pushed = append(pushed, p)
+	}
+	if len(updates) > 0 && !opts.NoVerify {
+		err = g.runPrePushHook(remote, url, pushed)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(updates) > 0 {
+		results, err := r.Push(g.store(), updates)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range results {
+			for i := range pushed {
+				if pushed[i].Branch == result.Branch {
+					pushed[i].Result = result
+				}
+			}
+		}
+	}
+
+	for _, p := range pushed {
+		if p.Rejected != "" {
+			continue
+		}
+		err = g.Refs.UpdateRef(refs.RemoteRef(remote, p.Branch), p.New)
+		if err != nil {
+			return nil, err
+		}
+		if opts.SetUpstream && g.Refs.BranchExists(p.Src) {
+			err = g.setUpstream(p.Src, remote, p.Branch)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return pushed, nil
+}
+
+// Runs the pre-push hook with a line per ref that changes
+func (g *Got) runPrePushHook(remote, url string, pushed []PushedRef) error {
+	var lines strings.Builder
+	for _, p := range pushed {
+		if p.UpToDate() {
+			continue
+		}
+		local := p.Src
+		if g.Refs.BranchExists(p.Src) {
+			local = refs.Dir + "/" + refs.HeadsDir + "/" + p.Src
+		}
+		old := string(p.Old)
+		if old == "" {
+			old = nullID
+		}
+		fmt.Fprintf(&lines, "%s %s %s/%s/%s %s\n", local, p.New, refs.Dir, refs.HeadsDir, p.Branch, old)
+	}
+	return g.runHook(hookPrePush, strings.NewReader(lines.String()), remote, url)
+}
+
+// Makes a branch of a remote the upstream of a local branch
+func (g *Got) setUpstream(branch, remote, remoteBranch string) error {
+	err := g.Config.Set("branch."+branch+".remote", remote)
+	if err != nil {
+		return err
+	}
+	return g.Config.Set("branch."+branch+".merge", refs.Dir+"/"+refs.HeadsDir+"/"+remoteBranch)
+}
+
+type PullOptions struct {
+	// Skip the pre-merge-commit hook
+	NoVerify bool
+}
+
+// Fetches the remote and merges a branch of it into HEAD, by default the
+// upstream of the checked out branch or the branch of the same name
+func (g *Got) Pull(remote, branch string, opts PullOptions) ([]FetchedRef, *MergeResult, error) {
+	fetched, ad, err := g.fetch(remote, false)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "couldn't pull from %s", remote)
+	}
+	if branch == "" {
+		branch, err = g.pullBranch(remote, ad)
+		if err != nil {
+			return fetched, nil, errors.Wrapf(err, "couldn't pull from %s", remote)
+		}
+	}
+	id, ok := ad.Branches[branch]
+	if !ok {
+		return fetched, nil, errors.Errorf("couldn't find remote ref %s", branch)
+	}
+	message := fmt.Sprintf("Merge branch '%s' of %s", branch, g.RemoteURL(remote))
+	result, err := g.merge(id, remote+"/"+branch, message, opts.NoVerify)
+	if err != nil {
+		return fetched, nil, errors.Wrapf(err, "couldn't merge %s/%s", remote, branch)
+	}
+	return fetched, result, nil
+}
+
+// Returns the branch of the remote that pull merges by default
+func (g *Got) pullBranch(remote string, ad *transport.Advertisement) (string, error) {
+	headType, err := g.HeadType()
+	if err != nil {
+		return "", err
+	}
+	if headType == HeadTypeEmpty {
+		if ad.Head == "" {
+			return "", errors.New("the remote has no branch to pull")
+		}
+		return ad.Head, nil
+	}
+	ref, err := g.HeadAsRef()
+	if err != nil {
+		return "", errors.New("you are not currently on a branch, name the branch to pull")
+	}
+	if g.Config.GetString("branch."+ref.Name()+".remote", "") == remote {
+		merge := g.Config.GetString("branch."+ref.Name()+".merge", "")
+		if merge != "" {
+			return strings.TrimPrefix(merge, refs.Dir+"/"+refs.HeadsDir+"/"), nil
+		}
+	}
+	return ref.Name(), nil
+}
+
+// Creates a repository in dir that has the repository at url as its remote
+// origin, fetches it and checks out the branch HEAD of the remote points at.
+// dir must not exist or be empty.
+func Clone(url, dir string) (*Got, error) {
+	g, err := clone(url, dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't clone %s", url)
+	}
+	return g, nil
+}
+
+func clone(url, dir string) (*Got, error) {
+	if entries, err := ioutil.ReadDir(dir); err == nil && len(entries) > 0 {
+		return nil, errors.Errorf("destination path '%s' already exists and is not an empty directory", dir)
+	}
+	if !strings.Contains(url, "://") {
+		abs, err := filepath.Abs(url)
+		if err != nil {
+			return nil, err
+		}
+		url = abs
+	}
+	// Fail before anything is created if there's no repository
+	r, err := openRemote(url)
+	if err == nil {
+		_, err = r.Advertise()
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+	_, err = Initialize(dir, InitOptions{})
+	if err != nil {
+		return nil, err
+	}
+	g, err := Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	err = g.RemoteAdd("origin", url)
+	if err != nil {
+		return nil, err
+	}
+	_, ad, err := g.fetch("origin", false)
+	if err != nil {
+		return nil, err
+	}
+	if ad.Head == "" {
+		return g, nil
+	}
+	id := ad.Branches[ad.Head]
+	tree, err := g.commitTree(id)
+	if err != nil {
+		return nil, err
+	}
+	err = g.switchTrees(nil, treeEntryMap(tree))
+	if err != nil {
+		return nil, err
+	}
+	ref, err := g.Refs.CreateBranchAt(ad.Head, id)
+	if err != nil {
+		return nil, err
+	}
+	err = g.updateHeadWithRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	err = g.setUpstream(ad.Head, "origin", ad.Head)
+	if err != nil {
+		return nil, err
+	}
+	// Only a global core.hooksPath can have hooks this early. The clone is
+	// done whatever the hook returns.
+	_ = g.runHook(hookPostCheckout, nil, nullID, string(id), "1")
+	return g, nil
+}
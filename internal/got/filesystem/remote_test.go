@@ -0,0 +1,47 @@
+package filesystem
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Adds a new bare repository in the working tree as the remote origin and
+// returns it
+func addTestRemote(t *testing.T, g *Got) *Got {
+	dir := filepath.Join(g.dir, ".remote")
+	_, err := Initialize(dir, InitOptions{Bare: true})
+	assert.Nil(t, err)
+	assert.Nil(t, g.RemoteAdd("origin", dir))
+	remote, err := Open(dir)
+	assert.Nil(t, err)
+	return remote
+}
+
+func TestPushHead(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	remote := addTestRemote(t, g)
+	commitFiles(t, g, "first", map[string]string{"a.txt": "a\n"})
+	assert.Nil(t, g.Checkout("feature", true))
+	head, err := g.ResolveRevision("HEAD")
+	assert.Nil(t, err)
+
+	pushed, err := g.Push("origin", []string{"HEAD"}, PushOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, pushed, 1)
+	assert.Equal(t, "feature", pushed[0].Branch)
+	assert.False(t, remote.Refs.BranchExists("HEAD"))
+	id, err := remote.Refs.IdAtBranch("feature")
+	assert.Nil(t, err)
+	assert.Equal(t, head, id)
+
+	pushed, err = g.Push("origin", []string{"HEAD:renamed"}, PushOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "renamed", pushed[0].Branch)
+
+	assert.Nil(t, g.updateHeadWithID(head))
+	_, err = g.Push("origin", []string{"HEAD"}, PushOptions{})
+	assert.EqualError(t, err, "couldn't push to origin: HEAD does not refer to a branch")
+}
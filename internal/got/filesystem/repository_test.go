@@ -0,0 +1,105 @@
+package filesystem
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Sets an environment variable and returns a function unsetting it again
+func setenv(t *testing.T, key, value string) func() {
+	assert.Nil(t, os.Setenv(key, value))
+	return func() { os.Unsetenv(key) }
+}
+
+func TestFindRepositoryFromSubdirectory(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	sub := filepath.Join(g.dir, "sub", "deeper")
+	assert.Nil(t, os.MkdirAll(sub, os.ModePerm))
+	assert.Nil(t, os.Chdir(sub))
+
+	found, err := NewGot()
+	assert.Nil(t, err)
+	assert.Equal(t, g.dir, found.dir)
+	assert.Equal(t, g.gotDir, found.gotDir)
+}
+
+func TestCeilingDirectories(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	sub := filepath.Join(g.dir, "sub")
+	assert.Nil(t, os.MkdirAll(sub, os.ModePerm))
+	defer setenv(t, EnvCeilingDirectories, "relative:"+g.dir)()
+
+	// The search doesn't go up into a ceiling directory, but starts in one
+	_, err := NewGot()
+	assert.Nil(t, err)
+	assert.Nil(t, os.Chdir(sub))
+	_, err = NewGot()
+	assert.EqualError(t, err, "repository not initialized")
+
+	os.Setenv(EnvCeilingDirectories, filepath.Dir(g.dir))
+	_, err = NewGot()
+	assert.Nil(t, err)
+}
+
+func TestIndexFile(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	commitFiles(t, g, "first", map[string]string{"a.txt": "a\n"})
+	writeFiles(t, g, map[string]string{"b.txt": "b\n"})
+	indexFile := filepath.Join(g.dir, "other-index")
+	defer setenv(t, EnvIndexFile, "other-index")()
+
+	// A missing index file starts out empty
+	other, err := NewGot()
+	assert.Nil(t, err)
+	assert.Empty(t, other.Index.SortedEntries())
+	assert.Nil(t, other.AddPath("b.txt"))
+	assert.FileExists(t, indexFile)
+
+	other, err = NewGot()
+	assert.Nil(t, err)
+	assert.True(t, other.Index.HasEntryFor("b.txt"))
+	assert.False(t, other.Index.HasEntryFor("a.txt"))
+
+	os.Unsetenv(EnvIndexFile)
+	g, err = NewGot()
+	assert.Nil(t, err)
+	assert.True(t, g.Index.HasEntryFor("a.txt"))
+	assert.False(t, g.Index.HasEntryFor("b.txt"))
+}
+
+func TestGotDirAndWorkTree(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	elsewhere, err := ioutil.TempDir("", "got-elsewhere")
+	assert.Nil(t, err)
+	defer os.RemoveAll(elsewhere)
+	elsewhere, err = filepath.EvalSymlinks(elsewhere)
+	assert.Nil(t, err)
+	assert.Nil(t, os.Chdir(elsewhere))
+
+	_, err = NewGot()
+	assert.EqualError(t, err, "repository not initialized")
+
+	// The working directory is the working tree unless one is given
+	defer setenv(t, EnvGotDir, g.gotDir)()
+	found, err := NewGot()
+	assert.Nil(t, err)
+	assert.Equal(t, g.gotDir, found.gotDir)
+	assert.Equal(t, elsewhere, found.dir)
+
+	defer setenv(t, EnvWorkTree, g.dir)()
+	found, err = NewGot()
+	assert.Nil(t, err)
+	assert.Equal(t, g.dir, found.dir)
+
+	os.Setenv(EnvGotDir, elsewhere)
+	_, err = NewGot()
+	assert.EqualError(t, err, "not a got repository: '"+elsewhere+"'")
+}
@@ -1,98 +1,75 @@
 package filesystem
 
 import (
-	"fmt"
-	"io/ioutil"
-	"os"
-	"path/filepath"
-
 	"github.com/pkg/errors"
+
+	"got/internal/index"
 )
 
+// Unstages the files matching the pathspecs, which are relative to the
+// working directory, by resetting their index entries to HEAD. Files that
+// aren't in HEAD are removed from the index.
 func (g *Got) UnstagePath(paths ...string) error {
-	var matches []string
-	for _, p := range paths {
-		ms, err := filepath.Glob(p)
-		if err != nil {
-			return errors.Wrapf(err, "couldn't unstage path %s", p)
-		}
-		matches = append(matches, ms...)
-	}
-	for _, m := range matches {
-		err := g.forAllFilesInRepo(m, func(localPath string, info os.FileInfo, err error) error {
-			return g.unstageFile(localPath)
-		})
-		if err != nil {
-			return errors.Wrapf(err, "couldn't unstage path %s", m)
-		}
-	}
-	return nil
-}
-
-func (g *Got) unstageFile(filename string) error {
-	rel, err := g.repoRel(filename)
+	ps, err := g.pathspec(paths)
 	if err != nil {
-		return errors.Wrapf(err, "couldn't unstage %s", filename)
+		return errors.Wrap(err, "couldn't unstage paths")
 	}
 	headTree, err := g.headTree()
 	if err != nil {
-		return errors.Wrapf(err, "couldn't unstage %s", rel)
+		return errors.Wrap(err, "couldn't unstage paths")
 	}
-	for _, te := range headTree.Entries {
-		if te.Name == rel {
-			return g.Index.AddFile(te.Name, te.ID)
+	head := treeEntryMap(headTree)
+	var candidates []string
+	for _, e := range g.Index.SortedEntries() {
+		if ps.Matches(e.Name) {
+			candidates = append(candidates, e.Name)
 		}
 	}
-	err = g.Index.RemoveFile(rel)
-	if err != nil {
-		return errors.Wrapf(err, "couldn't unstage %s", rel)
-	}
-	return nil
-}
-
-func (g *Got) DiscardPath(paths ...string) error {
-	var matches []string
-	for _, p := range paths {
-		ms, err := filepath.Glob(p)
-		if err != nil {
-			return errors.Wrapf(err, "couldn't discard path %s", p)
+	for name := range head {
+		if ps.Matches(name) {
+			candidates = append(candidates, name)
 		}
-		matches = append(matches, ms...)
 	}
-	for _, m := range matches {
-		err := g.forAllFilesInRepo(m, func(path string, info os.FileInfo, err error) error {
-			return g.discardFile(path)
-		})
+	if unmatched := ps.Unmatched(candidates); len(unmatched) > 0 {
+		return errors.Errorf("pathspec '%s' did not match any file(s) known to got", unmatched[0])
+	}
+	for _, name := range uniqueSorted(candidates) {
+		if te, ok := head[name]; ok {
+			err = g.Index.AddEntry(index.NewEntry(te.Mode, te.Type, te.ID, te.Name))
+		} else {
+			err = g.Index.RemoveFile(name)
+		}
 		if err != nil {
-			return errors.Wrapf(err, "couldn't discard path %s", m)
+			return errors.Wrapf(err, "couldn't unstage %s", name)
 		}
 	}
 	return nil
 }
 
-func (g *Got) discardFile(filename string) error {
-	rel, err := g.repoRel(filename)
-	if err != nil {
-		return errors.Wrapf(err, "couldn't discard changes in %s", filename)
-	}
-	headTree, err := g.headTree()
+// Discards the changes in the working tree to the files matching the
+// pathspecs, which are relative to the working directory, by restoring them
+// from the index. Deleted files are restored too.
+func (g *Got) DiscardPath(paths ...string) error {
+	ps, err := g.pathspec(paths)
 	if err != nil {
-		return errors.Wrapf(err, "couldn't discard changes in %s", rel)
+		return errors.Wrap(err, "couldn't discard paths")
 	}
-	for _, te := range headTree.Entries {
-		if te.Name == rel {
-			blob, err := g.Objects.GetBlob(te.ID)
-			if err != nil {
-				return errors.Wrapf(err, "couldn't discard changes in %s", rel)
-			}
-			err = ioutil.WriteFile(filename, []byte(blob.Contents), te.Mode)
-			if err != nil {
-				return errors.Wrapf(err, "couldn't discard changes in %s", rel)
-			}
+	var entries []index.Entry
+	var names []string
+	for _, e := range g.Index.SortedEntries() {
+		if ps.Matches(e.Name) {
+			entries = append(entries, e)
+			names = append(names, e.Name)
 		}
 	}
-	if !g.Index.HasEntryFor(rel) {
-		return fmt.Errorf("%s did not match any file(s) know to got", filename)
+	if unmatched := ps.Unmatched(names); len(unmatched) > 0 {
+		return errors.Errorf("pathspec '%s' did not match any file(s) known to got", unmatched[0])
+	}
+	for _, e := range entries {
+		err = g.writeBlobToFile(e.ID, e.Name, e.Perm)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't discard changes in %s", e.Name)
+		}
 	}
 	return nil
 }
@@ -0,0 +1,199 @@
+package filesystem
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"got/internal/commitgraph"
+	"got/internal/objects"
+	"got/internal/objects/disk"
+	"got/internal/refs"
+	"got/internal/revwalk"
+)
+
+var revisionSuffixRegex = regexp.MustCompile(`[~^][0-9]*$`)
+
+// Resolves a revision into the ID of a commit. A revision is 'HEAD', a branch
+// name, a remote-tracking branch such as 'origin/master', a ref such as
+// 'refs/heads/master' or a full or abbreviated ID,
+// optionally followed by any number of '~<n>' (n:th first parent) and '^<n>'
+// (n:th parent) suffixes.
+func (g *Got) ResolveRevision(rev string) (objects.ID, error) {
+	if loc := revisionSuffixRegex.FindStringIndex(rev); loc != nil && loc[0] > 0 {
+		id, err := g.ResolveRevision(rev[:loc[0]])
+		if err != nil {
+			return "", err
+		}
+		return g.followParents(id, rev[loc[0]:])
+	}
+
+	switch {
+	case rev == "HEAD":
+		id, err := g.idAtHead()
+		if err != nil {
+			return "", errors.Wrapf(err, "couldn't resolve %s", rev)
+		}
+		if id == nil {
+			return "", errors.New("HEAD does not point to a commit yet")
+		}
+		return *id, nil
+	case strings.HasPrefix(rev, refs.Dir+"/") && !strings.Contains(rev, ".."):
+		return g.Refs.IDFromRef(refs.Ref(rev))
+	case g.Refs.BranchExists(rev):
+		return g.Refs.IdAtBranch(rev)
+	case strings.Contains(rev, "/") && !strings.Contains(rev, "..") && g.Refs.Exists(refs.Ref(filepath.Join(refs.Dir, refs.RemotesDir, rev))):
+		// A remote-tracking branch such as 'origin/master'
+		return g.Refs.IDFromRef(refs.Ref(filepath.Join(refs.Dir, refs.RemotesDir, rev)))
+	}
+	if id, err := objects.IdFromString(rev); err == nil && len(rev) == len(id) {
+		if _, err := g.Objects.TypeOf(id); err == nil {
+			return id, nil
+		}
+	}
+	id, err := g.Objects.ExpandID(rev)
+	if err != nil {
+		return "", errors.Errorf("unknown revision %s", rev)
+	}
+	return id, nil
+}
+
+func (g *Got) followParents(id objects.ID, suffix string) (objects.ID, error) {
+	n := 1
+	if len(suffix) > 1 {
+		var err error
+		n, err = strconv.Atoi(suffix[1:])
+		if err != nil {
+			return "", errors.Wrapf(err, "bad revision suffix %s", suffix)
+		}
+	}
+	if suffix[0] == '^' {
+		if n == 0 {
+			return id, nil
+		}
+		c, err := g.Objects.GetCommit(id)
+		if err != nil {
+			return "", err
+		}
+		parents := c.Parents()
+		if n > len(parents) {
+			return "", errors.Errorf("%s has no parent number %d", id, n)
+		}
+		return parents[n-1], nil
+	}
+	for i := 0; i < n; i++ {
+		c, err := g.Objects.GetCommit(id)
+		if err != nil {
+			return "", err
+		}
+		if c.ParentID == nil {
+			return "", errors.Errorf("%s has no parent", id)
+		}
+		id = *c.ParentID
+	}
+	return id, nil
+}
+
+// Returns a walker over the commits of the repository. The walker uses the
+// commit-graph if one has been written.
+func (g *Got) RevWalker() *revwalk.Walker {
+	w := revwalk.NewWalker(g.Objects)
+	if graph := g.commitGraph(); graph != nil {
+		w.UseGraph(graph)
+	}
+	return w
+}
+
+// Returns the commit-graph of the repository, or nil if there is none or it
+// can't be read, in which case commits are read from their objects instead.
+func (g *Got) commitGraph() *commitgraph.Graph {
+	if g.graph == nil {
+		graph, err := commitgraph.Read(filepath.Join(g.gotDir, disk.ObjectsDir))
+		if err != nil {
+			return nil
+		}
+		g.graph = graph
+	}
+	return g.graph
+}
+
+// Writes a commit-graph of every commit reachable from a branch or HEAD and
+// returns the number of commits in it.
+func (g *Got) WriteCommitGraph() (int, error) {
+	var tips []objects.ID
+	branches, err := g.Refs.Branches()
+	if err != nil {
+		return 0, errors.Wrap(err, "couldn't write commit-graph")
+	}
+	for _, b := range branches {
+		id, err := g.Refs.IdAtBranch(b)
+		if err != nil {
+			return 0, errors.Wrap(err, "couldn't write commit-graph")
+		}
+		tips = append(tips, id)
+	}
+	if head, err := g.ResolveRevision("HEAD"); err == nil {
+		tips = append(tips, head)
+	}
+	n, err := commitgraph.Write(filepath.Join(g.gotDir, disk.ObjectsDir), g.Objects, tips)
+	if err != nil {
+		return 0, err
+	}
+	g.graph = nil
+	return n, nil
+}
+
+func (g *Got) VerifyCommitGraph() error {
+	return commitgraph.Verify(filepath.Join(g.gotDir, disk.ObjectsDir), g.Objects)
+}
+
+// Returns the IDs of the commits selected by the given revision specifiers,
+// see revwalk.Walker.PushSpec, sorted as given. At most n IDs are returned
+// unless n is 0.
+func (g *Got) RevList(specs []string, sort revwalk.Sort, n int) ([]objects.ID, error) {
+	w := g.RevWalker()
+	w.Sorting(sort)
+	w.MaxCount(n)
+	for _, spec := range specs {
+		err := w.PushSpec(spec, g.ResolveRevision)
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't list revisions")
+		}
+	}
+	ids, err := w.IDs()
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't list revisions")
+	}
+	return ids, nil
+}
+
+func (g *Got) MergeBases(a, b string) ([]objects.ID, error) {
+	aID, err := g.ResolveRevision(a)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't find merge base of %s and %s", a, b)
+	}
+	bID, err := g.ResolveRevision(b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't find merge base of %s and %s", a, b)
+	}
+	bases, err := g.RevWalker().MergeBases(aID, bID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't find merge base of %s and %s", a, b)
+	}
+	return bases, nil
+}
+
+func (g *Got) IsAncestor(ancestor, rev string) (bool, error) {
+	ancestorID, err := g.ResolveRevision(ancestor)
+	if err != nil {
+		return false, errors.Wrapf(err, "couldn't determine if %s is an ancestor of %s", ancestor, rev)
+	}
+	id, err := g.ResolveRevision(rev)
+	if err != nil {
+		return false, errors.Wrapf(err, "couldn't determine if %s is an ancestor of %s", ancestor, rev)
+	}
+	return g.RevWalker().IsAncestor(ancestorID, id)
+}
@@ -0,0 +1,171 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"got/internal/diff"
+)
+
+type RemoveOptions struct {
+	// Only remove the files from the index, leaving them in the working tree
+	Cached bool
+	// Remove the files inside the directories given
+	Recursive bool
+	// Remove the files even if that loses changes that aren't committed
+	Force bool
+}
+
+// Removes the tracked files matching the pathspecs from the index and,
+// unless cached, from the working tree. Pathspecs are relative to the
+// working directory. Nothing is removed if any of the files has
+// changes that would be lost, unless forced. Returns the repository relative
+// paths of the removed files.
+func (g *Got) Remove(paths []string, opts RemoveOptions) ([]string, error) {
+	ps, err := g.pathspec(paths)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't remove paths")
+	}
+	var removed []string
+	for _, e := range g.Index.SortedEntries() {
+		if ps.Matches(e.Name) {
+			removed = append(removed, e.Name)
+		}
+	}
+	if unmatched := ps.Unmatched(removed); len(unmatched) > 0 {
+		return nil, errors.Errorf("pathspec '%s' did not match any files", unmatched[0])
+	}
+	if !opts.Recursive {
+		// Only directories named as they are need -r, not patterns
+		for _, p := range paths {
+			rel, err := g.repoRel(p)
+			if err != nil {
+				continue
+			}
+			if !g.Index.HasEntryFor(rel) && (rel == "." || matchesDirectory(rel, removed)) {
+				return nil, errors.Errorf("not removing '%s' recursively without -r", p)
+			}
+		}
+	}
+	if !opts.Force {
+		err := g.checkRemovable(removed, opts.Cached)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, path := range removed {
+		err := g.Index.RemoveFile(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "couldn't remove %s", path)
+		}
+		if opts.Cached {
+			continue
+		}
+		err = g.removeFromWorkingTree(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "couldn't remove %s", path)
+		}
+	}
+	return removed, nil
+}
+
+// Reports whether any of the paths is inside the directory dir
+func matchesDirectory(dir string, paths []string) bool {
+	for _, p := range paths {
+		if strings.HasPrefix(p, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueSorted(paths []string) []string {
+	sort.Strings(paths)
+	var unique []string
+	for i, p := range paths {
+		if i == 0 || p != paths[i-1] {
+			unique = append(unique, p)
+		}
+	}
+	return unique
+}
+
+// Returns an error listing the files whose removal would lose changes. A
+// file in the index that differs from HEAD has staged changes, and one in the
+// working tree that differs from the index has local modifications. Without
+// cached both are lost, with cached only staged changes that are in neither
+// HEAD nor the working tree.
+func (g *Got) checkRemovable(paths []string, cached bool) error {
+	headTree, err := g.headTree()
+	if err != nil {
+		return errors.Wrap(err, "couldn't remove files")
+	}
+	head := treeEntryMap(headTree)
+	var stagedAndModified, staged, modified []string
+	for _, path := range paths {
+		e, _ := g.indexEntry(path)
+		he, inHead := head[path]
+		isStaged := !inHead || he.ID != e.ID || diff.ModeString(he.Mode) != diff.ModeString(e.Perm)
+		fd, _, err := g.diffWorkingTreeFile(path, e.Perm, e.ID)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't remove %s", path)
+		}
+		isModified := fd != nil && fd.EditType != diff.FileEditTypeDelete
+		switch {
+		case isStaged && isModified:
+			stagedAndModified = append(stagedAndModified, path)
+		case cached:
+		case isStaged:
+			staged = append(staged, path)
+		case isModified:
+			modified = append(modified, path)
+		}
+	}
+	var msgs []string
+	for _, problem := range []struct {
+		paths []string
+		what  string
+		hint  string
+	}{
+		{stagedAndModified, "staged content different from both the file and the HEAD", "(use -f to force removal)"},
+		{staged, "changes staged in the index", "(use --cached to keep the file, or -f to force removal)"},
+		{modified, "local modifications", "(use --cached to keep the file, or -f to force removal)"},
+	} {
+		if len(problem.paths) == 0 {
+			continue
+		}
+		msg := "the following file has " + problem.what + ":\n"
+		if len(problem.paths) > 1 {
+			msg = "the following files have " + problem.what + ":\n"
+		}
+		for _, p := range problem.paths {
+			msg += "    " + p + "\n"
+		}
+		msgs = append(msgs, msg+problem.hint)
+	}
+	if len(msgs) > 0 {
+		return errors.New(strings.Join(msgs, "\n"))
+	}
+	return nil
+}
+
+// Removes a file from the working tree together with the directories it
+// leaves empty
+func (g *Got) removeFromWorkingTree(path string) error {
+	err := os.Remove(filepath.Join(g.dir, path))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for dir := filepath.Dir(path); dir != "."; dir = filepath.Dir(dir) {
+		// Fails for directories that aren't empty
+		if os.Remove(filepath.Join(g.dir, dir)) != nil {
+			break
+		}
+	}
+	return nil
+}
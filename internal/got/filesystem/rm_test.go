@@ -0,0 +1,69 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemove(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	commitFiles(t, g, "first", map[string]string{"a.txt": "a\n", "dir/b.txt": "b\n", "dir/c.txt": "c\n"})
+
+	_, err := g.Remove([]string{"dir"}, RemoveOptions{})
+	assert.EqualError(t, err, "not removing 'dir' recursively without -r")
+
+	removed, err := g.Remove([]string{"dir"}, RemoveOptions{Recursive: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join("dir", "b.txt"), filepath.Join("dir", "c.txt")}, removed)
+	_, err = os.Stat(filepath.Join(g.dir, "dir"))
+	assert.True(t, os.IsNotExist(err))
+
+	removed, err = g.Remove([]string{"a.txt"}, RemoveOptions{Cached: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a.txt"}, removed)
+	assert.False(t, g.Index.HasEntryFor("a.txt"))
+	assert.Equal(t, "a\n", readFile(t, g, "a.txt"))
+
+	_, err = g.Remove([]string{"missing.txt"}, RemoveOptions{})
+	assert.EqualError(t, err, "pathspec 'missing.txt' did not match any files")
+}
+
+func TestRemoveKeepsChanges(t *testing.T) {
+	g, cleanup := newTestRepo(t)
+	defer cleanup()
+	commitFiles(t, g, "first", map[string]string{"modified.txt": "a\n", "staged.txt": "a\n", "both.txt": "a\n"})
+	writeFiles(t, g, map[string]string{"staged.txt": "b\n", "both.txt": "b\n"})
+	assert.Nil(t, g.AddPath("staged.txt", "both.txt"))
+	writeFiles(t, g, map[string]string{"modified.txt": "b\n", "both.txt": "c\n"})
+
+	tests := []struct {
+		path string
+		opts RemoveOptions
+		err  string
+	}{
+		{"modified.txt", RemoveOptions{}, "the following file has local modifications:\n    modified.txt\n(use --cached to keep the file, or -f to force removal)"},
+		{"staged.txt", RemoveOptions{}, "the following file has changes staged in the index:\n    staged.txt\n(use --cached to keep the file, or -f to force removal)"},
+		{"both.txt", RemoveOptions{}, "the following file has staged content different from both the file and the HEAD:\n    both.txt\n(use -f to force removal)"},
+		{"both.txt", RemoveOptions{Cached: true}, "the following file has staged content different from both the file and the HEAD:\n    both.txt\n(use -f to force removal)"},
+		{"modified.txt", RemoveOptions{Cached: true}, ""},
+		{"staged.txt", RemoveOptions{Cached: true}, ""},
+		{"both.txt", RemoveOptions{Force: true}, ""},
+	}
+	for _, tt := range tests {
+		_, err := g.Remove([]string{tt.path}, tt.opts)
+		if tt.err == "" {
+			assert.Nil(t, err, tt.path)
+			assert.False(t, g.Index.HasEntryFor(tt.path), tt.path)
+		} else {
+			assert.EqualError(t, err, tt.err, tt.path)
+			assert.True(t, g.Index.HasEntryFor(tt.path), tt.path)
+		}
+	}
+	_, err := os.Stat(filepath.Join(g.dir, "both.txt"))
+	assert.True(t, os.IsNotExist(err))
+	assert.Equal(t, "b\n", readFile(t, g, "modified.txt"))
+}
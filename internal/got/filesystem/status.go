@@ -1,14 +1,14 @@
 package filesystem
 
 import (
-	"crypto/sha1"
-	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"got/internal/diff"
-	"got/internal/diff/simple"
 	"got/internal/index"
 	"got/internal/objects"
+	"got/internal/refs"
 	"got/internal/status"
 )
 
@@ -23,7 +23,74 @@ func (g *Got) Status() (*status.Status, error) {
 	if err != nil {
 		return nil, err
 	}
-	return tree.GetStatus(), nil
+	s := tree.GetStatus()
+	s.Branch, err = g.branchStatus()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Returns where HEAD is and, if the checked out branch has an upstream, how
+// many commits each of them has that the other doesn't
+func (g *Got) branchStatus() (status.Branch, error) {
+	var b status.Branch
+	headType, err := g.HeadType()
+	if err != nil {
+		return b, err
+	}
+	switch headType {
+	case HeadTypeEmpty:
+		b.Head = g.initialBranch()
+		return b, nil
+	case HeadTypeID:
+		b.Commit, err = g.HeadAsID()
+		return b, err
+	}
+	ref, err := g.HeadAsRef()
+	if err != nil {
+		return b, err
+	}
+	b.Head = ref.Name()
+	b.Commit, err = g.Refs.IDFromRef(ref)
+	if err != nil {
+		return b, err
+	}
+	upstream, rev := g.upstream(b.Head)
+	b.Upstream = upstream
+	if upstream == "" {
+		return b, nil
+	}
+	if _, err := g.ResolveRevision(rev); err != nil {
+		b.Gone = true
+		return b, nil
+	}
+	ahead, err := g.RevList([]string{rev + "..HEAD"}, 0, 0)
+	if err != nil {
+		return b, err
+	}
+	behind, err := g.RevList([]string{"HEAD.." + rev}, 0, 0)
+	if err != nil {
+		return b, err
+	}
+	b.Ahead, b.Behind = len(ahead), len(behind)
+	return b, nil
+}
+
+// Returns the short name of the upstream of a branch as configured with
+// 'branch.<name>.remote' and 'branch.<name>.merge', e.g. 'origin/master',
+// and the ref it's stored in, or empty strings if the branch has none
+func (g *Got) upstream(branch string) (string, string) {
+	remote := g.Config.GetString("branch."+branch+".remote", "")
+	merge := g.Config.GetString("branch."+branch+".merge", "")
+	if remote == "" || merge == "" {
+		return "", ""
+	}
+	name := strings.TrimPrefix(merge, refs.Dir+"/"+refs.HeadsDir+"/")
+	if remote == "." {
+		return name, merge
+	}
+	return remote + "/" + name, string(refs.RemoteRef(remote, name))
 }
 
 func (g *Got) statusTree() (*status.Tree, error) {
@@ -31,31 +98,61 @@ func (g *Got) statusTree() (*status.Tree, error) {
 	if err != nil {
 		return nil, err
 	}
+	head, err := g.headSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	headDiff, err = g.detectRenames(headDiff, head, g.indexSnapshot(), g.Renames)
+	if err != nil {
+		return nil, err
+	}
 	workTreeDiff, untracked, err := g.diffFiles()
 	if err != nil {
 		return nil, err
 	}
 	tree := status.NewTree()
+	// Adds the modes and IDs that porcelain formats show to the changes of
+	// a file
+	addFile := func(path string, changes status.Changes) {
+		headPath := path
+		if changes.From != "" {
+			headPath = changes.From
+		}
+		if te, ok := head.entries[headPath]; ok {
+			changes.HeadMode, changes.HeadID = te.Mode, te.ID
+		}
+		if ie, ok := g.indexEntry(path); ok {
+			changes.IndexMode, changes.IndexID = ie.Perm, ie.ID
+		}
+		if info, err := os.Lstat(filepath.Join(g.dir, path)); err == nil {
+			changes.WorktreeMode = info.Mode()
+		}
+		tree.AddFile(path, changes, true)
+	}
 
 	for _, d := range headDiff {
 		switch d.EditType {
 		case diff.FileEditTypeInPlace:
-			tree.AddFile(d.SrcPath, status.Changes{Head: status.Modified}, true)
+			addFile(d.SrcPath, status.Changes{Head: status.Modified})
 		case diff.FileEditTypeDelete:
-			tree.AddFile(d.SrcPath, status.Changes{Head: status.Deleted}, true)
+			addFile(d.SrcPath, status.Changes{Head: status.Deleted})
 		case diff.FileEditTypeCreate:
-			tree.AddFile(d.DstPath, status.Changes{Head: status.Created}, true)
+			addFile(d.DstPath, status.Changes{Head: status.Created})
+		case diff.FileEditTypeRename:
+			addFile(d.DstPath, status.Changes{Head: status.Renamed, From: d.SrcPath, Similarity: d.Similarity})
+		case diff.FileEditTypeCopy:
+			addFile(d.DstPath, status.Changes{Head: status.Copied, From: d.SrcPath, Similarity: d.Similarity})
 		}
 	}
 
 	for _, d := range workTreeDiff {
 		switch d.EditType {
 		case diff.FileEditTypeInPlace:
-			tree.AddFile(d.SrcPath, status.Changes{Worktree: status.Modified}, true)
+			addFile(d.SrcPath, status.Changes{Worktree: status.Modified})
 		case diff.FileEditTypeDelete:
-			tree.AddFile(d.SrcPath, status.Changes{Worktree: status.Deleted}, true)
+			addFile(d.SrcPath, status.Changes{Worktree: status.Deleted})
 		case diff.FileEditTypeCreate:
-			tree.AddFile(d.DstPath, status.Changes{Worktree: status.Created}, true)
+			addFile(d.DstPath, status.Changes{Worktree: status.Created})
 		}
 	}
 
@@ -102,17 +199,11 @@ func (g *Got) diffFiles() ([]*diff.FileDiff, []string, error) {
 	var diffs []*diff.FileDiff
 	var files []*fileInfo
 	err := g.forAllInRepo(g.dir, func(path string, info os.FileInfo, err error) error {
-		path, err = g.repoRel(path)
-		if err != nil {
-			return err
-		}
-
 		if !info.IsDir() {
-			bs, err := ioutil.ReadFile(path)
+			hash, err := g.hashWorkingTreeFile(path, false)
 			if err != nil {
 				return err
 			}
-			hash := objects.IdFromSum(sha1.Sum(bs))
 			files = append(files, &fileInfo{
 				name: path,
 				hash: hash,
@@ -130,8 +221,9 @@ func (g *Got) diffFiles() ([]*diff.FileDiff, []string, error) {
 		if err != nil {
 			return nil, nil, err
 		}
+		// Tracked files missing from the working tree have been deleted
 		if d == nil {
-			d = diff.NewCreateFileDiff(ie.Perm, ie.ID, ie.Name)
+			d = diff.NewDeleteFileDiff(ie.Perm, ie.ID, ie.Name)
 		}
 		diffs = append(diffs, d)
 	}
@@ -144,7 +236,6 @@ func (g *Got) diffFiles() ([]*diff.FileDiff, []string, error) {
 }
 
 func (g *Got) diffEntryAgainstHead(ie index.Entry, headTree *objects.Tree) (*diff.FileDiff, error) {
-	d := simple.Diff{}
 	for _, te := range headTree.Entries {
 		if ie.Name != te.Name {
 			continue
@@ -152,25 +243,12 @@ func (g *Got) diffEntryAgainstHead(ie index.Entry, headTree *objects.Tree) (*dif
 		if ie.ID == te.ID {
 			return diff.NewUnmodifiedFileDiff(ie.Perm, ie.ID, ie.Name), nil
 		}
-		iBlob, err := g.Objects.GetBlob(ie.ID)
-		if err != nil {
-			return nil, err
-		}
-		tBlob, err := g.Objects.GetBlob(te.ID)
-		if err != nil {
-			return nil, err
-		}
-		_, err = d.DiffFiles([]byte(iBlob.Contents), []byte(tBlob.Contents))
-		if err != nil {
-			return nil, err
-		}
 		return diff.NewInPlaceFileDiff(te.Mode, ie.Perm, te.ID, ie.ID, ie.Name), nil
 	}
 	return nil, nil
 }
 
 func (g *Got) diffEntryAgainstFiles(ie index.Entry, files []*fileInfo) (*diff.FileDiff, error) {
-	d := simple.Diff{}
 	for _, f := range files {
 		if ie.Name != f.name {
 			continue
@@ -178,18 +256,6 @@ func (g *Got) diffEntryAgainstFiles(ie index.Entry, files []*fileInfo) (*diff.Fi
 		if ie.ID == f.hash {
 			return diff.NewUnmodifiedFileDiff(f.perm, f.hash, f.name), nil
 		}
-		iBlob, err := g.Objects.GetBlob(ie.ID)
-		if err != nil {
-			return nil, err
-		}
-		contents, err := ioutil.ReadFile(f.name)
-		if err != nil {
-			return nil, err
-		}
-		_, err = d.DiffFiles([]byte(iBlob.Contents), contents)
-		if err != nil {
-			return nil, err
-		}
 		return diff.NewInPlaceFileDiff(f.perm, ie.Perm, f.hash, ie.ID, f.name), nil
 	}
 	return nil, nil
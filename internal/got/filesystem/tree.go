@@ -0,0 +1,80 @@
+package filesystem
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"got/internal/diff"
+	"got/internal/objects"
+)
+
+// Returns the tree of the given commit
+func (g *Got) commitTree(id objects.ID) (*objects.Tree, error) {
+	c, err := g.Objects.GetCommit(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't get tree of commit %s", id)
+	}
+	tree, err := g.Objects.GetTree(c.TreeID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't get tree of commit %s", id)
+	}
+	return &tree, nil
+}
+
+// Compares two trees and returns a diff for every path that differs between
+// them, sorted by path. A nil tree is treated as an empty tree.
+func diffTrees(src, dst *objects.Tree) []*diff.FileDiff {
+	return diffEntries(treeEntryMap(src), treeEntryMap(dst))
+}
+
+// Compares two sets of entries by path and returns a diff for every path
+// that differs between them, sorted by path
+func diffEntries(srcEntries, dstEntries map[string]objects.TreeEntry) []*diff.FileDiff {
+	var diffs []*diff.FileDiff
+	for name, se := range srcEntries {
+		de, ok := dstEntries[name]
+		if !ok {
+			diffs = append(diffs, diff.NewDeleteFileDiff(se.Mode, se.ID, name))
+			continue
+		}
+		if se.ID != de.ID || diff.ModeString(se.Mode) != diff.ModeString(de.Mode) {
+			d := diff.NewInPlaceFileDiff(se.Mode, de.Mode, se.ID, de.ID, name)
+			d.DstPath = name
+			diffs = append(diffs, d)
+		}
+	}
+	for name, de := range dstEntries {
+		if _, ok := srcEntries[name]; !ok {
+			diffs = append(diffs, diff.NewCreateFileDiff(de.Mode, de.ID, name))
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Path() < diffs[j].Path()
+	})
+	return diffs
+}
+
+func treeEntryMap(tree *objects.Tree) map[string]objects.TreeEntry {
+	entries := make(map[string]objects.TreeEntry)
+	if tree == nil {
+		return entries
+	}
+	for _, te := range tree.Entries {
+		entries[te.Name] = te
+	}
+	return entries
+}
+
+// Returns the contents of the blob with the given ID, or nil if the ID is
+// empty.
+func (g *Got) blobContents(id objects.ID) ([]byte, error) {
+	if id == "" {
+		return nil, nil
+	}
+	blob, err := g.Objects.GetBlob(id)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(blob.Contents), nil
+}
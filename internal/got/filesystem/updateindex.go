@@ -13,7 +13,7 @@ func (g *Got) UpdateIndex(filename string) error {
 		return err
 	}
 	rel, err := filepath.Rel(g.dir, abs)
-	sum, err := g.HashFile(rel, false)
+	sum, err := g.hashWorkingTreeFile(rel, false)
 	if err != nil {
 		return errors.Wrapf(err, "couldn't add file %s to index", filename)
 	}
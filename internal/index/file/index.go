@@ -20,8 +20,10 @@ const (
 )
 
 type Index struct {
-	// The .got directory
-	Dir      string
+	// The .got directory. It isn't stored so that the repository can be moved.
+	Dir string `json:"-"`
+	// The file the index is written to if it isn't the index file in Dir
+	file     string
 	Version  int
 	Entries  index.EntryMap
 	Checksum string
@@ -36,7 +38,28 @@ func NewIndex(dir string) *Index {
 }
 
 func ReadFromFile(dir string) (*Index, error) {
-	bs, err := ioutil.ReadFile(filepath.Join(dir, IndexFile))
+	return read(dir, filepath.Join(dir, IndexFile))
+}
+
+// Reads the index from the given file instead of the index file of the .got
+// directory dir, and writes it back there. A missing file is an empty index,
+// so a new temporary index can be staged into.
+func ReadFromFileAt(dir, file string) (*Index, error) {
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		i := NewIndex(dir)
+		i.file = file
+		return i, nil
+	}
+	i, err := read(dir, file)
+	if err != nil {
+		return nil, err
+	}
+	i.file = file
+	return i, nil
+}
+
+func read(dir, file string) (*Index, error) {
+	bs, err := ioutil.ReadFile(file)
 	if err != nil {
 		return nil, errors.Wrap(err, "couldn't read index file")
 	}
@@ -51,6 +74,7 @@ func ReadFromFile(dir string) (*Index, error) {
 	if i.calculateChecksum() != i.Checksum {
 		return nil, errors.New("index file corrupted")
 	}
+	i.Dir = dir
 	return &i, nil
 }
 
@@ -69,6 +93,11 @@ func (i *Index) AddFile(filename string, id objects.ID) error {
 	return i.writeToFile()
 }
 
+func (i *Index) AddEntry(entry index.Entry) error {
+	i.Entries[entry.Name] = entry
+	return i.writeToFile()
+}
+
 func (i *Index) RemoveFile(filename string) error {
 	delete(i.Entries, filename)
 	return i.writeToFile()
@@ -112,7 +141,11 @@ func (i *Index) writeToFile() error {
 	if err != nil {
 		return errors.Wrapf(err, "couldn't write index to file")
 	}
-	err = ioutil.WriteFile(filepath.Join(i.Dir, IndexFile), bs, os.ModePerm)
+	file := i.file
+	if file == "" {
+		file = filepath.Join(i.Dir, IndexFile)
+	}
+	err = ioutil.WriteFile(file, bs, os.ModePerm)
 	if err != nil {
 		return errors.Wrapf(err, "couldn't write index to file")
 	}
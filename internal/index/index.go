@@ -11,6 +11,10 @@ type Index interface {
 	// path should be relative to the repository root.
 	AddFile(filename string, id objects.ID) error
 
+	// Adds an entry into the index as is, without looking at the working
+	// tree.
+	AddEntry(entry Entry) error
+
 	// Removes a file from the index
 	RemoveFile(filename string) error
 
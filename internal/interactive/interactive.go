@@ -0,0 +1,135 @@
+package interactive
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gookit/color"
+
+	"got/internal/diff"
+)
+
+// Edits text, e.g. by opening it in the user's editor, and returns the
+// edited text
+type EditFunc func(text string) (string, error)
+
+// Asks the user which hunks of a diff to act on, one hunk at a time, like
+// 'git add -p'
+type Session struct {
+	in  *bufio.Reader
+	out io.Writer
+	// The question asked for every hunk, e.g. 'Stage this hunk'
+	Question string
+	// Edits hunks for 'e', which is only offered if set
+	Edit EditFunc
+	// Set once the user quits, so that no more questions are asked
+	done bool
+}
+
+// Returns a session reading the answers from in and writing the hunks and
+// questions to out
+func NewSession(in io.Reader, out io.Writer, question string) *Session {
+	return &Session{in: bufio.NewReader(in), out: out, Question: question}
+}
+
+const help = `y - %[1]s this hunk
+n - do not %[1]s this hunk
+q - quit; do not %[1]s this hunk or any of the remaining ones
+a - %[1]s this hunk and all later hunks in the file
+d - do not %[1]s this hunk or any of the later hunks in the file
+s - split the current hunk into smaller hunks
+e - manually edit the current hunk
+? - print help
+`
+
+// Asks about every hunk of the file and returns the ones the user selected,
+// in order. Once the user quits no more questions are asked and the
+// selection of every following file is empty.
+func (s *Session) Select(fd *diff.FileDiff, hunks diff.Hunks) (diff.Hunks, error) {
+	if s.done || len(hunks) == 0 {
+		return nil, nil
+	}
+	buf := &strings.Builder{}
+	fd.WriteHeader(buf)
+	fmt.Fprint(s.out, buf.String())
+	fmt.Fprintln(s.out, color.OpBold.Sprintf("--- a/%s", fd.Path()))
+	fmt.Fprintln(s.out, color.OpBold.Sprintf("+++ b/%s", fd.Path()))
+
+	var selected diff.Hunks
+	queue := append(diff.Hunks{}, hunks...)
+	for i := 0; i < len(queue); {
+		h := queue[i]
+		fmt.Fprint(s.out, h)
+		options := "y,n,q,a,d"
+		canSplit := len(h.Split()) > 1
+		if canSplit {
+			options += ",s"
+		}
+		if s.Edit != nil {
+			options += ",e"
+		}
+		fmt.Fprint(s.out, color.Blue.Sprintf("(%d/%d) %s [%s,?]? ", i+1, len(queue), s.Question, options))
+		answer, err := s.in.ReadString('\n')
+		if err != nil && answer == "" {
+			// Running out of answers is the same as quitting
+			s.done = true
+			return selected, nil
+		}
+		switch strings.TrimSpace(answer) {
+		case "y":
+			selected = append(selected, h)
+			i++
+		case "n":
+			i++
+		case "q":
+			s.done = true
+			return selected, nil
+		case "a":
+			return append(selected, queue[i:]...), nil
+		case "d":
+			return selected, nil
+		case "s":
+			if !canSplit {
+				fmt.Fprintln(s.out, "Sorry, cannot split this hunk")
+				continue
+			}
+			split := h.Split()
+			fmt.Fprintf(s.out, "Split into %d hunks.\n", len(split))
+			queue = append(queue[:i], append(split, queue[i+1:]...)...)
+		case "e":
+			if s.Edit == nil {
+				s.printHelp()
+				continue
+			}
+			edited, err := s.edit(h)
+			if err != nil {
+				fmt.Fprintln(s.out, err)
+				continue
+			}
+			selected = append(selected, edited)
+			i++
+		default:
+			s.printHelp()
+		}
+	}
+	return selected, nil
+}
+
+func (s *Session) printHelp() {
+	verb := strings.ToLower(strings.Fields(s.Question)[0])
+	fmt.Fprint(s.out, color.Red.Sprintf(help, verb))
+}
+
+func (s *Session) edit(h diff.Hunk) (diff.Hunk, error) {
+	text := "# Manual hunk edit mode\n" + color.ClearCode(h.String()) +
+		"# To remove '-' lines, make them ' ' lines (context).\n" +
+		"# To remove '+' lines, delete them.\n" +
+		"# Lines starting with # will be removed.\n"
+	edited, err := s.Edit(text)
+	if err != nil {
+		return h, err
+	}
+	return diff.ParseEditedHunk(h, edited)
+}
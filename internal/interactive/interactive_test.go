@@ -0,0 +1,81 @@
+package interactive
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gookit/color"
+	"github.com/stretchr/testify/assert"
+
+	"got/internal/diff"
+	"got/internal/diff/algorithm"
+)
+
+func init() {
+	color.Disable()
+}
+
+const (
+	src = "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n"
+	dst = "1\ntwo\n3\n4\n5\nsix\n7\n8\n9\n10\n11\n12\n13\nfourteen\n15\n"
+)
+
+func session(answers string) (*Session, diff.Hunks, *bytes.Buffer) {
+	out := bytes.NewBuffer(nil)
+	s := NewSession(strings.NewReader(answers), out, "Stage this hunk")
+	return s, algorithm.Myers{}.DiffBytes([]byte(src), []byte(dst)).Hunks(3), out
+}
+
+var fd = diff.NewInPlaceFileDiff(0644, 0644, "a", "b", "f.txt")
+
+func TestSelect(t *testing.T) {
+	s, hs, out := session("n\ny\n")
+	assert.Len(t, hs, 2)
+	selected, err := s.Select(fd, hs)
+	assert.NoError(t, err)
+	assert.Equal(t, hs[1:], selected)
+	assert.Contains(t, out.String(), "(2/2) Stage this hunk [y,n,q,a,d,?]? ")
+}
+
+func TestSelectSplit(t *testing.T) {
+	s, hs, _ := session("s\ny\nn\ny\n")
+	selected, err := s.Select(fd, hs)
+	assert.NoError(t, err)
+	assert.Len(t, selected, 2)
+	assert.Equal(t, "1\ntwo\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\nfourteen\n15\n", string(diff.ApplyHunks([]byte(src), selected)))
+}
+
+func TestSelectAllAndQuit(t *testing.T) {
+	s, hs, _ := session("a\n")
+	selected, err := s.Select(fd, hs)
+	assert.NoError(t, err)
+	assert.Equal(t, hs, selected)
+
+	s, hs, _ = session("y\nq\n")
+	selected, err = s.Select(fd, hs)
+	assert.NoError(t, err)
+	assert.Equal(t, hs[:1], selected)
+	// Nothing is asked about the files after quitting
+	selected, err = s.Select(fd, hs)
+	assert.NoError(t, err)
+	assert.Empty(t, selected)
+}
+
+func TestSelectEdit(t *testing.T) {
+	s, hs, _ := session("e\nd\n")
+	s.Edit = func(text string) (string, error) {
+		return strings.Replace(text, "+six", "+SIX", 1), nil
+	}
+	selected, err := s.Select(fd, hs)
+	assert.NoError(t, err)
+	assert.Len(t, selected, 1)
+	assert.Equal(t, "1\ntwo\n3\n4\n5\nSIX\n7\n8\n9\n10\n11\n12\n13\n14\n15\n", string(diff.ApplyHunks([]byte(src), selected)))
+}
+
+func TestHelp(t *testing.T) {
+	s, hs, out := session("x\nn\nn\n")
+	_, err := s.Select(fd, hs)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "y - stage this hunk\n")
+}
@@ -0,0 +1,187 @@
+package mbox
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The date of the 'From ' line that starts each message. Like git we use a
+// fixed date so that the line can be told apart from a real mail.
+const fromLineDate = "Mon Sep 17 00:00:00 2001"
+
+// The separator between the message and the patch of a mail
+const patchSeparator = "---"
+
+// The signature that ends each mail
+const signature = "-- \ngot\n"
+
+// A commit sent as a mail
+type Message struct {
+	// The ID of the commit
+	ID          string
+	AuthorName  string
+	AuthorEmail string
+	Date        time.Time
+	Subject     string
+	Body        string
+	// Everything after the '---' line: the diffstat and the patch
+	Patch string
+}
+
+// Returns the commit message, i.e. the subject followed by the body
+func (m Message) CommitMessage() string {
+	if m.Body == "" {
+		return m.Subject
+	}
+	return m.Subject + "\n\n" + m.Body
+}
+
+// Writes the message as the n-th of total mails in mbox format. The subject
+// is prefixed with '[PATCH n/total]', or just '[PATCH]' if there is a single
+// mail.
+func (m Message) Write(w io.Writer, n, total int) error {
+	prefix := "[PATCH]"
+	if total > 1 {
+		prefix = fmt.Sprintf("[PATCH %d/%d]", n, total)
+	}
+	from := (&mail.Address{Name: m.AuthorName, Address: m.AuthorEmail}).String()
+	subject := mime.QEncoding.Encode("utf-8", m.Subject)
+	_, err := fmt.Fprintf(w, "From %s %s\nFrom: %s\nDate: %s\nSubject: %s %s\n\n",
+		m.ID, fromLineDate, from, m.Date.Format(time.RFC1123Z), prefix, subject)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't write mail of %s", m.ID)
+	}
+	if m.Body != "" {
+		_, err = fmt.Fprintf(w, "%s\n", escapeFromLines(strings.TrimRight(m.Body, "\n")))
+		if err != nil {
+			return errors.Wrapf(err, "couldn't write mail of %s", m.ID)
+		}
+	}
+	_, err = fmt.Fprintf(w, "%s\n%s\n%s\n", patchSeparator, escapeFromLines(m.Patch), signature)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't write mail of %s", m.ID)
+	}
+	return nil
+}
+
+// Lines starting with 'From ' after any number of '>'. As in the mboxrd
+// format they get another '>' in a mailbox so that they can't be taken for
+// the start of a mail, which is taken off again when the mail is parsed.
+var (
+	fromLines        = regexp.MustCompile(`(?m)^(>*From )`)
+	escapedFromLines = regexp.MustCompile(`(?m)^>(>*From )`)
+)
+
+func escapeFromLines(s string) string {
+	return fromLines.ReplaceAllString(s, ">$1")
+}
+
+func unescapeFromLines(s string) string {
+	return escapedFromLines.ReplaceAllString(s, "$1")
+}
+
+var nonAlnum = regexp.MustCompile(`[^A-Za-z0-9._]+`)
+
+// Returns the name of the file the n-th mail is written to, e.g.
+// '0001-Fix-the-parser.patch'
+func FileName(n int, subject string) string {
+	slug := strings.Trim(nonAlnum.ReplaceAllString(subject, "-"), "-.")
+	if len(slug) > 52 {
+		slug = strings.TrimRight(slug[:52], "-.")
+	}
+	return fmt.Sprintf("%04d-%s.patch", n, slug)
+}
+
+var fromLine = regexp.MustCompile(`^From \S+ \w{3} \w{3} [ \d]\d \d\d:\d\d:\d\d \d{4}`)
+
+// Splits a mailbox into the raw text of each of its mails. Input that doesn't
+// start with a 'From ' line is taken to be a single mail.
+func Split(r io.Reader) ([]string, error) {
+	var mails []string
+	var cur strings.Builder
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if fromLine.MatchString(line) && cur.Len() > 0 {
+			mails = append(mails, cur.String())
+			cur.Reset()
+		}
+		cur.WriteString(line)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't read mailbox")
+		}
+	}
+	if strings.TrimSpace(cur.String()) != "" {
+		mails = append(mails, cur.String())
+	}
+	return mails, nil
+}
+
+var subjectPrefix = regexp.MustCompile(`^\s*(?:(?i:re|fwd?):\s*)*(?:\[[^\]]*\]\s*)*`)
+
+// Parses the raw text of a mail written by Write
+func Parse(raw string) (Message, error) {
+	var m Message
+	if fromLine.MatchString(raw) {
+		fields := strings.Fields(raw)
+		m.ID = fields[1]
+		raw = raw[strings.Index(raw, "\n")+1:]
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(raw))
+	if err != nil {
+		return m, errors.Wrap(err, "couldn't parse mail")
+	}
+	from, err := mail.ParseAddress(msg.Header.Get("From"))
+	if err != nil {
+		return m, errors.Wrap(err, "couldn't parse author of mail")
+	}
+	m.AuthorName, m.AuthorEmail = from.Name, from.Address
+	m.Date, err = msg.Header.Date()
+	if err != nil {
+		return m, errors.Wrap(err, "couldn't parse date of mail")
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		return m, errors.Wrap(err, "couldn't parse subject of mail")
+	}
+	m.Subject = strings.TrimSpace(subjectPrefix.ReplaceAllString(subject, ""))
+
+	bs, err := ioutil.ReadAll(msg.Body)
+	if err != nil {
+		return m, errors.Wrap(err, "couldn't read mail")
+	}
+	m.Body, m.Patch = splitBody(unescapeFromLines(string(bs)))
+	return m, nil
+}
+
+// Splits the body of a mail at the '---' line, or at the start of the patch
+// if there is none
+func splitBody(body string) (string, string) {
+	lines := strings.SplitAfter(body, "\n")
+	for i, l := range lines {
+		trimmed := strings.TrimRight(l, "\r\n")
+		if trimmed == patchSeparator {
+			return joinBody(lines[:i]), strings.Join(lines[i+1:], "")
+		}
+		if strings.HasPrefix(trimmed, "diff --got ") || strings.HasPrefix(trimmed, "diff --git ") {
+			return joinBody(lines[:i]), strings.Join(lines[i:], "")
+		}
+	}
+	return joinBody(lines), ""
+}
+
+func joinBody(lines []string) string {
+	return strings.TrimSpace(strings.Join(lines, ""))
+}
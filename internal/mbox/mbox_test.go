@@ -0,0 +1,59 @@
+package mbox
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteAndParse(t *testing.T) {
+	date := time.Date(2020, 11, 3, 14, 5, 9, 0, time.FixedZone("", -7*60*60))
+	mails := []Message{
+		{
+			ID:          "42cc3de4b2885debfce5c5c0bf695e471728ce82",
+			AuthorName:  "Jöhn Doe",
+			AuthorEmail: "john@doe.com",
+			Date:        date,
+			Subject:     "Fix the parser",
+			Body:        "It choked on empty lines.\n\nNow it doesn't.",
+			Patch:       " f.txt | 2 +-\n 1 file changed, 1 insertion(+), 1 deletion(-)\n\ndiff --got a/f.txt b/f.txt\n",
+		},
+		{
+			ID:          "9d4a16717a8bd8ac2e2c5ea58b8b2b1a8f7b8a4e",
+			AuthorName:  "Jane Doe",
+			AuthorEmail: "jane@doe.com",
+			Date:        date,
+			Subject:     "Add [brackets] to the docs",
+			Body:        "From now on\n>From a quote\nFrom 1234 Mon Sep 17 00:00:00 2001",
+			Patch:       "diff --got a/g.txt b/g.txt\n",
+		},
+	}
+	buf := bytes.NewBuffer(nil)
+	for i, m := range mails {
+		assert.Nil(t, m.Write(buf, i+1, len(mails)))
+	}
+	assert.Contains(t, buf.String(), "Subject: [PATCH 1/2] Fix the parser\n")
+	assert.Contains(t, buf.String(), "\n>From now on\n>>From a quote\n>From 1234 ")
+
+	raws, err := Split(buf)
+	assert.Nil(t, err)
+	assert.Len(t, raws, 2)
+	for i, raw := range raws {
+		m, err := Parse(raw)
+		assert.Nil(t, err)
+		assert.Equal(t, mails[i].ID, m.ID)
+		assert.Equal(t, mails[i].AuthorName, m.AuthorName)
+		assert.Equal(t, mails[i].AuthorEmail, m.AuthorEmail)
+		assert.True(t, mails[i].Date.Equal(m.Date))
+		assert.Equal(t, mails[i].Subject, m.Subject)
+		assert.Equal(t, mails[i].Body, m.Body)
+		assert.Contains(t, m.Patch, mails[i].Patch)
+	}
+}
+
+func TestFileName(t *testing.T) {
+	assert.Equal(t, "0001-Fix-the-parser.patch", FileName(1, "Fix the parser"))
+	assert.Equal(t, "0012-Don-t-crash-on-foo.go.patch", FileName(12, "Don't crash on foo.go!"))
+}
@@ -0,0 +1,116 @@
+package merge
+
+import (
+	"strings"
+
+	"got/internal/diff"
+)
+
+// Markers around the two sides of a conflict
+const (
+	MarkerOurs   = "<<<<<<<"
+	MarkerSep    = "======="
+	MarkerTheirs = ">>>>>>>"
+)
+
+// Merges the changes that ours and theirs made to base, line by line. Where
+// both sides changed the same lines differently, the result contains both
+// versions between conflict markers labelled with oursName and theirsName,
+// and conflict is set.
+func Merge(differ diff.Differ, base, ours, theirs []byte, oursName, theirsName string) (result []byte, conflict bool) {
+	return merge(differ, base, ours, theirs, oursName, theirsName, false)
+}
+
+// Merges like Merge, except that conflicts are resolved by keeping the lines
+// of both sides, ours first, without markers
+func Union(differ diff.Differ, base, ours, theirs []byte) []byte {
+	result, _ := merge(differ, base, ours, theirs, "", "", true)
+	return result
+}
+
+func merge(differ diff.Differ, base, ours, theirs []byte, oursName, theirsName string, union bool) (result []byte, conflict bool) {
+	b, o, t := diff.Lines(base), diff.Lines(ours), diff.Lines(theirs)
+	mo := matches(differ.DiffBytes(base, ours), len(b))
+	mt := matches(differ.DiffBytes(base, theirs), len(b))
+
+	var out []string
+	i, oi, ti := 0, 0, 0
+	for {
+		// Lines that neither side changed
+		for i < len(b) && mo[i] == oi && mt[i] == ti {
+			out = append(out, b[i])
+			i++
+			oi++
+			ti++
+		}
+		// The next base line that both sides kept ends the changed chunk
+		k := i
+		for k < len(b) && (mo[k] < 0 || mt[k] < 0) {
+			k++
+		}
+		oe, te := len(o), len(t)
+		if k < len(b) {
+			oe, te = mo[k], mt[k]
+		}
+		if i == k && oi == oe && ti == te {
+			break
+		}
+		bc, oc, tc := b[i:k], o[oi:oe], t[ti:te]
+		switch {
+		case equal(oc, bc):
+			out = append(out, tc...)
+		case equal(tc, bc), equal(oc, tc):
+			out = append(out, oc...)
+		case union:
+			out = append(out, terminated(oc)...)
+			out = append(out, tc...)
+		default:
+			conflict = true
+			out = append(out, MarkerOurs+" "+oursName+"\n")
+			out = append(out, terminated(oc)...)
+			out = append(out, MarkerSep+"\n")
+			out = append(out, terminated(tc)...)
+			out = append(out, MarkerTheirs+" "+theirsName+"\n")
+		}
+		i, oi, ti = k, oe, te
+	}
+	return []byte(strings.Join(out, "")), conflict
+}
+
+// Maps each line of the source of the diff to the line of the destination
+// it's kept as, or -1 if it's deleted
+func matches(bd diff.BytesDiff, n int) []int {
+	m := make([]int, n)
+	for i := range m {
+		m[i] = -1
+	}
+	for _, le := range bd {
+		if le.EditType == diff.EQL {
+			m[le.ALine] = le.BLine
+		}
+	}
+	return m
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Makes sure the last line ends with a newline so that a conflict marker
+// after it starts a line of its own
+func terminated(lines []string) []string {
+	if len(lines) == 0 || strings.HasSuffix(lines[len(lines)-1], "\n") {
+		return lines
+	}
+	res := append([]string{}, lines...)
+	res[len(res)-1] += "\n"
+	return res
+}
@@ -0,0 +1,42 @@
+package merge
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"got/internal/diff/algorithm"
+)
+
+func TestMerge(t *testing.T) {
+	base := "1\n2\n3\n4\n5\n6\n"
+	tests := []struct {
+		name     string
+		ours     string
+		theirs   string
+		expected string
+		conflict bool
+	}{
+		{"unchanged", base, base, base, false},
+		{"ours only", "1\ntwo\n3\n4\n5\n6\n", base, "1\ntwo\n3\n4\n5\n6\n", false},
+		{"theirs only", base, "1\n2\n3\n4\n5\n", "1\n2\n3\n4\n5\n", false},
+		{"separate", "1\ntwo\n3\n4\n5\n6\n", "1\n2\n3\n4\nfive\n6\n", "1\ntwo\n3\n4\nfive\n6\n", false},
+		{"same change", "1\ntwo\n3\n4\n5\n6\n", "1\ntwo\n3\n4\n5\n6\n", "1\ntwo\n3\n4\n5\n6\n", false},
+		{"insertions", "0\n" + base, base + "7\n", "0\n" + base + "7\n", false},
+		{"conflict", "1\nTWO\n3\n4\n5\n6\n", "1\ndeux\n3\n4\n5\n6\n", "1\n<<<<<<< ours\nTWO\n=======\ndeux\n>>>>>>> theirs\n3\n4\n5\n6\n", true},
+	}
+	for _, tt := range tests {
+		res, conflict := Merge(algorithm.Myers{}, []byte(base), []byte(tt.ours), []byte(tt.theirs), "ours", "theirs")
+		assert.Equal(t, tt.expected, string(res), tt.name)
+		assert.Equal(t, tt.conflict, conflict, tt.name)
+	}
+}
+
+func TestUnion(t *testing.T) {
+	base := "a\nb\nc\n"
+	res := Union(algorithm.Myers{}, []byte(base), []byte("a\nours\nc\n"), []byte("a\ntheirs\nc\n"))
+	assert.Equal(t, "a\nours\ntheirs\nc\n", string(res))
+
+	res = Union(algorithm.Myers{}, nil, []byte("x"), []byte("y\n"))
+	assert.Equal(t, "x\ny\n", string(res))
+}
@@ -2,8 +2,13 @@ package objects
 
 import (
 	"crypto/sha1"
+	"io"
 )
 
+// The size of the buffers used when streaming blob contents between the
+// working tree and the object store.
+const BufferSize = 32 * 1024
+
 type Blob struct {
 	Contents string `json:"contents"`
 }
@@ -25,3 +30,17 @@ func (b Blob) Content() string {
 func (b Blob) ID() ID {
 	return IdFromSum(sha1.Sum([]byte(b.Content())))
 }
+
+// Calculates the ID of a blob with the contents read from r without keeping
+// more than BufferSize bytes of it in memory. Returns the ID together with
+// the number of bytes read.
+func HashBlob(r io.Reader) (ID, int64, error) {
+	h := sha1.New()
+	n, err := io.CopyBuffer(h, r, make([]byte, BufferSize))
+	if err != nil {
+		return "", n, err
+	}
+	var sum [20]byte
+	copy(sum[:], h.Sum(nil))
+	return IdFromSum(sum), n, nil
+}
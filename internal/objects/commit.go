@@ -3,14 +3,19 @@ package objects
 import (
 	"crypto/sha1"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Commit struct {
 	TreeID   ID
 	ParentID *ID
-	Author   string
-	Message  string
-	Checksum ID
+	// Additional parents of a merge commit, ParentID being the first parent
+	MergeParentIDs []ID `json:",omitempty"`
+	Author         string
+	Message        string
+	Checksum       ID
 }
 
 func NewCommit(treeID ID, parentID *ID, author string, message string) Commit {
@@ -30,6 +35,87 @@ func NewCommit(treeID ID, parentID *ID, author string, message string) Commit {
 	}
 }
 
+// Creates a commit with more than one parent. The first of the given parents
+// becomes the ParentID of the commit.
+func NewMergeCommit(treeID ID, parentIDs []ID, author string, message string) Commit {
+	if len(parentIDs) == 0 {
+		return NewCommit(treeID, nil, author, message)
+	}
+	first := parentIDs[0]
+	c := NewCommit(treeID, &first, author, message)
+	if len(parentIDs) == 1 {
+		return c
+	}
+	c.MergeParentIDs = append([]ID(nil), parentIDs[1:]...)
+	parents := string(treeID)
+	for _, p := range parentIDs {
+		parents += string(p)
+	}
+	c.Checksum = IdFromSum(sha1.Sum([]byte(parents + author + message)))
+	return c
+}
+
+// Returns the IDs of all parents of the commit, the first parent first.
+func (c Commit) Parents() []ID {
+	if c.ParentID == nil {
+		return nil
+	}
+	return append([]ID{*c.ParentID}, c.MergeParentIDs...)
+}
+
+// Returns the time the commit was authored, parsed from the trailing
+// '<unix seconds> <zone>' of the author. Returns the zero time if the author
+// carries no timestamp.
+func (c Commit) Time() time.Time {
+	fields := strings.Fields(c.Author)
+	if len(fields) < 2 {
+		return time.Time{}
+	}
+	secs, err := strconv.ParseInt(fields[len(fields)-2], 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	t := time.Unix(secs, 0)
+	zone, err := time.Parse("-0700", fields[len(fields)-1])
+	if err != nil {
+		return t
+	}
+	return t.In(zone.Location())
+}
+
+// Returns the name of the author, i.e. everything before the '<email>'.
+func (c Commit) AuthorName() string {
+	i := strings.Index(c.Author, "<")
+	if i < 0 {
+		return strings.TrimSpace(c.Author)
+	}
+	return strings.TrimSpace(c.Author[:i])
+}
+
+// Returns the email of the author without the surrounding '<>'.
+func (c Commit) AuthorEmail() string {
+	i := strings.Index(c.Author, "<")
+	j := strings.Index(c.Author, ">")
+	if i < 0 || j < i {
+		return ""
+	}
+	return c.Author[i+1 : j]
+}
+
+// Returns the first line of the message
+func (c Commit) Subject() string {
+	return strings.SplitN(strings.TrimSpace(c.Message), "\n", 2)[0]
+}
+
+// Returns the message without its subject and the blank line after it
+func (c Commit) Body() string {
+	parts := strings.SplitN(strings.TrimSpace(c.Message), "\n", 2)
+	if len(parts) < 2 {
+		return ""
+	}
+	return strings.TrimLeft(parts[1], "\n")
+}
+
 func (c Commit) Type() Type {
 	return TypeCommit
 }
@@ -37,7 +123,9 @@ func (c Commit) Type() Type {
 func (c Commit) Content() string {
 	var content string
 	content += fmt.Sprintf("tree %s\n", c.TreeID)
-	content += fmt.Sprintf("parent %s\n", c.ParentID)
+	for _, p := range c.Parents() {
+		content += fmt.Sprintf("parent %s\n", p)
+	}
 	content += fmt.Sprintf("author %s\n", c.Author)
 	content += fmt.Sprintf("message %s\n", c.Message)
 	content += fmt.Sprintf("checksum %s\n", c.Checksum)
@@ -1,11 +1,16 @@
 package disk
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/pkg/errors"
 
@@ -27,11 +32,22 @@ const (
 	ObjectsDir = "objects"
 )
 
+// Blobs are stored as this header followed by the raw contents of the blob.
+// Every other object is stored as JSON, which never starts with this header.
+var blobHeader = []byte("blob\x00")
+
 func (o *Objects) Store(obj objects.Object) error {
+	if blob, ok := obj.(objects.Blob); ok {
+		_, err := o.StoreBlobFrom(strings.NewReader(blob.Contents))
+		return err
+	}
 	id := obj.ID()
 	dir := string(id)[:2]
-	file := filepath.Join(o.dir, ObjectsDir, dir, string(id)[2:])
-	err := filesystem.MkDirIfIsNotExist(filepath.Join(o.dir, ObjectsDir, dir), os.ModePerm)
+	file := o.objectPath(id)
+	err := filesystem.MkDirIfIsNotExist(filepath.Join(o.dir, ObjectsDir), os.ModePerm)
+	if err == nil {
+		err = filesystem.MkDirIfIsNotExist(filepath.Join(o.dir, ObjectsDir, dir), os.ModePerm)
+	}
 	if err != nil {
 		return errors.Wrapf(err, "couldn't store %s %s", obj.Type(), id)
 	}
@@ -42,13 +58,92 @@ func (o *Objects) Store(obj objects.Object) error {
 	return ioutil.WriteFile(file, buf, os.ModePerm)
 }
 
+func (o *Objects) StoreBlobFrom(r io.Reader) (objects.ID, error) {
+	err := filesystem.MkDirIfIsNotExist(filepath.Join(o.dir, ObjectsDir), os.ModePerm)
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't store blob")
+	}
+	tmp, err := ioutil.TempFile(filepath.Join(o.dir, ObjectsDir), "tmp_blob_")
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't store blob")
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha1.New()
+	_, err = tmp.Write(blobHeader)
+	if err == nil {
+		_, err = io.CopyBuffer(io.MultiWriter(tmp, h), r, make([]byte, objects.BufferSize))
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't store blob")
+	}
+
+	var sum [20]byte
+	copy(sum[:], h.Sum(nil))
+	id := objects.IdFromSum(sum)
+	err = filesystem.MkDirIfIsNotExist(filepath.Join(o.dir, ObjectsDir, string(id)[:2]), os.ModePerm)
+	if err != nil {
+		return "", errors.Wrapf(err, "couldn't store blob %s", id)
+	}
+	err = os.Rename(tmp.Name(), o.objectPath(id))
+	if err != nil {
+		return "", errors.Wrapf(err, "couldn't store blob %s", id)
+	}
+	return id, nil
+}
+
+func (o *Objects) OpenBlob(id objects.ID) (io.ReadCloser, int64, error) {
+	f, err := os.Open(o.objectPath(id))
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "couldn't open blob %s", id)
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, errors.Wrapf(err, "couldn't open blob %s", id)
+	}
+	r := bufio.NewReaderSize(f, objects.BufferSize)
+	header, err := r.Peek(len(blobHeader))
+	if err == nil && bytes.Equal(header, blobHeader) {
+		_, _ = r.Discard(len(blobHeader))
+		return readCloser{r, f}, stat.Size() - int64(len(blobHeader)), nil
+	}
+	f.Close()
+
+	// Blobs written before the raw format was introduced are stored as JSON
+	blob, err := o.getLegacyBlob(id)
+	if err != nil {
+		return nil, 0, err
+	}
+	return ioutil.NopCloser(strings.NewReader(blob.Contents)), int64(len(blob.Contents)), nil
+}
+
 func (o *Objects) GetBlob(id objects.ID) (objects.Blob, error) {
-	file := filepath.Join(o.dir, ObjectsDir, string(id)[:2], string(id)[2:])
+	r, _, err := o.OpenBlob(id)
+	if err != nil {
+		return objects.Blob{}, errors.Wrapf(err, "couldn't get blob %s", id)
+	}
+	defer r.Close()
+	bs, err := ioutil.ReadAll(r)
+	if err != nil {
+		return objects.Blob{}, errors.Wrapf(err, "couldn't get blob %s", id)
+	}
+	return objects.NewBlob(bs), nil
+}
+
+func (o *Objects) getLegacyBlob(id objects.ID) (objects.Blob, error) {
 	var obj objects.Blob
-	bs, err := ioutil.ReadFile(file)
+	bs, err := ioutil.ReadFile(o.objectPath(id))
 	if err != nil {
 		return objects.Blob{}, errors.Wrapf(err, "couldn't get blob %s", id)
 	}
+	t, err := typeOfJSON(bs)
+	if err != nil || t != objects.TypeBlob {
+		return objects.Blob{}, errors.Errorf("couldn't get blob %s: object is not a blob", id)
+	}
 	err = json.Unmarshal(bs, &obj)
 	if err != nil {
 		return objects.Blob{}, errors.Wrapf(err, "couldn't get blob %s", id)
@@ -57,9 +152,8 @@ func (o *Objects) GetBlob(id objects.ID) (objects.Blob, error) {
 }
 
 func (o *Objects) GetTree(id objects.ID) (objects.Tree, error) {
-	file := filepath.Join(o.dir, ObjectsDir, string(id)[:2], string(id)[2:])
 	var tree objects.Tree
-	bs, err := ioutil.ReadFile(file)
+	bs, err := ioutil.ReadFile(o.objectPath(id))
 	if err != nil {
 		return objects.Tree{}, errors.Wrapf(err, "couldn't get tree %s", id)
 	}
@@ -71,9 +165,8 @@ func (o *Objects) GetTree(id objects.ID) (objects.Tree, error) {
 }
 
 func (o *Objects) GetCommit(id objects.ID) (objects.Commit, error) {
-	file := filepath.Join(o.dir, ObjectsDir, string(id)[:2], string(id)[2:])
 	var commit objects.Commit
-	bs, err := ioutil.ReadFile(file)
+	bs, err := ioutil.ReadFile(o.objectPath(id))
 	if err != nil {
 		return objects.Commit{}, errors.Wrapf(err, "couldn't get commit %s", id)
 	}
@@ -85,17 +178,154 @@ func (o *Objects) GetCommit(id objects.ID) (objects.Commit, error) {
 }
 
 func (o *Objects) TypeOf(id objects.ID) (objects.Type, error) {
-	_, err := o.GetBlob(id)
-	if err == nil {
+	f, err := os.Open(o.objectPath(id))
+	if err != nil {
+		return "", fmt.Errorf("couldn't get type of %s", id)
+	}
+	defer f.Close()
+	header := make([]byte, len(blobHeader))
+	n, _ := io.ReadFull(f, header)
+	if bytes.Equal(header[:n], blobHeader) {
 		return objects.TypeBlob, nil
 	}
-	_, err = o.GetTree(id)
+	bs, err := ioutil.ReadFile(o.objectPath(id))
+	if err != nil {
+		return "", fmt.Errorf("couldn't get type of %s", id)
+	}
+	t, err := typeOfJSON(bs)
+	if err != nil {
+		return "", fmt.Errorf("couldn't get type of %s", id)
+	}
+	return t, nil
+}
+
+func (o *Objects) ExpandID(prefix string) (objects.ID, error) {
+	if len(prefix) < 4 || !isHex(prefix) {
+		return "", errors.Errorf("%s is not an abbreviated id", prefix)
+	}
+	files, err := ioutil.ReadDir(filepath.Join(o.dir, ObjectsDir, prefix[:2]))
+	if err != nil {
+		return "", errors.Errorf("no object found for %s", prefix)
+	}
+	var found objects.ID
+	for _, f := range files {
+		id := objects.ID(prefix[:2] + f.Name())
+		if !strings.HasPrefix(string(id), prefix) {
+			continue
+		}
+		if found != "" {
+			return "", errors.Errorf("short id %s is ambiguous", prefix)
+		}
+		found = id
+	}
+	if found == "" {
+		return "", errors.Errorf("no object found for %s", prefix)
+	}
+	return found, nil
+}
+
+// Reports whether an object with the given ID is stored
+func (o *Objects) Has(id objects.ID) bool {
+	return len(id) > 2 && filesystem.FileExists(o.objectPath(id))
+}
+
+// Opens the object with the given ID as it is stored, for copying it into
+// another repository with StoreRaw. The caller must close the returned
+// reader.
+func (o *Objects) OpenRaw(id objects.ID) (io.ReadCloser, error) {
+	f, err := os.Open(o.objectPath(id))
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't open object %s", id)
+	}
+	return f, nil
+}
+
+// Stores an object as read from OpenRaw of another repository
+func (o *Objects) StoreRaw(id objects.ID, r io.Reader) error {
+	err := filesystem.MkDirIfIsNotExist(filepath.Join(o.dir, ObjectsDir), os.ModePerm)
 	if err == nil {
-		return objects.TypeTree, nil
+		err = filesystem.MkDirIfIsNotExist(filepath.Join(o.dir, ObjectsDir, string(id)[:2]), os.ModePerm)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "couldn't store object %s", id)
+	}
+	tmp, err := ioutil.TempFile(filepath.Join(o.dir, ObjectsDir), "tmp_obj_")
+	if err != nil {
+		return errors.Wrapf(err, "couldn't store object %s", id)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.CopyBuffer(tmp, r, make([]byte, objects.BufferSize))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
 	}
-	_, err = o.GetCommit(id)
 	if err == nil {
+		// Readers of the object never see it half written
+		err = os.Rename(tmp.Name(), o.objectPath(id))
+	}
+	if err != nil {
+		return errors.Wrapf(err, "couldn't store object %s", id)
+	}
+	return nil
+}
+
+// Calculates the ID of an object from the bytes it is stored as, as read
+// from OpenRaw
+func (o *Objects) HashRaw(bs []byte) (objects.ID, error) {
+	if bytes.HasPrefix(bs, blobHeader) {
+		return objects.IdFromSum(sha1.Sum(bs[len(blobHeader):])), nil
+	}
+	t, err := typeOfJSON(bs)
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't hash object")
+	}
+	switch t {
+	case objects.TypeBlob:
+		var blob objects.Blob
+		err = json.Unmarshal(bs, &blob)
+		return blob.ID(), errors.Wrap(err, "couldn't hash blob")
+	case objects.TypeTree:
+		var tree objects.Tree
+		err = json.Unmarshal(bs, &tree)
+		return tree.ID(), errors.Wrap(err, "couldn't hash tree")
+	}
+	var commit objects.Commit
+	err = json.Unmarshal(bs, &commit)
+	return commit.ID(), errors.Wrap(err, "couldn't hash commit")
+}
+
+func isHex(s string) bool {
+	for _, r := range s {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func (o *Objects) objectPath(id objects.ID) string {
+	return filepath.Join(o.dir, ObjectsDir, string(id)[:2], string(id)[2:])
+}
+
+// Determines the type of a JSON encoded object by the fields it contains
+func typeOfJSON(bs []byte) (objects.Type, error) {
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(bs, &fields)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := fields["contents"]; ok {
+		return objects.TypeBlob, nil
+	}
+	if _, ok := fields["Entries"]; ok {
+		return objects.TypeTree, nil
+	}
+	if _, ok := fields["TreeID"]; ok {
 		return objects.TypeCommit, nil
 	}
-	return "", fmt.Errorf("couldn't get type of %s", id)
+	return "", errors.New("unknown object type")
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
 }
@@ -0,0 +1,55 @@
+package disk
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"got/internal/objects"
+)
+
+func TestStoreBlobFromIsBinarySafe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "got-objects")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	contents := []byte{0x00, 0xff, '\n', '"', '\\', 0x80, 0x01}
+	o := NewObjects(dir)
+	id, err := o.StoreBlobFrom(bytes.NewReader(contents))
+	assert.Nil(t, err)
+	assert.Equal(t, objects.NewBlob(contents).ID(), id)
+
+	r, size, err := o.OpenBlob(id)
+	assert.Nil(t, err)
+	defer r.Close()
+	bs, err := ioutil.ReadAll(r)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(len(contents)), size)
+	assert.Equal(t, contents, bs)
+
+	typ, err := o.TypeOf(id)
+	assert.Nil(t, err)
+	assert.Equal(t, objects.TypeBlob, typ)
+}
+
+func TestTypeOf(t *testing.T) {
+	dir, err := ioutil.TempDir("", "got-objects")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	o := NewObjects(dir)
+	tree := objects.Tree{Entries: []objects.TreeEntry{{Name: "a", ID: objects.NewBlob(nil).ID()}}}
+	assert.Nil(t, o.Store(tree))
+	commit := objects.NewCommit(tree.ID(), nil, "author", "message")
+	assert.Nil(t, o.Store(commit))
+
+	typ, err := o.TypeOf(tree.ID())
+	assert.Nil(t, err)
+	assert.Equal(t, objects.TypeTree, typ)
+	typ, err = o.TypeOf(commit.ID())
+	assert.Nil(t, err)
+	assert.Equal(t, objects.TypeCommit, typ)
+}
@@ -1,6 +1,7 @@
 package objects
 
 import (
+	"io"
 	"os"
 )
 
@@ -8,6 +9,15 @@ type Objects interface {
 	// Retrieves a Blob from a given ID
 	GetBlob(id ID) (Blob, error)
 
+	// Opens the contents of the Blob with the given ID for reading together
+	// with the size of the contents in bytes. The caller must close the
+	// returned reader.
+	OpenBlob(id ID) (io.ReadCloser, int64, error)
+
+	// Stores the contents read from r as a Blob without holding the whole
+	// contents in memory and returns the ID of the stored Blob.
+	StoreBlobFrom(r io.Reader) (ID, error)
+
 	// Retrieves a Tree from a given ID
 	GetTree(id ID) (Tree, error)
 
@@ -19,6 +29,10 @@ type Objects interface {
 
 	// Returns the Type of the objects with the given ID
 	TypeOf(id ID) (Type, error)
+
+	// Returns the ID of the only stored object whose ID starts with the given
+	// prefix
+	ExpandID(prefix string) (ID, error)
 }
 
 const (
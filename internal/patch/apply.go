@@ -0,0 +1,107 @@
+package patch
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"got/internal/diff"
+	"got/internal/objects"
+)
+
+// Applies hunks to contents. A hunk whose lines are no longer at the
+// position its header names is searched for above and below it, and later
+// hunks are moved by the same offset.
+func Apply(contents []byte, hunks diff.Hunks) ([]byte, error) {
+	lines := diff.Lines(contents)
+	var result []string
+	// The position in lines up to which the result has been built
+	done := 0
+	offset := 0
+	for n, h := range hunks {
+		pre, post := images(h)
+		want := h.SrcStart - 1 + offset
+		if h.SrcLines == 0 {
+			want++
+		}
+		pos, ok := find(lines, pre, want, done)
+		if !ok {
+			return nil, errors.Errorf("hunk #%d (%s) doesn't apply", n+1, h.Header())
+		}
+		offset += pos - want
+		result = append(result, lines[done:pos]...)
+		result = append(result, post...)
+		done = pos + len(pre)
+	}
+	result = append(result, lines[done:]...)
+	return []byte(strings.Join(result, "")), nil
+}
+
+// Applies a binary patch to contents, which have to be what the patch was
+// made against: the contents the patch holds to undo it, or else the blob
+// named on its full 'index' line
+func ApplyBinary(fp *FilePatch, contents []byte) ([]byte, error) {
+	if fp.NewData == nil {
+		return nil, errors.Errorf("cannot apply binary patch to '%s' without full index line", fp.Path())
+	}
+	if fp.IsNew() {
+		return fp.NewData, nil
+	}
+	if fp.OldData != nil {
+		if !bytes.Equal(fp.OldData, contents) {
+			return nil, errors.Errorf("the patch applies to '%s', which does not match the current contents", fp.Path())
+		}
+		return fp.NewData, nil
+	}
+	if _, err := objects.IdFromString(fp.OldID); err != nil {
+		return nil, errors.Errorf("cannot apply binary patch to '%s' without full index line", fp.Path())
+	}
+	if objects.NewBlob(contents).ID() != objects.ID(fp.OldID) {
+		return nil, errors.Errorf("the patch applies to '%s' (%s), which does not match the current contents", fp.Path(), fp.OldID)
+	}
+	return fp.NewData, nil
+}
+
+// Returns the lines a hunk expects to find and the lines it replaces them
+// with
+func images(h diff.Hunk) ([]string, []string) {
+	var pre, post []string
+	for _, le := range h.Edits {
+		line := le.Text
+		if !le.NoNewline {
+			line += "\n"
+		}
+		if le.EditType != diff.INS {
+			pre = append(pre, line)
+		}
+		if le.EditType != diff.DEL {
+			post = append(post, line)
+		}
+	}
+	return pre, post
+}
+
+// Searches for pre in lines at or after min, starting at want and moving
+// outwards from it
+func find(lines, pre []string, want, min int) (int, bool) {
+	max := len(lines) - len(pre)
+	for d := 0; want-d >= min || want+d <= max; d++ {
+		if p := want - d; p >= min && p <= max && matches(lines[p:], pre) {
+			return p, true
+		}
+		if p := want + d; d > 0 && p >= min && p <= max && matches(lines[p:], pre) {
+			return p, true
+		}
+	}
+	return 0, false
+}
+
+func matches(lines, pre []string) bool {
+	for i, l := range pre {
+		if lines[i] != l {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,430 @@
+package patch
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"got/internal/diff"
+)
+
+// The changes a patch makes to a single file. Paths have their first
+// component, i.e. the 'a/' or 'b/' prefix, stripped.
+type FilePatch struct {
+	// Empty if the patch creates the file
+	OldPath string
+	// Empty if the patch deletes the file
+	NewPath string
+	// Zero if the patch doesn't mention the mode
+	OldMode os.FileMode
+	NewMode os.FileMode
+	// The possibly abbreviated blob IDs of the 'index' line
+	OldID string
+	NewID string
+	// Set if NewPath is a renamed or copied OldPath
+	Rename bool
+	Copy   bool
+	Hunks  diff.Hunks
+	// Set for patches of binary files, which replace the file as a whole.
+	// Git binary patches have the contents of the file after the patch in
+	// NewData and before it in OldData, while other binary patches only say
+	// that the file differs and can't be applied.
+	Binary  bool
+	NewData []byte
+	OldData []byte
+}
+
+// Reports whether the patch creates its file
+func (fp *FilePatch) IsNew() bool {
+	return fp.OldPath == ""
+}
+
+// Reports whether the patch deletes its file
+func (fp *FilePatch) IsDelete() bool {
+	return fp.NewPath == ""
+}
+
+// Returns the path of the file after the patch, or before it if the patch
+// deletes the file.
+func (fp *FilePatch) Path() string {
+	if fp.NewPath != "" {
+		return fp.NewPath
+	}
+	return fp.OldPath
+}
+
+// Returns a patch that undoes the changes of this one
+func (fp *FilePatch) Reverse() *FilePatch {
+	r := &FilePatch{
+		OldPath: fp.NewPath,
+		NewPath: fp.OldPath,
+		OldMode: fp.NewMode,
+		NewMode: fp.OldMode,
+		OldID:   fp.NewID,
+		NewID:   fp.OldID,
+		Rename:  fp.Rename,
+		Copy:    fp.Copy,
+		Binary:  fp.Binary,
+		NewData: fp.OldData,
+		OldData: fp.NewData,
+	}
+	for _, h := range fp.Hunks {
+		rh := diff.Hunk{
+			SrcStart: h.DstStart,
+			SrcLines: h.DstLines,
+			DstStart: h.SrcStart,
+			DstLines: h.SrcLines,
+		}
+		for _, le := range h.Edits {
+			switch le.EditType {
+			case diff.INS:
+				le.EditType = diff.DEL
+			case diff.DEL:
+				le.EditType = diff.INS
+			}
+			le.ALine, le.BLine = le.BLine, le.ALine
+			rh.Edits = append(rh.Edits, le)
+		}
+		r.Hunks = append(r.Hunks, rh)
+	}
+	return r
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+type parser struct {
+	r       *bufio.Reader
+	line    string
+	lineNum int
+	eof     bool
+}
+
+// Parses the file patches of a unified diff. Anything that isn't part of a
+// file patch, such as the message of a mailed commit, is skipped. Both the
+// extended headers written by got and git and plain unified diffs are
+// understood.
+func Parse(r io.Reader) ([]*FilePatch, error) {
+	p := &parser{r: bufio.NewReader(r)}
+	var patches []*FilePatch
+	p.next()
+	for !p.eof {
+		switch {
+		case strings.HasPrefix(p.line, "diff --got ") || strings.HasPrefix(p.line, "diff --git "):
+			fp, err := p.parseFilePatch()
+			if err != nil {
+				return nil, err
+			}
+			patches = append(patches, fp)
+		case strings.HasPrefix(p.line, "--- ") && p.peekIs("+++ "):
+			fp := &FilePatch{}
+			err := p.parseBody(fp)
+			if err != nil {
+				return nil, err
+			}
+			patches = append(patches, fp)
+		default:
+			p.next()
+		}
+	}
+	return patches, nil
+}
+
+func (p *parser) next() {
+	line, err := p.r.ReadString('\n')
+	if err != nil && line == "" {
+		p.eof = true
+		p.line = ""
+		return
+	}
+	p.lineNum++
+	// A '\r' in front of the newline belongs to the line, like the CRLF
+	// line endings of the files being patched
+	p.line = strings.TrimSuffix(line, "\n")
+}
+
+func (p *parser) peekIs(prefix string) bool {
+	bs, _ := p.r.Peek(len(prefix))
+	return string(bs) == prefix
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return errors.Errorf("line %d: "+format, append([]interface{}{p.lineNum}, args...)...)
+}
+
+// Parses a patch starting at its 'diff --got' line
+func (p *parser) parseFilePatch() (*FilePatch, error) {
+	fp := &FilePatch{}
+	old, new := splitDiffPaths(p.line[len("diff --got "):])
+	fp.OldPath, fp.NewPath = old, new
+	p.next()
+	for !p.eof {
+		line := p.line
+		var err error
+		switch {
+		case strings.HasPrefix(line, "new file mode "):
+			fp.OldPath = ""
+			fp.NewMode, err = diff.ParseMode(strings.TrimPrefix(line, "new file mode "))
+		case strings.HasPrefix(line, "deleted file mode "):
+			fp.NewPath = ""
+			fp.OldMode, err = diff.ParseMode(strings.TrimPrefix(line, "deleted file mode "))
+		case strings.HasPrefix(line, "old mode "):
+			fp.OldMode, err = diff.ParseMode(strings.TrimPrefix(line, "old mode "))
+		case strings.HasPrefix(line, "new mode "):
+			fp.NewMode, err = diff.ParseMode(strings.TrimPrefix(line, "new mode "))
+		case strings.HasPrefix(line, "rename from "):
+			fp.Rename = true
+			fp.OldPath = strings.TrimPrefix(line, "rename from ")
+		case strings.HasPrefix(line, "rename to "):
+			fp.Rename = true
+			fp.NewPath = strings.TrimPrefix(line, "rename to ")
+		case strings.HasPrefix(line, "copy from "):
+			fp.Copy = true
+			fp.OldPath = strings.TrimPrefix(line, "copy from ")
+		case strings.HasPrefix(line, "copy to "):
+			fp.Copy = true
+			fp.NewPath = strings.TrimPrefix(line, "copy to ")
+		case strings.HasPrefix(line, "index "):
+			err = parseIndexLine(fp, strings.TrimPrefix(line, "index "))
+		case strings.HasPrefix(line, "similarity index "), strings.HasPrefix(line, "dissimilarity index "):
+		case strings.HasPrefix(line, "--- "), strings.HasPrefix(line, "@@ "):
+			return fp, p.parseBody(fp)
+		case line == diff.BinaryPatchMarker:
+			return fp, p.parseBinary(fp)
+		case strings.HasPrefix(line, "Binary files ") && strings.HasSuffix(line, " differ"):
+			fp.Binary = true
+			p.next()
+			return fp, nil
+		default:
+			// The patch has no hunks, e.g. because it only changes the mode
+			return fp, nil
+		}
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		p.next()
+	}
+	return fp, nil
+}
+
+// Parses the '---' and '+++' lines, if any, and the hunks of a patch
+func (p *parser) parseBody(fp *FilePatch) error {
+	if strings.HasPrefix(p.line, "--- ") {
+		fp.OldPath = parsePatchPath(p.line[len("--- "):])
+		p.next()
+		if !strings.HasPrefix(p.line, "+++ ") {
+			return p.errorf("expected '+++' line after '---' line")
+		}
+		fp.NewPath = parsePatchPath(p.line[len("+++ "):])
+		p.next()
+	}
+	for !p.eof && strings.HasPrefix(p.line, "@@ ") {
+		h, err := p.parseHunk()
+		if err != nil {
+			return err
+		}
+		fp.Hunks = append(fp.Hunks, h)
+	}
+	return nil
+}
+
+// Parses the 'literal' hunks of a git binary patch, starting at its 'GIT
+// binary patch' line. The second hunk, which undoes the patch, is optional.
+func (p *parser) parseBinary(fp *FilePatch) error {
+	fp.Binary = true
+	p.next()
+	var err error
+	fp.NewData, err = p.parseLiteral()
+	if err != nil {
+		return err
+	}
+	if !p.eof && (strings.HasPrefix(p.line, "literal ") || strings.HasPrefix(p.line, "delta ")) {
+		fp.OldData, err = p.parseLiteral()
+	}
+	return err
+}
+
+// Parses a 'literal' line and the lines of base85 after it up to the empty
+// line ending the hunk
+func (p *parser) parseLiteral() ([]byte, error) {
+	if strings.HasPrefix(p.line, "delta ") {
+		return nil, p.errorf("delta binary patches aren't supported")
+	}
+	if !strings.HasPrefix(p.line, "literal ") {
+		return nil, p.errorf("expected 'literal' line in binary patch")
+	}
+	size, err := strconv.Atoi(strings.TrimPrefix(p.line, "literal "))
+	if err != nil {
+		return nil, p.errorf("malformed binary patch size %q", p.line)
+	}
+	p.next()
+	var lines []string
+	for !p.eof && p.line != "" {
+		lines = append(lines, p.line)
+		p.next()
+	}
+	data, err := diff.DecodeLiteral(size, lines)
+	if err != nil {
+		return nil, p.errorf("%v", err)
+	}
+	p.next()
+	if data == nil {
+		data = []byte{}
+	}
+	return data, nil
+}
+
+func (p *parser) parseHunk() (diff.Hunk, error) {
+	m := hunkHeader.FindStringSubmatch(p.line)
+	if m == nil {
+		return diff.Hunk{}, p.errorf("malformed hunk header %q", p.line)
+	}
+	h := diff.Hunk{
+		SrcStart: atoi(m[1], 0),
+		SrcLines: atoi(m[2], 1),
+		DstStart: atoi(m[3], 0),
+		DstLines: atoi(m[4], 1),
+	}
+	a, b := h.SrcStart-1, h.DstStart-1
+	if h.SrcLines == 0 {
+		a++
+	}
+	if h.DstLines == 0 {
+		b++
+	}
+	srcLeft, dstLeft := h.SrcLines, h.DstLines
+	p.next()
+	for srcLeft > 0 || dstLeft > 0 {
+		if p.eof {
+			return diff.Hunk{}, p.errorf("unexpected end of patch in hunk")
+		}
+		var editType diff.EditType
+		switch {
+		// Some editors strip the space of empty context lines
+		case p.line == "":
+			editType = diff.EQL
+			p.line = " "
+		case p.line[0] == ' ':
+			editType = diff.EQL
+		case p.line[0] == '-':
+			editType = diff.DEL
+		case p.line[0] == '+':
+			editType = diff.INS
+		case p.line[0] == '\\':
+			p.markNoNewline(&h)
+			p.next()
+			continue
+		default:
+			return diff.Hunk{}, p.errorf("malformed hunk line %q", p.line)
+		}
+		if editType != diff.INS {
+			srcLeft--
+		}
+		if editType != diff.DEL {
+			dstLeft--
+		}
+		if srcLeft < 0 || dstLeft < 0 {
+			return diff.Hunk{}, p.errorf("hunk has more lines than its header says")
+		}
+		h.Edits = append(h.Edits, diff.NewLineEdit(editType, p.line[1:], a, b))
+		if editType != diff.INS {
+			a++
+		}
+		if editType != diff.DEL {
+			b++
+		}
+		p.next()
+	}
+	if !p.eof && strings.HasPrefix(p.line, "\\") {
+		p.markNoNewline(&h)
+		p.next()
+	}
+	return h, nil
+}
+
+func (p *parser) markNoNewline(h *diff.Hunk) {
+	if len(h.Edits) > 0 {
+		h.Edits[len(h.Edits)-1].NoNewline = true
+	}
+}
+
+// Splits the 'a/<old> b/<new>' of a 'diff --got' line. Paths containing
+// spaces are only split correctly if both are the same, which they are
+// unless the file is renamed or copied, in which case the 'rename' or 'copy'
+// lines name the paths anyway.
+func splitDiffPaths(s string) (string, string) {
+	if len(s)%2 == 1 {
+		half := len(s) / 2
+		old, new := s[:half], s[half+1:]
+		if s[half] == ' ' && stripComponent(old) == stripComponent(new) {
+			return stripComponent(old), stripComponent(new)
+		}
+	}
+	i := strings.Index(s, " b/")
+	if i < 0 {
+		i = strings.LastIndex(s, " ")
+	}
+	if i < 0 {
+		return stripComponent(s), stripComponent(s)
+	}
+	return stripComponent(s[:i]), stripComponent(s[i+1:])
+}
+
+// Parses the path of a '---' or '+++' line, which may be followed by a tab
+// and a timestamp
+func parsePatchPath(s string) string {
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	if s == diff.DevNull {
+		return ""
+	}
+	return stripComponent(s)
+}
+
+func stripComponent(path string) string {
+	if i := strings.IndexByte(path, '/'); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
+
+func parseIndexLine(fp *FilePatch, s string) error {
+	fields := strings.Fields(s)
+	ids := strings.Split(fields[0], "..")
+	if len(ids) != 2 {
+		return errors.Errorf("malformed index line %q", s)
+	}
+	fp.OldID, fp.NewID = nonNull(ids[0]), nonNull(ids[1])
+	if len(fields) > 1 {
+		mode, err := diff.ParseMode(fields[1])
+		if err != nil {
+			return err
+		}
+		fp.OldMode, fp.NewMode = mode, mode
+	}
+	return nil
+}
+
+// Returns the ID, or nothing if it's the all-zero ID of a missing file
+func nonNull(id string) string {
+	if strings.Trim(id, "0") == "" {
+		return ""
+	}
+	return id
+}
+
+func atoi(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return def
+	}
+	return i
+}
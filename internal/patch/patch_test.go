@@ -0,0 +1,205 @@
+package patch
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/gookit/color"
+	"github.com/stretchr/testify/assert"
+
+	"got/internal/diff"
+	"got/internal/diff/algorithm"
+)
+
+func init() {
+	color.Disable()
+}
+
+// Generates the contents of a file from a small alphabet of lines, with or
+// without a newline at the end. A third of the files have CRLF line endings.
+func randomContents(r *rand.Rand) []byte {
+	n := r.Intn(30)
+	crlf := r.Intn(3) == 0
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = string(rune('a' + r.Intn(6)))
+		if crlf {
+			lines[i] += "\r"
+		}
+	}
+	s := strings.Join(lines, "\n")
+	if n > 0 && r.Intn(4) > 0 {
+		s += "\n"
+	}
+	return []byte(s)
+}
+
+type contents []byte
+
+func (contents) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(contents(randomContents(r)))
+}
+
+func writePatch(a, b []byte, context int) *bytes.Buffer {
+	buf := bytes.NewBuffer(nil)
+	fd := diff.NewInPlaceFileDiff(0644, 0644, "1111111", "2222222", "f.txt")
+	fd.DstPath = "f.txt"
+	fd.WritePatch(buf, algorithm.Myers{}.DiffBytes(a, b).Hunks(context))
+	return buf
+}
+
+func TestRoundTrip(t *testing.T) {
+	property := func(a, b contents, context uint8) bool {
+		fps, err := Parse(writePatch(a, b, int(context%4)))
+		if err != nil {
+			return false
+		}
+		if bytes.Equal(a, b) {
+			return len(fps) == 0
+		}
+		forward, err := Apply(a, fps[0].Hunks)
+		if err != nil || !bytes.Equal(forward, b) {
+			return false
+		}
+		backward, err := Apply(b, fps[0].Reverse().Hunks)
+		return err == nil && bytes.Equal(backward, a)
+	}
+	assert.Nil(t, quick.Check(property, &quick.Config{MaxCount: 1000}))
+}
+
+func TestRoundTripCRLF(t *testing.T) {
+	a := []byte("one\r\ntwo\r\nthree\r\n")
+	b := []byte("one\r\n2\r\nthree\r\n")
+	fps, err := Parse(writePatch(a, b, 3))
+	assert.Nil(t, err)
+	res, err := Apply(a, fps[0].Hunks)
+	assert.Nil(t, err)
+	assert.Equal(t, string(b), string(res))
+}
+
+func TestApplyWithOffset(t *testing.T) {
+	a := []byte("1\n2\n3\n4\n5\n6\n7\n8\n9\n")
+	b := []byte("1\n2\n3\nfour\n5\n6\n7\n8\nnine\n")
+	fps, err := Parse(writePatch(a, b, 1))
+	assert.Nil(t, err)
+
+	moved := []byte("0\n0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n")
+	res, err := Apply(moved, fps[0].Hunks)
+	assert.Nil(t, err)
+	assert.Equal(t, "0\n0\n1\n2\n3\nfour\n5\n6\n7\n8\nnine\n", string(res))
+
+	_, err = Apply([]byte("1\n2\n3\nx\n5\n6\n7\n8\n9\n"), fps[0].Hunks)
+	assert.EqualError(t, err, "hunk #1 (@@ -3,3 +3,3 @@) doesn't apply")
+}
+
+func TestParseExtendedHeaders(t *testing.T) {
+	input := `From 1234 Mon Sep 17 00:00:00 2001
+Subject: [PATCH] Move things around
+
+diff --got a/old.txt b/new.txt
+similarity index 90%
+rename from old.txt
+rename to new.txt
+index 1111111..2222222 100644
+--- a/old.txt
++++ b/new.txt
+@@ -1 +1 @@
+-a
++b
+diff --got a/run.sh b/run.sh
+old mode 100644
+new mode 100755
+diff --got a/gone.txt b/gone.txt
+deleted file mode 100644
+index 3333333..0000000
+--- a/gone.txt
++++ /dev/null
+@@ -1,2 +0,0 @@
+-x
+-y
+\ No newline at end of file
+--- plain.txt	2020-01-01 00:00:00
++++ plain.txt	2020-01-02 00:00:00
+@@ -0,0 +1 @@
++z
+`
+	fps, err := Parse(strings.NewReader(input))
+	assert.Nil(t, err)
+	assert.Len(t, fps, 4)
+
+	assert.Equal(t, "old.txt", fps[0].OldPath)
+	assert.Equal(t, "new.txt", fps[0].NewPath)
+	assert.True(t, fps[0].Rename)
+	assert.Equal(t, "1111111", fps[0].OldID)
+	assert.Len(t, fps[0].Hunks, 1)
+
+	assert.Equal(t, "run.sh", fps[1].Path())
+	assert.Equal(t, 0644, int(fps[1].OldMode))
+	assert.Equal(t, 0755, int(fps[1].NewMode))
+	assert.Empty(t, fps[1].Hunks)
+
+	assert.True(t, fps[2].IsDelete())
+	assert.Equal(t, "", fps[2].NewID)
+	res, err := Apply([]byte("x\ny"), fps[2].Hunks)
+	assert.Nil(t, err)
+	assert.Empty(t, res)
+
+	assert.Equal(t, "plain.txt", fps[3].Path())
+}
+
+func TestParseBinary(t *testing.T) {
+	// As written by git diff --binary
+	input := `diff --git a/x.bin b/x.bin
+index 735a95829dd77133742c0416d45e0e0de7153ef6..94061424c950fccc6cc6d024c2a2b040a75676f1 100644
+GIT binary patch
+literal 7
+OcmYdHVn|L&O#=W3;sRCx
+
+literal 5
+McmYdHVn|K_00f)?ivR!s
+
+diff --got a/y.png b/y.png
+index 1111111..2222222 100644
+Binary files a/y.png and b/y.png differ
+`
+	fps, err := Parse(strings.NewReader(input))
+	assert.Nil(t, err)
+	assert.Len(t, fps, 2)
+	assert.True(t, fps[0].Binary)
+	assert.Equal(t, []byte("ab\x00cdef"), fps[0].NewData)
+	assert.Equal(t, []byte("ab\x00cd"), fps[0].OldData)
+
+	contents, err := ApplyBinary(fps[0], []byte("ab\x00cd"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("ab\x00cdef"), contents)
+	contents, err = ApplyBinary(fps[0].Reverse(), []byte("ab\x00cdef"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("ab\x00cd"), contents)
+	_, err = ApplyBinary(fps[0], []byte("other"))
+	assert.Error(t, err)
+
+	assert.True(t, fps[1].Binary)
+	_, err = ApplyBinary(fps[1], nil)
+	assert.EqualError(t, err, "cannot apply binary patch to 'y.png' without full index line")
+}
+
+func TestWhitespaceErrors(t *testing.T) {
+	a := []byte("a\nb\nc\n")
+	b := []byte("a\nb  \n \tc\nd\n")
+	fps, err := Parse(writePatch(a, b, 1))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"f.txt:2: trailing whitespace.",
+		"f.txt:3: space before tab in indent.",
+	}, fps[0].WhitespaceErrors())
+
+	fps[0].FixWhitespace()
+	assert.Empty(t, fps[0].WhitespaceErrors())
+	res, err := Apply(a, fps[0].Hunks)
+	assert.Nil(t, err)
+	assert.Equal(t, "a\nb\n\tc\nd\n", string(res))
+}
@@ -0,0 +1,45 @@
+package patch
+
+import (
+	"fmt"
+	"strings"
+
+	"got/internal/diff"
+)
+
+// Returns the whitespace errors of the lines the patch adds, one for each
+// line that has any, as '<path>:<line>: <errors>.' with the number of the
+// line in the patched file
+func (fp *FilePatch) WhitespaceErrors() []string {
+	var errs []string
+	for _, h := range fp.Hunks {
+		line := h.DstStart
+		for _, le := range h.Edits {
+			if le.EditType == diff.DEL {
+				continue
+			}
+			if le.EditType == diff.INS {
+				var kinds []string
+				for _, e := range diff.WhitespaceErrors(le.Text) {
+					kinds = append(kinds, e.Kind)
+				}
+				if len(kinds) > 0 {
+					errs = append(errs, fmt.Sprintf("%s:%d: %s.", fp.Path(), line, strings.Join(kinds, ", ")))
+				}
+			}
+			line++
+		}
+	}
+	return errs
+}
+
+// Fixes the whitespace errors of the lines the patch adds
+func (fp *FilePatch) FixWhitespace() {
+	for _, h := range fp.Hunks {
+		for i, le := range h.Edits {
+			if le.EditType == diff.INS {
+				h.Edits[i].Text = diff.FixWhitespace(le.Text)
+			}
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package pathspec
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// A list of patterns selecting paths in a repository, with git's pathspec
+// semantics. A pattern matches a path that is equal to it, a path inside the
+// directory it names, or, if it has wildcards, a path or leading directory
+// of a path that it matches as a glob. Patterns may start with magic that
+// changes how they match:
+//
+//	:(top) or :/       the pattern is relative to the root of the repository
+//	                   instead of the working directory
+//	:(exclude) or :!   paths that the pattern matches are excluded
+//	:(icase)           letters match regardless of case
+//	:(literal)         wildcards are matched literally
+//	:(glob)            '*' doesn't match '/', and '**/' matches any number
+//	                   of directories
+//
+// Magic words can be combined, e.g. ':(top,icase)'. A pathspec without any
+// patterns, or with only excluding ones, matches every path.
+type Pathspec struct {
+	items []item
+}
+
+type item struct {
+	// The pattern as given, for error messages
+	original string
+	// The pattern relative to the repository root, empty for the whole
+	// repository
+	pattern string
+	exclude bool
+	icase   bool
+	literal bool
+	glob    bool
+	// Set if the pattern ends with a separator and only matches directories
+	dir bool
+}
+
+// Parses patterns relative to prefix, the repository relative path of the
+// working directory
+func Parse(prefix string, patterns ...string) (*Pathspec, error) {
+	ps := &Pathspec{}
+	for _, p := range patterns {
+		it, err := parseItem(prefix, p)
+		if err != nil {
+			return nil, err
+		}
+		ps.items = append(ps.items, it)
+	}
+	return ps, nil
+}
+
+// Returns a pathspec matching the given repository relative paths literally
+func Paths(paths ...string) *Pathspec {
+	ps := &Pathspec{}
+	for _, p := range paths {
+		ps.items = append(ps.items, item{original: p, pattern: filepath.Clean(p), literal: true})
+	}
+	return ps
+}
+
+func parseItem(prefix, s string) (item, error) {
+	it := item{original: s}
+	pattern := s
+	switch {
+	case strings.HasPrefix(s, ":("):
+		end := strings.Index(s, ")")
+		if end < 0 {
+			return it, errors.Errorf("missing ')' at the end of pathspec magic in '%s'", s)
+		}
+		for _, word := range strings.Split(s[2:end], ",") {
+			switch strings.TrimSpace(word) {
+			case "top":
+				prefix = ""
+			case "exclude":
+				it.exclude = true
+			case "icase":
+				it.icase = true
+			case "literal":
+				it.literal = true
+			case "glob":
+				it.glob = true
+			case "":
+			default:
+				return it, errors.Errorf("invalid pathspec magic '%s' in '%s'", word, s)
+			}
+		}
+		pattern = s[end+1:]
+	case strings.HasPrefix(s, ":"):
+		i := 1
+	short:
+		for ; i < len(s); i++ {
+			switch s[i] {
+			case '/':
+				prefix = ""
+			case '!', '^':
+				it.exclude = true
+			case ':':
+				i++
+				break short
+			default:
+				break short
+			}
+		}
+		pattern = s[i:]
+	}
+	if it.literal && it.glob {
+		return it, errors.Errorf("'%s': 'literal' and 'glob' are incompatible", s)
+	}
+	it.dir = strings.HasSuffix(pattern, "/")
+	joined := filepath.Clean(filepath.Join(prefix, pattern))
+	if joined == ".." || strings.HasPrefix(joined, ".."+string(filepath.Separator)) || filepath.IsAbs(pattern) {
+		return it, errors.Errorf("'%s' is outside repository", s)
+	}
+	if joined != "." {
+		it.pattern = joined
+	}
+	return it, nil
+}
+
+// Reports whether the pathspec has no patterns
+func (ps *Pathspec) Empty() bool {
+	return ps == nil || len(ps.items) == 0
+}
+
+// Reports whether a repository relative path is matched by any pattern that
+// doesn't exclude it, and by none that does
+func (ps *Pathspec) Matches(path string) bool {
+	if ps.Empty() {
+		return true
+	}
+	included, hasIncludes := false, false
+	for _, it := range ps.items {
+		if it.exclude {
+			continue
+		}
+		hasIncludes = true
+		if it.matches(path) {
+			included = true
+			break
+		}
+	}
+	if hasIncludes && !included {
+		return false
+	}
+	for _, it := range ps.items {
+		if it.exclude && it.matches(path) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the patterns, as given, that don't exclude paths and match none of
+// the paths
+func (ps *Pathspec) Unmatched(paths []string) []string {
+	if ps.Empty() {
+		return nil
+	}
+	var unmatched []string
+	for _, it := range ps.items {
+		if it.exclude {
+			continue
+		}
+		found := false
+		for _, p := range paths {
+			if it.matches(p) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			unmatched = append(unmatched, it.original)
+		}
+	}
+	return unmatched
+}
+
+func (it item) matches(path string) bool {
+	pattern := it.pattern
+	if it.icase {
+		pattern, path = strings.ToLower(pattern), strings.ToLower(path)
+	}
+	if pattern == "" {
+		return true
+	}
+	if !it.dir && path == pattern {
+		return true
+	}
+	if strings.HasPrefix(path, pattern+string(filepath.Separator)) {
+		return true
+	}
+	if it.literal || !strings.ContainsAny(pattern, `*?[\`) {
+		return false
+	}
+	// The pattern may match the path or any directory it's in
+	for p := path; ; {
+		if (!it.dir || p != path) && Wildmatch(pattern, p, it.glob) {
+			return true
+		}
+		i := strings.LastIndexByte(p, filepath.Separator)
+		if i < 0 {
+			return false
+		}
+		p = p[:i]
+	}
+}
+
+// Matches name against a glob pattern. With pathname wildcards don't match
+// separators, except for '**', which matches anything at the end of the
+// pattern and any number of directories when followed by a separator.
+func Wildmatch(pattern, name string, pathname bool) bool {
+	const sep = filepath.Separator
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			stars := len(pattern) - len(strings.TrimLeft(pattern, "*"))
+			pattern = pattern[stars:]
+			if pathname && stars >= 2 {
+				if pattern == "" {
+					return true
+				}
+				if pattern[0] == sep {
+					rest := pattern[1:]
+					if Wildmatch(rest, name, true) {
+						return true
+					}
+					for i := 0; i < len(name); i++ {
+						if name[i] == sep && Wildmatch(rest, name[i+1:], true) {
+							return true
+						}
+					}
+					return false
+				}
+			}
+			for i := 0; i <= len(name); i++ {
+				if Wildmatch(pattern, name[i:], pathname) {
+					return true
+				}
+				if i < len(name) && pathname && name[i] == sep {
+					return false
+				}
+			}
+			return false
+		case '?':
+			if name == "" || (pathname && name[0] == sep) {
+				return false
+			}
+		case '[':
+			width, ok := matchClass(pattern, name)
+			if !ok {
+				return false
+			}
+			pattern, name = pattern[width:], name[1:]
+			continue
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if name == "" || name[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return name == ""
+}
+
+// Matches the first byte of name against the character class at the start
+// of pattern, e.g. '[a-z]' or '[!0-9]', and returns the length of the class.
+// A '[' without a closing ']' only matches itself.
+func matchClass(pattern, name string) (int, bool) {
+	end := strings.IndexByte(pattern[1:], ']')
+	if end == 0 {
+		// A ']' right after the '[' is part of the class
+		end = strings.IndexByte(pattern[2:], ']') + 1
+	}
+	if end <= 0 {
+		return 1, name != "" && name[0] == '['
+	}
+	end++
+	if name == "" {
+		return 0, false
+	}
+	class := pattern[1:end]
+	negate := false
+	if class[0] == '!' || class[0] == '^' {
+		negate, class = true, class[1:]
+	}
+	c := name[0]
+	matched := false
+	for i := 0; i < len(class); i++ {
+		if i+2 < len(class) && class[i+1] == '-' {
+			if class[i] <= c && c <= class[i+2] {
+				matched = true
+			}
+			i += 2
+			continue
+		}
+		if class[i] == c {
+			matched = true
+		}
+	}
+	return end + 1, matched != negate
+}
@@ -0,0 +1,68 @@
+package pathspec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		prefix  string
+		pattern string
+		matches []string
+		misses  []string
+	}{
+		{"", "a.txt", []string{"a.txt"}, []string{"b.txt", "a.txt2", "dir/a.txt"}},
+		{"", "dir", []string{"dir", "dir/a.txt", "dir/sub/b.go"}, []string{"dir2/a.txt"}},
+		{"", "dir/", []string{"dir/a.txt"}, []string{"dir"}},
+		{"", ".", []string{"a.txt", "dir/a.txt"}, nil},
+		{"", "*.go", []string{"main.go", "cmd/got/main.go"}, []string{"main.go.txt"}},
+		{"", "src/**/*.go", []string{"src/a/b.go", "src/a/b/c.go"}, []string{"src/b.go", "lib/a/b.go"}},
+		{"", ":(glob)src/**/*.go", []string{"src/b.go", "src/a/b/c.go"}, []string{"lib/b.go"}},
+		{"", ":(glob)*.go", []string{"main.go"}, []string{"cmd/main.go"}},
+		{"", ":(glob)src/**", []string{"src/a", "src/a/b"}, []string{"src"}},
+		{"", "d?r/[a-c].txt", []string{"dir/a.txt", "dor/c.txt"}, []string{"dir/d.txt"}},
+		{"", "[!a]*", []string{"b.txt"}, []string{"a.txt"}},
+		{"", ":(literal)*.go", []string{"*.go"}, []string{"main.go"}},
+		{"", ":(icase)README.md", []string{"readme.md", "ReadMe.MD"}, []string{"readme.txt"}},
+		{"sub", "a.txt", []string{"sub/a.txt"}, []string{"a.txt"}},
+		{"sub", "../a.txt", []string{"a.txt"}, []string{"sub/a.txt"}},
+		{"sub", ":/a.txt", []string{"a.txt"}, []string{"sub/a.txt"}},
+		{"sub", ":(top)a.txt", []string{"a.txt"}, []string{"sub/a.txt"}},
+		{"", ":!a.txt", []string{"b.txt", "dir/a.txt"}, []string{"a.txt"}},
+		{"", ":(exclude)dir", []string{"a.txt"}, []string{"dir/a.txt"}},
+	}
+	for _, test := range tests {
+		ps, err := Parse(test.prefix, test.pattern)
+		assert.NoError(t, err, test.pattern)
+		for _, m := range test.matches {
+			assert.True(t, ps.Matches(m), "%s should match %s", test.pattern, m)
+		}
+		for _, m := range test.misses {
+			assert.False(t, ps.Matches(m), "%s shouldn't match %s", test.pattern, m)
+		}
+	}
+}
+
+func TestExcludeWithIncludes(t *testing.T) {
+	ps, err := Parse("", "dir", ":!dir/*.o")
+	assert.NoError(t, err)
+	assert.True(t, ps.Matches("dir/a.c"))
+	assert.False(t, ps.Matches("dir/a.o"))
+	assert.False(t, ps.Matches("other/a.c"))
+	assert.True(t, (&Pathspec{}).Matches("anything"))
+}
+
+func TestUnmatched(t *testing.T) {
+	ps, err := Parse("", "a.txt", "*.go", ":!b.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"*.go"}, ps.Unmatched([]string{"a.txt", "b.txt"}))
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, p := range []string{"../a", ":(top", ":(unknown)a", ":(literal,glob)a", "/abs"} {
+		_, err := Parse("", p)
+		assert.Error(t, err, p)
+	}
+}
@@ -1,6 +1,7 @@
 package terminal
 
 import (
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"strconv"
@@ -9,6 +10,16 @@ import (
 	"github.com/pkg/errors"
 )
 
+// Reports whether the file is a terminal rather than e.g. a pipe or a
+// regular file
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
 func Height() (int, error) {
 	cmd := exec.Command("stty", "size")
 	cmd.Stdin = os.Stdin
@@ -35,3 +46,34 @@ func RunLess(s string) error {
 	}
 	return nil
 }
+
+// Opens text in the editor, which is run by the shell with the name of a
+// temporary file holding the text, and returns the text as the editor left
+// it
+func Edit(editor, text string) (string, error) {
+	f, err := ioutil.TempFile("", "got-edit-*")
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't start editor")
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(text)
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't start editor")
+	}
+	cmd := exec.Command("sh", "-c", editor+` "$@"`, editor, f.Name())
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		return "", errors.Wrapf(err, "couldn't run editor %s", editor)
+	}
+	edited, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't read edited file")
+	}
+	return string(edited), nil
+}
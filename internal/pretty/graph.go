@@ -0,0 +1,132 @@
+package pretty
+
+import (
+	"bytes"
+	"strings"
+
+	"got/internal/objects"
+)
+
+// Draws an ASCII graph of the history next to the log entries. Entries must
+// be given in topological order, newest first.
+type Graph struct {
+	// The commits that each column of the graph is waiting for
+	columns []objects.ID
+}
+
+func NewGraph() *Graph {
+	return &Graph{}
+}
+
+// Returns text with the graph drawn to the left of it. The first line of text
+// is marked with a '*' in the column of the commit.
+func (g *Graph) Next(id objects.ID, parents []objects.ID, text string) string {
+	buf := bytes.NewBuffer(nil)
+	col := g.indexOf(id, 0)
+	if col < 0 {
+		g.columns = append(g.columns, id)
+		col = len(g.columns) - 1
+	}
+
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for i, line := range lines {
+		mark := "*"
+		if i > 0 {
+			mark = "|"
+			if len(parents) == 0 {
+				mark = " "
+			}
+		}
+		buf.WriteString(g.row(col, mark) + " " + line)
+		buf.WriteString("\n")
+	}
+
+	// Replace the commit with its parents, the first parent taking over the
+	// column of the commit
+	if len(parents) == 0 {
+		g.columns = append(g.columns[:col], g.columns[col+1:]...)
+		if col < len(g.columns) {
+			buf.WriteString(g.shiftRow(col) + "\n")
+		}
+		return buf.String()
+	}
+	g.columns[col] = parents[0]
+	added := 0
+	for _, p := range parents[1:] {
+		if g.indexOf(p, 0) >= 0 {
+			continue
+		}
+		at := col + 1 + added
+		g.columns = append(g.columns[:at], append([]objects.ID{p}, g.columns[at:]...)...)
+		added++
+	}
+	if added > 0 {
+		buf.WriteString(g.branchRow(col, added) + "\n")
+	}
+
+	// Columns waiting for the same commit are merged into the leftmost one
+	for i := range g.columns {
+		if j := g.indexOf(g.columns[i], i+1); j >= 0 {
+			g.columns = append(g.columns[:j], g.columns[j+1:]...)
+			buf.WriteString(g.collapseRow(j) + "\n")
+			break
+		}
+	}
+	return buf.String()
+}
+
+func (g *Graph) indexOf(id objects.ID, from int) int {
+	for i := from; i < len(g.columns); i++ {
+		if g.columns[i] == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// A row with mark in column col and '|' in every other column
+func (g *Graph) row(col int, mark string) string {
+	var cells []string
+	for i := range g.columns {
+		if i == col {
+			cells = append(cells, mark)
+		} else {
+			cells = append(cells, "|")
+		}
+	}
+	return strings.Join(cells, " ")
+}
+
+// A row showing n new columns branching off to the right of col, as in '|\'
+func (g *Graph) branchRow(col int, n int) string {
+	var s string
+	for i := 0; i <= col; i++ {
+		if i > 0 {
+			s += " "
+		}
+		s += "|"
+	}
+	s += strings.Repeat("\\ ", n)
+	for i := col + n + 1; i < len(g.columns); i++ {
+		s += "\\ "
+	}
+	return strings.TrimRight(s, " ")
+}
+
+// A row showing column col joining the column to its left, as in '|/'
+func (g *Graph) collapseRow(col int) string {
+	s := strings.Repeat("| ", col-1) + "|/"
+	for i := col; i < len(g.columns); i++ {
+		s += " /"
+	}
+	return s
+}
+
+// A row showing the columns right of a removed column moving left
+func (g *Graph) shiftRow(col int) string {
+	s := strings.Repeat("| ", col)
+	for i := col; i < len(g.columns); i++ {
+		s += " /"
+	}
+	return strings.TrimRight(s, " ")
+}
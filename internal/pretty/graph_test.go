@@ -0,0 +1,40 @@
+package pretty
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"got/internal/objects"
+)
+
+func TestGraphMerge(t *testing.T) {
+	// f merges e into c, both of which descend from b
+	g := NewGraph()
+	var out string
+	out += g.Next("f", []objects.ID{"c", "e"}, "f")
+	out += g.Next("e", []objects.ID{"d"}, "e")
+	out += g.Next("d", []objects.ID{"b"}, "d")
+	out += g.Next("c", []objects.ID{"b"}, "c")
+	out += g.Next("b", []objects.ID{"a"}, "b")
+	out += g.Next("a", nil, "a")
+
+	expected := `* f
+|\
+| * e
+| * d
+* | c
+|/
+* b
+* a
+`
+	assert.Equal(t, expected, out)
+}
+
+func TestExpand(t *testing.T) {
+	parent := objects.ID("0123456789012345678901234567890123456789")
+	c := objects.NewCommit("tree", &parent, "Jane Doe <jane@doe.com> 0 +0000", "subject\n\nbody")
+	e := Entry{ID: "abcdefabcdefabcdefabcdefabcdefabcdefabcd", Commit: c, Decorations: []string{"HEAD -> master"}}
+	assert.Equal(t, "abcdefa 0123456 Jane Doe <jane@doe.com>%d (HEAD -> master) subject/body",
+		Expand("%h %p %an <%ae>%%d%d %s/%b", e))
+}
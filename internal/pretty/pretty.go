@@ -0,0 +1,241 @@
+package pretty
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gookit/color"
+
+	"got/internal/objects"
+)
+
+// The length of abbreviated IDs
+const AbbrevLength = 7
+
+// The built-in formats that can be given to --pretty
+const (
+	Oneline = "oneline"
+	Short   = "short"
+	Medium  = "medium"
+	Full    = "full"
+)
+
+const dateLayout = "Mon Jan 2 15:04:05 2006 -0700"
+
+// A commit together with what is needed to describe it
+type Entry struct {
+	ID          objects.ID
+	Commit      objects.Commit
+	Decorations []string
+}
+
+// Formats the entry according to a --pretty argument, which is either the
+// name of a built-in format, 'format:<placeholders>', 'tformat:<placeholders>'
+// or a string of placeholders. The returned string always ends with a
+// newline.
+func Format(pretty string, e Entry) string {
+	switch pretty {
+	case Oneline:
+		return formatOneline(e)
+	case Short, Medium, Full, "":
+		return formatLong(pretty, e)
+	}
+	pretty = strings.TrimPrefix(pretty, "tformat:")
+	pretty = strings.TrimPrefix(pretty, "format:")
+	return Expand(pretty, e) + "\n"
+}
+
+func formatOneline(e Entry) string {
+	s := color.Yellow.Sprint(Abbrev(e.ID))
+	if len(e.Decorations) > 0 {
+		s += " " + decorate(e.Decorations, true)
+	}
+	return s + " " + e.Commit.Subject() + "\n"
+}
+
+func formatLong(pretty string, e Entry) string {
+	buf := bytes.NewBuffer(nil)
+	header := "commit " + string(e.ID)
+	if len(e.Decorations) > 0 {
+		header += " " + decorate(e.Decorations, true)
+	}
+	fmt.Fprintln(buf, color.Yellow.Sprint(header))
+	if parents := e.Commit.Parents(); len(parents) > 1 {
+		var abbrevs []string
+		for _, p := range parents {
+			abbrevs = append(abbrevs, Abbrev(p))
+		}
+		fmt.Fprintf(buf, "Merge: %s\n", strings.Join(abbrevs, " "))
+	}
+	fmt.Fprintf(buf, "Author: %s <%s>\n", e.Commit.AuthorName(), e.Commit.AuthorEmail())
+	if pretty != Short {
+		if pretty == Full {
+			fmt.Fprintf(buf, "Commit: %s <%s>\n", e.Commit.AuthorName(), e.Commit.AuthorEmail())
+		} else {
+			fmt.Fprintf(buf, "Date:   %s\n", e.Commit.Time().Format(dateLayout))
+		}
+	}
+	fmt.Fprintln(buf)
+	message := strings.TrimRight(e.Commit.Message, "\n")
+	if pretty == Short {
+		message = e.Commit.Subject()
+	}
+	for _, line := range strings.Split(message, "\n") {
+		fmt.Fprintf(buf, "    %s\n", line)
+	}
+	return buf.String()
+}
+
+// Expands the placeholders in format:
+//
+//	%H, %h  commit ID, abbreviated commit ID
+//	%T, %t  tree ID, abbreviated tree ID
+//	%P, %p  parent IDs, abbreviated parent IDs
+//	%an     author name
+//	%ae     author email
+//	%ad     author date
+//	%ar     author date, relative
+//	%at     author date, UNIX timestamp
+//	%ai     author date, ISO 8601-like
+//	%s      subject
+//	%b      body
+//	%B      raw message
+//	%d, %D  ref names, ref names without the surrounding ' (' and ')'
+//	%n      newline
+//	%%      a raw '%'
+//	%Cred, %Cgreen, %Cblue, %Cyellow, %Creset  switch color
+//
+// Unknown placeholders are left as they are.
+func Expand(format string, e Entry) string {
+	buf := bytes.NewBuffer(nil)
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i == len(format)-1 {
+			buf.WriteByte(format[i])
+			continue
+		}
+		n, s := expandPlaceholder(format[i+1:], e)
+		if n == 0 {
+			buf.WriteByte(format[i])
+			continue
+		}
+		buf.WriteString(s)
+		i += n
+	}
+	return buf.String()
+}
+
+// Returns the number of bytes of the placeholder at the start of p, excluding
+// the '%', and what it expands to.
+func expandPlaceholder(p string, e Entry) (int, string) {
+	for name, code := range colors {
+		if strings.HasPrefix(p, name) {
+			return len(name), code
+		}
+	}
+	c := e.Commit
+	if len(p) >= 2 {
+		switch p[:2] {
+		case "an":
+			return 2, c.AuthorName()
+		case "ae":
+			return 2, c.AuthorEmail()
+		case "ad":
+			return 2, c.Time().Format(dateLayout)
+		case "ar":
+			return 2, RelativeDate(c.Time(), time.Now())
+		case "at":
+			return 2, fmt.Sprint(c.Time().Unix())
+		case "ai":
+			return 2, c.Time().Format("2006-01-02 15:04:05 -0700")
+		}
+	}
+	switch p[0] {
+	case 'H':
+		return 1, string(e.ID)
+	case 'h':
+		return 1, Abbrev(e.ID)
+	case 'T':
+		return 1, string(c.TreeID)
+	case 't':
+		return 1, Abbrev(c.TreeID)
+	case 'P', 'p':
+		var ps []string
+		for _, id := range c.Parents() {
+			if p[0] == 'p' {
+				ps = append(ps, Abbrev(id))
+			} else {
+				ps = append(ps, string(id))
+			}
+		}
+		return 1, strings.Join(ps, " ")
+	case 's':
+		return 1, c.Subject()
+	case 'b':
+		return 1, c.Body()
+	case 'B':
+		return 1, c.Message
+	case 'd':
+		if len(e.Decorations) == 0 {
+			return 1, ""
+		}
+		return 1, " " + decorate(e.Decorations, true)
+	case 'D':
+		return 1, decorate(e.Decorations, false)
+	case 'n':
+		return 1, "\n"
+	case '%':
+		return 1, "%"
+	}
+	return 0, ""
+}
+
+var colors = map[string]string{
+	"Cred":    "\x1b[31m",
+	"Cgreen":  "\x1b[32m",
+	"Cyellow": "\x1b[33m",
+	"Cblue":   "\x1b[34m",
+	"Creset":  "\x1b[0m",
+}
+
+func decorate(decorations []string, parens bool) string {
+	s := strings.Join(decorations, ", ")
+	if parens {
+		return "(" + s + ")"
+	}
+	return s
+}
+
+func Abbrev(id objects.ID) string {
+	if len(id) <= AbbrevLength {
+		return string(id)
+	}
+	return string(id)[:AbbrevLength]
+}
+
+// Describes how long before now t is, e.g. '3 days ago'
+func RelativeDate(t, now time.Time) string {
+	d := now.Sub(t)
+	plural := func(n int64, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s ago", unit)
+		}
+		return fmt.Sprintf("%d %ss ago", n, unit)
+	}
+	switch {
+	case d < time.Minute:
+		return plural(int64(d/time.Second), "second")
+	case d < time.Hour:
+		return plural(int64(d/time.Minute), "minute")
+	case d < 24*time.Hour:
+		return plural(int64(d/time.Hour), "hour")
+	case d < 14*24*time.Hour:
+		return plural(int64(d/(24*time.Hour)), "day")
+	case d < 60*24*time.Hour:
+		return plural(int64(d/(7*24*time.Hour)), "week")
+	case d < 365*24*time.Hour:
+		return plural(int64(d/(30*24*time.Hour)), "month")
+	}
+	return plural(int64(d/(365*24*time.Hour)), "year")
+}
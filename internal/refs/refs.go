@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 
 	"got/internal/pkg/filesystem"
 
@@ -17,6 +18,10 @@ import (
 const Dir = "refs"
 const HeadsDir = "heads"
 
+// The directory of the remote-tracking branches, which has a directory for
+// every remote
+const RemotesDir = "remotes"
+
 var refRegex *regexp.Regexp
 
 func init() {
@@ -38,10 +43,20 @@ func RefFromString(s string) (Ref, error) {
 }
 
 func (r Ref) Name() string {
+	if name, err := filepath.Rel(filepath.Join(Dir, RemotesDir), string(r)); err == nil && !strings.HasPrefix(name, "..") {
+		return name
+	}
 	name, _ := filepath.Rel(filepath.Join(Dir, HeadsDir), string(r))
 	return name
 }
 
+// Returns the ref of the remote-tracking branch that stores where a branch
+// of a remote was when it was last fetched, e.g.
+// 'refs/remotes/origin/master'
+func RemoteRef(remote, branch string) Ref {
+	return Ref(filepath.Join(Dir, RemotesDir, remote, branch))
+}
+
 type Refs struct {
 	gotDir string
 }
@@ -79,7 +94,10 @@ func (r *Refs) IDFromRef(ref Ref) (objects.ID, error) {
 }
 
 func (r *Refs) UpdateRef(ref Ref, id objects.ID) error {
-	err := ioutil.WriteFile(filepath.Join(r.gotDir, string(ref)), []byte(id), os.ModePerm)
+	err := os.MkdirAll(filepath.Dir(filepath.Join(r.gotDir, string(ref))), os.ModePerm)
+	if err == nil {
+		err = ioutil.WriteFile(filepath.Join(r.gotDir, string(ref)), []byte(id), os.ModePerm)
+	}
 	if err != nil {
 		return errors.Wrapf(err, "couldn't update ref to %s", id)
 	}
@@ -149,6 +167,68 @@ func (r *Refs) Branches() ([]string, error) {
 	return branches, nil
 }
 
+// Reports whether the ref, e.g. 'refs/remotes/origin/master', exists
+func (r *Refs) Exists(ref Ref) bool {
+	return filesystem.FileExists(filepath.Join(r.gotDir, string(ref)))
+}
+
+// Returns the remote-tracking branches of a remote by the names of the
+// branches on the remote
+func (r *Refs) RemoteBranches(remote string) (map[string]objects.ID, error) {
+	branches := make(map[string]objects.ID)
+	dir := filepath.Join(r.gotDir, Dir, RemotesDir, remote)
+	if !filesystem.DirExists(dir) {
+		return branches, nil
+	}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		branch, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		branches[branch], err = r.IDFromRef(RemoteRef(remote, branch))
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't get the branches of remote %s", remote)
+	}
+	return branches, nil
+}
+
+// Removes the remote-tracking branch of a branch of a remote
+func (r *Refs) DeleteRemoteRef(remote, branch string) error {
+	err := os.Remove(filepath.Join(r.gotDir, string(RemoteRef(remote, branch))))
+	if err != nil {
+		return errors.Wrapf(err, "couldn't delete %s/%s", remote, branch)
+	}
+	return nil
+}
+
+// Removes every remote-tracking branch of a remote
+func (r *Refs) DeleteRemoteRefs(remote string) error {
+	err := os.RemoveAll(filepath.Join(r.gotDir, Dir, RemotesDir, remote))
+	if err != nil {
+		return errors.Wrapf(err, "couldn't delete the branches of remote %s", remote)
+	}
+	return nil
+}
+
 func (r *Refs) headsDir() string {
 	return filepath.Join(r.gotDir, Dir, HeadsDir)
 }
+
+// Reports whether name can be the name of a branch, which mustn't lead out
+// of the directory of the branches
+func ValidBranchName(name string) bool {
+	if name == "" || filepath.IsAbs(name) {
+		return false
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,152 @@
+package revwalk
+
+import (
+	"container/heap"
+
+	"got/internal/objects"
+)
+
+const (
+	reachableFromA = 1 << iota
+	reachableFromB
+	stale
+)
+
+// Returns the best common ancestors of a and b, i.e. the common ancestors
+// that are not ancestors of another common ancestor. Usually there is only
+// one, but criss-cross merges can produce several.
+func (w *Walker) MergeBases(a, b objects.ID) ([]objects.ID, error) {
+	if a == b {
+		return []objects.ID{a}, nil
+	}
+	flags := make(map[objects.ID]int)
+	// The number of times each commit is queued, and the number of queued
+	// items whose commit isn't stale, which ends the walk when it drops to 0
+	queued := make(map[objects.ID]int)
+	active := 0
+	setFlags := func(id objects.ID, flag int) {
+		if flags[id]&stale == 0 && flag&stale != 0 {
+			active -= queued[id]
+		}
+		flags[id] |= flag
+	}
+	q := &queue{}
+	push := func(id objects.ID, flag int) error {
+		if flags[id]&flag == flag {
+			return nil
+		}
+		n, err := w.node(id)
+		if err != nil {
+			return err
+		}
+		setFlags(id, flag)
+		queued[id]++
+		if flags[id]&stale == 0 {
+			active++
+		}
+		heap.Push(q, &item{id: id, time: n.time, order: q.next()})
+		return nil
+	}
+	if err := push(a, reachableFromA); err != nil {
+		return nil, err
+	}
+	if err := push(b, reachableFromB); err != nil {
+		return nil, err
+	}
+
+	var candidates []objects.ID
+	for active > 0 {
+		it := heap.Pop(q).(*item)
+		queued[it.id]--
+		if flags[it.id]&stale == 0 {
+			active--
+		}
+		flag := flags[it.id] & (reachableFromA | reachableFromB | stale)
+		if flag&(reachableFromA|reachableFromB) == reachableFromA|reachableFromB && flag&stale == 0 {
+			candidates = append(candidates, it.id)
+			flag |= stale
+			setFlags(it.id, flag)
+		}
+		n, err := w.node(it.id)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range n.parents {
+			if err := push(p, flag); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// A candidate found early may still turn out to be an ancestor of another
+	// candidate when commit times are skewed
+	var bases []objects.ID
+	for _, c := range candidates {
+		reachable, err := w.reachableFromAny(c, candidates)
+		if err != nil {
+			return nil, err
+		}
+		if !reachable {
+			bases = append(bases, c)
+		}
+	}
+	return bases, nil
+}
+
+// Returns the first of the merge bases of a and b, or an empty ID if they
+// have no common ancestor.
+func (w *Walker) MergeBase(a, b objects.ID) (objects.ID, error) {
+	bases, err := w.MergeBases(a, b)
+	if err != nil || len(bases) == 0 {
+		return "", err
+	}
+	return bases[0], nil
+}
+
+// Returns true if ancestor can be reached by following the parents of id,
+// or if they are the same commit.
+func (w *Walker) IsAncestor(ancestor, id objects.ID) (bool, error) {
+	target, err := w.node(ancestor)
+	if err != nil {
+		return false, err
+	}
+	seen := map[objects.ID]bool{id: true}
+	stack := []objects.ID{id}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if current == ancestor {
+			return true, nil
+		}
+		n, err := w.node(current)
+		if err != nil {
+			return false, err
+		}
+		// Commits with a generation no greater than the ancestor's can't
+		// have it as an ancestor
+		if n.generation != 0 && target.generation != 0 && n.generation <= target.generation {
+			continue
+		}
+		for _, p := range n.parents {
+			if !seen[p] {
+				seen[p] = true
+				stack = append(stack, p)
+			}
+		}
+	}
+	return false, nil
+}
+
+// Reports whether id is an ancestor of any of the other commits
+func (w *Walker) reachableFromAny(id objects.ID, others []objects.ID) (bool, error) {
+	for _, o := range others {
+		if o == id {
+			continue
+		}
+		ok, err := w.IsAncestor(id, o)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
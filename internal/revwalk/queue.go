@@ -0,0 +1,47 @@
+package revwalk
+
+import "got/internal/objects"
+
+type item struct {
+	id   objects.ID
+	time int64
+	// Breaks ties between commits with the same time in insertion order
+	order int
+}
+
+// A priority queue of commits with the newest commit first
+type queue struct {
+	items []*item
+	count int
+}
+
+func (q *queue) next() int {
+	q.count++
+	return q.count
+}
+
+func (q queue) Len() int {
+	return len(q.items)
+}
+
+func (q queue) Less(i, j int) bool {
+	if q.items[i].time != q.items[j].time {
+		return q.items[i].time > q.items[j].time
+	}
+	return q.items[i].order < q.items[j].order
+}
+
+func (q queue) Swap(i, j int) {
+	q.items[i], q.items[j] = q.items[j], q.items[i]
+}
+
+func (q *queue) Push(x interface{}) {
+	q.items = append(q.items, x.(*item))
+}
+
+func (q *queue) Pop() interface{} {
+	old := q.items
+	it := old[len(old)-1]
+	q.items = old[:len(old)-1]
+	return it
+}
@@ -0,0 +1,414 @@
+package revwalk
+
+import (
+	"container/heap"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"got/internal/objects"
+)
+
+// The source of commits for a walk
+type CommitGetter interface {
+	GetCommit(id objects.ID) (objects.Commit, error)
+}
+
+// A cache of the parents, times and generation numbers of commits that lets
+// a walk avoid loading commit objects. A generation of 0 means unknown.
+type CommitGraph interface {
+	Lookup(id objects.ID) (parents []objects.ID, time int64, generation uint32, ok bool)
+}
+
+// The parts of a commit needed to traverse the history
+type node struct {
+	parents    []objects.ID
+	time       int64
+	generation uint32
+}
+
+// Resolves a revision such as a branch name, 'HEAD' or an ID into the ID of a
+// commit
+type Resolver func(rev string) (objects.ID, error)
+
+type Sort int
+
+const (
+	// Commits are listed newest first, which is also the order in which they
+	// are reached by the walk
+	SortDate Sort = 1 << iota
+	// No commit is listed before all of its children are listed
+	SortTopological
+	// Reverses the order of whichever of the other sorts is used
+	SortReverse
+)
+
+type Walker struct {
+	commits  CommitGetter
+	sort     Sort
+	maxCount int
+	include  []objects.ID
+	exclude  []objects.ID
+	graph    CommitGraph
+	cache    map[objects.ID]objects.Commit
+	nodes    map[objects.ID]node
+}
+
+func NewWalker(commits CommitGetter) *Walker {
+	return &Walker{
+		commits: commits,
+		sort:    SortDate,
+		cache:   make(map[objects.ID]objects.Commit),
+		nodes:   make(map[objects.ID]node),
+	}
+}
+
+// Makes the walker look up commits in the given graph before loading them
+func (w *Walker) UseGraph(graph CommitGraph) {
+	w.graph = graph
+}
+
+func (w *Walker) Sorting(sort Sort) {
+	w.sort = sort
+}
+
+// Stops the walk after the first n commits, or never if n is 0
+func (w *Walker) MaxCount(n int) {
+	w.maxCount = n
+}
+
+// Includes the given commit and its ancestors in the walk
+func (w *Walker) Push(id objects.ID) {
+	w.include = append(w.include, id)
+}
+
+// Excludes the given commit and its ancestors from the walk
+func (w *Walker) Hide(id objects.ID) {
+	w.exclude = append(w.exclude, id)
+}
+
+// Pushes and hides commits according to a revision specifier:
+//
+//	'b'      includes b
+//	'^c'     excludes c
+//	'a..b'   includes b but excludes a
+//	'a...b'  includes a and b but excludes their merge bases
+//
+// An empty side of a range means HEAD.
+func (w *Walker) PushSpec(spec string, resolve Resolver) error {
+	resolveOrHead := func(rev string) (objects.ID, error) {
+		if rev == "" {
+			rev = "HEAD"
+		}
+		return resolve(rev)
+	}
+	if strings.HasPrefix(spec, "^") {
+		id, err := resolve(spec[1:])
+		if err != nil {
+			return errors.Wrapf(err, "bad revision %s", spec)
+		}
+		w.Hide(id)
+		return nil
+	}
+	if i := strings.Index(spec, "..."); i >= 0 {
+		a, err := resolveOrHead(spec[:i])
+		if err != nil {
+			return errors.Wrapf(err, "bad revision %s", spec)
+		}
+		b, err := resolveOrHead(spec[i+3:])
+		if err != nil {
+			return errors.Wrapf(err, "bad revision %s", spec)
+		}
+		bases, err := w.MergeBases(a, b)
+		if err != nil {
+			return errors.Wrapf(err, "bad revision %s", spec)
+		}
+		w.Push(a)
+		w.Push(b)
+		for _, base := range bases {
+			w.Hide(base)
+		}
+		return nil
+	}
+	if i := strings.Index(spec, ".."); i >= 0 {
+		a, err := resolveOrHead(spec[:i])
+		if err != nil {
+			return errors.Wrapf(err, "bad revision %s", spec)
+		}
+		b, err := resolveOrHead(spec[i+2:])
+		if err != nil {
+			return errors.Wrapf(err, "bad revision %s", spec)
+		}
+		w.Hide(a)
+		w.Push(b)
+		return nil
+	}
+	id, err := resolve(spec)
+	if err != nil {
+		return errors.Wrapf(err, "bad revision %s", spec)
+	}
+	w.Push(id)
+	return nil
+}
+
+// Calls f for every commit that is reachable from the pushed commits but not
+// from the hidden ones, in the order given by the sorting of the walker. The
+// walk stops at the first error returned by f.
+func (w *Walker) Walk(f func(id objects.ID, c objects.Commit) error) error {
+	return w.walkIDs(func(id objects.ID) error {
+		c, err := w.commit(id)
+		if err != nil {
+			return err
+		}
+		return f(id, c)
+	})
+}
+
+// Returns the IDs of every commit that Walk would visit, in the same order.
+func (w *Walker) IDs() ([]objects.ID, error) {
+	var ids []objects.ID
+	err := w.walkIDs(func(id objects.ID) error {
+		ids = append(ids, id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+var errMaxCount = errors.New("max count reached")
+
+// Calls f for the ID of every commit of the walk. Commits in date order are
+// passed on as they're reached, so a walk that's stopped early doesn't read
+// the rest of the history. Hidden commits can only be told apart once the
+// walk is over, as a commit may be reached before one of its hidden
+// descendants when commit times are skewed, and the other sorts need every
+// commit as well.
+func (w *Walker) walkIDs(f func(id objects.ID) error) error {
+	count := 0
+	emit := func(id objects.ID) error {
+		err := f(id)
+		if err != nil {
+			return err
+		}
+		count++
+		if count == w.maxCount {
+			return errMaxCount
+		}
+		return nil
+	}
+	if w.sort&(SortTopological|SortReverse) == 0 && len(w.exclude) == 0 {
+		_, err := w.visit(emit)
+		if err == errMaxCount {
+			return nil
+		}
+		return err
+	}
+
+	ids, err := w.limit()
+	if err != nil {
+		return err
+	}
+	if w.sort&SortTopological != 0 {
+		ids, err = w.topoSort(ids)
+		if err != nil {
+			return err
+		}
+	}
+	if w.sort&SortReverse != 0 {
+		// The commits shown are the first ones before they're reversed
+		if w.maxCount > 0 && len(ids) > w.maxCount {
+			ids = ids[:w.maxCount]
+		}
+		for i, j := 0, len(ids)-1; i < j; i, j = i+1, j-1 {
+			ids[i], ids[j] = ids[j], ids[i]
+		}
+	}
+	for _, id := range ids {
+		err = emit(id)
+		if err == errMaxCount {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the commits that visit reaches and that are never hidden, in the
+// order they're reached.
+func (w *Walker) limit() ([]objects.ID, error) {
+	var visited []objects.ID
+	hidden, err := w.visit(func(id objects.ID) error {
+		visited = append(visited, id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// A commit may have been visited before one of its hidden descendants
+	// when commit times are skewed
+	var ids []objects.ID
+	for _, id := range visited {
+		if !hidden[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// Walks from the pushed and hidden commits newest first, propagating
+// 'hidden' to the ancestors of hidden commits, until only hidden commits are
+// left to visit. Calls f for every commit that isn't hidden when it's
+// reached and returns the commits that ended up hidden.
+func (w *Walker) visit(f func(id objects.ID) error) (map[objects.ID]bool, error) {
+	hidden := make(map[objects.ID]bool)
+	seen := make(map[objects.ID]bool)
+	queued := make(map[objects.ID]bool)
+	// The number of queued commits that aren't hidden
+	interesting := 0
+	q := &queue{}
+	push := func(id objects.ID) error {
+		n, err := w.node(id)
+		if err != nil {
+			return err
+		}
+		seen[id] = true
+		queued[id] = true
+		if !hidden[id] {
+			interesting++
+		}
+		heap.Push(q, &item{id: id, time: n.time, order: q.next()})
+		return nil
+	}
+	hide := func(id objects.ID) {
+		if !hidden[id] && queued[id] {
+			interesting--
+		}
+		hidden[id] = true
+	}
+	for _, id := range w.exclude {
+		hide(id)
+		if !seen[id] {
+			if err := push(id); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, id := range w.include {
+		if !seen[id] {
+			if err := push(id); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for interesting > 0 {
+		it := heap.Pop(q).(*item)
+		delete(queued, it.id)
+		if !hidden[it.id] {
+			interesting--
+		}
+		n, err := w.node(it.id)
+		if err != nil {
+			return nil, err
+		}
+		if !hidden[it.id] {
+			if err := f(it.id); err != nil {
+				return nil, err
+			}
+		}
+		for _, p := range n.parents {
+			if hidden[it.id] {
+				hide(p)
+			}
+			if !seen[p] {
+				if err := push(p); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return hidden, nil
+}
+
+// Orders the given commits so that every commit comes after all its children,
+// preferring newer commits when several are ready.
+func (w *Walker) topoSort(ids []objects.ID) ([]objects.ID, error) {
+	included := make(map[objects.ID]bool)
+	for _, id := range ids {
+		included[id] = true
+	}
+	children := make(map[objects.ID]int)
+	for _, id := range ids {
+		n, err := w.node(id)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range n.parents {
+			if included[p] {
+				children[p]++
+			}
+		}
+	}
+
+	q := &queue{}
+	for _, id := range ids {
+		if children[id] == 0 {
+			n, _ := w.node(id)
+			heap.Push(q, &item{id: id, time: n.time, order: q.next()})
+		}
+	}
+	var sorted []objects.ID
+	for q.Len() > 0 {
+		it := heap.Pop(q).(*item)
+		sorted = append(sorted, it.id)
+		n, _ := w.node(it.id)
+		for _, p := range n.parents {
+			if !included[p] {
+				continue
+			}
+			children[p]--
+			if children[p] == 0 {
+				pn, _ := w.node(p)
+				heap.Push(q, &item{id: p, time: pn.time, order: q.next()})
+			}
+		}
+	}
+	return sorted, nil
+}
+
+func (w *Walker) node(id objects.ID) (node, error) {
+	if n, ok := w.nodes[id]; ok {
+		return n, nil
+	}
+	if w.graph != nil {
+		if parents, t, gen, ok := w.graph.Lookup(id); ok {
+			n := node{parents: parents, time: t, generation: gen}
+			w.nodes[id] = n
+			return n, nil
+		}
+	}
+	c, err := w.commit(id)
+	if err != nil {
+		return node{}, err
+	}
+	n := node{parents: c.Parents(), time: c.Time().Unix()}
+	w.nodes[id] = n
+	return n, nil
+}
+
+func (w *Walker) commit(id objects.ID) (objects.Commit, error) {
+	if c, ok := w.cache[id]; ok {
+		return c, nil
+	}
+	c, err := w.commits.GetCommit(id)
+	if err != nil {
+		return objects.Commit{}, err
+	}
+	w.cache[id] = c
+	return c, nil
+}
@@ -0,0 +1,264 @@
+package revwalk
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"got/internal/objects"
+)
+
+type memCommits map[objects.ID]objects.Commit
+
+func (m memCommits) GetCommit(id objects.ID) (objects.Commit, error) {
+	c, ok := m[id]
+	if !ok {
+		return objects.Commit{}, errors.Errorf("no commit %s", id)
+	}
+	return c, nil
+}
+
+// Builds the history
+//
+//	a - b - c - f   (master)
+//	     \     /
+//	      d - e     (topic)
+//
+// where every commit is one second newer than the one before it in the
+// order a, b, c, d, e, f.
+func history() (memCommits, map[string]objects.ID) {
+	commits := make(memCommits)
+	ids := make(map[string]objects.ID)
+	add := func(name string, secs int, parents ...string) {
+		var ps []objects.ID
+		for _, p := range parents {
+			ps = append(ps, ids[p])
+		}
+		c := objects.NewMergeCommit("", ps, fmt.Sprintf("A <a@b.c> %d +0000", secs), name)
+		ids[name] = c.ID()
+		commits[c.ID()] = c
+	}
+	add("a", 1)
+	add("b", 2, "a")
+	add("c", 3, "b")
+	add("d", 4, "b")
+	add("e", 5, "d")
+	add("f", 6, "c", "e")
+	return commits, ids
+}
+
+// Counts the commits that are read
+type countingCommits struct {
+	memCommits
+	reads int
+}
+
+func (c *countingCommits) GetCommit(id objects.ID) (objects.Commit, error) {
+	c.reads++
+	return c.memCommits.GetCommit(id)
+}
+
+// Builds a linear history of n commits and returns their IDs newest first
+func linearHistory(n int) (memCommits, []objects.ID) {
+	commits := make(memCommits)
+	var ids []objects.ID
+	var parents []objects.ID
+	for i := 1; i <= n; i++ {
+		c := objects.NewMergeCommit("", parents, fmt.Sprintf("A <a@b.c> %d +0000", i), fmt.Sprint(i))
+		commits[c.ID()] = c
+		ids = append([]objects.ID{c.ID()}, ids...)
+		parents = []objects.ID{c.ID()}
+	}
+	return commits, ids
+}
+
+func names(commits memCommits, ids []objects.ID) []string {
+	var ns []string
+	for _, id := range ids {
+		ns = append(ns, commits[id].Message)
+	}
+	return ns
+}
+
+func resolver(ids map[string]objects.ID) Resolver {
+	return func(rev string) (objects.ID, error) {
+		if rev == "HEAD" {
+			rev = "f"
+		}
+		id, ok := ids[rev]
+		if !ok {
+			return "", errors.Errorf("unknown revision %s", rev)
+		}
+		return id, nil
+	}
+}
+
+func TestWalkDateOrder(t *testing.T) {
+	commits, ids := history()
+	w := NewWalker(commits)
+	w.Push(ids["f"])
+	got, err := w.IDs()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"f", "e", "d", "c", "b", "a"}, names(commits, got))
+}
+
+func TestWalkTopologicalReverse(t *testing.T) {
+	commits, ids := history()
+	w := NewWalker(commits)
+	w.Sorting(SortTopological | SortReverse)
+	w.Push(ids["f"])
+	got, err := w.IDs()
+	assert.Nil(t, err)
+	ns := names(commits, got)
+	assert.Equal(t, "a", ns[0])
+	assert.Equal(t, "f", ns[len(ns)-1])
+	pos := make(map[string]int)
+	for i, n := range ns {
+		pos[n] = i
+	}
+	assert.True(t, pos["d"] < pos["e"])
+	assert.True(t, pos["b"] < pos["c"])
+}
+
+func TestMaxCountStopsWalk(t *testing.T) {
+	commits, ids := linearHistory(100)
+	counting := &countingCommits{memCommits: commits}
+	w := NewWalker(counting)
+	w.MaxCount(3)
+	w.Push(ids[0])
+	got, err := w.IDs()
+	assert.Nil(t, err)
+	assert.Equal(t, ids[:3], got)
+	assert.True(t, counting.reads <= 4, "read %d commits", counting.reads)
+
+	var walked []objects.ID
+	err = w.Walk(func(id objects.ID, c objects.Commit) error {
+		walked = append(walked, id)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, ids[:3], walked)
+}
+
+func TestWalkStopsAtError(t *testing.T) {
+	commits, ids := linearHistory(100)
+	counting := &countingCommits{memCommits: commits}
+	w := NewWalker(counting)
+	w.Push(ids[0])
+	stop := errors.New("stop")
+	count := 0
+	err := w.Walk(func(id objects.ID, c objects.Commit) error {
+		count++
+		if count == 2 {
+			return stop
+		}
+		return nil
+	})
+	assert.Equal(t, stop, err)
+	assert.True(t, counting.reads <= 3, "read %d commits", counting.reads)
+}
+
+func TestMaxCountAfterSorting(t *testing.T) {
+	tests := []struct {
+		sort     Sort
+		specs    []string
+		expected []string
+	}{
+		{SortDate, []string{"f"}, []string{"f", "e"}},
+		{SortDate | SortReverse, []string{"f"}, []string{"e", "f"}},
+		{SortTopological | SortReverse, []string{"f"}, []string{"e", "f"}},
+		{SortDate | SortReverse, []string{"f", "^d"}, []string{"e", "f"}},
+		{SortDate, []string{"f", "^d"}, []string{"f", "e"}},
+	}
+	for _, test := range tests {
+		commits, ids := history()
+		w := NewWalker(commits)
+		w.Sorting(test.sort)
+		w.MaxCount(2)
+		for _, spec := range test.specs {
+			assert.Nil(t, w.PushSpec(spec, resolver(ids)))
+		}
+		got, err := w.IDs()
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, names(commits, got), "%v %v", test.sort, test.specs)
+	}
+}
+
+func TestPushSpec(t *testing.T) {
+	tests := []struct {
+		specs    []string
+		expected []string
+	}{
+		{[]string{"c..e"}, []string{"e", "d"}},
+		{[]string{"e..c"}, []string{"c"}},
+		{[]string{"c...e"}, []string{"e", "d", "c"}},
+		{[]string{"f", "^d"}, []string{"f", "e", "c"}},
+		{[]string{"b.."}, []string{"f", "e", "d", "c"}},
+	}
+	for _, test := range tests {
+		commits, ids := history()
+		w := NewWalker(commits)
+		for _, spec := range test.specs {
+			assert.Nil(t, w.PushSpec(spec, resolver(ids)))
+		}
+		got, err := w.IDs()
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, names(commits, got), "%v", test.specs)
+	}
+}
+
+func TestMergeBase(t *testing.T) {
+	commits, ids := history()
+	w := NewWalker(commits)
+	base, err := w.MergeBase(ids["c"], ids["e"])
+	assert.Nil(t, err)
+	assert.Equal(t, ids["b"], base)
+
+	base, err = w.MergeBase(ids["f"], ids["e"])
+	assert.Nil(t, err)
+	assert.Equal(t, ids["e"], base)
+}
+
+func TestMergeBasesCrissCross(t *testing.T) {
+	commits := make(memCommits)
+	ids := make(map[string]objects.ID)
+	add := func(name string, secs int, parents ...string) {
+		var ps []objects.ID
+		for _, p := range parents {
+			ps = append(ps, ids[p])
+		}
+		c := objects.NewMergeCommit("", ps, fmt.Sprintf("A <a@b.c> %d +0000", secs), name)
+		ids[name] = c.ID()
+		commits[c.ID()] = c
+	}
+	add("a", 1)
+	add("b", 2, "a")
+	add("c", 3, "a")
+	add("d", 4, "b", "c")
+	add("e", 5, "c", "b")
+	w := NewWalker(commits)
+	bases, err := w.MergeBases(ids["d"], ids["e"])
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"b", "c"}, names(commits, bases))
+}
+
+func TestMergeBasesMissingCommit(t *testing.T) {
+	commits, ids := history()
+	delete(commits, ids["b"])
+	w := NewWalker(commits)
+	_, err := w.MergeBases(ids["c"], ids["e"])
+	assert.NotNil(t, err)
+}
+
+func TestIsAncestor(t *testing.T) {
+	commits, ids := history()
+	w := NewWalker(commits)
+	ok, err := w.IsAncestor(ids["d"], ids["f"])
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, err = w.IsAncestor(ids["c"], ids["e"])
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
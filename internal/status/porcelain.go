@@ -0,0 +1,242 @@
+package status
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gookit/color"
+
+	"got/internal/diff"
+	"got/internal/objects"
+)
+
+// Formats the status like 'git status --short': two letters for the staged
+// and unstaged change of every file, followed by its path. With branch the
+// branch and how it relates to its upstream are shown first.
+func (s *Status) Short(branch bool) string {
+	buf := bytes.NewBuffer(nil)
+	if branch {
+		fmt.Fprintf(buf, "## %s\n", s.branchSummary(true))
+	}
+	for _, c := range s.Changes {
+		if c.Untracked {
+			fmt.Fprintf(buf, "%s %s\n", color.Red.Sprint("??"), quotePath(c.Path))
+			continue
+		}
+		x, y := shortLetter(c.Head), shortLetter(c.Worktree)
+		fmt.Fprintf(buf, "%s%s %s\n", color.Green.Sprint(x), color.Red.Sprint(y), c.shortPath(" -> ", false))
+	}
+	return buf.String()
+}
+
+// Formats the status like 'git status --porcelain=v1', which is the short
+// format without colors that stays the same across versions. With z entries
+// are terminated by NUL instead of newlines and paths aren't quoted.
+func (s *Status) PorcelainV1(branch, z bool) string {
+	eol := lineEnd(z)
+	buf := bytes.NewBuffer(nil)
+	if branch {
+		fmt.Fprintf(buf, "## %s%s", s.branchSummary(false), eol)
+	}
+	for _, c := range s.Changes {
+		if c.Untracked {
+			fmt.Fprintf(buf, "?? %s%s", c.path(z), eol)
+			continue
+		}
+		sep := " -> "
+		if z {
+			sep = "\x00"
+		}
+		fmt.Fprintf(buf, "%s%s %s%s", shortLetter(c.Head), shortLetter(c.Worktree), c.shortPath(sep, z), eol)
+	}
+	return buf.String()
+}
+
+// Formats the status like 'git status --porcelain=v2', which shows the
+// modes and IDs of changed files in HEAD, the index and the working tree. With
+// branch the branch headers come first. With z entries are terminated by
+// NUL instead of newlines and paths aren't quoted.
+func (s *Status) PorcelainV2(branch, z bool) string {
+	eol := lineEnd(z)
+	buf := bytes.NewBuffer(nil)
+	if branch {
+		b := s.Branch
+		commit := string(b.Commit)
+		if commit == "" {
+			commit = "(initial)"
+		}
+		fmt.Fprintf(buf, "# branch.oid %s%s", commit, eol)
+		head := b.Head
+		if head == "" {
+			head = "(detached)"
+		}
+		fmt.Fprintf(buf, "# branch.head %s%s", head, eol)
+		if b.Upstream != "" {
+			fmt.Fprintf(buf, "# branch.upstream %s%s", b.Upstream, eol)
+			if !b.Gone {
+				fmt.Fprintf(buf, "# branch.ab +%d -%d%s", b.Ahead, b.Behind, eol)
+			}
+		}
+	}
+	var untracked []Change
+	for _, c := range s.Changes {
+		if c.Untracked {
+			untracked = append(untracked, c)
+			continue
+		}
+		fields := []string{
+			c.Head.Letter() + c.Worktree.Letter(),
+			// Submodules aren't supported
+			"N...",
+			modeString(c.HeadMode, c.HeadID != ""),
+			modeString(c.IndexMode, c.IndexID != ""),
+			modeString(c.WorktreeMode, c.WorktreeMode != 0),
+			idString(c.HeadID),
+			idString(c.IndexID),
+		}
+		if c.From == "" {
+			fmt.Fprintf(buf, "1 %s %s%s", strings.Join(fields, " "), c.path(z), eol)
+			continue
+		}
+		sep := "\t"
+		if z {
+			sep = "\x00"
+		}
+		score := fmt.Sprintf("%s%d", c.Head.Letter(), c.Similarity)
+		fmt.Fprintf(buf, "2 %s %s %s%s%s%s", strings.Join(fields, " "), score, c.path(z), sep, c.fromPath(z), eol)
+	}
+	for _, c := range untracked {
+		fmt.Fprintf(buf, "? %s%s", c.path(z), eol)
+	}
+	return buf.String()
+}
+
+// Returns what follows '## ' in the branch line of the short format, e.g.
+// 'master...origin/master [ahead 1]'
+func (s *Status) branchSummary(colored bool) string {
+	b := s.Branch
+	green, red := color.Green.Sprint, color.Red.Sprint
+	if !colored {
+		green, red = fmt.Sprint, fmt.Sprint
+	}
+	switch {
+	case b.Head == "":
+		return red("HEAD (no branch)")
+	case b.Commit == "":
+		return "No commits yet on " + green(b.Head)
+	case b.Upstream == "":
+		return green(b.Head)
+	}
+	summary := green(b.Head) + "..." + red(b.Upstream)
+	var counts []string
+	if b.Gone {
+		counts = append(counts, "gone")
+	}
+	if b.Ahead > 0 {
+		counts = append(counts, "ahead "+green(fmt.Sprint(b.Ahead)))
+	}
+	if b.Behind > 0 {
+		counts = append(counts, "behind "+red(fmt.Sprint(b.Behind)))
+	}
+	if len(counts) > 0 {
+		summary += " [" + strings.Join(counts, ", ") + "]"
+	}
+	return summary
+}
+
+// Returns the path of the change for the short formats, preceded by the
+// path in HEAD and sep if it was renamed or copied. With -z the new path
+// comes first.
+func (c Change) shortPath(sep string, z bool) string {
+	if c.From == "" {
+		return c.path(z)
+	}
+	if z {
+		return c.path(z) + sep + c.fromPath(z)
+	}
+	return c.fromPath(z) + sep + c.path(z)
+}
+
+func (c Change) path(z bool) string {
+	if z {
+		return c.Path
+	}
+	return quotePath(c.Path)
+}
+
+func (c Change) fromPath(z bool) string {
+	if z {
+		return c.From
+	}
+	return quotePath(c.From)
+}
+
+func shortLetter(t ChangeType) string {
+	if t == "" {
+		return " "
+	}
+	return t.Letter()
+}
+
+func lineEnd(z bool) string {
+	if z {
+		return "\x00"
+	}
+	return "\n"
+}
+
+func modeString(mode os.FileMode, exists bool) string {
+	if !exists {
+		return "000000"
+	}
+	return diff.ModeString(mode)
+}
+
+func idString(id objects.ID) string {
+	if id == "" {
+		return strings.Repeat("0", 40)
+	}
+	return string(id)
+}
+
+// Quotes a path the way git does if it contains double quotes, backslashes,
+// control characters or bytes outside of ASCII
+func quotePath(path string) string {
+	if !strings.ContainsAny(path, "\"\\") && strings.IndexFunc(path, func(r rune) bool { return r < 0x20 || r >= 0x7f }) < 0 {
+		return path
+	}
+	buf := &strings.Builder{}
+	buf.WriteByte('"')
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch c {
+		case '"', '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case '\a':
+			buf.WriteString(`\a`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\v':
+			buf.WriteString(`\v`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\r':
+			buf.WriteString(`\r`)
+		default:
+			if c < 0x20 || c >= 0x7f {
+				fmt.Fprintf(buf, `\%03o`, c)
+			} else {
+				buf.WriteByte(c)
+			}
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
@@ -0,0 +1,82 @@
+package status
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gookit/color"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	color.Disable()
+}
+
+const (
+	idA = "3f786850e387550fdab836ed7e6dc881de23001b"
+	idB = "9d84fcec9ab0ea9547cbce8e7a219e0ea7600fbc"
+)
+
+func testStatus() *Status {
+	tree := NewTree()
+	tree.AddFile("a.txt", Changes{Worktree: Modified, HeadMode: 0644, IndexMode: 0644, WorktreeMode: 0644, HeadID: idA, IndexID: idA}, true)
+	tree.AddFile("src/new.txt", Changes{Head: Renamed, From: "old.txt", Similarity: 90, HeadMode: 0644, IndexMode: 0755, WorktreeMode: 0755, HeadID: idA, IndexID: idB}, true)
+	tree.AddFile("added.txt", Changes{Head: Created, IndexMode: 0644, IndexID: idB}, true)
+	tree.AddFile("added.txt", Changes{Worktree: Deleted}, true)
+	tree.AddFile("tmp/x", Changes{}, false)
+	tree.AddFile("q\"t", Changes{}, false)
+	s := tree.GetStatus()
+	s.Branch = Branch{Commit: idA, Head: "master", Upstream: "origin/master", Ahead: 2, Behind: 1}
+	return s
+}
+
+func TestShort(t *testing.T) {
+	expected := `## master...origin/master [ahead 2, behind 1]
+ M a.txt
+AD added.txt
+R  old.txt -> src/new.txt
+?? "q\"t"
+?? tmp/
+`
+	assert.Equal(t, expected, testStatus().Short(true))
+	assert.Equal(t, expected, testStatus().PorcelainV1(true, false))
+}
+
+func TestPorcelainV1Z(t *testing.T) {
+	expected := " M a.txt\x00AD added.txt\x00R  src/new.txt\x00old.txt\x00?? q\"t\x00?? tmp/\x00"
+	assert.Equal(t, expected, testStatus().PorcelainV1(false, true))
+}
+
+func TestPorcelainV2(t *testing.T) {
+	null := strings.Repeat("0", 40)
+	expected := "# branch.oid " + idA + "\n" +
+		"# branch.head master\n" +
+		"# branch.upstream origin/master\n" +
+		"# branch.ab +2 -1\n" +
+		"1 .M N... 100644 100644 100644 " + idA + " " + idA + " a.txt\n" +
+		"1 AD N... 000000 100644 000000 " + null + " " + idB + " added.txt\n" +
+		"2 R. N... 100644 100755 100755 " + idA + " " + idB + " R90 src/new.txt\told.txt\n" +
+		"? \"q\\\"t\"\n" +
+		"? tmp/\n"
+	assert.Equal(t, expected, testStatus().PorcelainV2(true, false))
+
+	s := testStatus()
+	s.Branch = Branch{Head: "master"}
+	assert.True(t, strings.HasPrefix(s.PorcelainV2(true, true), "# branch.oid (initial)\x00# branch.head master\x00"))
+}
+
+func TestLong(t *testing.T) {
+	long := testStatus().String()
+	assert.True(t, strings.HasPrefix(long, "On branch master\nYour branch and 'origin/master' have diverged,\n"))
+	assert.Contains(t, long, "renamed:    old.txt -> src/new.txt\n")
+	assert.Contains(t, long, "deleted:    added.txt\n")
+	assert.NotContains(t, long, "git ")
+}
+
+func TestJSONModes(t *testing.T) {
+	c := Change{Path: "added.txt", Changes: Changes{Head: Created, Worktree: Modified, IndexMode: 0755, WorktreeMode: 0644, IndexID: idB}}
+	bs, err := json.Marshal(c)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"path":"added.txt","staged":"added","unstaged":"modified","indexID":"`+idB+`","indexMode":"100755","worktreeMode":"100644"}`, string(bs))
+}
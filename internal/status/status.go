@@ -2,9 +2,15 @@ package status
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"os"
 
 	"github.com/gookit/color"
+
+	"got/internal/diff"
+	"got/internal/objects"
+	"got/internal/pretty"
 )
 
 type ChangeType string
@@ -14,53 +20,203 @@ const (
 	Modified   ChangeType = "modified:"
 	Created    ChangeType = "new file:"
 	Deleted    ChangeType = "deleted: "
+	Renamed    ChangeType = "renamed: "
+	Copied     ChangeType = "copied:  "
 )
 
+// Returns the letter that short and porcelain formats show for the change,
+// '.' if there is none
+func (t ChangeType) Letter() string {
+	switch t {
+	case Modified:
+		return "M"
+	case Created:
+		return "A"
+	case Deleted:
+		return "D"
+	case Renamed:
+		return "R"
+	case Copied:
+		return "C"
+	}
+	return "."
+}
+
+// Encodes the change as a single word, e.g. 'added', for JSON output
+func (t ChangeType) MarshalText() ([]byte, error) {
+	switch t {
+	case Modified:
+		return []byte("modified"), nil
+	case Created:
+		return []byte("added"), nil
+	case Deleted:
+		return []byte("deleted"), nil
+	case Renamed:
+		return []byte("renamed"), nil
+	case Copied:
+		return []byte("copied"), nil
+	case UnModified, "":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown change type %q", string(t))
+}
+
 type Changes struct {
-	Head     ChangeType
-	Worktree ChangeType
+	// How the index differs from HEAD, empty if it doesn't
+	Head ChangeType `json:"staged,omitempty"`
+	// How the working tree differs from the index, empty if it doesn't
+	Worktree ChangeType `json:"unstaged,omitempty"`
+	// The path in HEAD of a file that's staged as renamed or copied
+	From string `json:"from,omitempty"`
+	// How similar a renamed or copied file is to the file in HEAD, in percent
+	Similarity int `json:"similarity,omitempty"`
+	// The modes and IDs of the file in HEAD and the index and its mode in
+	// the working tree. IDs are empty and modes zero where the file is
+	// missing.
+	HeadMode     os.FileMode `json:"headMode,omitempty"`
+	IndexMode    os.FileMode `json:"indexMode,omitempty"`
+	WorktreeMode os.FileMode `json:"worktreeMode,omitempty"`
+	HeadID       objects.ID  `json:"headID,omitempty"`
+	IndexID      objects.ID  `json:"indexID,omitempty"`
 }
 
+// A changed or untracked file, or an untracked directory
 type Change struct {
-	path       string
-	changeType *ChangeType
+	// The repository relative path, which ends in a separator for untracked
+	// directories
+	Path      string `json:"path"`
+	Untracked bool   `json:"untracked,omitempty"`
+	Changes
+}
+
+// Encodes the change with its modes as octal strings like git's, e.g.
+// '100644', rather than as numbers
+func (c Change) MarshalJSON() ([]byte, error) {
+	// The local type drops this method so that encoding it doesn't recurse
+	type change Change
+	return json.Marshal(struct {
+		change
+		HeadMode     string `json:"headMode,omitempty"`
+		IndexMode    string `json:"indexMode,omitempty"`
+		WorktreeMode string `json:"worktreeMode,omitempty"`
+	}{
+		change:       change(c),
+		HeadMode:     jsonMode(c.HeadMode),
+		IndexMode:    jsonMode(c.IndexMode),
+		WorktreeMode: jsonMode(c.WorktreeMode),
+	})
+}
+
+// Returns the mode as an octal string, empty for the zero mode of a
+// missing file so that it's left out
+func jsonMode(mode os.FileMode) string {
+	if mode == 0 {
+		return ""
+	}
+	return diff.ModeString(mode)
+}
+
+// Where HEAD is and how it relates to its upstream branch
+type Branch struct {
+	// The commit at HEAD, empty if there are no commits yet
+	Commit objects.ID `json:"commit,omitempty"`
+	// The checked out branch, empty if HEAD is detached
+	Head string `json:"head,omitempty"`
+	// The upstream of the branch, e.g. 'origin/master', empty if it has none
+	Upstream string `json:"upstream,omitempty"`
+	// Set if the upstream is configured but doesn't exist
+	Gone bool `json:"gone,omitempty"`
+	// The number of commits on the branch that aren't on its upstream and
+	// the other way around
+	Ahead  int `json:"ahead"`
+	Behind int `json:"behind"`
 }
 
 type Status struct {
-	staged    []Change
-	unstaged  []Change
-	untracked []Change
+	Branch  Branch   `json:"branch"`
+	Changes []Change `json:"changes"`
 }
 
 func (s *Status) String() string {
 	buf := bytes.NewBuffer(nil)
+	s.writeBranch(buf)
 
-	if len(s.staged) > 0 {
+	var staged, unstaged, untracked []Change
+	for _, c := range s.Changes {
+		if c.Untracked {
+			untracked = append(untracked, c)
+			continue
+		}
+		if c.Head != "" {
+			staged = append(staged, c)
+		}
+		if c.Worktree != "" {
+			unstaged = append(unstaged, c)
+		}
+	}
+
+	if len(staged) > 0 {
 		fmt.Fprintln(buf, "Changes to be committed:")
-		fmt.Fprintln(buf, "  (use \"git restore --staged <file>...\" to unstage)")
-		for _, staged := range s.staged {
-			fmt.Fprint(buf, color.Green.Sprintf("        %s   %s\n", *staged.changeType, staged.path))
+		fmt.Fprintln(buf, "  (use \"got restore --staged <file>...\" to unstage)")
+		for _, c := range staged {
+			path := c.Path
+			if c.From != "" {
+				path = c.From + " -> " + path
+			}
+			fmt.Fprint(buf, color.Green.Sprintf("        %s   %s\n", c.Head, path))
 		}
 		fmt.Fprintln(buf)
 	}
 
-	if len(s.unstaged) > 0 {
+	if len(unstaged) > 0 {
 		fmt.Fprintln(buf, "Changes not staged for commit:")
-		fmt.Fprintln(buf, "  (use \"git add <file>...\" to update what will be committed)")
-		fmt.Fprintln(buf, "  (use \"git restore <file>...\" to discard changes in working directory)")
-		for _, unstaged := range s.unstaged {
-			fmt.Fprint(buf, color.Red.Sprintf("        %s   %s\n", *unstaged.changeType, unstaged.path))
+		fmt.Fprintln(buf, "  (use \"got add <file>...\" to update what will be committed)")
+		fmt.Fprintln(buf, "  (use \"got restore <file>...\" to discard changes in working directory)")
+		for _, c := range unstaged {
+			fmt.Fprint(buf, color.Red.Sprintf("        %s   %s\n", c.Worktree, c.Path))
 		}
 		fmt.Fprintln(buf)
 	}
 
-	if len(s.untracked) > 0 {
+	if len(untracked) > 0 {
 		fmt.Fprintln(buf, "Untracked files:")
-		fmt.Fprintln(buf, "  (use \"git add <file>...\" to include in what will be committed)")
-		for _, untracked := range s.untracked {
-			fmt.Fprint(buf, color.Red.Sprintf("        %s\n", untracked.path))
+		fmt.Fprintln(buf, "  (use \"got add <file>...\" to include in what will be committed)")
+		for _, c := range untracked {
+			fmt.Fprint(buf, color.Red.Sprintf("        %s\n", c.Path))
 		}
 	}
 
 	return buf.String()
 }
+
+func (s *Status) writeBranch(buf *bytes.Buffer) {
+	b := s.Branch
+	if b.Head == "" {
+		fmt.Fprintf(buf, "HEAD detached at %s\n", pretty.Abbrev(b.Commit))
+	} else {
+		fmt.Fprintf(buf, "On branch %s\n", b.Head)
+	}
+	switch {
+	case b.Commit == "":
+		fmt.Fprint(buf, "\nNo commits yet\n")
+	case b.Upstream == "":
+	case b.Gone:
+		fmt.Fprintf(buf, "Your branch is based on '%s', but the upstream is gone.\n", b.Upstream)
+	case b.Ahead > 0 && b.Behind > 0:
+		fmt.Fprintf(buf, "Your branch and '%s' have diverged,\nand have %d and %d different commits each, respectively.\n", b.Upstream, b.Ahead, b.Behind)
+	case b.Ahead > 0:
+		fmt.Fprintf(buf, "Your branch is ahead of '%s' by %s.\n", b.Upstream, commits(b.Ahead))
+	case b.Behind > 0:
+		fmt.Fprintf(buf, "Your branch is behind '%s' by %s, and can be fast-forwarded.\n", b.Upstream, commits(b.Behind))
+	default:
+		fmt.Fprintf(buf, "Your branch is up to date with '%s'.\n", b.Upstream)
+	}
+	fmt.Fprintln(buf)
+}
+
+func commits(n int) string {
+	if n == 1 {
+		return "1 commit"
+	}
+	return fmt.Sprintf("%d commits", n)
+}
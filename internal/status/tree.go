@@ -2,6 +2,7 @@ package status
 
 import (
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -82,9 +83,19 @@ func (t *Tree) addFile(splitPath []string, changes Changes, tracked bool) {
 	tree.addFile(splitPath[1:], changes, tracked)
 }
 
+// Returns the changed and untracked files of the tree, the changed ones
+// first, each sorted by path. The branch of the status is left empty.
 func (t *Tree) GetStatus() *Status {
 	s := Status{}
-	return t.getStatus("", &s)
+	t.getStatus("", &s)
+	sort.SliceStable(s.Changes, func(i, j int) bool {
+		a, b := s.Changes[i], s.Changes[j]
+		if a.Untracked != b.Untracked {
+			return b.Untracked
+		}
+		return a.Path < b.Path
+	})
+	return &s
 }
 
 func (t *Tree) getStatus(rel string, s *Status) *Status {
@@ -94,20 +105,16 @@ func (t *Tree) getStatus(rel string, s *Status) *Status {
 		// If n is a file
 		if n.cs == nil {
 			if !n.tracked {
-				s.untracked = append(s.untracked, Change{path, nil})
-			}
-			if n.changes.Worktree != "" {
-				s.unstaged = append(s.unstaged, Change{path, &n.changes.Worktree})
-			}
-			if n.changes.Head != "" {
-				s.staged = append(s.staged, Change{path, &n.changes.Head})
+				s.Changes = append(s.Changes, Change{Path: path, Untracked: true})
+			} else if n.changes.HasChanges() {
+				s.Changes = append(s.Changes, Change{Path: path, Changes: n.changes})
 			}
 			continue
 		}
 
 		// If path isn't tracked just add the path into the
 		if !n.tracked {
-			s.untracked = append(s.untracked, Change{path + "/", nil})
+			s.Changes = append(s.Changes, Change{Path: path + "/", Untracked: true})
 			continue
 		}
 
@@ -124,6 +131,21 @@ func (c Changes) AddChanges(changes Changes) Changes {
 	if c.Worktree == "" {
 		c.Worktree = changes.Worktree
 	}
+	if c.From == "" {
+		c.From = changes.From
+	}
+	if c.Similarity == 0 {
+		c.Similarity = changes.Similarity
+	}
+	if c.HeadID == "" {
+		c.HeadMode, c.HeadID = changes.HeadMode, changes.HeadID
+	}
+	if c.IndexID == "" {
+		c.IndexMode, c.IndexID = changes.IndexMode, changes.IndexID
+	}
+	if c.WorktreeMode == 0 {
+		c.WorktreeMode = changes.WorktreeMode
+	}
 	return c
 }
 
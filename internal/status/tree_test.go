@@ -10,6 +10,7 @@ func TestTree(t *testing.T) {
 	tree.AddFile("cmd/got/got.go", Changes{Head: Created}, true)
 	tree.AddFile("cmd/got/got.go", Changes{Worktree: Modified}, true)
 	tree.AddFile("internal/got/got.go", Changes{Worktree: Modified}, true)
+	tree.AddFile("internal/got/status.go", Changes{Head: Renamed, From: "internal/got/state.go"}, true)
 	tree.AddFile("internal/cmd/add.go", Changes{}, false)
 	tree.AddFile("internal/cmd/commit.go", Changes{}, false)
 	tree.AddFile("internal/status/status.go", Changes{}, false)
@@ -0,0 +1,136 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"got/internal/objects"
+	"got/internal/transport"
+)
+
+// A repository served by a Handler
+type Remote struct {
+	url    string
+	client *http.Client
+}
+
+// Reports whether the URL is one of a repository served over HTTP
+func IsURL(url string) bool {
+	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+}
+
+// Connects to the repository at the URL with the default client. Nothing is
+// requested until the first call.
+func Open(url string) *Remote {
+	return NewRemote(url, http.DefaultClient)
+}
+
+func NewRemote(url string, client *http.Client) *Remote {
+	return &Remote{url: strings.TrimRight(url, "/"), client: client}
+}
+
+func (r *Remote) Advertise() (*transport.Advertisement, error) {
+	resp, err := r.do(http.MethodGet, infoRefs, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	ad := &transport.Advertisement{}
+	err = json.NewDecoder(resp.Body).Decode(ad)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid response from %s", r.url)
+	}
+	if ad.Branches == nil {
+		ad.Branches = make(map[string]objects.ID)
+	}
+	return ad, nil
+}
+
+func (r *Remote) Fetch(dst transport.Store, wants, haves []objects.ID) error {
+	if len(wants) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(fetchRequest{Wants: wants, Haves: haves})
+	if err != nil {
+		return err
+	}
+	resp, err := r.do(http.MethodPost, fetchPack, jsonType, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = transport.ReadPack(resp.Body, dst)
+	return err
+}
+
+func (r *Remote) Push(src transport.Store, updates []transport.Update) ([]transport.Result, error) {
+	ad, err := r.Advertise()
+	if err != nil {
+		return nil, err
+	}
+	var haves, wants []objects.ID
+	for _, id := range ad.Branches {
+		haves = append(haves, id)
+	}
+	for _, u := range updates {
+		wants = append(wants, u.New)
+	}
+	known, err := transport.Known(src, haves)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := transport.Missing(src, known, wants)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't find the objects to push")
+	}
+
+	// The pack is streamed while it's written
+	pr, pw := io.Pipe()
+	go func() {
+		err := json.NewEncoder(pw).Encode(updates)
+		if err == nil {
+			err = transport.WritePack(pw, src, ids)
+		}
+		pw.CloseWithError(err)
+	}()
+	resp, err := r.do(http.MethodPost, receivePack, packType, pr)
+	pr.Close()
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var results []transport.Result
+	err = json.NewDecoder(resp.Body).Decode(&results)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid response from %s", r.url)
+	}
+	return results, nil
+}
+
+// Sends a request to the endpoint of the repository and returns the
+// response if it succeeded. The caller must close its body.
+func (r *Remote) do(method, endpoint, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, r.url+endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, errors.Errorf("%s: %s: %s", r.url, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
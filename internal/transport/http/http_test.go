@@ -0,0 +1,86 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"got/internal/objects"
+	"got/internal/objects/disk"
+	"got/internal/refs"
+	"got/internal/transport"
+	"got/internal/transport/transporttest"
+)
+
+// Creates a bare repository in dir with HEAD at master
+func newRepo(t *testing.T, dir string) (*disk.Objects, *refs.Refs) {
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, disk.ObjectsDir), os.ModePerm))
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, refs.Dir, refs.HeadsDir), os.ModePerm))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "HEAD"), []byte("refs/heads/master"), os.ModePerm))
+	return disk.NewObjects(dir), refs.NewRefs(dir)
+}
+
+func TestFetchAndPush(t *testing.T) {
+	root, err := ioutil.TempDir("", "got-http")
+	assert.Nil(t, err)
+	defer os.RemoveAll(root)
+	serverObjects, serverRefs := newRepo(t, filepath.Join(root, "repos", "app"))
+	first := transporttest.StoreCommit(t, serverObjects, "one", nil)
+	assert.Nil(t, serverRefs.UpdateRef(refs.Ref("refs/heads/master"), first))
+
+	server := httptest.NewServer(NewHandler(filepath.Join(root, "repos")))
+	defer server.Close()
+	r := NewRemote(server.URL+"/app/", server.Client())
+
+	ad, err := r.Advertise()
+	assert.Nil(t, err)
+	assert.Equal(t, "master", ad.Head)
+	assert.Equal(t, map[string]objects.ID{"master": first}, ad.Branches)
+
+	clientObjects, _ := newRepo(t, filepath.Join(root, "client"))
+	assert.Nil(t, r.Fetch(clientObjects, []objects.ID{first}, nil))
+	c, err := clientObjects.GetCommit(first)
+	assert.Nil(t, err)
+	blob, err := clientObjects.GetBlob(objects.NewBlob([]byte("one")).ID())
+	assert.Nil(t, err)
+	assert.Equal(t, "one", blob.Contents)
+	_, err = clientObjects.GetTree(c.TreeID)
+	assert.Nil(t, err)
+
+	second := transporttest.StoreCommit(t, clientObjects, "two", &first)
+	results, err := r.Push(clientObjects, []transport.Update{{Branch: "master", Old: first, New: second}})
+	assert.Nil(t, err)
+	assert.Equal(t, []transport.Result{{Update: transport.Update{Branch: "master", Old: first, New: second}}}, results)
+	id, err := serverRefs.IdAtBranch("master")
+	assert.Nil(t, err)
+	assert.Equal(t, second, id)
+	_, err = serverObjects.GetCommit(second)
+	assert.Nil(t, err)
+
+	other := transporttest.StoreCommit(t, clientObjects, "other", &first)
+	results, err = r.Push(clientObjects, []transport.Update{{Branch: "master", Old: second, New: other}})
+	assert.Nil(t, err)
+	assert.Equal(t, transport.RejectedNonFastForward, results[0].Rejected)
+	id, err = serverRefs.IdAtBranch("master")
+	assert.Nil(t, err)
+	assert.Equal(t, second, id)
+}
+
+func TestRepositoriesStayInsideTheRoot(t *testing.T) {
+	root, err := ioutil.TempDir("", "got-http")
+	assert.Nil(t, err)
+	defer os.RemoveAll(root)
+	newRepo(t, filepath.Join(root, "outside"))
+	assert.Nil(t, os.Mkdir(filepath.Join(root, "served"), os.ModePerm))
+
+	server := httptest.NewServer(NewHandler(filepath.Join(root, "served")))
+	defer server.Close()
+	_, err = NewRemote(server.URL+"/../outside", server.Client()).Advertise()
+	assert.NotNil(t, err)
+	_, err = NewRemote(server.URL+"/missing", server.Client()).Advertise()
+	assert.Contains(t, err.Error(), "404")
+}
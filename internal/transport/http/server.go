@@ -0,0 +1,160 @@
+// Package http serves repositories over HTTP and connects to repositories
+// served that way.
+//
+// A repository is served at a URL like http://host/path/to/repo with three
+// endpoints below it:
+//
+//	GET  info/refs     the branches of the repository as a JSON Advertisement
+//	POST fetch-pack    takes a JSON fetchRequest and responds with a pack of
+//	                   the objects the client is missing
+//	POST receive-pack  takes a line with a JSON list of Updates followed by a
+//	                   pack of the objects they need and responds with a
+//	                   JSON list of Results
+package http
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"got/internal/objects"
+	"got/internal/transport"
+	"got/internal/transport/local"
+)
+
+const (
+	infoRefs    = "/info/refs"
+	fetchPack   = "/fetch-pack"
+	receivePack = "/receive-pack"
+
+	packType = "application/x-got-pack"
+	jsonType = "application/json"
+)
+
+type fetchRequest struct {
+	Wants []objects.ID
+	Haves []objects.ID
+}
+
+// Serves the repositories in a directory, each at its path relative to the
+// directory, or the directory itself at the root if it's a repository
+type Handler struct {
+	root string
+	// Serializes pushes so that two pushes can't both pass the checks of
+	// the same branch before either updates it
+	pushMu sync.Mutex
+}
+
+func NewHandler(root string) *Handler {
+	return &Handler{root: root}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var repo, endpoint string
+	for _, e := range []string{infoRefs, fetchPack, receivePack} {
+		if strings.HasSuffix(req.URL.Path, e) {
+			repo, endpoint = strings.TrimSuffix(req.URL.Path, e), e
+		}
+	}
+	if endpoint == "" {
+		http.NotFound(w, req)
+		return
+	}
+	if (endpoint == infoRefs) != (req.Method == http.MethodGet) {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// Cleaning the path as absolute keeps it inside the root
+	r, err := local.Open(filepath.Join(h.root, filepath.FromSlash(path.Clean("/"+repo))))
+	if err != nil {
+		http.Error(w, "repository not found", http.StatusNotFound)
+		return
+	}
+
+	switch endpoint {
+	case infoRefs:
+		h.advertise(w, r)
+	case fetchPack:
+		h.fetch(w, req, r)
+	case receivePack:
+		h.push(w, req, r)
+	}
+}
+
+func (h *Handler) advertise(w http.ResponseWriter, r *local.Remote) {
+	ad, err := r.Advertise()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, ad)
+}
+
+func (h *Handler) fetch(w http.ResponseWriter, req *http.Request, r *local.Remote) {
+	var fr fetchRequest
+	err := json.NewDecoder(req.Body).Decode(&fr)
+	if err != nil {
+		http.Error(w, "invalid fetch request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	store := r.Store()
+	known, err := transport.Known(store, fr.Haves)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, id := range fr.Wants {
+		if !store.Has(id) {
+			http.Error(w, "not our object "+string(id), http.StatusBadRequest)
+			return
+		}
+	}
+	ids, err := transport.Missing(store, known, fr.Wants)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", packType)
+	// Once the pack is being streamed the status can't change anymore, the
+	// client notices a failure by the missing end of the pack
+	_ = transport.WritePack(w, store, ids)
+}
+
+func (h *Handler) push(w http.ResponseWriter, req *http.Request, r *local.Remote) {
+	// The updates are a line of JSON before the pack
+	body := bufio.NewReader(req.Body)
+	line, err := body.ReadBytes('\n')
+	var updates []transport.Update
+	if err == nil {
+		err = json.Unmarshal(line, &updates)
+	}
+	if err != nil {
+		http.Error(w, "invalid push request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, err = transport.ReadPack(body, r.Store())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.pushMu.Lock()
+	defer h.pushMu.Unlock()
+	// The objects are stored already, so the push only checks and updates
+	// the branches
+	results, err := r.Push(r.Store(), updates)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, results)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", jsonType)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,169 @@
+package local
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"got/internal/config"
+	"got/internal/objects"
+	"got/internal/objects/disk"
+	"got/internal/pkg/filesystem"
+	"got/internal/refs"
+	"got/internal/revwalk"
+	"got/internal/transport"
+)
+
+// The directory of a repository inside its working tree
+const GotDir = ".got"
+
+// A repository on the local filesystem, e.g. in a directory shared over NFS
+type Remote struct {
+	gotDir string
+	// Set if the repository has a working tree, whose checked out branch
+	// isn't updated by pushes
+	worktree bool
+	objects  *disk.Objects
+	refs     *refs.Refs
+}
+
+// Opens the repository at path, which is either a working tree with a .got
+// directory or a bare repository. 'file://' URLs are accepted too.
+func Open(path string) (*Remote, error) {
+	path = strings.TrimPrefix(path, "file://")
+	r := &Remote{}
+	switch {
+	case isGotDir(filepath.Join(path, GotDir)):
+		r.gotDir, r.worktree = filepath.Join(path, GotDir), true
+	case isGotDir(path):
+		r.gotDir = path
+	default:
+		return nil, errors.Errorf("'%s' does not appear to be a got repository", path)
+	}
+	r.objects = disk.NewObjects(r.gotDir)
+	r.refs = refs.NewRefs(r.gotDir)
+	return r, nil
+}
+
+func isGotDir(dir string) bool {
+	return filesystem.FileExists(filepath.Join(dir, "HEAD")) &&
+		filesystem.DirExists(filepath.Join(dir, disk.ObjectsDir)) &&
+		filesystem.DirExists(filepath.Join(dir, refs.Dir, refs.HeadsDir))
+}
+
+// Returns the objects of the repository
+func (r *Remote) Store() transport.Store {
+	return r.objects
+}
+
+func (r *Remote) Advertise() (*transport.Advertisement, error) {
+	ad := &transport.Advertisement{Branches: make(map[string]objects.ID)}
+	branches, err := r.refs.Branches()
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range branches {
+		ad.Branches[b], err = r.refs.IdAtBranch(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if head := r.head(); head != "" {
+		if _, ok := ad.Branches[head]; ok {
+			ad.Head = head
+		}
+	}
+	return ad, nil
+}
+
+// Returns the branch HEAD points at, empty if it doesn't point at one. An
+// empty HEAD points at the branch the first commit or push creates.
+func (r *Remote) head() string {
+	bs, err := ioutil.ReadFile(filepath.Join(r.gotDir, "HEAD"))
+	if err != nil {
+		return ""
+	}
+	if len(bs) == 0 {
+		conf, err := config.Read(r.gotDir)
+		if err != nil {
+			return ""
+		}
+		return conf.GetString("init.defaultBranch", "master")
+	}
+	ref, err := refs.RefFromString(strings.TrimSpace(string(bs)))
+	if err != nil {
+		return ""
+	}
+	return ref.Name()
+}
+
+func (r *Remote) Fetch(dst transport.Store, wants, haves []objects.ID) error {
+	// The store is at hand, so what dst already has is known exactly
+	_, err := transport.Copy(r.objects, dst, wants)
+	return err
+}
+
+func (r *Remote) Push(src transport.Store, updates []transport.Update) ([]transport.Result, error) {
+	var wants []objects.ID
+	for _, u := range updates {
+		wants = append(wants, u.New)
+	}
+	_, err := transport.Copy(src, r.objects, wants)
+	if err != nil {
+		return nil, err
+	}
+	var results []transport.Result
+	for _, u := range updates {
+		result := transport.Result{Update: u}
+		result.Rejected, err = r.check(u)
+		if err != nil {
+			return nil, err
+		}
+		if result.Rejected == "" {
+			err = r.refs.UpdateRef(refs.Ref(filepath.Join(refs.Dir, refs.HeadsDir, u.Branch)), u.New)
+			if err != nil {
+				return nil, err
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Returns why the update has to be rejected, or an empty string if it can
+// be made
+func (r *Remote) check(u transport.Update) (string, error) {
+	if !refs.ValidBranchName(u.Branch) {
+		return "", errors.Errorf("invalid branch name '%s'", u.Branch)
+	}
+	if r.worktree && r.head() == u.Branch {
+		return transport.RejectedCheckedOut, nil
+	}
+	if u.Force {
+		return "", nil
+	}
+	var current objects.ID
+	if r.refs.BranchExists(u.Branch) {
+		var err error
+		current, err = r.refs.IdAtBranch(u.Branch)
+		if err != nil {
+			return "", err
+		}
+	}
+	if current != u.Old {
+		return transport.RejectedStale, nil
+	}
+	if current == "" {
+		return "", nil
+	}
+	ok, err := revwalk.NewWalker(r.objects).IsAncestor(current, u.New)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return transport.RejectedNonFastForward, nil
+	}
+	return "", nil
+}
@@ -0,0 +1,140 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"got/internal/objects"
+)
+
+// A pack is a stream of objects as they are stored, each preceded by a line
+// with its ID and size in bytes, and terminated by a line 'done' so that a
+// truncated pack is noticed:
+//
+//	<id> <size>\n<size bytes of the object>...done\n
+const packEnd = "done"
+
+// The largest object a pack may have, so that a pack can't make its reader
+// run out of memory
+const maxPackObjectSize = 1 << 30
+
+// Writes the objects with the given IDs from src to w as a pack
+func WritePack(w io.Writer, src Store, ids []objects.ID) error {
+	for _, id := range ids {
+		r, err := src.OpenRaw(id)
+		if err != nil {
+			return err
+		}
+		bs, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return errors.Wrapf(err, "couldn't read object %s", id)
+		}
+		_, err = fmt.Fprintf(w, "%s %d\n", id, len(bs))
+		if err == nil {
+			_, err = w.Write(bs)
+		}
+		if err != nil {
+			return errors.Wrap(err, "couldn't write pack")
+		}
+	}
+	_, err := fmt.Fprintln(w, packEnd)
+	return errors.Wrap(err, "couldn't write pack")
+}
+
+// Stores the objects of the pack read from r in dst and returns how many
+// were stored. Objects dst already has are skipped, and objects whose
+// contents don't match their ID are rejected. Objects before an error in the
+// pack stay stored, which is safe as they come after the objects they refer
+// to.
+func ReadPack(r io.Reader, dst Store) (int, error) {
+	br := bufio.NewReader(r)
+	n := 0
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return n, errors.Wrap(err, "couldn't read pack")
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == packEnd {
+			return n, nil
+		}
+		fields := strings.Split(line, " ")
+		if len(fields) != 2 {
+			return n, errors.Errorf("invalid pack header '%s'", line)
+		}
+		// The ID names the file the object is stored in, so it mustn't be
+		// anything else
+		id, err := objects.IdFromString(fields[0])
+		if err != nil || len(id) != 40 {
+			return n, errors.Errorf("invalid pack header '%s'", line)
+		}
+		size, err := strconv.Atoi(fields[1])
+		if err != nil || size < 0 {
+			return n, errors.Errorf("invalid pack header '%s'", line)
+		}
+		if size > maxPackObjectSize {
+			return n, errors.Errorf("object %s of pack is too large", id)
+		}
+		if dst.Has(id) {
+			_, err = io.CopyN(ioutil.Discard, br, int64(size))
+			if err != nil {
+				return n, errors.Wrapf(err, "couldn't read object %s from pack", id)
+			}
+			continue
+		}
+		// The buffer only grows as the object is read, however large the
+		// pack says it is
+		buf := bytes.NewBuffer(nil)
+		_, err = io.CopyN(buf, br, int64(size))
+		if err != nil {
+			return n, errors.Wrapf(err, "couldn't read object %s from pack", id)
+		}
+		sum, err := dst.HashRaw(buf.Bytes())
+		if err != nil || sum != id {
+			return n, errors.Errorf("object %s of pack doesn't match its ID", id)
+		}
+		err = dst.StoreRaw(id, buf)
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// Returns a function reporting whether an object is known to be in a
+// repository that has the commits haves, which are the commits among haves
+// that src has and the files of their trees
+func Known(src Store, haves []objects.ID) (func(objects.ID) bool, error) {
+	known := make(map[objects.ID]bool)
+	for _, id := range haves {
+		if known[id] || !src.Has(id) {
+			continue
+		}
+		c, err := src.GetCommit(id)
+		if err != nil {
+			return nil, err
+		}
+		known[id] = true
+		err = walkTree(src, c.TreeID, func(id objects.ID) bool {
+			if known[id] {
+				return false
+			}
+			known[id] = true
+			return true
+		}, func(objects.ID) {})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return func(id objects.ID) bool {
+		return known[id]
+	}, nil
+}
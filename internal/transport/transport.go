@@ -0,0 +1,187 @@
+package transport
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+
+	"got/internal/objects"
+)
+
+// The objects of a repository, which can be copied between repositories as
+// they are stored
+type Store interface {
+	objects.Objects
+
+	// Reports whether an object with the given ID is stored
+	Has(id objects.ID) bool
+
+	// Opens an object as it is stored. The caller must close the returned
+	// reader.
+	OpenRaw(id objects.ID) (io.ReadCloser, error)
+
+	// Stores an object as read from OpenRaw of another store
+	StoreRaw(id objects.ID, r io.Reader) error
+
+	// Calculates the ID of an object from the bytes it is stored as
+	HashRaw(bs []byte) (objects.ID, error)
+}
+
+// The branches of a remote repository
+type Advertisement struct {
+	// The branch HEAD of the remote points at, empty if it's detached or
+	// doesn't point at a branch with commits
+	Head string
+	// The commits the branches point at by their names, e.g. 'master'
+	Branches map[string]objects.ID
+}
+
+// A change of a branch of a remote repository
+type Update struct {
+	Branch string
+	// The commit the branch is expected to point at, empty if it's expected
+	// not to exist
+	Old objects.ID
+	New objects.ID
+	// Update the branch even if New doesn't contain Old in its history
+	Force bool
+}
+
+// The outcome of an update
+type Result struct {
+	Update
+	// Why the update was rejected, empty if it was made
+	Rejected string
+}
+
+// Why updates are rejected
+const (
+	RejectedNonFastForward = "non-fast-forward"
+	RejectedStale          = "fetch first"
+	RejectedCheckedOut     = "branch is currently checked out"
+)
+
+// A connection to a remote repository
+type Remote interface {
+	// Returns the branches of the remote
+	Advertise() (*Advertisement, error)
+
+	// Copies the objects reachable from the commits wants into dst. Haves are
+	// commits that dst already has, whose history doesn't have to be copied.
+	Fetch(dst Store, wants, haves []objects.ID) error
+
+	// Copies the objects the updates need from src and updates the branches
+	// of the remote. Every update is made or rejected on its own.
+	Push(src Store, updates []Update) ([]Result, error)
+}
+
+// Returns the IDs of the objects reachable from the commits wants for which
+// has returns false, ordered so that every object comes after the objects
+// it refers to. The history of a commit that has returns true for isn't
+// walked.
+func Missing(src objects.Objects, has func(objects.ID) bool, wants []objects.ID) ([]objects.ID, error) {
+	var ids []objects.ID
+	seen := make(map[objects.ID]bool)
+	addTree := func(id objects.ID) error {
+		return walkTree(src, id, func(id objects.ID) bool {
+			if seen[id] || has(id) {
+				return false
+			}
+			seen[id] = true
+			return true
+		}, func(id objects.ID) {
+			ids = append(ids, id)
+		})
+	}
+
+	type frame struct {
+		id objects.ID
+		// Set once the parents of the commit have been pushed
+		parentsDone bool
+		commit      objects.Commit
+	}
+	var stack []frame
+	for i := len(wants) - 1; i >= 0; i-- {
+		stack = append(stack, frame{id: wants[i]})
+	}
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if f.parentsDone {
+			err := addTree(f.commit.TreeID)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, f.id)
+			continue
+		}
+		if seen[f.id] || has(f.id) {
+			continue
+		}
+		seen[f.id] = true
+		c, err := src.GetCommit(f.id)
+		if err != nil {
+			return nil, err
+		}
+		stack = append(stack, frame{id: f.id, parentsDone: true, commit: c})
+		parents := c.Parents()
+		for i := len(parents) - 1; i >= 0; i-- {
+			if !seen[parents[i]] && !has(parents[i]) {
+				stack = append(stack, frame{id: parents[i]})
+			}
+		}
+	}
+	return ids, nil
+}
+
+// Calls add for the blobs and trees of the tree, the tree last, skipping
+// those for which visit returns false
+func walkTree(src objects.Objects, id objects.ID, visit func(objects.ID) bool, add func(objects.ID)) error {
+	if !visit(id) {
+		return nil
+	}
+	tree, err := src.GetTree(id)
+	if err != nil {
+		return err
+	}
+	for _, e := range tree.Entries {
+		if e.Type == objects.TypeTree {
+			err = walkTree(src, e.ID, visit, add)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if visit(e.ID) {
+			add(e.ID)
+		}
+	}
+	add(id)
+	return nil
+}
+
+// Copies the objects reachable from the commits wants that dst doesn't have
+// from src to dst, in an order that never leaves a commit in dst without its
+// history, and returns how many objects were copied
+func Copy(src, dst Store, wants []objects.ID) (int, error) {
+	ids, err := Missing(src, dst.Has, wants)
+	if err != nil {
+		return 0, errors.Wrap(err, "couldn't find the objects to copy")
+	}
+	for _, id := range ids {
+		err = copyObject(src, dst, id)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(ids), nil
+}
+
+func copyObject(src, dst Store, id objects.ID) error {
+	r, err := src.OpenRaw(id)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return dst.StoreRaw(id, r)
+}
@@ -0,0 +1,108 @@
+package transport
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"got/internal/objects"
+	"got/internal/objects/disk"
+	"got/internal/transport/transporttest"
+)
+
+func tempObjects(t *testing.T) (*disk.Objects, func()) {
+	dir, err := ioutil.TempDir("", "got-transport")
+	assert.Nil(t, err)
+	return disk.NewObjects(dir), func() { os.RemoveAll(dir) }
+}
+
+func TestMissingOrdersObjectsAfterTheirReferences(t *testing.T) {
+	src, cleanup := tempObjects(t)
+	defer cleanup()
+	first := transporttest.StoreCommit(t, src, "one", nil)
+	second := transporttest.StoreCommit(t, src, "two", &first)
+
+	ids, err := Missing(src, func(objects.ID) bool { return false }, []objects.ID{second})
+	assert.Nil(t, err)
+	assert.Len(t, ids, 6)
+	index := make(map[objects.ID]int)
+	for i, id := range ids {
+		index[id] = i
+	}
+	assert.True(t, index[first] < index[second])
+	c, err := src.GetCommit(second)
+	assert.Nil(t, err)
+	assert.True(t, index[c.TreeID] < index[second])
+
+	ids, err = Missing(src, func(id objects.ID) bool { return id == first }, []objects.ID{second})
+	assert.Nil(t, err)
+	assert.Len(t, ids, 3)
+	assert.Equal(t, second, ids[2])
+}
+
+func TestCopy(t *testing.T) {
+	src, cleanup := tempObjects(t)
+	defer cleanup()
+	dst, cleanupDst := tempObjects(t)
+	defer cleanupDst()
+	first := transporttest.StoreCommit(t, src, "one", nil)
+	second := transporttest.StoreCommit(t, src, "two", &first)
+
+	n, err := Copy(src, dst, []objects.ID{first})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+	n, err = Copy(src, dst, []objects.ID{second})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+
+	c, err := dst.GetCommit(second)
+	assert.Nil(t, err)
+	assert.Equal(t, []objects.ID{first}, c.Parents())
+	n, err = Copy(src, dst, []objects.ID{second})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestReadPack(t *testing.T) {
+	src, cleanup := tempObjects(t)
+	defer cleanup()
+	dst, cleanupDst := tempObjects(t)
+	defer cleanupDst()
+	first := transporttest.StoreCommit(t, src, "one", nil)
+	second := transporttest.StoreCommit(t, src, "two", &first)
+	ids, err := Missing(src, func(objects.ID) bool { return false }, []objects.ID{second})
+	assert.Nil(t, err)
+	buf := bytes.NewBuffer(nil)
+	assert.Nil(t, WritePack(buf, src, ids))
+	pack := buf.String()
+
+	n, err := ReadPack(strings.NewReader(pack), dst)
+	assert.Nil(t, err)
+	assert.Equal(t, 6, n)
+	c, err := dst.GetCommit(second)
+	assert.Nil(t, err)
+	assert.Equal(t, []objects.ID{first}, c.Parents())
+
+	// Objects that are already stored are skipped
+	n, err = ReadPack(strings.NewReader(pack), dst)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestReadPackRejectsObjectsNotMatchingTheirID(t *testing.T) {
+	dst, cleanup := tempObjects(t)
+	defer cleanup()
+	id := objects.NewBlob([]byte("one")).ID()
+	contents := "blob\x00two"
+	_, err := ReadPack(strings.NewReader(fmt.Sprintf("%s %d\n%sdone\n", id, len(contents), contents)), dst)
+	assert.EqualError(t, err, "object "+string(id)+" of pack doesn't match its ID")
+	assert.False(t, dst.Has(id))
+
+	_, err = ReadPack(strings.NewReader(fmt.Sprintf("%s %d\n", id, int64(maxPackObjectSize)+1)), dst)
+	assert.EqualError(t, err, "object "+string(id)+" of pack is too large")
+}
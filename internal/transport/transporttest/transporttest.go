@@ -0,0 +1,22 @@
+// Package transporttest has helpers for testing the transports
+package transporttest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"got/internal/objects"
+	"got/internal/objects/disk"
+)
+
+// Stores a commit of a tree with a single file of the given contents
+func StoreCommit(t *testing.T, o *disk.Objects, contents string, parent *objects.ID) objects.ID {
+	blob := objects.NewBlob([]byte(contents))
+	assert.Nil(t, o.Store(blob))
+	tree := objects.Tree{Entries: []objects.TreeEntry{{Mode: 0644, Type: objects.TypeBlob, Name: "f.txt", ID: blob.ID()}}}
+	assert.Nil(t, o.Store(tree))
+	commit := objects.NewCommit(tree.ID(), parent, "a <a@b.c>", contents)
+	assert.Nil(t, o.Store(commit))
+	return commit.ID()
+}